@@ -0,0 +1,202 @@
+package cherry
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Accepts returns the offered content type the client prefers according to
+// the Accept header, or "" when it accepts none of them. Offers are media
+// types, like application/json, or extensions, like json. Wildcards like
+// text/* and q-values are honored, ties are won by the offer that comes
+// first and without an Accept header the first offer is returned.
+//
+//	switch ctx.Accepts("json", "html") {
+//	case "json":
+//		return ctx.JSON(http.StatusOK, user)
+//	case "html":
+//		return ctx.HTML(http.StatusOK, "user.html", user)
+//	}
+//	return cherry.NewHTTPError(http.StatusNotAcceptable)
+func (c *Context) Accepts(offers ...string) string {
+	return negotiate(c.Request().Header.Values("Accept"), offers, matching(matchMediaType))
+}
+
+// AcceptsEncodings returns the offered content coding the client prefers
+// according to the Accept-Encoding header, or "" when it accepts none of
+// them. The identity coding is acceptable unless it is excluded.
+//
+// ctx.AcceptsEncodings("br", "gzip", "identity").
+func (c *Context) AcceptsEncodings(offers ...string) string {
+	return negotiate(c.Request().Header.Values("Accept-Encoding"), offers, encodingQuality)
+}
+
+// AcceptsCharsets returns the offered charset the client prefers according
+// to the Accept-Charset header, or "" when it accepts none of them.
+//
+// ctx.AcceptsCharsets("utf-8", "iso-8859-1").
+func (c *Context) AcceptsCharsets(offers ...string) string {
+	return negotiate(c.Request().Header.Values("Accept-Charset"), offers, matching(matchToken))
+}
+
+// AcceptsLanguages returns the offered language the client prefers
+// according to the Accept-Language header, or "" when it accepts none of
+// them. A language matches its regional variants and the other way around,
+// en matches en-US, with a lower precedence than an exact match. See
+// Context.Locale for matching with CLDR language data.
+//
+// ctx.AcceptsLanguages("en", "fr", "pt-BR").
+func (c *Context) AcceptsLanguages(offers ...string) string {
+	return negotiate(c.Request().Header.Values("Accept-Language"), offers, matching(matchLanguage))
+}
+
+// acceptElem is an element of an Accept-* header.
+type acceptElem struct {
+	value  string
+	params map[string]string
+	q      float64
+}
+
+// parseAccept parses the comma separated elements of Accept-* headers.
+// Values are lowercased and q-values are clamped to [0, 1].
+func parseAccept(headers []string) []acceptElem {
+	var elems []acceptElem
+	for _, header := range headers {
+		for _, s := range strings.Split(header, ",") {
+			value, params, _ := strings.Cut(s, ";")
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value == "" {
+				continue
+			}
+			e := acceptElem{value: value, q: 1}
+			for _, param := range strings.Split(params, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok {
+					continue
+				}
+				k, v = strings.ToLower(strings.TrimSpace(k)), strings.Trim(strings.TrimSpace(v), `"`)
+				if k != "q" {
+					if e.params == nil {
+						e.params = map[string]string{}
+					}
+					e.params[k] = v
+					continue
+				}
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					e.q = min(max(f, 0), 1)
+				}
+			}
+			elems = append(elems, e)
+		}
+	}
+	return elems
+}
+
+// negotiate returns the offer with the highest quality in the headers.
+// Ties are won by the offer that comes first.
+func negotiate(headers []string, offers []string, quality func(elems []acceptElem, offer string) float64) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if len(headers) == 0 {
+		return offers[0]
+	}
+	elems := parseAccept(headers)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(elems, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// matching returns a quality function that takes the quality of the most
+// specific element that matches the offer. match returns the specificity
+// of a match, or -1.
+func matching(match func(e acceptElem, offer string) int) func(elems []acceptElem, offer string) float64 {
+	return func(elems []acceptElem, offer string) float64 {
+		q, specificity := 0.0, -1
+		for _, e := range elems {
+			if s := match(e, offer); s > specificity {
+				q, specificity = e.q, s
+			}
+		}
+		return q
+	}
+}
+
+// matchToken matches tokens case-insensitively, * matches any.
+func matchToken(e acceptElem, offer string) int {
+	switch {
+	case e.value == strings.ToLower(offer):
+		return 1
+	case e.value == "*":
+		return 0
+	}
+	return -1
+}
+
+var tokenQuality = matching(matchToken)
+
+// encodingQuality returns the quality of a content coding. The identity
+// coding is acceptable with the lowest quality unless the elements exclude
+// it, with identity;q=0 or *;q=0.
+func encodingQuality(elems []acceptElem, encoding string) float64 {
+	q := tokenQuality(elems, encoding)
+	if q == 0 && strings.EqualFold(encoding, "identity") {
+		for _, e := range elems {
+			if e.value == "identity" || e.value == "*" {
+				return q
+			}
+		}
+		return 0.001
+	}
+	return q
+}
+
+// matchLanguage matches a language with itself, its regional variants and
+// its base language.
+func matchLanguage(e acceptElem, offer string) int {
+	offer = strings.ToLower(offer)
+	switch {
+	case e.value == offer:
+		return 3
+	case strings.HasPrefix(offer, e.value+"-"):
+		return 2
+	case strings.HasPrefix(e.value, offer+"-"):
+		return 1
+	case e.value == "*":
+		return 0
+	}
+	return -1
+}
+
+// matchMediaType matches a media type, an extension offer is matched with
+// its media type. Parameters of the element must be present in the offer.
+func matchMediaType(e acceptElem, offer string) int {
+	if !strings.Contains(offer, "/") {
+		offer = mime.TypeByExtension("." + offer)
+	}
+	mediaType, params, err := mime.ParseMediaType(offer)
+	if err != nil {
+		return -1
+	}
+	for k, v := range e.params {
+		if !strings.EqualFold(params[k], v) {
+			return -1
+		}
+	}
+	typ, sub, _ := strings.Cut(mediaType, "/")
+	eTyp, eSub, _ := strings.Cut(e.value, "/")
+	switch {
+	case eTyp == typ && eSub == sub:
+		return 2 + len(e.params)
+	case eTyp == typ && eSub == "*":
+		return 1
+	case eTyp == "*" && eSub == "*":
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,58 @@
+package cherry
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		header string
+		value  string
+		offers []string
+		expect string
+	}{
+		{"Accept", "", []string{"json", "html"}, "json"},
+		{"Accept", "text/html", []string{"json", "html"}, "html"},
+		{"Accept", "application/json;q=0.5, text/*", []string{"application/json", "text/plain"}, "text/plain"},
+		{"Accept", "text/*, text/plain;q=0", []string{"text/plain", "text/csv"}, "text/csv"},
+		{"Accept", "*/*;q=0.1, application/json", []string{"text/html", "json"}, "json"},
+		{"Accept", "text/html;level=1", []string{"text/html", "text/html;level=1"}, "text/html;level=1"},
+		{"Accept", "image/png", []string{"json", "html"}, ""},
+		{"Accept", "application/json;q=1.5, text/html;q=invalid", []string{"json", "html"}, "json"},
+		{"Accept-Encoding", "gzip;q=0.8, br", []string{"gzip", "br"}, "br"},
+		{"Accept-Encoding", "gzip", []string{"br", "identity"}, "identity"},
+		{"Accept-Encoding", "gzip, identity;q=0", []string{"br", "identity"}, ""},
+		{"Accept-Encoding", "*;q=0", []string{"identity"}, ""},
+		{"Accept-Charset", "iso-8859-1, utf-8;q=0.7", []string{"UTF-8", "ISO-8859-1"}, "ISO-8859-1"},
+		{"Accept-Charset", "*", []string{"utf-8"}, "utf-8"},
+		{"Accept-Language", "fr-CH, fr;q=0.9, en;q=0.8", []string{"en", "fr"}, "fr"},
+		{"Accept-Language", "en", []string{"de", "en-US"}, "en-US"},
+		{"Accept-Language", "en-GB, pt-BR;q=0.5", []string{"en-US", "pt-BR"}, "pt-BR"},
+		{"Accept-Language", "da", []string{"en"}, ""},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.value != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		ctx := &Context{request: r}
+		var got string
+		switch test.header {
+		case "Accept":
+			got = ctx.Accepts(test.offers...)
+		case "Accept-Encoding":
+			got = ctx.AcceptsEncodings(test.offers...)
+		case "Accept-Charset":
+			got = ctx.AcceptsCharsets(test.offers...)
+		case "Accept-Language":
+			got = ctx.AcceptsLanguages(test.offers...)
+		}
+		if got != test.expect {
+			t.Errorf("%s %q %v: expecting %q got %q", test.header, test.value, test.offers, test.expect, got)
+		}
+	}
+	if got := (&Context{request: httptest.NewRequest("GET", "/", nil)}).Accepts(); got != "" {
+		t.Errorf("expecting no offer got %q", got)
+	}
+}
@@ -0,0 +1,195 @@
+package cherry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// AccessLogEntry holds the details of a request that are written to the
+// access-log.
+type AccessLogEntry struct {
+	Time    time.Time
+	Latency time.Duration
+	// Host is the host of the request, without the port.
+	Host string
+	// RemoteAddr is the address of the client, without the port.
+	RemoteAddr string
+	User       string
+	Method     string
+	URI        string
+	Proto      string
+	Status     int
+	Size       int
+	// Route is the pattern of the matched route, empty when no route matched.
+	Route string
+	// RequestID is taken from the X-Request-ID header of the request or
+	// the response.
+	RequestID string
+	UserAgent string
+	Referer   string
+}
+
+// AccessLogFormat writes an AccessLogEntry to w, followed by a newline.
+type AccessLogFormat func(w io.Writer, e *AccessLogEntry)
+
+// Predefined access-log formats.
+var (
+	// LogCommon is the Apache common log format, starting with the host of
+	// the request.
+	//
+	// localhost - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+	LogCommon AccessLogFormat = func(w io.Writer, e *AccessLogEntry) {
+		buf := bufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer bufferPool.Put(buf)
+		buf.Grow(256)
+		b := appendCommon(buf.AvailableBuffer(), e)
+		w.Write(append(b, '\n'))
+	}
+
+	// LogCombined is the Apache combined log format, which adds the referer
+	// and user agent to LogCommon, followed by the latency in milliseconds
+	// and the address of the client.
+	LogCombined AccessLogFormat = func(w io.Writer, e *AccessLogEntry) {
+		buf := bufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer bufferPool.Put(buf)
+		buf.Grow(256)
+		b := appendCommon(buf.AvailableBuffer(), e)
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, orDash(e.Referer))
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, orDash(e.UserAgent))
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, float64(e.Latency)/float64(time.Millisecond), 'f', 3, 64)
+		b = append(b, ' ')
+		b = append(b, e.RemoteAddr...)
+		w.Write(append(b, '\n'))
+	}
+
+	// LogJSON writes every entry as a JSON object on a single line.
+	LogJSON AccessLogFormat = func(w io.Writer, e *AccessLogEntry) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"time":        e.Time.Format(time.RFC3339),
+			"latency_ms":  float64(e.Latency) / float64(time.Millisecond),
+			"host":        e.Host,
+			"remote_addr": e.RemoteAddr,
+			"user":        e.User,
+			"method":      e.Method,
+			"uri":         e.URI,
+			"route":       e.Route,
+			"proto":       e.Proto,
+			"status":      e.Status,
+			"size":        e.Size,
+			"request_id":  e.RequestID,
+			"user_agent":  e.UserAgent,
+			"referer":     e.Referer,
+		})
+	}
+)
+
+const commonLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// appendCommon appends the entry in the common log format to b, without a
+// newline. The formats append to a pooled buffer and write it at once,
+// which avoids the allocations of fmt on the hot path.
+func appendCommon(b []byte, e *AccessLogEntry) []byte {
+	b = append(b, e.Host...)
+	b = append(b, " - "...)
+	b = append(b, e.User...)
+	b = append(b, " ["...)
+	b = e.Time.AppendFormat(b, commonLogTime)
+	b = append(b, "] \""...)
+	b = append(b, e.Method...)
+	b = append(b, ' ')
+	b = append(b, e.URI...)
+	b = append(b, ' ')
+	b = append(b, e.Proto...)
+	b = append(b, "\" "...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	return strconv.AppendInt(b, int64(e.Size), 10)
+}
+
+// LogTemplate returns an AccessLogFormat that executes a text/template with
+// the AccessLogEntry as its data.
+//
+// cherry.LogTemplate(`{{.Method}} {{.Route}} {{.Status}} {{.Latency}} {{.RequestID}}`)
+func LogTemplate(text string) (AccessLogFormat, error) {
+	tmpl, err := template.New("access-log").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, e *AccessLogEntry) {
+		buf := bufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer bufferPool.Put(buf)
+		if err := tmpl.Execute(buf, e); err != nil {
+			return
+		}
+		buf.WriteByte('\n')
+		w.Write(buf.Bytes())
+	}, nil
+}
+
+// SetAccessLogFormat enables the access-log and sets its format.
+//
+// app.SetAccessLogFormat(cherry.LogCombined).
+func (c *Cherry) SetAccessLogFormat(format AccessLogFormat) {
+	c.HasAccessLog = true
+	c.AccessLogFormat = format
+}
+
+func newAccessLogEntry(r *http.Request, start time.Time, l *responseLogger) *AccessLogEntry {
+	e := &AccessLogEntry{
+		Time:       start,
+		Latency:    time.Since(start),
+		Host:       "-",
+		RemoteAddr: "-",
+		User:       "-",
+		Method:     r.Method,
+		URI:        r.RequestURI,
+		Proto:      r.Proto,
+		Status:     l.Status(),
+		Size:       l.Size(),
+		Route:      l.route,
+		RequestID:  r.Header.Get("X-Request-ID"),
+		UserAgent:  r.UserAgent(),
+		Referer:    r.Referer(),
+	}
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		e.Host = host
+	} else if r.Host != "" {
+		e.Host = r.Host
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		e.RemoteAddr = host
+	} else if r.RemoteAddr != "" {
+		e.RemoteAddr = r.RemoteAddr
+	}
+	if r.URL.User != nil {
+		if name := r.URL.User.Username(); name != "" {
+			e.User = name
+		}
+	}
+	if e.URI == "" {
+		e.URI = r.URL.RequestURI()
+	}
+	if e.RequestID == "" {
+		e.RequestID = l.Header().Get("X-Request-ID")
+	}
+	return e
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
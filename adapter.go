@@ -0,0 +1,114 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// WrapHandler adapts an http.Handler to a Handler, so existing handlers can
+// be registered on a route or used as middleware of a Group. The url
+// parameters of the route are available to h through
+// httprouter.ParamsFromContext, like for routes registered with Handle.
+//
+// app.Get("/legacy/:id", cherry.WrapHandler(legacyHandler)).
+func WrapHandler(h http.Handler) Handler {
+	return func(ctx *Context) error {
+		r := ctx.Request()
+		if len(ctx.vars) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, ctx.vars))
+		}
+		h.ServeHTTP(ctx.Response(), r)
+		return nil
+	}
+}
+
+// WrapHandlerFunc adapts an http.HandlerFunc to a Handler, see WrapHandler.
+func WrapHandlerFunc(fn http.HandlerFunc) Handler {
+	return WrapHandler(fn)
+}
+
+// HandlerFor returns an http.Handler that serves the Cherry route
+// registered with the given pattern, including its middleware and error
+// handler, so it can be reused in a standard library mux. The handler of
+// the request method is used, other methods are answered with a 405. The
+// url parameters are parsed from the request path against the pattern, a
+// path that does not match the pattern is answered with a 404.
+//
+// mux.Handle("/users/", app.HandlerFor("/users/:id")).
+func (c *Cherry) HandlerFor(route string) http.Handler {
+	pattern := path.Join(c.prefix, route)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params, ok := matchParams(pattern, r.URL.Path)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		handle, ok := c.handles.get(pattern, r.Method)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		handle(w, r, params)
+	})
+}
+
+// handles holds the router handles by pattern and method, it is shared by
+// the application and all of its groups.
+type handles struct {
+	mu sync.RWMutex
+	m  map[string]map[string]httprouter.Handle
+}
+
+func (h *handles) add(pattern, method string, handle httprouter.Handle) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.m == nil {
+		h.m = map[string]map[string]httprouter.Handle{}
+	}
+	if h.m[pattern] == nil {
+		h.m[pattern] = map[string]httprouter.Handle{}
+	}
+	h.m[pattern][method] = handle
+}
+
+func (h *handles) get(pattern, method string) (httprouter.Handle, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	handle, ok := h.m[pattern][method]
+	if !ok && method == http.MethodHead {
+		handle, ok = h.m[pattern][http.MethodGet]
+	}
+	return handle, ok
+}
+
+// matchParams matches p against pattern like the router does and returns
+// the values of its :name and *name segments. It reports false when a static
+// segment or the number of segments differs, or a :name segment is empty.
+func matchParams(pattern, p string) (httprouter.Params, bool) {
+	var params httprouter.Params
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, s := range segments {
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(s, ":"):
+			if parts[i] == "" {
+				return nil, false
+			}
+			params = append(params, httprouter.Param{Key: s[1:], Value: parts[i]})
+		case strings.HasPrefix(s, "*"):
+			params = append(params, httprouter.Param{Key: s[1:], Value: "/" + strings.Join(parts[i:], "/")})
+			return params, true
+		case s != parts[i]:
+			return nil, false
+		}
+	}
+	return params, len(parts) == len(segments)
+}
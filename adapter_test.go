@@ -0,0 +1,89 @@
+package cherry
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestWrapHandler(t *testing.T) {
+	c := New()
+	c.Get("/legacy/:id", WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := httprouter.ParamsFromContext(r.Context()).ByName("id")
+		w.Write([]byte("legacy " + id))
+	})))
+	c.Get("/func", WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("func"))
+	}))
+
+	code, body := doRequest(t, "GET", "/legacy/42", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "legacy 42" {
+		t.Errorf("expecting legacy 42 got %s", body)
+	}
+	if _, body := doRequest(t, "GET", "/func", nil, c); body != "func" {
+		t.Errorf("expecting func got %s", body)
+	}
+}
+
+func TestHandlerFor(t *testing.T) {
+	c := New()
+	api := c.Group("/api")
+	api.Use(func(ctx *Context) error {
+		ctx.Response().Header().Set("X-Middleware", "yes")
+		return nil
+	})
+	api.Get("/users/:id", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "user "+ctx.Param("id"))
+	})
+	api.Get("/files/*path", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.Param("path"))
+	})
+	api.Post("/fail", func(ctx *Context) error {
+		return errors.New("failed")
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/users/", api.HandlerFor("/users/:id"))
+	mux.Handle("/api/files/", c.HandlerFor("/api/files/*path"))
+	mux.Handle("/api/fail", api.HandlerFor("/fail"))
+	mux.Handle("/api/fail/", api.HandlerFor("/fail"))
+	mux.Handle("/accounts/", api.HandlerFor("/users/:id"))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"GET", "/api/users/42", http.StatusOK, "user 42"},
+		{"GET", "/api/files/css/site.css", http.StatusOK, "/css/site.css"},
+		{"POST", "/api/users/42", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+		{"POST", "/api/fail", http.StatusInternalServerError, "failed\n"},
+		{"GET", "/accounts/1", http.StatusNotFound, "Not Found\n"},
+		{"GET", "/api/users/1/extra", http.StatusNotFound, "Not Found\n"},
+		{"GET", "/api/users/", http.StatusNotFound, "Not Found\n"},
+		{"GET", "/api/fail/", http.StatusNotFound, "Not Found\n"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, srv.URL+test.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.code || string(b) != test.body {
+			t.Errorf("%s %s: expecting %d %q got %d %q", test.method, test.path, test.code, test.body, resp.StatusCode, b)
+		}
+		if test.code == http.StatusOK && resp.Header.Get("X-Middleware") != "yes" {
+			t.Errorf("%s: expecting the middleware to run", test.path)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cherry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Assets serves static files under content addressed paths, like
+// /assets/css/app.3f2a1b9c04.css. Because the path changes whenever the
+// content changes, the files are served with an immutable Cache-Control
+// header. Use Path, or the "asset" template function, to resolve the URL of
+// a file.
+type Assets struct {
+	prefix string
+	// paths maps the names of the files to their fingerprinted names.
+	paths map[string]string
+	// files maps the fingerprinted names back to the files in the fs.FS.
+	files map[string]string
+}
+
+// Assets hashes all the files of fsys and serves them under prefix with
+// their fingerprinted names. The files can also still be requested by their
+// plain name, without the immutable caching.
+//
+//	assets, err := app.Assets("/assets", os.DirFS("public"))
+//	tmpl := template.New("").Funcs(assets.FuncMap())
+//	// {{asset "css/app.css"}} => /assets/css/app.3f2a1b9c04.css
+func (c *Cherry) Assets(prefix string, fsys fs.FS, opts ...StaticOption) (*Assets, error) {
+	a := newAssets(c.prefix, prefix)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		a.add(name, fingerprint(name, sum), name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.serveAssets(a, prefix, fsys, opts)
+	return a, nil
+}
+
+// AssetsFromManifest serves the files of fsys under prefix and resolves the
+// fingerprinted names from a manifest generated by a bundler. The manifest
+// is a JSON object mapping the plain names to the fingerprinted ones, which
+// must exist in fsys.
+//
+// {"css/app.css": "css/app.3f2a1b9c04.css"}
+func (c *Cherry) AssetsFromManifest(prefix string, fsys fs.FS, manifest string, opts ...StaticOption) (*Assets, error) {
+	b, err := fs.ReadFile(fsys, manifest)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	a := newAssets(c.prefix, prefix)
+	for name, hashed := range m {
+		hashed = strings.TrimPrefix(path.Clean("/"+hashed), "/")
+		a.add(strings.TrimPrefix(path.Clean("/"+name), "/"), hashed, hashed)
+	}
+	c.serveAssets(a, prefix, fsys, opts)
+	return a, nil
+}
+
+func newAssets(groupPrefix, prefix string) *Assets {
+	return &Assets{
+		prefix: path.Join("/", groupPrefix, prefix),
+		paths:  map[string]string{},
+		files:  map[string]string{},
+	}
+}
+
+func (a *Assets) add(name, hashed, file string) {
+	a.paths[name] = hashed
+	a.files[hashed] = file
+}
+
+func (c *Cherry) serveAssets(a *Assets, prefix string, fsys fs.FS, opts []StaticOption) {
+	h := c.staticHandler(http.FS(fsys), opts, func(ctx *Context) string {
+		name := strings.TrimPrefix(ctx.Param("filepath"), "/")
+		if file, ok := a.files[name]; ok {
+			ctx.Response().Header().Set("Cache-Control", Immutable.String())
+			return file
+		}
+		return name
+	})
+	route := path.Join(prefix, "*filepath")
+	c.add("GET", route, h)
+	c.add("HEAD", route, h)
+}
+
+// Path returns the URL of the named file. Unknown files resolve to their
+// plain URL.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if hashed, ok := a.paths[name]; ok {
+		return path.Join(a.prefix, hashed)
+	}
+	return path.Join(a.prefix, name)
+}
+
+// FuncMap returns the template functions of the asset helper, containing
+// "asset" which resolves to Path.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": a.Path,
+	}
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:10], nil
+}
+
+// fingerprint inserts sum before the extension of name.
+func fingerprint(name, sum string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + sum + ext
+}
@@ -0,0 +1,103 @@
+package cherry
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAssets(t *testing.T) {
+	c := New()
+	assets, err := c.Assets("/assets", fstest.MapFS{
+		"css/app.css": &fstest.MapFile{Data: []byte("body{}")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := assets.Path("css/app.css")
+	if !regexp.MustCompile(`^/assets/css/app\.[0-9a-f]{10}\.css$`).MatchString(url) {
+		t.Fatalf("unexpected asset path %s", url)
+	}
+	r, _ := http.NewRequest("GET", url, nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Body.String() != "body{}" {
+		t.Errorf("expecting body{} got %s", rw.Body.String())
+	}
+	if rw.Header().Get("Cache-Control") != Immutable.String() {
+		t.Errorf("expecting immutable caching got %s", rw.Header().Get("Cache-Control"))
+	}
+
+	r, _ = http.NewRequest("GET", "/assets/css/app.css", nil)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Header().Get("Cache-Control") != "" {
+		t.Error("expecting no immutable caching for the plain name")
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("").Funcs(assets.FuncMap()).Parse(`{{asset "/css/app.css"}}`))
+	if err := tmpl.Execute(buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != url {
+		t.Errorf("expecting %s got %s", url, buf.String())
+	}
+}
+
+func TestAssetsFromManifest(t *testing.T) {
+	c := New()
+	assets, err := c.AssetsFromManifest("/static", fstest.MapFS{
+		"manifest.json":    &fstest.MapFile{Data: []byte(`{"app.js": "app.abc123.js"}`)},
+		"app.abc123.js":    &fstest.MapFile{Data: []byte("js")},
+		"unversioned.html": &fstest.MapFile{Data: []byte("html")},
+	}, "manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := assets.Path("app.js"); p != "/static/app.abc123.js" {
+		t.Fatalf("unexpected asset path %s", p)
+	}
+	if p := assets.Path("unversioned.html"); p != "/static/unversioned.html" {
+		t.Fatalf("unexpected asset path %s", p)
+	}
+	code, body := doRequest(t, "GET", "/static/app.abc123.js", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "js" {
+		t.Errorf("expecting js got %s", body)
+	}
+}
+
+func TestAssetsMaxAge(t *testing.T) {
+	c := New()
+	assets, err := c.Assets("/assets", fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte("js")},
+	}, WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := http.NewRequest("GET", assets.Path("app.js"), nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Header().Get("Cache-Control") != Immutable.String() {
+		t.Errorf("expecting immutable caching got %s", rw.Header().Get("Cache-Control"))
+	}
+
+	r, _ = http.NewRequest("GET", "/assets/app.js", nil)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("expecting the max-age for the plain name got %s", rw.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,125 @@
+package cherry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UserProvider connects the users of an application to Context.Login and
+// Context.User, which keep the id of the logged in user in the session.
+type UserProvider interface {
+	// UserID returns the id of a user given to Login.
+	UserID(user any) string
+	// LoadUser returns the user with the id, or nil when the user no longer
+	// exists.
+	LoadUser(ctx context.Context, id string) (any, error)
+}
+
+// currentUser caches the user of a request.
+type currentUser struct {
+	user   any
+	loaded bool
+}
+
+// Login logs the user in, the id of the user is kept in the session. The
+// session gets a new id, so an id planted before the login (session
+// fixation) does not get the privileges of the user.
+//
+// err := ctx.Login(user).
+func (c *Context) Login(user any) error {
+	if c.cherry == nil || c.cherry.Users == nil {
+		return fmt.Errorf("cherry: Login requires a UserProvider in app.Users")
+	}
+	session := c.Session()
+	session.Regenerate()
+	session.Set(SessionUserKey, c.cherry.Users.UserID(user))
+	session.Delete(sessionRemembered)
+	c.current().user, c.current().loaded = user, true
+	return nil
+}
+
+// Logout logs the user out, it destroys the session and ends the
+// remember-me series of the request.
+func (c *Context) Logout() {
+	c.Forget()
+	c.Session().Destroy()
+	c.current().user, c.current().loaded = nil, true
+}
+
+// User returns the logged in user, or nil. The user is loaded once per
+// request by the UserProvider of app.Users, errors are logged.
+//
+// user, _ := ctx.User().(*User).
+func (c *Context) User() any {
+	user, err := c.loadUser()
+	if err != nil {
+		if c.cherry != nil {
+			c.cherry.logError(c, err)
+		}
+		return nil
+	}
+	return user
+}
+
+func (c *Context) current() *currentUser {
+	u, ok := Value[*currentUser](c)
+	if !ok {
+		u = &currentUser{}
+		WithValue(c, u)
+	}
+	return u
+}
+
+func (c *Context) loadUser() (any, error) {
+	u := c.current()
+	if u.loaded {
+		return u.user, nil
+	}
+	if c.cherry == nil || c.cherry.Users == nil {
+		return nil, nil
+	}
+	id, _ := c.Session().Get(SessionUserKey).(string)
+	if id == "" {
+		u.loaded = true
+		return nil, nil
+	}
+	user, err := c.cherry.Users.LoadUser(c.Request().Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// the user was deleted since the login.
+		c.Session().Delete(SessionUserKey)
+	}
+	u.user, u.loaded = user, true
+	return user, nil
+}
+
+// RequireLogin returns a middleware Handler that only lets the logged in
+// users through. The others are redirected to redirectTo with the requested
+// URL in the next query parameter, or get a 401 when redirectTo is empty.
+//
+// app.Group("/account").Use(cherry.RequireLogin("/login")).
+func RequireLogin(redirectTo string) Handler {
+	return func(ctx *Context) error {
+		user, err := ctx.loadUser()
+		if err != nil {
+			return err
+		}
+		if user != nil {
+			return nil
+		}
+		if redirectTo == "" {
+			return NewHTTPError(http.StatusUnauthorized)
+		}
+		sep := "?"
+		if strings.Contains(redirectTo, "?") {
+			sep = "&"
+		}
+		ctx.Abort()
+		return ctx.Redirect(redirectTo+sep+"next="+url.QueryEscape(ctx.Request().URL.RequestURI()), http.StatusSeeOther)
+	}
+}
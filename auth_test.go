@@ -0,0 +1,134 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type authUser struct {
+	ID   string
+	Name string
+}
+
+type authUsers map[string]*authUser
+
+func (u authUsers) UserID(user any) string {
+	return user.(*authUser).ID
+}
+
+func (u authUsers) LoadUser(ctx context.Context, id string) (any, error) {
+	if id == "broken" {
+		return nil, errors.New("database is down")
+	}
+	if user, ok := u[id]; ok {
+		return user, nil
+	}
+	return nil, nil
+}
+
+func TestLogin(t *testing.T) {
+	users := authUsers{"1": {ID: "1", Name: "ann"}}
+	c := New()
+	c.Users = users
+	c.Use(Sessions(SessionConfig{}))
+	c.Post("/login", func(ctx *Context) error {
+		if err := ctx.Login(users["1"]); err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, ctx.User().(*authUser).Name)
+	})
+	c.Post("/logout", func(ctx *Context) error {
+		ctx.Logout()
+		if ctx.User() != nil {
+			t.Error("expecting no user after Logout")
+		}
+		return nil
+	})
+	account := c.Group("/account")
+	account.Use(RequireLogin("/login"))
+	account.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "hello "+ctx.User().(*authUser).Name)
+	})
+	c.Get("/api/me", Chain(func(ctx *Context) error {
+		return ctx.JSON(http.StatusOK, ctx.User())
+	}, RequireLogin("")))
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/account?tab=1", nil))
+	if rw.Code != http.StatusSeeOther || rw.Header().Get("Location") != "/login?next=%2Faccount%3Ftab%3D1" {
+		t.Errorf("expecting a redirect to the login got %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+	if code, _ := doRequest(t, "GET", "/api/me", nil, c); code != http.StatusUnauthorized {
+		t.Errorf("expecting 401 without a redirect got %d", code)
+	}
+
+	fixed := &http.Cookie{Name: "session", Value: "fixed"}
+	r := httptest.NewRequest("POST", "/login", nil)
+	r.AddCookie(fixed)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Body.String() != "ann" {
+		t.Fatalf("expecting the user to be logged in got %q", rw.Body)
+	}
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value == "fixed" {
+		t.Fatalf("expecting a new session id got %v", cookies)
+	}
+	session := cookies[0]
+
+	r = httptest.NewRequest("GET", "/account", nil)
+	r.AddCookie(session)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Body.String() != "hello ann" {
+		t.Errorf("expecting the user of the session got %d %q", rw.Code, rw.Body)
+	}
+
+	r = httptest.NewRequest("POST", "/logout", nil)
+	r.AddCookie(session)
+	c.ServeHTTP(httptest.NewRecorder(), r)
+	r = httptest.NewRequest("GET", "/account", nil)
+	r.AddCookie(session)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusSeeOther {
+		t.Errorf("expecting the session to be logged out got %d", rw.Code)
+	}
+}
+
+func TestRequireLoginErrors(t *testing.T) {
+	store := NewMemorySessionStore()
+	store.Set("s1", map[string]any{SessionUserKey: "broken"}, time.Hour)
+	store.Set("s2", map[string]any{SessionUserKey: "deleted"}, time.Hour)
+	c := New()
+	c.Users = authUsers{}
+	c.Use(Sessions(SessionConfig{Store: store}))
+	c.Get("/", Chain(noopHandler, RequireLogin("")))
+
+	for id, code := range map[string]int{"s1": http.StatusInternalServerError, "s2": http.StatusUnauthorized} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: id})
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != code {
+			t.Errorf("expecting %d for session %s got %d", code, id, rw.Code)
+		}
+	}
+	if data, _ := store.Get("s2"); data[SessionUserKey] != nil {
+		t.Errorf("expecting a deleted user to be logged out got %v", data)
+	}
+}
+
+func TestLoginWithoutProvider(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Login(&authUser{ID: "1"})
+	})
+	if code, _ := doRequest(t, "GET", "/", nil, c); code != http.StatusInternalServerError {
+		t.Errorf("expecting Login to fail without app.Users got %d", code)
+	}
+}
@@ -0,0 +1,245 @@
+package cherry
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Validator is implemented by the inputs of Bind that check themselves
+// after they were bound.
+type Validator interface {
+	Validate() error
+}
+
+// Bind decodes the request into v, which must be a pointer to a struct.
+// A JSON body is decoded first, then the fields tagged with param, query,
+// header or form are set from the url parameters, the query string, the
+// request headers and the form values. When v implements Validator it is
+// validated last.
+//
+// Decoding errors are returned as a 400 HTTPError and validation errors as
+// a 422 HTTPError, so the error handler responds with them as is. The
+// messages of a FieldError or ValidationErrors, also of a value that is not
+// of the type of its field, are in the language of the request, see
+// Context.ErrorMessage.
+//
+//	type input struct {
+//		ID    int    `param:"id"`
+//		Limit int    `query:"limit"`
+//		Name  string `json:"name"`
+//	}
+func (c *Context) Bind(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cherry: Bind expects a pointer to a struct got %T", v)
+	}
+	if err := c.bindBody(v); err != nil {
+		return err
+	}
+	if err := c.bindFields(rv.Elem()); err != nil {
+		var he *HTTPError
+		if errors.As(err, &he) {
+			return err
+		}
+		return NewHTTPError(http.StatusBadRequest, c.ErrorMessage(err))
+	}
+	if val, ok := v.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			var he *HTTPError
+			var errs ValidationErrors
+			switch {
+			case errors.As(err, &he):
+				return err
+			case errors.As(err, &errs) && len(errs) == 0:
+				return nil
+			}
+			return NewHTTPError(http.StatusUnprocessableEntity, c.ErrorMessage(err))
+		}
+	}
+	return nil
+}
+
+// bindBody decodes a JSON request body into v. Form bodies are bound by
+// field in bindFields.
+func (c *Context) bindBody(v any) error {
+	r := c.Request()
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case ct == "application/json" || (len(ct) > 5 && ct[len(ct)-5:] == "+json"):
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+			var he *HTTPError
+			if errors.As(err, &he) {
+				return err
+			}
+			return NewHTTPError(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		}
+	case ct == "application/x-www-form-urlencoded", ct == "multipart/form-data":
+	case ct == "":
+	default:
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	return nil
+}
+
+// bindSources are the struct tags understood by Bind, in order of
+// precedence: a url parameter overrides a query value and so on.
+var bindSources = []string{"form", "header", "query", "param"}
+
+func (c *Context) bindFields(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := c.bindFields(v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, source := range bindSources {
+			name := field.Tag.Get(source)
+			if name == "" || name == "-" {
+				continue
+			}
+			values, err := c.bindValues(source, name)
+			if err != nil {
+				return err
+			}
+			if len(values) == 0 {
+				continue
+			}
+			if err := setValue(v.Field(i), values); err != nil {
+				return &FieldError{Field: name, Tag: "type", Param: typeName(field.Type), Value: values[0]}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Context) bindValues(source, name string) ([]string, error) {
+	r := c.Request()
+	switch source {
+	case "param":
+		for _, p := range c.vars {
+			if p.Key == name {
+				return []string{p.Value}, nil
+			}
+		}
+	case "query":
+		return c.queryValues()[name], nil
+	case "header":
+		return r.Header.Values(name), nil
+	case "form":
+		if err := c.parseForm(); err != nil {
+			return nil, err
+		}
+		return r.PostForm[name], nil
+	}
+	return nil, nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// typeName names the type of a field in the messages of binding errors.
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer || (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return t.Name()
+}
+
+// setValue sets f from the string values, slices take all of them and any
+// other kind the first one.
+func setValue(f reflect.Value, values []string) error {
+	if f.Kind() == reflect.Slice && f.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(f.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(f.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setString(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	return setString(f, values[0])
+}
+
+func setString(f reflect.Value, value string) error {
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		return setString(f.Elem(), value)
+	}
+	if f.CanAddr() && f.Addr().Type().Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cherry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindInput struct {
+	ID      int           `param:"id"`
+	Limit   int           `query:"limit"`
+	Tags    []string      `query:"tag"`
+	Timeout time.Duration `query:"timeout"`
+	Token   string        `header:"X-Token"`
+	Name    string        `json:"name" form:"name"`
+	Active  *bool         `json:"active"`
+}
+
+func (in bindInput) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func bindRequest(t *testing.T, r *http.Request) (bindInput, int, string) {
+	var in bindInput
+	c := New()
+	c.Post("/users/:id", func(ctx *Context) error {
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	return in, rw.Code, rw.Body.String()
+}
+
+func TestBind(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/users/7?limit=25&tag=a&tag=b&timeout=2s", strings.NewReader(`{"name":"toby","active":true}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Token", "secret")
+	in, code, body := bindRequest(t, r)
+	if code != http.StatusOK {
+		t.Fatalf("expecting 200 got %d: %s", code, body)
+	}
+	if in.ID != 7 || in.Limit != 25 || in.Token != "secret" || in.Name != "toby" || in.Timeout != 2*time.Second {
+		t.Errorf("unexpected input %+v", in)
+	}
+	if len(in.Tags) != 2 || in.Tags[1] != "b" {
+		t.Errorf("expecting tags a and b got %v", in.Tags)
+	}
+	if in.Active == nil || !*in.Active {
+		t.Error("expecting active to be set")
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	form := url.Values{"name": {"toby"}}
+	r, _ := http.NewRequest("POST", "/users/1", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	in, code, _ := bindRequest(t, r)
+	isHTTPStatusOK(t, code)
+	if in.Name != "toby" {
+		t.Errorf("expecting toby got %s", in.Name)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		route       string
+		contentType string
+		body        string
+		expect      int
+	}{
+		{"/users/abc", "application/json", `{"name":"toby"}`, http.StatusBadRequest},
+		{"/users/1?limit=many", "application/json", `{"name":"toby"}`, http.StatusBadRequest},
+		{"/users/1", "application/json", `{"name":`, http.StatusBadRequest},
+		{"/users/1", "application/xml", `<name>toby</name>`, http.StatusUnsupportedMediaType},
+		{"/users/1", "application/json", `{}`, http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("POST", test.route, strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		_, code, body := bindRequest(t, r)
+		if code != test.expect {
+			t.Errorf("%s %s: expecting %d got %d: %s", test.route, test.body, test.expect, code, body)
+		}
+	}
+
+	ctx := &Context{request: httptest.NewRequest("GET", "/", nil)}
+	var s string
+	if err := ctx.Bind(&s); err == nil {
+		t.Error("expecting an error when binding a string")
+	}
+}
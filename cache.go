@@ -0,0 +1,345 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored by the ResponseCache.
+type CachedResponse struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Stored  time.Time   `json:"stored"`
+	Expires time.Time   `json:"expires"`
+
+	// StaleWhileRevalidate and StaleIfError extend the lifetime of the
+	// response past Expires, see CacheOptions.
+	StaleWhileRevalidate time.Duration `json:"stale_while_revalidate,omitempty"`
+	StaleIfError         time.Duration `json:"stale_if_error,omitempty"`
+}
+
+// CacheStore is the storage backend of a ResponseCache.
+type CacheStore interface {
+	// Get returns the response stored under key.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores the response under key for the duration of ttl.
+	Set(key string, resp *CachedResponse, ttl time.Duration)
+	// Delete removes the response stored under key.
+	Delete(key string)
+	// Keys returns all the keys in the store.
+	Keys() []string
+}
+
+// CacheOptions configures a ResponseCache.
+type CacheOptions struct {
+	// TTL is used for responses that do not specify a max-age or s-maxage in
+	// their Cache-Control header. Defaults to one minute.
+	TTL time.Duration
+
+	// Vary lists the request headers that are part of the cache key.
+	// Defaults to Accept-Encoding, so compressed and plain responses are
+	// cached separately.
+	Vary []string
+
+	// StaleWhileRevalidate is the window after expiry in which a stale
+	// response is served immediately while it is refreshed in the
+	// background. Only one refresh per key runs at a time.
+	StaleWhileRevalidate time.Duration
+
+	// StaleIfError is the window after expiry in which a stale response is
+	// served when refreshing it fails with a 5xx response.
+	//
+	// Both windows can be overridden per response with the
+	// stale-while-revalidate and stale-if-error Cache-Control directives.
+	StaleIfError time.Duration
+}
+
+// ResponseCache is a server side cache for GET responses. Responses are
+// keyed by their method, path, query and Vary headers. The Cache-Control
+// headers of both the request and the response are respected: private and
+// no-store responses are never cached. Neither are event streams, upgrades
+// and responses that the handler flushes or hijacks, they are passed
+// through as they are written.
+type ResponseCache struct {
+	store CacheStore
+	opts  CacheOptions
+
+	mu           sync.Mutex
+	revalidating map[string]struct{}
+}
+
+// revalidateKey marks the requests that refresh a stale response in the
+// background.
+type revalidateKey struct{}
+
+// NewResponseCache returns a ResponseCache that keeps its responses in store.
+//
+//	cache := cherry.NewResponseCache(cherry.NewMemoryStore(), cherry.CacheOptions{TTL: time.Minute})
+//	app.Get("/reports", reportsHandler)
+//	app.Use(cache.Handler())
+func NewResponseCache(store CacheStore, opts CacheOptions) *ResponseCache {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.Vary == nil {
+		opts.Vary = []string{"Accept-Encoding"}
+	}
+	for i, h := range opts.Vary {
+		opts.Vary[i] = http.CanonicalHeaderKey(h)
+	}
+	return &ResponseCache{
+		store:        store,
+		opts:         opts,
+		revalidating: map[string]struct{}{},
+	}
+}
+
+// Handler returns the caching middleware.
+func (rc *ResponseCache) Handler() Handler {
+	return func(ctx *Context) error {
+		r := ctx.Request()
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return nil
+		}
+		reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+		if reqCC.has("no-store") || r.Header.Get("Authorization") != "" || isStreamRequest(r) {
+			return nil
+		}
+		key := rc.key(r)
+		revalidation := r.Context().Value(revalidateKey{}) != nil
+		var stale *CachedResponse
+		if !reqCC.has("no-cache") && !revalidation {
+			if entry, ok := rc.store.Get(key); ok {
+				now := time.Now()
+				switch {
+				case now.Before(entry.Expires):
+					entry.write(ctx.Response(), r.Method == http.MethodHead, "HIT")
+					ctx.Abort()
+					return nil
+				case now.Before(entry.Expires.Add(entry.StaleWhileRevalidate)):
+					entry.write(ctx.Response(), r.Method == http.MethodHead, "STALE")
+					ctx.Abort()
+					rc.revalidate(ctx, key)
+					return nil
+				case now.Before(entry.Expires.Add(entry.StaleIfError)):
+					stale = entry
+				}
+			}
+		}
+
+		bw := &bufferWriter{ResponseWriter: ctx.Response()}
+		ctx.response = bw
+		ctx.onFinish(func() {
+			if bw.passthrough {
+				// streamed or hijacked responses are never cached.
+				return
+			}
+			if stale != nil && bw.code >= http.StatusInternalServerError {
+				for k := range bw.Header() {
+					bw.Header().Del(k)
+				}
+				stale.write(bw.ResponseWriter, r.Method == http.MethodHead, "STALE")
+				return
+			}
+			if r.Method == http.MethodGet {
+				if entry, ttl, ok := rc.cacheable(bw); ok {
+					rc.store.Set(key, entry, ttl)
+				}
+			}
+			bw.Header().Set("X-Cache", "MISS")
+			bw.flush()
+		})
+		return nil
+	}
+}
+
+// revalidate refreshes the response stored under key in the background by
+// dispatching a copy of the request through the router.
+func (rc *ResponseCache) revalidate(ctx *Context, key string) {
+	rc.mu.Lock()
+	if _, ok := rc.revalidating[key]; ok {
+		rc.mu.Unlock()
+		return
+	}
+	rc.revalidating[key] = struct{}{}
+	rc.mu.Unlock()
+
+	base := context.WithValue(context.Background(), revalidateKey{}, true)
+	r := ctx.Request().Clone(base)
+	r.Method = http.MethodGet
+	r.Header.Del("If-None-Match")
+	r.Header.Del("If-Modified-Since")
+	router := ctx.cherry.router
+	go func() {
+		defer func() {
+			rc.mu.Lock()
+			delete(rc.revalidating, key)
+			rc.mu.Unlock()
+		}()
+		router.ServeHTTP(&discardWriter{header: http.Header{}}, r)
+	}()
+}
+
+// Purge removes all cached responses whose path matches pattern. The
+// pattern syntax is the one of path.Match, e.g. "/users/*". It returns the
+// number of removed responses.
+func (rc *ResponseCache) Purge(pattern string) int {
+	n := 0
+	for _, key := range rc.store.Keys() {
+		if ok, _ := path.Match(pattern, keyPath(key)); ok {
+			rc.store.Delete(key)
+			n++
+		}
+	}
+	return n
+}
+
+// key builds the cache key of r. HEAD requests share the key of GET.
+func (rc *ResponseCache) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString("GET ")
+	b.WriteString(r.URL.Path)
+	if q := r.URL.Query(); len(q) > 0 {
+		b.WriteByte('?')
+		b.WriteString(q.Encode())
+	}
+	for _, h := range rc.opts.Vary {
+		b.WriteByte('\n')
+		b.WriteString(h)
+		b.WriteByte('=')
+		b.WriteString(r.Header.Get(h))
+	}
+	return b.String()
+}
+
+// keyPath extracts the url path from a cache key.
+func keyPath(key string) string {
+	_, p, _ := strings.Cut(key, " ")
+	if i := strings.IndexAny(p, "?\n"); i >= 0 {
+		p = p[:i]
+	}
+	return p
+}
+
+// cacheable reports whether the buffered response may be stored. It returns
+// the entry to store and the duration the store should keep it.
+func (rc *ResponseCache) cacheable(bw *bufferWriter) (*CachedResponse, time.Duration, bool) {
+	if bw.code != http.StatusOK {
+		return nil, 0, false
+	}
+	h := bw.Header()
+	if h.Get("Set-Cookie") != "" {
+		return nil, 0, false
+	}
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" || !rc.varies(name) {
+				return nil, 0, false
+			}
+		}
+	}
+	cc := parseCacheControl(h.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return nil, 0, false
+	}
+	ttl := rc.opts.TTL
+	if d, ok := cc.duration("s-maxage"); ok {
+		ttl = d
+	} else if d, ok := cc.duration("max-age"); ok {
+		ttl = d
+	}
+	swr := rc.opts.StaleWhileRevalidate
+	if d, ok := cc.duration("stale-while-revalidate"); ok {
+		swr = d
+	}
+	sie := rc.opts.StaleIfError
+	if d, ok := cc.duration("stale-if-error"); ok {
+		sie = d
+	}
+	if ttl <= 0 && swr <= 0 && sie <= 0 {
+		return nil, 0, false
+	}
+	now := time.Now()
+	entry := &CachedResponse{
+		Status:               bw.code,
+		Header:               h.Clone(),
+		Body:                 append([]byte(nil), bw.buf.Bytes()...),
+		Stored:               now,
+		Expires:              now.Add(ttl),
+		StaleWhileRevalidate: swr,
+		StaleIfError:         sie,
+	}
+	entry.Header.Del("X-Cache")
+	return entry, ttl + max(swr, sie), true
+}
+
+func (rc *ResponseCache) varies(header string) bool {
+	for _, h := range rc.opts.Vary {
+		if h == header {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *CachedResponse) write(w http.ResponseWriter, head bool, status string) {
+	h := w.Header()
+	for k, v := range e.Header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set("Age", strconv.Itoa(int(time.Since(e.Stored).Seconds())))
+	h.Set("X-Cache", status)
+	w.WriteHeader(e.Status)
+	if !head {
+		w.Write(e.Body)
+	}
+}
+
+// discardWriter is the http.ResponseWriter of background revalidations.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// cacheControl holds the parsed directives of a Cache-Control header.
+type cacheControl map[string]string
+
+func parseCacheControl(header string) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), "\"")
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func (cc cacheControl) duration(directive string) (time.Duration, bool) {
+	v, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
@@ -0,0 +1,137 @@
+package cherry
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory CacheStore. Expired responses are evicted
+// lazily.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   map[string]*memoryEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Get implements CacheStore.
+func (s *MemoryStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.RLock()
+	e, ok := s.entries[key]
+	s.mu.RUnlock()
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.resp, true
+}
+
+// Set implements CacheStore.
+func (s *MemoryStore) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &memoryEntry{resp: resp, expires: now.Add(ttl)}
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
+// Delete implements CacheStore.
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+}
+
+// Keys implements CacheStore.
+func (s *MemoryStore) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.entries))
+	for k := range s.entries {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// RedisClient is the subset of a Redis client used by RedisStore. Cherry does
+// not depend on a Redis driver, wrap the client of your choice to satisfy
+// this interface.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	Keys(ctx context.Context, pattern string) ([]string, error)
+}
+
+// RedisStore is a CacheStore backed by Redis, which lets several instances
+// of an application share their cache. Redis errors are treated as cache
+// misses.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore that prefixes all its keys with prefix.
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Get implements CacheStore.
+func (s *RedisStore) Get(key string) (*CachedResponse, bool) {
+	b, err := s.client.Get(context.Background(), s.prefix+key)
+	if err != nil || b == nil {
+		return nil, false
+	}
+	resp := &CachedResponse{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, false
+	}
+	return resp, true
+}
+
+// Set implements CacheStore.
+func (s *RedisStore) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	s.client.Set(context.Background(), s.prefix+key, b, ttl)
+}
+
+// Delete implements CacheStore.
+func (s *RedisStore) Delete(key string) {
+	s.client.Del(context.Background(), s.prefix+key)
+}
+
+// Keys implements CacheStore.
+func (s *RedisStore) Keys() []string {
+	keys, err := s.client.Keys(context.Background(), s.prefix+"*")
+	if err != nil {
+		return nil
+	}
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, s.prefix)
+	}
+	return keys
+}
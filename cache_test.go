@@ -0,0 +1,251 @@
+package cherry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func cacheTestApp(store CacheStore) (*Cherry, *ResponseCache, *int) {
+	hits := 0
+	c := New()
+	cache := NewResponseCache(store, CacheOptions{})
+	c.Use(cache.Handler())
+	c.Get("/users/:id", func(ctx *Context) error {
+		hits++
+		return ctx.Text(http.StatusOK, "user "+ctx.Param("id")+" "+strconv.Itoa(hits))
+	})
+	c.Get("/private", func(ctx *Context) error {
+		hits++
+		ctx.Response().Header().Set("Cache-Control", "private, max-age=60")
+		return ctx.Text(http.StatusOK, "private")
+	})
+	return c, cache, &hits
+}
+
+func testResponseCache(t *testing.T, store CacheStore) {
+	c, cache, hits := cacheTestApp(store)
+
+	_, body := doRequest(t, "GET", "/users/1", nil, c)
+	_, cached := doRequest(t, "GET", "/users/1", nil, c)
+	if body != cached || *hits != 1 {
+		t.Errorf("expecting cached response got %s (hits %d)", cached, *hits)
+	}
+
+	r, _ := http.NewRequest("GET", "/users/1", nil)
+	r.Header.Set("Cache-Control", "no-cache")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if *hits != 2 || rw.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expecting no-cache to bypass the cache (hits %d)", *hits)
+	}
+
+	doRequest(t, "GET", "/private", nil, c)
+	doRequest(t, "GET", "/private", nil, c)
+	if *hits != 4 {
+		t.Errorf("expecting private responses not to be cached (hits %d)", *hits)
+	}
+
+	doRequest(t, "GET", "/users/2", nil, c)
+	if n := cache.Purge("/users/*"); n != 2 {
+		t.Errorf("expecting 2 purged responses got %d", n)
+	}
+	doRequest(t, "GET", "/users/1", nil, c)
+	if *hits != 6 {
+		t.Errorf("expecting purged response to be refetched (hits %d)", *hits)
+	}
+}
+
+func TestResponseCacheMemory(t *testing.T) {
+	testResponseCache(t, NewMemoryStore())
+}
+
+func TestResponseCacheRedis(t *testing.T) {
+	testResponseCache(t, NewRedisStore(newFakeRedis(), "cherry:"))
+}
+
+func TestResponseCacheMaxAge(t *testing.T) {
+	store := NewMemoryStore()
+	c := New()
+	c.Use(NewResponseCache(store, CacheOptions{}).Handler())
+	c.Get("/", func(ctx *Context) error {
+		ctx.Response().Header().Set("Cache-Control", "public, max-age=0")
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+	doRequest(t, "GET", "/", nil, c)
+	if len(store.Keys()) != 0 {
+		t.Error("expecting max-age=0 response not to be cached")
+	}
+}
+
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}}
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.data[key], nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[key] = value
+	return nil
+}
+
+func (r *fakeRedis) Del(ctx context.Context, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range keys {
+		delete(r.data, k)
+	}
+	return nil
+}
+
+func (r *fakeRedis) Keys(ctx context.Context, pattern string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// only prefix patterns are supported.
+	var keys []string
+	for k := range r.data {
+		if strings.HasPrefix(k, strings.TrimSuffix(pattern, "*")) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	c := New()
+	c.Use(NewResponseCache(NewMemoryStore(), CacheOptions{}).Handler())
+	c.Get("/", func(ctx *Context) error {
+		n := atomic.AddInt32(&hits, 1)
+		ctx.Response().Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		return ctx.Text(http.StatusOK, strconv.Itoa(int(n)))
+	})
+
+	_, body := doRequest(t, "GET", "/", nil, c)
+	if body != "1" {
+		t.Fatalf("expecting 1 got %s", body)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Body.String() != "1" || rw.Header().Get("X-Cache") != "STALE" {
+		t.Errorf("expecting stale response 1 got %s (%s)", rw.Body.String(), rw.Header().Get("X-Cache"))
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&hits) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Fatal("expecting the stale response to be revalidated in the background")
+	}
+	deadline = time.Now().Add(time.Second)
+	for body != "2" && time.Now().Before(deadline) {
+		_, body = doRequest(t, "GET", "/", nil, c)
+	}
+	if body != "2" {
+		t.Errorf("expecting the revalidated response 2 got %s", body)
+	}
+}
+
+func TestResponseCacheStaleIfError(t *testing.T) {
+	fail := false
+	c := New()
+	c.Use(NewResponseCache(NewMemoryStore(), CacheOptions{StaleIfError: time.Minute}).Handler())
+	c.Get("/", func(ctx *Context) error {
+		if fail {
+			return errors.New("origin down")
+		}
+		ctx.Response().Header().Set("Cache-Control", "max-age=0")
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+
+	doRequest(t, "GET", "/", nil, c)
+	fail = true
+	code, body := doRequest(t, "GET", "/", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "cherry" {
+		t.Errorf("expecting the stale response got %s", body)
+	}
+}
+
+func TestResponseCacheStreaming(t *testing.T) {
+	store := NewMemoryStore()
+	release := make(chan struct{})
+	c := New()
+	c.Use(NewResponseCache(store, CacheOptions{}).Handler())
+	c.Get("/events", func(ctx *Context) error {
+		if err := ctx.SSE(Event{Data: "tick"}); err != nil {
+			return err
+		}
+		<-release
+		return nil
+	})
+	c.Get("/progress", func(ctx *Context) error {
+		ctx.Response().Write([]byte("10%\n"))
+		http.NewResponseController(ctx.Response()).Flush()
+		<-release
+		return nil
+	})
+	c.Get("/flushed", func(ctx *Context) error {
+		ctx.Response().Write([]byte("part 1\n"))
+		http.NewResponseController(ctx.Response()).Flush()
+		ctx.Response().Write([]byte("part 2\n"))
+		return nil
+	})
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	defer close(release)
+
+	for path, expect := range map[string]string{"/events": "data: tick\n", "/progress": "10%\n"} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line := make(chan string, 1)
+		go func() {
+			s, _ := bufio.NewReader(res.Body).ReadString('\n')
+			line <- s
+		}()
+		select {
+		case s := <-line:
+			if s != expect {
+				t.Errorf("expecting %q from %s got %q", expect, path, s)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("expecting %s to be streamed before the handler returns", path)
+		}
+		res.Body.Close()
+	}
+
+	// the body ends after the middleware has finished.
+	res, err := http.Get(srv.URL + "/flushed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(res.Body)
+	res.Body.Close()
+	if keys := store.Keys(); len(keys) != 0 {
+		t.Errorf("expecting flushed responses not to be cached got %v", keys)
+	}
+}
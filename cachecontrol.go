@@ -0,0 +1,92 @@
+package cherry
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache describes the directives of a Cache-Control response header.
+// Durations are rounded down to whole seconds, zero durations are omitted.
+type Cache struct {
+	MaxAge               time.Duration
+	SMaxAge              time.Duration
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	NoTransform          bool
+	MustRevalidate       bool
+	ProxyRevalidate      bool
+	Immutable            bool
+}
+
+// Presets for common caching policies.
+var (
+	// NoStore forbids any cache from storing the response.
+	NoStore = Cache{NoStore: true}
+
+	// NoCache lets caches store the response but forces them to revalidate
+	// it before each use.
+	NoCache = Cache{NoCache: true}
+
+	// PrivateShort lets only the browser cache the response for a minute.
+	PrivateShort = Cache{Private: true, MaxAge: time.Minute}
+
+	// Immutable is meant for fingerprinted assets that never change.
+	Immutable = Cache{Public: true, MaxAge: 365 * 24 * time.Hour, Immutable: true}
+)
+
+// String returns the value of the Cache-Control header. When NoStore is set
+// all other directives are dropped, they have no meaning alongside it. Private
+// wins over Public when both are set.
+func (c Cache) String() string {
+	if c.NoStore {
+		return "no-store"
+	}
+	var d []string
+	switch {
+	case c.Private:
+		d = append(d, "private")
+	case c.Public:
+		d = append(d, "public")
+	}
+	if c.NoCache {
+		d = append(d, "no-cache")
+	}
+	if c.NoTransform {
+		d = append(d, "no-transform")
+	}
+	d = appendSeconds(d, "max-age", c.MaxAge)
+	if !c.Private {
+		d = appendSeconds(d, "s-maxage", c.SMaxAge)
+	}
+	if c.MustRevalidate {
+		d = append(d, "must-revalidate")
+	}
+	if c.ProxyRevalidate && !c.Private {
+		d = append(d, "proxy-revalidate")
+	}
+	if c.Immutable {
+		d = append(d, "immutable")
+	}
+	d = appendSeconds(d, "stale-while-revalidate", c.StaleWhileRevalidate)
+	d = appendSeconds(d, "stale-if-error", c.StaleIfError)
+	return strings.Join(d, ", ")
+}
+
+func appendSeconds(d []string, directive string, v time.Duration) []string {
+	if v <= 0 {
+		return d
+	}
+	return append(d, directive+"="+strconv.FormatInt(int64(v/time.Second), 10))
+}
+
+// CacheControl sets the Cache-Control header of the response.
+//
+// ctx.CacheControl(cherry.Cache{MaxAge: time.Hour, Public: true, Immutable: true}).
+func (c *Context) CacheControl(cache Cache) {
+	c.Response().Header().Set("Cache-Control", cache.String())
+}
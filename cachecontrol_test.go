@@ -0,0 +1,42 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheString(t *testing.T) {
+	tests := []struct {
+		cache  Cache
+		expect string
+	}{
+		{Cache{MaxAge: time.Hour, Public: true, Immutable: true}, "public, max-age=3600, immutable"},
+		{NoStore, "no-store"},
+		{Cache{NoStore: true, MaxAge: time.Hour}, "no-store"},
+		{PrivateShort, "private, max-age=60"},
+		{Cache{Public: true, Private: true, SMaxAge: time.Minute}, "private"},
+		{Cache{NoCache: true, MustRevalidate: true}, "no-cache, must-revalidate"},
+		{Cache{MaxAge: 90 * time.Second, StaleWhileRevalidate: time.Minute, StaleIfError: time.Hour}, "max-age=90, stale-while-revalidate=60, stale-if-error=3600"},
+	}
+	for _, test := range tests {
+		if got := test.cache.String(); got != test.expect {
+			t.Errorf("expecting %q got %q", test.expect, got)
+		}
+	}
+}
+
+func TestContextCacheControl(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		ctx.CacheControl(Immutable)
+		return nil
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if cc := rw.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Errorf("unexpected Cache-Control %s", cc)
+	}
+}
@@ -0,0 +1,177 @@
+package cherry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CaptchaResult is the result of the verification of a CAPTCHA token.
+type CaptchaResult struct {
+	Success bool
+	// Score is the score of reCAPTCHA v3 and hCaptcha Enterprise, from 0
+	// for a bot to 1 for a human.
+	Score float64
+	// Action is the action of reCAPTCHA v3 and Turnstile.
+	Action      string
+	Hostname    string
+	ChallengeTS time.Time
+	ErrorCodes  []string
+}
+
+// CaptchaVerifier verifies the CAPTCHA tokens that the widgets of the
+// providers put in the forms.
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (*CaptchaResult, error)
+}
+
+// SiteVerify is a CaptchaVerifier for the siteverify APIs of reCAPTCHA,
+// hCaptcha and Turnstile, see ReCaptcha, HCaptcha and Turnstile.
+type SiteVerify struct {
+	// URL is the siteverify endpoint of the provider.
+	URL string
+	// Secret is the secret key of the site.
+	Secret string
+	// Field is the form field in which the widget puts the token.
+	Field string
+	// Client sends the requests. The default is an http.Client with a
+	// timeout of 10 seconds.
+	Client *http.Client
+}
+
+// ReCaptcha returns a CaptchaVerifier for Google reCAPTCHA v2 and v3.
+func ReCaptcha(secret string) *SiteVerify {
+	return &SiteVerify{URL: "https://www.google.com/recaptcha/api/siteverify", Secret: secret, Field: "g-recaptcha-response"}
+}
+
+// HCaptcha returns a CaptchaVerifier for hCaptcha.
+func HCaptcha(secret string) *SiteVerify {
+	return &SiteVerify{URL: "https://api.hcaptcha.com/siteverify", Secret: secret, Field: "h-captcha-response"}
+}
+
+// Turnstile returns a CaptchaVerifier for Cloudflare Turnstile.
+func Turnstile(secret string) *SiteVerify {
+	return &SiteVerify{URL: "https://challenges.cloudflare.com/turnstile/v0/siteverify", Secret: secret, Field: "cf-turnstile-response"}
+}
+
+var captchaClient = &http.Client{Timeout: 10 * time.Second}
+
+// Verify implements CaptchaVerifier.
+func (v *SiteVerify) Verify(ctx context.Context, token, remoteIP string) (*CaptchaResult, error) {
+	form := url.Values{"secret": {v.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := v.Client
+	if client == nil {
+		client = captchaClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cherry: verifying captcha: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cherry: verifying captcha: %s", res.Status)
+	}
+	var body struct {
+		Success     bool     `json:"success"`
+		Score       float64  `json:"score"`
+		Action      string   `json:"action"`
+		Hostname    string   `json:"hostname"`
+		ChallengeTS string   `json:"challenge_ts"`
+		ErrorCodes  []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("cherry: verifying captcha: %w", err)
+	}
+	result := &CaptchaResult{
+		Success:    body.Success,
+		Score:      body.Score,
+		Action:     body.Action,
+		Hostname:   body.Hostname,
+		ErrorCodes: body.ErrorCodes,
+	}
+	result.ChallengeTS, _ = time.Parse(time.RFC3339, body.ChallengeTS)
+	return result, nil
+}
+
+// CaptchaConfig configures the Captcha middleware.
+type CaptchaConfig struct {
+	// Verifier verifies the tokens
+	Verifier CaptchaVerifier
+
+	// Field is the form field that holds the token. The default is the Field
+	// of a SiteVerify, or "captcha"
+	Field string
+
+	// Header is the header that holds the token, for the clients that do not
+	// post forms. The default is X-Captcha-Token
+	Header string
+
+	// MinScore rejects the tokens with a lower score, for the providers that
+	// score the clients. The default accepts every successful token
+	MinScore float64
+
+	// Action, when set, rejects the tokens of another action
+	Action string
+}
+
+// Captcha returns a middleware Handler that verifies the CAPTCHA token of
+// the POST, PUT, PATCH and DELETE requests, which are rejected with a 400
+// when the token is missing and with a 403 when it is invalid. The result is
+// available to the handler with Context.Captcha.
+//
+// app.Post("/signup", cherry.Chain(signup, cherry.Captcha(cherry.CaptchaConfig{Verifier: cherry.Turnstile(secret)}))).
+func Captcha(cfg CaptchaConfig) Handler {
+	if cfg.Field == "" {
+		cfg.Field = "captcha"
+		if v, ok := cfg.Verifier.(*SiteVerify); ok && v.Field != "" {
+			cfg.Field = v.Field
+		}
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Captcha-Token"
+	}
+	return func(ctx *Context) error {
+		switch ctx.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return nil
+		}
+		token := ctx.Header(cfg.Header)
+		if token == "" {
+			if err := ctx.parseForm(); err != nil {
+				return err
+			}
+			token = ctx.Form(cfg.Field)
+		}
+		if token == "" {
+			return NewHTTPError(http.StatusBadRequest, "missing captcha token")
+		}
+		result, err := cfg.Verifier.Verify(ctx.Request().Context(), token, ctx.ClientIP())
+		if err != nil {
+			return err
+		}
+		WithValue(ctx, result)
+		if !result.Success || result.Score < cfg.MinScore || (cfg.Action != "" && result.Action != cfg.Action) {
+			return NewHTTPError(http.StatusForbidden, "captcha verification failed")
+		}
+		return nil
+	}
+}
+
+// Captcha returns the result of the verification of the CAPTCHA token of the
+// request by the Captcha middleware, or nil.
+func (c *Context) Captcha() *CaptchaResult {
+	result, _ := Value[*CaptchaResult](c)
+	return result
+}
@@ -0,0 +1,92 @@
+package cherry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCaptcha(t *testing.T) {
+	var form url.Values
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		switch r.PostForm.Get("response") {
+		case "human":
+			io.WriteString(w, `{"success":true,"score":0.9,"action":"signup","hostname":"example.com","challenge_ts":"2024-01-02T15:04:05Z"}`)
+		case "bot":
+			io.WriteString(w, `{"success":true,"score":0.1,"action":"signup"}`)
+		case "login":
+			io.WriteString(w, `{"success":true,"score":0.9,"action":"login"}`)
+		default:
+			io.WriteString(w, `{"success":false,"error-codes":["invalid-input-response"]}`)
+		}
+	}))
+	defer provider.Close()
+
+	verifier := ReCaptcha("site-secret")
+	verifier.URL = provider.URL
+	c := New()
+	c.Output = io.Discard
+	c.Post("/signup", Chain(func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, fmt.Sprint(ctx.Captcha().Score, " ", ctx.Captcha().Hostname))
+	}, Captcha(CaptchaConfig{Verifier: verifier, MinScore: 0.5, Action: "signup"})))
+
+	tests := []struct {
+		token  string
+		header bool
+		code   int
+	}{
+		{"human", false, http.StatusOK},
+		{"human", true, http.StatusOK},
+		{"bot", false, http.StatusForbidden},
+		{"login", false, http.StatusForbidden},
+		{"forged", false, http.StatusForbidden},
+		{"", false, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		var r *http.Request
+		if test.header {
+			r = httptest.NewRequest("POST", "/signup", strings.NewReader(`{"email":"ann@example.com"}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("X-Captcha-Token", test.token)
+		} else {
+			r = httptest.NewRequest("POST", "/signup", strings.NewReader(url.Values{"g-recaptcha-response": {test.token}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code {
+			t.Errorf("expecting %d for the token %q got %d %s", test.code, test.token, rw.Code, rw.Body)
+		}
+		if test.code == http.StatusOK && rw.Body.String() != "0.9 example.com" {
+			t.Errorf("expecting the result on the context got %q", rw.Body)
+		}
+	}
+	if form.Get("secret") != "site-secret" || form.Get("remoteip") != "192.0.2.1" {
+		t.Errorf("expecting the secret and the ip of the client to be sent got %v", form)
+	}
+
+	provider.Close()
+	r := httptest.NewRequest("POST", "/signup", nil)
+	r.Header.Set("X-Captcha-Token", "human")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusInternalServerError {
+		t.Errorf("expecting 500 when the provider is down got %d", rw.Code)
+	}
+}
+
+func TestCaptchaSafeMethods(t *testing.T) {
+	c := New()
+	contact := c.Group("/contact")
+	contact.Use(Captcha(CaptchaConfig{Verifier: Turnstile("secret")}))
+	contact.Get("/form", noopHandler)
+	if code, _ := doRequest(t, "GET", "/contact/form", nil, c); code != http.StatusOK {
+		t.Errorf("expecting GET requests not to be verified got %d", code)
+	}
+}
@@ -1,29 +1,46 @@
 package cherry
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
-	"github.com/pooulad/cherry/utils"
+	"golang.org/x/text/language"
 )
 
 //go:embed assets/banner.txt
 var banner []byte
 
-// errorHandler is the default error handler for cherry.
+// errorHandler is the default error handler for cherry. In ReleaseMode the
+// message of errors that are not an HTTPError is replaced by the status text
+// of the code, so internal details do not leak to clients.
 var errorHandler = func(ctx *Context, err error) {
-	http.Error(ctx.Response(), err.Error(), http.StatusInternalServerError)
+	code := statusCode(err)
+	if code >= http.StatusInternalServerError && ctx.cherry != nil {
+		ctx.cherry.logError(ctx, err)
+	}
+	msg := err.Error()
+	var he *HTTPError
+	if !IsDebug() && !errors.As(err, &he) {
+		msg = http.StatusText(code)
+	}
+	http.Error(ctx.Response(), msg, code)
 }
 
 // ErrorHandlerFunc used for centralize error handling when an error happens in Handler.
@@ -42,40 +59,258 @@ type Cherry struct {
 	// Output writes the access-log and debug parameters for web-server
 	Output io.Writer
 
+	// Logger, when set, receives the access-log, startup messages and
+	// internal errors as structured records instead of the plain text that
+	// is written to Output
+	Logger *slog.Logger
+
 	// HasAccessLog enables access-log for cherry. The default is false
 	HasAccessLog bool
 
+	// AccessLogFormat formats the access-log written to Output. The default
+	// is LogCommon
+	AccessLogFormat AccessLogFormat
+
 	// HTTP2 enables the HTTP2 protocol on the server(TLS)
 	HTTP2 bool
 
+	// HTTP2Options tunes the HTTP2 protocol when HTTP2 is enabled
+	HTTP2Options HTTP2Options
+
+	// UploadLimits limits the multipart forms parsed by the Context. The
+	// Uploads middleware sets limits per route
+	UploadLimits UploadLimits
+
+	// LocaleKey is the query parameter and the cookie that override the
+	// Accept-Language header in Context.Locale. The default is "lang"
+	LocaleKey string
+
+	// ContentTypes sets the Content-Type of the responses of Context.Text,
+	// Context.JSON and the other helpers. The default adds a utf-8 charset
+	// to text types and sniffs unknown types
+	ContentTypes ContentTypes
+
+	// Envelope shapes the body of Context.OK, Context.Created and
+	// Context.Fail. The default responds with the Envelope as is
+	Envelope EnvelopeFunc
+
+	// H2C enables HTTP2 over cleartext connections, which gRPC clients use
+	// without TLS. The default is false
+	H2C bool
+
+	// PanicGoroutines adds the stacks of all the goroutines to the reports
+	// of panics, which helps to find deadlocks and races but makes the
+	// reports large. The default is false
+	PanicGoroutines bool
+
+	// Users loads the users of Context.Login, Context.User and the
+	// RequireLogin middleware
+	Users UserProvider
+
+	// Client configures the clients of Context.Client, which forward the
+	// deadline, the trace and the request id of the request. The default
+	// sends the requests with http.DefaultTransport
+	Client ClientConfig
+
+	// JSONETag makes Context.JSON tag successful GET responses with an ETag
+	// computed over the encoded payload and respond with 304 Not Modified
+	// when it matches the If-None-Match header. The default is false
+	JSONETag bool
+
+	// TLSConfig is the TLS configuration of ServeTLS. The default is the
+	// hardened configuration returned by the TLSConfig function
+	TLSConfig *tls.Config
+
+	// ACMEHandler, when set, answers the ACME HTTP challenges on the
+	// redirect server of ServeTLSWithRedirect, like the HTTPHandler of an
+	// autocert.Manager
+	ACMEHandler http.Handler
+
+	// GetCertificate, when set, returns the TLS certificate for the server
+	// name of a client (SNI). When it returns nil the certificates added with
+	// AddCertificate and the certificate files given to ServeTLS are used
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// ReadTimeout is the maximum duration for reading an entire request,
+	// including the body, by the server started with Serve or ServeTLS. The
+	// default is 5 seconds, zero means no timeout
+	ReadTimeout time.Duration
+
+	// ReadHeaderTimeout is the maximum duration for reading the request
+	// headers. Zero means ReadTimeout is used
+	ReadHeaderTimeout time.Duration
+
+	// WriteTimeout is the maximum duration before timing out writes of the
+	// response. The default is 10 seconds, zero means no timeout
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the maximum time to wait for the next request on a
+	// keep-alive connection. Zero means ReadTimeout is used
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes is the maximum size of the request headers. Zero means
+	// http.DefaultMaxHeaderBytes (1MB)
+	MaxHeaderBytes int
+
+	// DeregistrationDelay is the time a graceful shutdown keeps serving
+	// after the readiness endpoints report not ready, before the listeners
+	// are closed, so load balancers can stop sending traffic first. It comes
+	// on top of ShutdownTimeout. The default is zero
+	DeregistrationDelay time.Duration
+
+	// MaxConns limits the number of simultaneous connections, connections
+	// above the limit wait until others are closed. Zero means no limit
+	MaxConns int
+
+	// MaxConnsWait, when set, makes connections above MaxConns wait at most
+	// this duration for a free slot, after which they are closed. Unlike
+	// waiting in the backlog, this fails fast under connection floods
+	MaxConnsWait time.Duration
+
+	// ConnReadTimeout is the maximum time a new connection may stay silent
+	// before it is closed, which protects against clients that open
+	// connections and never send a request. It applies until the first bytes
+	// are read. Zero means no timeout
+	ConnReadTimeout time.Duration
+
+	// KeepAlive is the TCP keep-alive period of accepted connections. Zero
+	// uses the default of the net package (15 seconds), a negative value
+	// disables keep-alives
+	KeepAlive time.Duration
+
+	// ListenControl, when set, is called with the raw socket before it is
+	// bound, to set socket options like SO_REUSEPORT. The size of the
+	// accept backlog is taken from the operating system (somaxconn on Linux)
+	ListenControl func(network, address string, c syscall.RawConn) error
+
+	// WrapConn, when set, wraps every accepted connection, for example to
+	// count bytes or instrument connections
+	WrapConn func(net.Conn) net.Conn
+
+	// ShutdownTimeout is the maximum time a graceful shutdown waits for open
+	// connections to drain before closing them. The default is to wait until
+	// all connections are idle
+	ShutdownTimeout time.Duration
+
 	router     *httprouter.Router
 	middleware []Handler
-	prefix     string
-	context    context.Context
-}
-
-// New returns a new Cherry object.
-func New() *Cherry {
-	return &Cherry{
+	phases     []Phase
+	slowAfter  time.Duration
+	metrics    *Metrics
+	stats      *stats
+	health     *health
+	servers    *servers
+	hooks      *hooks
+	tasks      *tasks
+	state      *State
+	views      *views
+	routes     *routes
+	providers  *providers
+	handles    *handles
+	signals    []os.Signal
+	// grpc serves the gRPC requests, see GRPC.
+	grpc http.Handler
+	// config is the configuration given to NewFromConfig.
+	config *Config
+	// trustedProxies may set the client address in X-Forwarded-For.
+	trustedProxies []*net.IPNet
+	// baseDir is the directory relative paths of static files are resolved
+	// against.
+	baseDir string
+	// certificates are served in addition to the files given to ServeTLS.
+	certificates []tls.Certificate
+	prefix       string
+	// context is the context bound with BindContext, parent is the
+	// application or group a Group was created from.
+	context context.Context
+	parent  *Cherry
+}
+
+// New returns a new Cherry object configured by the options. New panics
+// when an option is invalid, since that is a programming error.
+//
+// app := cherry.New(cherry.WithAccessLog(cherry.LogCombined), cherry.WithTimeouts(5*time.Second, 30*time.Second, time.Minute)).
+func New(opts ...Option) *Cherry {
+	c := &Cherry{
 		router:       httprouter.New(),
+		metrics:      NewMetrics(),
+		stats:        newStats(),
+		health:       &health{},
+		servers:      &servers{},
+		hooks:        &hooks{},
+		tasks:        newTasks(),
+		state:        newState(),
+		views:        &views{},
+		routes:       &routes{},
+		providers:    newProviders(),
+		handles:      &handles{},
+		signals:      defaultSignals,
 		Output:       os.Stderr,
 		ErrorHandler: errorHandler,
 		HasAccessLog: false,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		LocaleKey:    "lang",
+		ContentTypes: defaultContentTypes,
 	}
+	c.metrics.Collect(c.stats.collect)
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			panic("cherry: " + err.Error())
+		}
+	}
+	return c
 }
 
 // Serve method serves the cherry web server on the given port.
 func (c *Cherry) Serve(port int) error {
-	srv := newServer(fmt.Sprintf(":%d", port), c, c.HTTP2)
+	srv := newServer(fmt.Sprintf(":%d", port), c)
 	return c.serve(srv)
 }
 
 // ServeTLS method serves the application one the given port with TLS encryption.
+// The certificate files may be empty when certificates are added with
+// AddCertificate or returned by GetCertificate.
 func (c *Cherry) ServeTLS(port int, certFile, keyFile string) error {
-	srv := newServer(fmt.Sprintf(":%d", port), c, c.HTTP2)
+	srv := newServer(fmt.Sprintf(":%d", port), c)
 	return c.serve(srv, certFile, keyFile)
 }
 
+// SetBaseDir sets the directory that relative paths given to Static,
+// StaticFile and StaticHosts are resolved against. The default is the
+// working directory of the process, which cherry never changes. Groups
+// created after SetBaseDir inherit it.
+//
+// exe, _ := os.Executable()
+// app.SetBaseDir(filepath.Dir(exe)).
+func (c *Cherry) SetBaseDir(dir string) {
+	c.baseDir = dir
+}
+
+// resolve returns path relative to the base directory.
+func (c *Cherry) resolve(path string) string {
+	if c.baseDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.baseDir, path)
+}
+
+// AddCertificate loads a certificate and key pair that ServeTLS serves to
+// clients asking for one of the names of the certificate (SNI), so a single
+// application can terminate TLS for several domains.
+//
+// app.AddCertificate("example.com.crt", "example.com.key")
+// app.AddCertificate("example.org.crt", "example.org.key")
+// app.ServeTLS(443, "", "").
+func (c *Cherry) AddCertificate(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	c.certificates = append(c.certificates, cert)
+	return nil
+}
+
 // ServeCustom method serves the application with custom server configuration.
 func (c *Cherry) ServeCustom(s *http.Server) error {
 	return c.serve(s)
@@ -87,87 +322,272 @@ func (c *Cherry) ServeCustomTLS(s *http.Server, certFile, keyFile string) error
 }
 
 func (c *Cherry) serve(s *http.Server, files ...string) error {
-	srv := &server{
-		Server: s,
-		quit:   make(chan struct{}, 1),
-		fquit:  make(chan struct{}, 1),
+	if modeErr != nil {
+		c.logFailure("invalid mode", modeErr)
 	}
-
-	packagePath, _ := os.Executable()
-	packageDir := filepath.Dir(packagePath)
-	err := os.Chdir(packageDir)
-	if err != nil {
-		return err
+	c.printStartup()
+	return c.run(c.wrap(s, c.signals), files...)
+}
+
+// wrap returns the graceful server of s, which handles the given signals.
+func (c *Cherry) wrap(s *http.Server, signals []os.Signal) *server {
+	return &server{
+		Server:          s,
+		stats:           c.stats,
+		health:          c.health,
+		shutdown:        c.Shutdown,
+		started:         c.hooks.runStart,
+		reload:          c.reload,
+		signals:         signals,
+		maxConns:        c.MaxConns,
+		maxConnsWait:    c.MaxConnsWait,
+		connReadTimeout: c.ConnReadTimeout,
+		keepAlive:       c.KeepAlive,
+		listenControl:   c.ListenControl,
+		wrapConn:        c.WrapConn,
+		certificates:    c.certificates,
+		getCertificate:  c.GetCertificate,
+		listening:       make(chan struct{}),
+		stopped:         make(chan struct{}),
 	}
+}
 
-	fmt.Fprint(c.Output, utils.Colorize(utils.ColorRed, string(banner))+"\n")
+// run serves srv until it is shut down, with TLS when the certificate files
+// are given.
+func (c *Cherry) run(srv *server, files ...string) error {
+	c.servers.add(srv)
+	defer c.servers.remove(srv)
 
 	if len(files) == 0 {
-		fmt.Fprintf(c.Output, "Cherry🍒 listening on 0.0.0.0:%s\n", s.Addr)
+		c.logListening(srv.Addr, false)
 		return srv.ListenAndServe()
 	}
 	if len(files) == 2 {
-		fmt.Fprintf(c.Output, "Cherry🍒 listening TLS on 0.0.0.0:%s\n", s.Addr)
+		c.logListening(srv.Addr, true)
 		return srv.ListenAndServeTLS(files[0], files[1])
 	}
 	return errors.New("invalid server configuration detected")
 }
 
+// Addr returns the address the server is listening on, which is useful when
+// serving on port 0. It returns nil until the listener is bound. With more
+// than one server the address of the first one is returned.
+func (c *Cherry) Addr() net.Addr {
+	return c.servers.addr()
+}
+
+// Shutdown gracefully shuts down the running servers without interrupting
+// active connections. First the context of the background tasks started
+// with Go is cancelled, the readiness endpoints report not ready and the
+// servers keep serving for DeregistrationDelay. Then the listeners are
+// closed and Shutdown waits for the open connections to become idle or for
+// ctx to be done, whichever comes first. Connections that are still open
+// after that, or after ShutdownTimeout, are closed. Finally Shutdown waits
+// for the background tasks to return and the OnShutdown hooks run. Serve returns ErrServerClosed once the connections are drained,
+// or ErrForcedStop when connections had to be closed.
+func (c *Cherry) Shutdown(ctx context.Context) error {
+	c.hooks.runStop()
+	c.tasks.cancel()
+	c.health.shuttingDown.Store(true)
+	if c.DeregistrationDelay > 0 {
+		c.deregister(ctx)
+	}
+	if c.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.ShutdownTimeout)
+		defer cancel()
+	}
+	var errs []error
+	for _, srv := range c.servers.all() {
+		errs = append(errs, srv.Shutdown(ctx))
+	}
+	errs = append(errs, c.tasks.wait(ctx))
+	c.hooks.runShutdown(ctx)
+	return errors.Join(errs...)
+}
+
+// deregister keeps serving for DeregistrationDelay while the readiness
+// endpoints report not ready, so load balancers stop sending new traffic
+// before the listeners are closed. Keep-alives are disabled meanwhile so
+// clients move their connections elsewhere.
+func (c *Cherry) deregister(ctx context.Context) {
+	srvs := c.servers.all()
+	if len(srvs) == 0 {
+		return
+	}
+	for _, srv := range srvs {
+		srv.SetKeepAlivesEnabled(false)
+	}
+	t := time.NewTimer(c.DeregistrationDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// Close immediately closes the listeners and all connections of the running
+// servers. Serve returns ErrForcedStop.
+func (c *Cherry) Close() error {
+	c.hooks.runStop()
+	var errs []error
+	for _, srv := range c.servers.all() {
+		errs = append(errs, srv.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// SetSignals sets the signals that trigger a graceful shutdown. The default
+// is SIGINT, SIGTERM and SIGQUIT, or os.Interrupt on Windows.
+func (c *Cherry) SetSignals(sig ...os.Signal) {
+	c.signals = sig
+}
+
+// DisableSignalHandling stops cherry from listening for signals, for
+// applications or orchestrators that manage them themselves and call
+// Shutdown.
+func (c *Cherry) DisableSignalHandling() {
+	c.signals = nil
+}
+
 // Handle adapts the usage of an http.Handler and will be invoked when
 // the router matches the prefix and request method.
-func (c *Cherry) Handle(method, path string, h http.Handler) {
+func (c *Cherry) Handle(method, path string, h http.Handler) *Route {
 	c.router.Handler(method, path, h)
+	c.hooks.routeRegistered(nil, RouteInfo{Method: method, Path: path, Handler: httpHandlerName(h)})
+	return c.route(method, path)
 }
 
 // Get invokes when request method in handler is set to GET.
-func (c *Cherry) Get(route string, h Handler) {
-	c.add("GET", route, h)
+func (c *Cherry) Get(route string, h Handler) *Route {
+	return c.add("GET", route, h)
 }
 
 // Post invokes when request method in handler is set to POST.
-func (c *Cherry) Post(route string, h Handler) {
-	c.add("POST", route, h)
+func (c *Cherry) Post(route string, h Handler) *Route {
+	return c.add("POST", route, h)
 }
 
 // Put invokes when request method in handler is set to PUT.
-func (c *Cherry) Put(route string, h Handler) {
-	c.add("PUT", route, h)
+func (c *Cherry) Put(route string, h Handler) *Route {
+	return c.add("PUT", route, h)
+}
+
+// Patch invokes when request method in handler is set to PATCH.
+func (c *Cherry) Patch(route string, h Handler) *Route {
+	return c.add("PATCH", route, h)
 }
 
 // Delete invokes when request method in handler is set to DELETE.
-func (c *Cherry) Delete(route string, h Handler) {
-	c.add("DELETE", route, h)
+func (c *Cherry) Delete(route string, h Handler) *Route {
+	return c.add("DELETE", route, h)
 }
 
 // Head invokes when request method in handler is set to HEAD.
-func (c *Cherry) Head(route string, h Handler) {
-	c.add("HEAD", route, h)
+func (c *Cherry) Head(route string, h Handler) *Route {
+	return c.add("HEAD", route, h)
 }
 
 // Options invokes when request method in handler is set to OPTIONS.
-func (c *Cherry) Options(route string, h Handler) {
-	c.add("OPTIONS", route, h)
-}
-
-// Static registers the prefix to the router and start to act as a fileserver
-//
-// app.Static("/public", "./assets").
-func (c *Cherry) Static(prefix, dir string) {
-	c.router.ServeFiles(path.Join(prefix, "*filepath"), http.Dir(dir))
+func (c *Cherry) Options(route string, h Handler) *Route {
+	return c.add("OPTIONS", route, h)
 }
 
 // BindContext lets you provide a context that will live a full http roundtrip
 // BindContext is mostly used in a func main() to provide init variables that
 // may be created only once, like a database connection. If BindContext is not
-// called, Cherry will use a context.Background().
+// called, the context of a request is the context of the http.Request.
+//
+// The context bound to a Group is layered on top of the context of its
+// parent: values of the group take precedence and the values of the parent,
+// bound before or after the group was created, are still visible. The
+// context of a request is done when the request or one of the bound
+// contexts is done.
 func (c *Cherry) BindContext(ctx context.Context) {
 	c.context = ctx
 }
 
-// Use appends a Handler to the middleware. Different middleware can be set
-// for each sub-router.
+// boundContexts returns the contexts bound to c and to its parents, the one
+// of c first.
+func (c *Cherry) boundContexts() []context.Context {
+	var bound []context.Context
+	for app := c; app != nil; app = app.parent {
+		if app.context != nil && app.context != context.Background() {
+			bound = append(bound, app.context)
+		}
+	}
+	return bound
+}
+
+// boundContext returns the values of the bound contexts of c on top of a
+// background context.
+func (c *Cherry) boundContext() context.Context {
+	bound := c.boundContexts()
+	if len(bound) == 0 {
+		return context.Background()
+	}
+	return &layeredContext{Context: context.Background(), bound: bound}
+}
+
+// requestContext returns the context of a request with the parent context
+// r. It has the values of r and then the values of the bound contexts of c,
+// and is done when r or one of the bound contexts is done, with the earliest
+// of their deadlines. release must be called once the request is served.
+func (c *Cherry) requestContext(r context.Context) (ctx context.Context, release func()) {
+	bound := c.boundContexts()
+	if len(bound) == 0 {
+		return r, func() {}
+	}
+	done, cancel := context.WithCancelCause(r)
+	stops := make([]func() bool, 0, len(bound))
+	var deadline time.Time
+	for _, b := range bound {
+		if d, ok := b.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+		if b.Done() != nil {
+			b := b
+			stops = append(stops, context.AfterFunc(b, func() { cancel(context.Cause(b)) }))
+		}
+	}
+	cancelDeadline := context.CancelFunc(func() {})
+	if !deadline.IsZero() {
+		done, cancelDeadline = context.WithDeadline(done, deadline)
+	}
+	return &layeredContext{Context: done, bound: bound}, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancelDeadline()
+		cancel(nil)
+	}
+}
+
+// layeredContext is the context of the requests of a group. Values are
+// looked up in the embedded context, which provides the cancellation, and
+// then in the bound contexts, the one of the group before the ones of its
+// parents.
+type layeredContext struct {
+	context.Context
+	bound []context.Context
+}
+
+func (c *layeredContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	for _, b := range c.bound {
+		if v := b.Value(key); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// Use appends a Handler to the middleware of PhaseDefault. Different
+// middleware can be set for each sub-router.
 func (c *Cherry) Use(handlers ...Handler) {
-	c.middleware = append(c.middleware, handlers...)
+	c.UsePhase(PhaseDefault, handlers...)
 }
 
 // Group returns a new Group that will inherit all of its parents middleware.
@@ -175,6 +595,8 @@ func (c *Cherry) Use(handlers ...Handler) {
 func (c *Cherry) Group(prefix string) *Group {
 	g := &Group{*c}
 	g.Cherry.prefix += prefix
+	g.Cherry.parent = c
+	g.Cherry.context = nil
 	return g
 }
 
@@ -186,6 +608,7 @@ type Group struct {
 // Reset clears all middleware.
 func (g *Group) Reset() *Group {
 	g.Cherry.middleware = nil
+	g.Cherry.phases = nil
 	return g
 }
 
@@ -207,74 +630,129 @@ func (c *Cherry) SetErrorHandler(h ErrorHandlerFunc) {
 	c.ErrorHandler = h
 }
 
+// serverHeader is shared by all responses, which saves an allocation per
+// request. Its capacity equals its length, so appending to it copies.
+var serverHeader = []string{"Cherry🍒/1.0"}
+
 // ServeHTTP satisfies the http.Handler interface.
 func (c *Cherry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if c.grpc != nil && isGRPC(r) {
+		c.grpc.ServeHTTP(rw, r)
+		return
+	}
 	if rw != nil {
-		rw.Header().Set("Server", "Cherry🍒/1.0")
+		rw.Header()["Server"] = serverHeader
 	}
-	if c.HasAccessLog {
+	if c.HasAccessLog || c.slowAfter > 0 {
 		start := time.Now()
-		logger := &responseLogger{c: rw}
+		logger := newResponseLogger(rw)
+		defer logger.release()
 		c.router.ServeHTTP(logger, r)
-		c.writeLog(r, start, logger.Status(), logger.Size())
+		if c.HasAccessLog {
+			c.writeLog(r, start, logger)
+		}
+		if c.slowAfter > 0 && time.Since(start) > c.slowAfter {
+			c.logSlowRequest(r, start, logger)
+		}
 		// saves an allocation by separating the whole logger if log is disabled
 	} else {
 		c.router.ServeHTTP(rw, r)
 	}
 }
 
-func (c *Cherry) add(method, route string, h Handler) {
+func (c *Cherry) add(method, route string, h Handler) *Route {
 	path := path.Join(c.prefix, route)
-	c.router.Handle(method, path, c.makeHttpRouterHandle(h))
+	handle := c.makeHttpRouterHandle(path, h)
+	c.router.Handle(method, path, handle)
+	c.handles.add(path, method, handle)
+	c.hooks.routeRegistered(c, RouteInfo{Method: method, Path: path, Handler: funcName(h)})
+	return c.route(method, path)
+}
+
+// Chain returns a Handler that calls the middleware before h, which sets
+// middleware for a single route.
+//
+// app.Post("/avatar", cherry.Chain(saveAvatar, requireLogin)).
+func Chain(h Handler, middleware ...Handler) Handler {
+	return chain(h, middleware...)
 }
 
-func (c *Cherry) makeHttpRouterHandle(h Handler) httprouter.Handle {
+// chain returns a Handler that calls the given middleware before h, like
+// the middleware registered with Use.
+func chain(h Handler, middleware ...Handler) Handler {
+	if len(middleware) == 0 {
+		return h
+	}
+	return func(ctx *Context) error {
+		for _, handler := range middleware {
+			if err := handler(ctx); err != nil {
+				return err
+			}
+			if ctx.aborted {
+				return nil
+			}
+		}
+		return h(ctx)
+	}
+}
+
+func (c *Cherry) makeHttpRouterHandle(route string, h Handler) httprouter.Handle {
 	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		if c.context == nil {
-			c.context = context.Background()
+		if l, ok := rw.(*responseLogger); ok {
+			l.route = route
+			l.params = params
 		}
-		ctx := &Context{
-			Context:  c.context,
-			vars:     params,
-			response: rw,
-			request:  r,
-			cherry:   c,
+		if c.metrics.enabled.Load() {
+			start := time.Now()
+			rec := newResponseLogger(rw)
+			rw = rec
+			defer func() {
+				c.metrics.observeRequest(r.Method, route, rec.Status(), start)
+				rec.release()
+			}()
 		}
+		reqCtx, releaseContext := c.requestContext(r.Context())
+		defer releaseContext()
+		ctx := contextPool.Get().(*Context)
+		ctx.Context = reqCtx
+		ctx.vars = params
+		ctx.route = route
+		ctx.response = rw
+		ctx.request = r
+		ctx.cherry = c
+		defer ctx.release()
+		c.stats.inflight.Add(1)
+		defer c.stats.inflight.Add(-1)
+		defer ctx.finish()
+		defer c.recoverPanic(ctx)
 		for _, handler := range c.middleware {
-			if err := handler(ctx); err != nil {
-				c.ErrorHandler(ctx, err)
+			if err := c.stats.runMiddleware(handler, ctx); err != nil {
+				c.handleError(ctx, err, nil)
+				return
+			}
+			if ctx.aborted {
 				return
 			}
 		}
 		if err := h(ctx); err != nil {
-			c.ErrorHandler(ctx, err)
+			c.handleError(ctx, err, nil)
 			return
 		}
 	}
 }
 
-func (c *Cherry) writeLog(r *http.Request, start time.Time, status, size int) {
-	host, _, _ := net.SplitHostPort(r.Host)
-	username := "-"
-	if r.URL.User != nil {
-		if name := r.URL.User.Username(); name != "" {
-			username = name
-		}
-	}
-	fmt.Fprintf(c.Output, "%s - %s [%s] \"%s %s %s\" %d %d\n",
-		host,
-		username,
-		start.Format("02/Jan/2006:15:04:05 -0700"),
-		r.Method,
-		r.RequestURI,
-		r.Proto,
-		status,
-		size,
-	)
+// handleError reports an error of a request to the OnError hooks and
+// responds with the error handler.
+func (c *Cherry) handleError(ctx *Context, err error, stack []byte) {
+	ctx.err = err
+	c.hooks.runError(ctx, err, stack)
+	c.ErrorHandler(ctx, err)
 }
 
 // Context is required in each cherry Handler and can be used to pass information
-// between requests.
+// between requests. Contexts are pooled and reused for other requests once
+// the handler has returned, so a Context must not be kept or used from
+// another goroutine after that. Copy the values that are needed instead.
 type Context struct {
 	// Context is a idiomatic way to pass information between requests.
 	// More information about context.Context can be found here:
@@ -283,7 +761,25 @@ type Context struct {
 	response http.ResponseWriter
 	request  *http.Request
 	vars     httprouter.Params
+	route    string
 	cherry   *Cherry
+	// deferred holds the functions registered by middleware that need to run
+	// after the handler has returned, like flushing a compressed response.
+	deferred []func()
+	aborted  bool
+	// err is the error returned by the middleware or the handler.
+	err error
+	// deps caches the dependencies constructed by Resolve.
+	deps map[any]any
+	// preferred caches the languages preferred by the client and locale
+	// the language resolved by Locale.
+	preferred []language.Tag
+	locale    *language.Tag
+	// meta holds the meta values of the envelope, see SetMeta.
+	meta map[string]any
+	// query caches the parsed query string rawQuery of the request.
+	query    url.Values
+	rawQuery string
 }
 
 // Response returns a default http.ResponseWriter.
@@ -299,14 +795,44 @@ func (c *Context) Request() *http.Request {
 // JSON is a helper function for writing a JSON encoded representation of v to
 // the ResponseWriter.
 func (c *Context) JSON(code int, v interface{}) error {
-	c.Response().Header().Set("Content-Type", "application/json")
+	if c.cherry != nil && c.cherry.JSONETag && code == http.StatusOK &&
+		(c.request.Method == http.MethodGet || c.request.Method == http.MethodHead) {
+		return c.jsonWithETag(v)
+	}
+	c.Response().Header().Set("Content-Type", c.contentType(c.contentTypes().JSON))
 	c.Response().WriteHeader(code)
 	return json.NewEncoder(c.Response()).Encode(v)
 }
 
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// jsonWithETag encodes v into a pooled buffer so the ETag can be computed
+// before anything is written.
+func (c *Context) jsonWithETag(v interface{}) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	h := c.Response().Header()
+	etag := computeETag(buf.Bytes(), false)
+	h.Set("ETag", etag)
+	if ifNoneMatch(c.request.Header.Get("If-None-Match"), etag) {
+		writeNotModified(c.Response())
+		return nil
+	}
+	h.Set("Content-Type", c.contentType(c.contentTypes().JSON))
+	c.Response().WriteHeader(http.StatusOK)
+	_, err := c.Response().Write(buf.Bytes())
+	return err
+}
+
 // Text is a helper function for writing a text/plain string to the ResponseWriter.
 func (c *Context) Text(code int, text string) error {
-	c.Response().Header().Set("Content-Type", "text/plain")
+	c.Response().Header().Set("Content-Type", c.contentType(c.contentTypes().Text))
 	c.Response().WriteHeader(code)
 	c.Response().Write([]byte(text))
 	return nil
@@ -324,15 +850,37 @@ func (c *Context) Param(name string) string {
 	return c.vars.ByName(name)
 }
 
+// FullPath returns the route pattern that matched the request, like
+// /users/:id.
+func (c *Context) FullPath() string {
+	return c.route
+}
+
 // Query returns the url query parameter by its name.
 // app.Get("/api?limit=25", ..) => ctx.Query("limit").
 func (c *Context) Query(name string) string {
-	return c.request.URL.Query().Get(name)
+	return c.queryValues().Get(name)
+}
+
+// queryValues returns the parsed query string of the request, it is parsed
+// again only when the query string changed.
+func (c *Context) queryValues() url.Values {
+	if c.query == nil || c.rawQuery != c.request.URL.RawQuery {
+		c.query, _ = url.ParseQuery(c.request.URL.RawQuery)
+		c.rawQuery = c.request.URL.RawQuery
+	}
+	return c.query
 }
 
-// Form returns the form parameter by its name.
+// Form returns the form parameter by its name. A multipart form is parsed
+// within the UploadLimits of the application, a form that exceeds them has
+// no form values.
 func (c *Context) Form(name string) string {
-	return c.request.FormValue(name)
+	c.parseForm()
+	if values := c.request.Form[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
 }
 
 // Header returns the request header by name.
@@ -349,10 +897,60 @@ func (c *Context) Redirect(url string, code int) error {
 	return nil
 }
 
+// Abort prevents the pending middleware and the handler from being called.
+// Middleware that already wrote a complete response, like a cache hit,
+// calls Abort to short-circuit the request.
+func (c *Context) Abort() {
+	c.aborted = true
+}
+
+// onFinish registers fn to be called after the handler chain has finished.
+// The functions are called in reverse order of registration.
+func (c *Context) onFinish(fn func()) {
+	c.deferred = append(c.deferred, fn)
+}
+
+func (c *Context) finish() {
+	for i := len(c.deferred) - 1; i >= 0; i-- {
+		c.deferred[i]()
+	}
+}
+
+var contextPool = sync.Pool{
+	New: func() any { return new(Context) },
+}
+
+// release resets the Context and puts it back in the pool. The slice of
+// deferred functions and the dependency cache keep their memory.
+func (c *Context) release() {
+	clear(c.deferred)
+	clear(c.deps)
+	*c = Context{deferred: c.deferred[:0], deps: c.deps}
+	contextPool.Put(c)
+}
+
 type responseLogger struct {
 	c      http.ResponseWriter
 	status int
 	size   int
+	route  string
+	params httprouter.Params
+}
+
+var responseLoggerPool = sync.Pool{
+	New: func() any { return new(responseLogger) },
+}
+
+func newResponseLogger(rw http.ResponseWriter) *responseLogger {
+	l := responseLoggerPool.Get().(*responseLogger)
+	l.c = rw
+	return l
+}
+
+// release resets the responseLogger and puts it back in the pool.
+func (l *responseLogger) release() {
+	*l = responseLogger{}
+	responseLoggerPool.Put(l)
 }
 
 func (l *responseLogger) Write(p []byte) (int, error) {
@@ -373,6 +971,22 @@ func (l *responseLogger) WriteHeader(code int) {
 	l.status = code
 }
 
+// Flush flushes the response to the client when the underlying writer
+// supports it, which streaming handlers and proxies rely on.
+func (l *responseLogger) Flush() {
+	if l.status == 0 {
+		l.status = http.StatusOK
+	}
+	if f, ok := l.c.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (l *responseLogger) Unwrap() http.ResponseWriter {
+	return l.c
+}
+
 func (l *responseLogger) Status() int {
 	return l.status
 }
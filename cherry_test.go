@@ -9,6 +9,8 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -93,6 +95,23 @@ func TestStatic(t *testing.T) {
 	}
 }
 
+func TestStaticFS(t *testing.T) {
+	c := New()
+	c.StaticFS("/public", fstest.MapFS{
+		"css/app.css": &fstest.MapFile{Data: []byte("body{}")},
+	})
+	code, body := doRequest(t, "GET", "/public/css/app.css", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "body{}" {
+		t.Errorf("expecting body{} got %s", body)
+	}
+
+	code, _ = doRequest(t, "GET", "/public/nofile", nil, c)
+	if code != http.StatusNotFound {
+		t.Errorf("expecting status 404 got %d", code)
+	}
+}
+
 func TestContext(t *testing.T) {
 	c := New()
 	c.Get("/", checkContext(t, "m1", "m1"))
@@ -154,6 +173,72 @@ func TestBindContextSubrouter(t *testing.T) {
 	isHTTPStatusOK(t, code)
 }
 
+func TestBindContextLayered(t *testing.T) {
+	c := New()
+	admin := c.Group("/admin")
+	admin.BindContext(context.WithValue(context.Background(), "role", "admin"))
+	c.BindContext(context.WithValue(context.WithValue(context.Background(), "role", "user"), "db", "main"))
+	reports := admin.Group("/reports")
+
+	c.Get("/", checkContext(t, "role", "user"))
+	admin.Get("/", checkContext(t, "role", "admin"))
+	admin.Get("/db", checkContext(t, "db", "main"))
+	reports.Get("/", checkContext(t, "role", "admin"))
+	reports.Get("/db", checkContext(t, "db", "main"))
+
+	for _, route := range []string{"/", "/admin", "/admin/db", "/admin/reports", "/admin/reports/db"} {
+		code, _ := doRequest(t, "GET", route, nil, c)
+		isHTTPStatusOK(t, code)
+	}
+}
+
+func TestBindContextCancellation(t *testing.T) {
+	c := New()
+	bound, cancelBound := context.WithCancel(context.WithValue(context.Background(), "db", "main"))
+	defer cancelBound()
+	admin := c.Group("/admin")
+	admin.BindContext(context.WithValue(context.Background(), "role", "admin"))
+	c.BindContext(bound)
+	deadline := time.Now().Add(time.Hour)
+	reports := admin.Group("/reports")
+	reportsCtx, cancelReports := context.WithDeadline(context.Background(), deadline)
+	defer cancelReports()
+	reports.BindContext(reportsCtx)
+
+	var err error
+	admin.Get("/", func(ctx *Context) error {
+		<-ctx.Context.Done()
+		err = ctx.Context.Err()
+		if ctx.Context.Value("db") != "main" || ctx.Context.Value("role") != "admin" {
+			t.Errorf("expecting the bound values got %v %v", ctx.Context.Value("db"), ctx.Context.Value("role"))
+		}
+		return nil
+	})
+	reports.Get("/", func(ctx *Context) error {
+		if d, ok := ctx.Context.Deadline(); !ok || !d.Equal(deadline) {
+			t.Errorf("expecting the deadline of the bound context got %v", d)
+		}
+		return nil
+	})
+
+	// the client goes away.
+	r, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin", nil).WithContext(r))
+	if err != context.Canceled {
+		t.Errorf("expecting the request cancellation got %v", err)
+	}
+
+	// the bound context of the application is cancelled.
+	err = nil
+	time.AfterFunc(10*time.Millisecond, cancelBound)
+	c.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin", nil))
+	if err != context.Canceled {
+		t.Errorf("expecting the cancellation of the bound context got %v", err)
+	}
+	doRequest(t, "GET", "/admin/reports", nil, c)
+}
+
 func checkContext(t *testing.T, key, expect string) Handler {
 	return func(ctx *Context) error {
 		value := ctx.Context.Value(key).(string)
@@ -335,6 +420,11 @@ func TestContextURLQuery(t *testing.T) {
 	if ctx.Query("name") != "anthony" {
 		t.Errorf("expected anthony got %s", ctx.Query("name"))
 	}
+	// the cached query is parsed again when the query string changes.
+	req.URL.RawQuery = "name=cherry"
+	if ctx.Query("name") != "cherry" {
+		t.Errorf("expected cherry got %s", ctx.Query("name"))
+	}
 }
 
 func TestContextForm(t *testing.T) {
@@ -366,7 +456,17 @@ func TestTerminal(t *testing.T) {
 	c.SetMethodNotAllowed(handler)
 	c.Get("/", noopHandler)
 
-	c.Serve(3000)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Serve(0)
+	}()
+	waitForServer(t, c)
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != ErrServerClosed {
+		t.Errorf("expecting ErrServerClosed got %v", err)
+	}
 }
 
 func isHTTPStatusOK(t *testing.T, code int) {
@@ -384,3 +484,129 @@ func doRequest(t *testing.T, method, route string, body io.Reader, c *Cherry) (i
 	c.ServeHTTP(rw, r)
 	return rw.Code, rw.Body.String()
 }
+
+func TestContextRelease(t *testing.T) {
+	c := New()
+	c.Get("/set", func(ctx *Context) error {
+		WithValue(ctx, "leaked")
+		ctx.deps = map[any]any{"dep": 1}
+		ctx.onFinish(func() {})
+		return errors.New("failed")
+	})
+	c.Get("/check", func(ctx *Context) error {
+		if _, ok := Value[string](ctx); ok {
+			t.Error("expecting the values of the previous request to be reset")
+		}
+		if len(ctx.deps) != 0 || len(ctx.deferred) != 0 || ctx.err != nil || ctx.aborted {
+			t.Error("expecting the state of the previous request to be reset")
+		}
+		return nil
+	})
+	for i := 0; i < 10; i++ {
+		doRequest(t, "GET", "/set", nil, c)
+		doRequest(t, "GET", "/check", nil, c)
+	}
+}
+
+func benchmarkRoute(b *testing.B, c *Cherry, route string) {
+	r := httptest.NewRequest("GET", route, nil)
+	w := &discardWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	c := New()
+	c.Get("/hello", noopHandler)
+	benchmarkRoute(b, c, "/hello")
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	c := New()
+	c.Get("/hello/:name", func(ctx *Context) error {
+		ctx.Param("name")
+		return nil
+	})
+	benchmarkRoute(b, c, "/hello/cherry")
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	c := New()
+	c.Use(noopHandler, noopHandler, noopHandler)
+	c.Get("/hello", noopHandler)
+	benchmarkRoute(b, c, "/hello")
+}
+
+func BenchmarkAccessLog(b *testing.B) {
+	c := New(WithOutput(io.Discard), WithAccessLog())
+	c.Get("/hello", noopHandler)
+	benchmarkRoute(b, c, "/hello")
+}
+
+// benchmarkAPI is the route table of BenchmarkRouteTable, a typical REST API.
+var benchmarkAPI = []string{
+	"/users", "/users/:id", "/users/:id/orders", "/users/:id/orders/:order",
+	"/orders", "/orders/:id", "/orders/:id/items", "/products", "/products/:id",
+	"/products/:id/reviews", "/carts/:id", "/carts/:id/items/:item",
+	"/search", "/health", "/metrics", "/static/*filepath",
+}
+
+func BenchmarkRouteTable(b *testing.B) {
+	c := New()
+	for _, route := range benchmarkAPI {
+		c.Get(route, noopHandler)
+	}
+	benchmarkRoute(b, c, "/users/42/orders/7")
+}
+
+func BenchmarkGroupMiddleware(b *testing.B) {
+	c := New()
+	c.Use(noopHandler)
+	api := c.Group("/api")
+	api.Use(noopHandler, noopHandler)
+	api.Get("/users/:id", noopHandler)
+	benchmarkRoute(b, c, "/api/users/42")
+}
+
+func BenchmarkParallel(b *testing.B) {
+	c := New()
+	c.Get("/hello/:name", noopHandler)
+	r := httptest.NewRequest("GET", "/hello/cherry", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		w := &discardWriter{header: http.Header{}}
+		for pb.Next() {
+			c.ServeHTTP(w, r)
+		}
+	})
+}
+
+func BenchmarkQuery(b *testing.B) {
+	c := New()
+	c.Get("/search", func(ctx *Context) error {
+		ctx.Query("q")
+		ctx.Query("page")
+		ctx.Query("limit")
+		return nil
+	})
+	benchmarkRoute(b, c, "/search?q=cherry&page=2&limit=25")
+}
+
+func BenchmarkJSON(b *testing.B) {
+	c := New()
+	user := map[string]any{"id": 42, "name": "cherry", "tags": []string{"go", "http"}}
+	c.Get("/user", func(ctx *Context) error {
+		return ctx.JSON(http.StatusOK, user)
+	})
+	benchmarkRoute(b, c, "/user")
+}
+
+func BenchmarkAccessLogCombined(b *testing.B) {
+	c := New(WithOutput(io.Discard), WithAccessLog(LogCombined))
+	c.Get("/hello", noopHandler)
+	benchmarkRoute(b, c, "/hello")
+}
@@ -0,0 +1,202 @@
+// Package cherrytest helps testing Cherry applications.
+//
+// Snapshot records a response to a golden file the first time and compares
+// the response with it afterwards, so a regression shows as a diff. Run the
+// tests with -update-snapshots, or CHERRY_UPDATE_SNAPSHOTS=1, to record the
+// responses again after an intended change.
+//
+//	func TestListUsers(t *testing.T) {
+//		rw := httptest.NewRecorder()
+//		app.ServeHTTP(rw, httptest.NewRequest("GET", "/users", nil))
+//		cherrytest.Snapshot(t, "users", rw.Result(), cherrytest.IgnoreFields("created_at"))
+//	}
+package cherrytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update-snapshots", false, "record the snapshots of cherrytest again")
+
+// Dir is the directory of the golden files.
+var Dir = filepath.Join("testdata", "snapshots")
+
+type config struct {
+	headers  []string
+	ignored  map[string]bool
+	replaces []replace
+}
+
+type replace struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// Option configures a Snapshot.
+type Option func(*config)
+
+// Headers sets the headers that are recorded. The default is Content-Type.
+func Headers(names ...string) Option {
+	return func(c *config) {
+		c.headers = names
+	}
+}
+
+// IgnoreFields replaces the values of the fields of a JSON body with the
+// given names, at any depth, like ids and timestamps that change with every
+// run.
+func IgnoreFields(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.ignored[name] = true
+		}
+	}
+}
+
+// Replace replaces the matches of the regular expression in the recorded
+// body, like the nonces of an html page.
+func Replace(pattern, repl string) Option {
+	re := regexp.MustCompile(pattern)
+	return func(c *config) {
+		c.replaces = append(c.replaces, replace{re: re, repl: repl})
+	}
+}
+
+// Snapshot compares the status, the headers and the normalized body of res
+// with the golden file name in Dir, and records it when the file does not
+// exist yet. JSON bodies are indented with sorted keys.
+func Snapshot(t testing.TB, name string, res *http.Response, opts ...Option) {
+	t.Helper()
+	cfg := config{headers: []string{"Content-Type"}, ignored: map[string]bool{}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("cherrytest: reading the body: %v", err)
+	}
+	got := format(res, body, cfg)
+
+	file := filepath.Join(Dir, strings.NewReplacer("/", "_", " ", "_").Replace(name)+".golden")
+	want, err := os.ReadFile(file)
+	if *update || os.Getenv("CHERRY_UPDATE_SNAPSHOTS") == "1" || os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("cherrytest: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+			t.Fatalf("cherrytest: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("cherrytest: %v", err)
+	}
+	if string(want) != got {
+		t.Errorf("cherrytest: response does not match %s, run with -update-snapshots to record it\n%s", file, diff(string(want), got))
+	}
+}
+
+// format returns the recorded form of a response.
+func format(res *http.Response, body []byte, cfg config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP %d\n", res.StatusCode)
+	for _, name := range cfg.headers {
+		for _, v := range res.Header.Values(name) {
+			fmt.Fprintf(&b, "%s: %s\n", http.CanonicalHeaderKey(name), v)
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(normalize(body, cfg))
+	return b.String()
+}
+
+func normalize(body []byte, cfg config) string {
+	var v any
+	if json.Unmarshal(body, &v) == nil {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		enc.Encode(ignore(v, cfg.ignored))
+		body = buf.Bytes()
+	}
+	s := string(body)
+	for _, r := range cfg.replaces {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	s = strings.Join(lines, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
+
+// ignore replaces the values of the ignored fields.
+func ignore(v any, ignored map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if ignored[k] {
+				v[k] = "<ignored>"
+				continue
+			}
+			v[k] = ignore(field, ignored)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = ignore(e, ignored)
+		}
+	}
+	return v
+}
+
+// diff returns a line diff of want and got.
+func diff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
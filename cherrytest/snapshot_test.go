@@ -0,0 +1,90 @@
+package cherrytest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pooulad/cherry"
+)
+
+// recorder records the errors of a Snapshot instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func response(app *cherry.Cherry, target string) *http.Response {
+	rw := httptest.NewRecorder()
+	app.ServeHTTP(rw, httptest.NewRequest("GET", target, nil))
+	return rw.Result()
+}
+
+func TestSnapshot(t *testing.T) {
+	Dir = t.TempDir()
+	defer func() { Dir = filepath.Join("testdata", "snapshots") }()
+
+	id := 1
+	app := cherry.New()
+	app.Get("/users", func(ctx *cherry.Context) error {
+		ctx.Response().Header().Set("X-Request-ID", fmt.Sprint(id))
+		return ctx.JSON(http.StatusOK, []map[string]any{{"name": "ada", "id": id, "tags": []string{"<admin>"}}})
+	})
+
+	Snapshot(t, "users/list", response(app, "/users"), IgnoreFields("id"))
+	golden, err := os.ReadFile(filepath.Join(Dir, "users_list.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "HTTP 200\nContent-Type: application/json; charset=utf-8\n\n[\n  {\n    \"id\": \"<ignored>\",\n    \"name\": \"ada\",\n    \"tags\": [\n      \"<admin>\"\n    ]\n  }\n]\n"
+	if string(golden) != expect {
+		t.Errorf("unexpected golden file\n%s", golden)
+	}
+
+	// the ignored id changes without a diff.
+	id = 2
+	rec := &recorder{TB: t}
+	Snapshot(rec, "users/list", response(app, "/users"), IgnoreFields("id"))
+	if len(rec.errors) != 0 {
+		t.Errorf("expecting the snapshot to match got %v", rec.errors)
+	}
+
+	// a recorded header that changes is a diff.
+	Snapshot(rec, "users/list", response(app, "/users"), IgnoreFields("id"), Headers("Content-Type", "X-Request-ID"))
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "+ X-Request-Id: 2") {
+		t.Errorf("expecting a diff got %v", rec.errors)
+	}
+}
+
+func TestSnapshotReplace(t *testing.T) {
+	Dir = t.TempDir()
+	defer func() { Dir = filepath.Join("testdata", "snapshots") }()
+
+	nonce := "abc"
+	app := cherry.New()
+	app.Get("/", func(ctx *cherry.Context) error {
+		return ctx.Blob(http.StatusOK, "text/html", []byte(`<script nonce="`+nonce+`"></script>   `))
+	})
+	Snapshot(t, "page", response(app, "/"), Replace(`nonce="\w+"`, `nonce="*"`))
+	nonce = "def"
+	rec := &recorder{TB: t}
+	Snapshot(rec, "page", response(app, "/"), Replace(`nonce="\w+"`, `nonce="*"`))
+	if len(rec.errors) != 0 {
+		t.Errorf("expecting the snapshot to match got %v", rec.errors)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := diff("a\nb\nc", "a\nx\nc")
+	if got != "  a\n- b\n+ x\n  c\n" {
+		t.Errorf("unexpected diff\n%s", got)
+	}
+}
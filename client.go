@@ -0,0 +1,114 @@
+package cherry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ClientConfig configures the clients of Context.Client.
+type ClientConfig struct {
+	// Transport sends the requests. The default is http.DefaultTransport
+	Transport http.RoundTripper
+
+	// Timeout limits the time of a request, on top of the deadline of the
+	// incoming request. The default is no timeout
+	Timeout time.Duration
+
+	// ForwardAuth forwards the Authorization header of the incoming request,
+	// for services that trust the same credentials. The default is false
+	ForwardAuth bool
+
+	// ForwardHeaders are other headers of the incoming request that are
+	// forwarded, like Accept-Language
+	ForwardHeaders []string
+}
+
+// Client returns an http.Client for the calls of a handler to other
+// services. Its requests are canceled with the incoming request and carry
+// its deadline, its trace (see Context.Trace) and its X-Request-ID, and the
+// headers that app.Client forwards. Headers set on a request are not
+// overridden. The client must not be used after the handler has returned.
+//
+// res, err := ctx.Client().Get("http://billing/invoices").
+func (c *Context) Client() *http.Client {
+	var cfg ClientConfig
+	if c.cherry != nil {
+		cfg = c.cherry.Client
+	}
+	base := cfg.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.Trace()
+	r := c.Request()
+	t := &clientTransport{base: TraceTransport(base), ctx: r.Context(), header: http.Header{}}
+	if cfg.ForwardAuth {
+		cfg.ForwardHeaders = append([]string{"Authorization"}, cfg.ForwardHeaders...)
+	}
+	for _, name := range cfg.ForwardHeaders {
+		if values := r.Header.Values(name); len(values) > 0 {
+			t.header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return &http.Client{Transport: t, Timeout: cfg.Timeout}
+}
+
+// clientTransport binds the outgoing requests to an incoming request.
+type clientTransport struct {
+	base http.RoundTripper
+	// ctx is the context of the incoming request.
+	ctx    context.Context
+	header http.Header
+}
+
+func (t *clientTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(r.Context())
+	stop := context.AfterFunc(t.ctx, func() { cancel(context.Cause(t.ctx)) })
+	release := func() {
+		stop()
+		cancel(nil)
+	}
+	if deadline, ok := t.ctx.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		release = func() {
+			stop()
+			cancelDeadline()
+			cancel(nil)
+		}
+	}
+	for _, key := range []any{typeKey[TraceContext]{}, typeKey[requestID]{}} {
+		if v := t.ctx.Value(key); v != nil && ctx.Value(key) == nil {
+			ctx = context.WithValue(ctx, key, v)
+		}
+	}
+	// a RoundTripper must not modify the request.
+	r = r.Clone(ctx)
+	for name, values := range t.header {
+		if _, ok := r.Header[name]; !ok {
+			r.Header[name] = values
+		}
+	}
+	res, err := t.base.RoundTrip(r)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &releaseBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+// releaseBody releases the context of a request when its response body is
+// closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,104 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContextClient(t *testing.T) {
+	var outgoing http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Clone()
+		io.WriteString(w, "pong")
+	}))
+	defer upstream.Close()
+
+	c := New()
+	c.Client = ClientConfig{ForwardAuth: true, ForwardHeaders: []string{"accept-language", "X-Tenant"}}
+	c.Get("/", func(ctx *Context) error {
+		req, _ := http.NewRequest("GET", upstream.URL, nil)
+		req.Header.Set("X-Tenant", "own")
+		res, err := ctx.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return ctx.Text(http.StatusOK, string(b))
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	r.Header.Set("Accept-Language", "fr")
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Set("X-Request-ID", "req-1")
+	r.Header.Set("traceparent", testTraceparent)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK || rw.Body.String() != "pong" {
+		t.Fatalf("expecting 200 pong got %d %s", rw.Code, rw.Body)
+	}
+	tc, ok := ParseTraceContext(outgoing)
+	if !ok || tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expecting the trace to be forwarded got %v", outgoing)
+	}
+	expect := map[string]string{"Authorization": "Bearer token", "Accept-Language": "fr", "X-Tenant": "own", "X-Request-Id": "req-1"}
+	for name, value := range expect {
+		if outgoing.Get(name) != value {
+			t.Errorf("expecting %s: %s got %q", name, value, outgoing.Get(name))
+		}
+	}
+
+	c.Client.ForwardAuth = false
+	c.ServeHTTP(httptest.NewRecorder(), r)
+	if outgoing.Get("Authorization") != "" {
+		t.Error("expecting the Authorization header not to be forwarded by default")
+	}
+}
+
+func TestContextClientCanceled(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	c := New()
+	var (
+		clientErr error
+		deadline  bool
+	)
+	c.Client.Transport = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		_, deadline = r.Context().Deadline()
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	c.Get("/", func(ctx *Context) error {
+		_, clientErr = ctx.Client().Get(upstream.URL)
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	c.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if !errors.Is(clientErr, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("expecting the outgoing request to end with the incoming request got %v", clientErr)
+	}
+	if !deadline {
+		t.Error("expecting the outgoing request to carry the deadline")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
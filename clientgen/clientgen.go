@@ -0,0 +1,280 @@
+// Package clientgen generates typed clients of a Cherry application from its
+// route table, so the consumers of a service do not hand-write requests
+// that drift from the server. The models of the routes are documented with
+// Route.Request and Route.Response.
+//
+//	app.Get("/users/:id", showUser).Name("getUser").Response(User{})
+//	app.Post("/users", createUser).Name("createUser").Request(User{}).Response(User{})
+//
+//	var buf bytes.Buffer
+//	err := clientgen.Go(&buf, app.Routes(), clientgen.Options{Package: "usersapi"})
+//
+// The Go client has a method for every route, with the url parameters as
+// arguments, like GetUser(ctx, id string) (*User, error). The TypeScript
+// client has the same methods in lower camel case.
+package clientgen
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pooulad/cherry"
+)
+
+// Options configure the generated clients.
+type Options struct {
+	// Package is the name of the package of the Go client. The default is
+	// client
+	Package string
+
+	// Tag limits the client to the routes with the tag. The default is all
+	// of the routes
+	Tag string
+
+	// Envelope unwraps the data of the responses of Context.OK and
+	// Context.Created
+	Envelope bool
+}
+
+// endpoint is a method of a client.
+type endpoint struct {
+	name     string
+	method   string
+	path     string
+	params   []param
+	request  reflect.Type
+	response reflect.Type
+}
+
+// param is a url parameter of an endpoint, catchAll params hold slashes.
+type param struct {
+	name     string
+	catchAll bool
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// endpoints returns the endpoints of the routes in the order of the routes.
+// Routes are named after their Name, or after their method and path.
+func endpoints(routes []cherry.RouteInfo, opts Options) ([]endpoint, error) {
+	var eps []endpoint
+	names := map[string]string{}
+	for _, r := range routes {
+		if opts.Tag != "" && !hasTag(r.Tags, opts.Tag) {
+			continue
+		}
+		if r.Method == "HEAD" || r.Method == "OPTIONS" {
+			continue
+		}
+		ep := endpoint{method: r.Method, path: r.Path, request: r.Request, response: r.Response}
+		for _, seg := range strings.Split(r.Path, "/") {
+			if seg != "" && (seg[0] == ':' || seg[0] == '*') {
+				ep.params = append(ep.params, param{name: seg[1:], catchAll: seg[0] == '*'})
+			}
+		}
+		ep.name = exported(r.Name)
+		if r.Name == "" {
+			ep.name = routeName(r.Method, r.Path)
+		}
+		if prev, ok := names[ep.name]; ok {
+			return nil, fmt.Errorf("clientgen: %s %s and %s are both named %s", r.Method, r.Path, prev, ep.name)
+		}
+		names[ep.name] = r.Method + " " + r.Path
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// routeName names a route after its method and path, GET /users/:id/orders
+// is GetUsersByIDOrders.
+func routeName(method, path string) string {
+	name := exported(strings.ToLower(method))
+	for _, seg := range strings.Split(path, "/") {
+		switch {
+		case seg == "":
+		case seg[0] == ':' || seg[0] == '*':
+			name += "By" + exported(seg[1:])
+		default:
+			name += exported(seg)
+		}
+	}
+	return name
+}
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{"id": true, "url": true, "uri": true, "api": true, "http": true, "json": true, "ip": true, "uuid": true}
+
+// exported returns s in upper camel case, blog-posts.show is BlogPostsShow.
+func exported(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	var b strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "Call" + name
+	}
+	return name
+}
+
+// unexported returns s in lower camel case, which is not a keyword.
+func unexported(s string) string {
+	name := exported(s)
+	upper := 0
+	for upper < len(name) && unicode.IsUpper(rune(name[upper])) {
+		upper++
+	}
+	if upper > 1 && upper < len(name) {
+		// keeps the upper case letter of the next word, IDList is idList.
+		upper--
+	}
+	name = strings.ToLower(name[:upper]) + name[upper:]
+	if token.IsKeyword(name) {
+		name += "Param"
+	}
+	return name
+}
+
+// models collects the named struct types of the models of the endpoints.
+type models struct {
+	types map[string]reflect.Type
+}
+
+func collectModels(eps []endpoint) (*models, error) {
+	m := &models{types: map[string]reflect.Type{}}
+	for _, ep := range eps {
+		for _, t := range []reflect.Type{ep.request, ep.response} {
+			if err := m.collect(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *models) collect(t reflect.Type) error {
+	if t == nil || isOpaque(t) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return m.collect(t.Elem())
+	case reflect.Map:
+		return m.collect(t.Elem())
+	case reflect.Struct:
+		if t.Name() != "" {
+			if prev, ok := m.types[t.Name()]; ok {
+				if prev != t {
+					return fmt.Errorf("clientgen: %s and %s are both named %s", prev, t, t.Name())
+				}
+				return nil
+			}
+			m.types[t.Name()] = t
+		}
+		for _, f := range fields(t) {
+			if err := m.collect(f.typ); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// names returns the names of the models in alphabetical order.
+func (m *models) names() []string {
+	names := make([]string, 0, len(m.types))
+	for name := range m.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isOpaque reports whether t is encoded by itself rather than by its
+// fields, like time.Time.
+func isOpaque(t reflect.Type) bool {
+	return t == timeType || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// field is an encoded field of a model.
+type field struct {
+	goName    string
+	jsonName  string
+	tag       string
+	typ       reflect.Type
+	embedded  bool
+	omitEmpty bool
+}
+
+// fields returns the fields of a struct that encoding/json encodes.
+func fields(t reflect.Type) []field {
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			base := ft
+			if base.Kind() == reflect.Pointer {
+				base = base.Elem()
+			}
+			if base.Kind() == reflect.Struct && !isOpaque(base) {
+				fs = append(fs, field{goName: base.Name(), typ: ft, embedded: true})
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs = append(fs, field{
+			goName:    f.Name,
+			jsonName:  name,
+			tag:       tag,
+			typ:       ft,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fs
+}
@@ -0,0 +1,197 @@
+package clientgen
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pooulad/cherry"
+)
+
+type Address struct {
+	City string `json:"city"`
+}
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type User struct {
+	Audit
+	ID       int               `json:"id"`
+	Name     string            `json:"name"`
+	Email    string            `json:"email,omitempty"`
+	Address  *Address          `json:"address"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Password string            `json:"-"`
+	internal int
+}
+
+func testApp() *cherry.Cherry {
+	app := cherry.New()
+	users := map[string]*User{"7": {ID: 7, Name: "anthony", Address: &Address{City: "Paris"}}}
+	app.Get("/users", func(ctx *cherry.Context) error {
+		return ctx.JSON(http.StatusOK, []*User{users["7"]})
+	}).Name("listUsers").Response([]User{}).Tag("public")
+	app.Get("/users/:id", func(ctx *cherry.Context) error {
+		u, ok := users[ctx.Param("id")]
+		if !ok {
+			return cherry.NewHTTPError(http.StatusNotFound, "no such user")
+		}
+		return ctx.JSON(http.StatusOK, u)
+	}).Name("getUser").Response(User{}).Tag("public")
+	app.Post("/users", func(ctx *cherry.Context) error {
+		u := &User{}
+		if err := ctx.Bind(u); err != nil {
+			return err
+		}
+		u.ID = 8
+		return ctx.JSON(http.StatusCreated, u)
+	}).Name("createUser").Request(User{}).Response(User{})
+	app.Delete("/users/:id", func(ctx *cherry.Context) error {
+		return ctx.Text(http.StatusNoContent, "")
+	})
+	app.Get("/files/*path", func(ctx *cherry.Context) error {
+		return ctx.JSON(http.StatusOK, ctx.Param("path"))
+	}).Response("")
+	return app
+}
+
+func TestGo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Go(buf, testApp().Routes(), Options{Package: "usersapi"}); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	for _, expect := range []string{
+		"package usersapi",
+		"func (c *Client) ListUsers(ctx context.Context) ([]User, error)",
+		"func (c *Client) GetUser(ctx context.Context, id string) (*User, error)",
+		"func (c *Client) CreateUser(ctx context.Context, body User) (*User, error)",
+		"func (c *Client) DeleteUsersByID(ctx context.Context, id string) error",
+		"func (c *Client) GetFilesByPath(ctx context.Context, path string) (string, error)",
+		"type User struct {\n\tAudit\n\tID      int               `json:\"id\"`",
+		"Address *Address          `json:\"address\"`",
+		"CreatedAt time.Time `json:\"created_at\"`",
+	} {
+		if !strings.Contains(src, expect) {
+			t.Errorf("expecting the client to contain %s got\n%s", expect, src)
+		}
+	}
+	if strings.Contains(src, "Password") || strings.Contains(src, "internal") {
+		t.Errorf("expecting the fields that are not encoded to be skipped got\n%s", src)
+	}
+}
+
+func TestGoTag(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Go(buf, testApp().Routes(), Options{Tag: "public"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "GetUser(") || strings.Contains(buf.String(), "CreateUser(") {
+		t.Errorf("expecting only the public routes got\n%s", buf.String())
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	routes := []cherry.RouteInfo{
+		{Method: "GET", Path: "/users", Name: "users"},
+		{Method: "POST", Path: "/users", Name: "Users"},
+	}
+	if err := Go(&bytes.Buffer{}, routes, Options{}); err == nil {
+		t.Error("expecting an error for routes with the same name")
+	}
+}
+
+func TestRouteName(t *testing.T) {
+	tests := map[[2]string]string{
+		{"GET", "/users/:id/orders"}:  "GetUsersByIDOrders",
+		{"DELETE", "/blog-posts/:id"}: "DeleteBlogPostsByID",
+		{"GET", "/"}:                  "Get",
+		{"GET", "/static/*filepath"}:  "GetStaticByFilepath",
+	}
+	for route, expect := range tests {
+		if name := routeName(route[0], route[1]); name != expect {
+			t.Errorf("%v: expecting %s got %s", route, expect, name)
+		}
+	}
+	if name := unexported("type"); name != "typeParam" {
+		t.Errorf("expecting typeParam got %s", name)
+	}
+	if name := unexported("user_id"); name != "userID" {
+		t.Errorf("expecting userID got %s", name)
+	}
+}
+
+const clientProgram = `package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"example.com/client/usersapi"
+)
+
+func main() {
+	c := usersapi.New(os.Getenv("BASE_URL"))
+	ctx := context.Background()
+	users, err := c.ListUsers(ctx)
+	fmt.Println(len(users), users[0].Name, users[0].Address.City, err)
+	u, err := c.CreateUser(ctx, usersapi.User{Name: "cherry"})
+	fmt.Println(u.ID, u.Name, err)
+	_, err = c.GetUser(ctx, "9")
+	var apiErr *usersapi.Error
+	fmt.Println(errors.As(err, &apiErr) && apiErr.StatusCode == 404)
+	fmt.Println(c.DeleteUsersByID(ctx, "7"))
+	p, err := c.GetFilesByPath(ctx, "docs/a b.txt")
+	fmt.Println(p, err)
+}
+`
+
+// TestGoClient builds the generated client and calls the application with
+// it.
+func TestGoClient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	app := testApp()
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	buf := &bytes.Buffer{}
+	if err := Go(buf, app.Routes(), Options{Package: "usersapi"}); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":             "module example.com/client\n\ngo 1.21\n",
+		"main.go":            clientProgram,
+		"usersapi/client.go": buf.String(),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "BASE_URL="+srv.URL)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	expect := "1 anthony Paris <nil>\n8 cherry <nil>\ntrue\n<nil>\n/docs/a b.txt <nil>\n"
+	if string(out) != expect {
+		t.Errorf("expecting\n%s\ngot\n%s", expect, out)
+	}
+}
@@ -0,0 +1,266 @@
+package clientgen
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pooulad/cherry"
+)
+
+// Go writes a Go client package of the routes.
+func Go(w io.Writer, routes []cherry.RouteInfo, opts Options) error {
+	eps, err := endpoints(routes, opts)
+	if err != nil {
+		return err
+	}
+	m, err := collectModels(eps)
+	if err != nil {
+		return err
+	}
+	g := &goWriter{imports: map[string]bool{
+		"bytes": true, "context": true, "encoding/json": true, "fmt": true,
+		"io": true, "net/http": true, "strings": true,
+	}}
+	for _, name := range m.names() {
+		g.model(m.types[name])
+	}
+	for _, ep := range eps {
+		g.endpoint(ep)
+	}
+	g.runtime(opts.Envelope)
+
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "client"
+	}
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by cherry clientgen. DO NOT EDIT.\n\n// Package %s is a client of the API.\npackage %s\n\nimport (\n", pkg, pkg)
+	for _, imp := range sortedKeys(g.imports) {
+		fmt.Fprintf(&src, "\t%q\n", imp)
+	}
+	src.WriteString(")\n")
+	src.WriteString(g.body.String())
+	b, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return fmt.Errorf("clientgen: formatting the client: %w", err)
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+type goWriter struct {
+	body    strings.Builder
+	imports map[string]bool
+}
+
+func (g *goWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *goWriter) model(t reflect.Type) {
+	g.printf("\n// %s is a model of the API.\ntype %s %s\n", t.Name(), t.Name(), g.structType(t))
+}
+
+func (g *goWriter) structType(t reflect.Type) string {
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, f := range fields(t) {
+		if f.embedded {
+			fmt.Fprintf(&b, "%s\n", g.typeOf(f.typ))
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s", f.goName, g.typeOf(f.typ))
+		if f.tag != "" {
+			fmt.Fprintf(&b, " `json:%q`", f.tag)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// typeOf returns the Go type expression of t, named structs are models.
+func (g *goWriter) typeOf(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		g.imports["time"] = true
+		return "time.Time"
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return "json.RawMessage"
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + g.typeOf(t.Elem())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "[]byte"
+		}
+		return "[]" + g.typeOf(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.typeOf(t.Elem()))
+	case reflect.Map:
+		return "map[" + g.typeOf(t.Key()) + "]" + g.typeOf(t.Elem())
+	case reflect.Struct:
+		if t.Name() != "" {
+			return t.Name()
+		}
+		return g.structType(t)
+	case reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return "any"
+	}
+	// named basic types like type Status string are written as their kind.
+	return t.Kind().String()
+}
+
+func (g *goWriter) endpoint(ep endpoint) {
+	args := []string{"ctx context.Context"}
+	path := `"` + ep.path + `"`
+	if len(ep.params) > 0 {
+		g.imports["net/url"] = true
+		var parts []string
+		rest := ep.path
+		for _, p := range ep.params {
+			arg := unexported(p.name)
+			if arg == "ctx" || arg == "body" {
+				arg += "Param"
+			}
+			args = append(args, arg+" string")
+			marker := ":" + p.name
+			if p.catchAll {
+				marker = "*" + p.name
+			}
+			before, after, _ := strings.Cut(rest, marker)
+			if before != "" {
+				parts = append(parts, fmt.Sprintf("%q", before))
+			}
+			escape := "url.PathEscape"
+			if p.catchAll {
+				escape = "escapePath"
+			}
+			parts = append(parts, escape+"("+arg+")")
+			rest = after
+		}
+		if rest != "" {
+			parts = append(parts, fmt.Sprintf("%q", rest))
+		}
+		path = strings.Join(parts, " + ")
+	}
+	in := "nil"
+	if ep.request != nil {
+		args = append(args, "body "+g.typeOf(ep.request))
+		in = "body"
+	}
+
+	g.printf("\n// %s calls %s %s.\n", ep.name, ep.method, ep.path)
+	if ep.response == nil {
+		g.printf("func (c *Client) %s(%s) error {\n", ep.name, strings.Join(args, ", "))
+		g.printf("return c.do(ctx, %q, %s, %s, nil)\n}\n", ep.method, path, in)
+		return
+	}
+	out := g.typeOf(ep.response)
+	result, ref, ret := out, "&out", "out"
+	if ep.response.Kind() == reflect.Struct {
+		result, ret = "*"+out, "&out"
+	}
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", ep.name, strings.Join(args, ", "), result)
+	g.printf("var out %s\n", out)
+	g.printf("if err := c.do(ctx, %q, %s, %s, %s); err != nil {\n", ep.method, path, in, ref)
+	if ep.response.Kind() == reflect.Struct {
+		g.printf("return nil, err\n}\n")
+	} else {
+		g.printf("return out, err\n}\n")
+	}
+	g.printf("return %s, nil\n}\n", ret)
+}
+
+func (g *goWriter) runtime(envelope bool) {
+	g.body.WriteString(goRuntime)
+	decode := "return json.NewDecoder(res.Body).Decode(out)"
+	if envelope {
+		decode = "return json.NewDecoder(res.Body).Decode(&struct {\nData any `json:\"data\"`\n}{out})"
+	}
+	g.printf("%s\n}\n", decode)
+	if g.imports["net/url"] {
+		g.body.WriteString(goEscapePath)
+	}
+}
+
+const goRuntime = `
+// Client calls the API.
+type Client struct {
+	// BaseURL is the URL of the API, like https://api.example.com.
+	BaseURL string
+	// HTTPClient sends the requests. The default is http.DefaultClient.
+	HTTPClient *http.Client
+	// Header is sent with every request, like an Authorization header.
+	Header http.Header
+}
+
+// New returns a client of the API at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+// Error is the error of a response with a status code of 400 or above.
+type Error struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), bytes.TrimSpace(e.Body))
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+		return &Error{StatusCode: res.StatusCode, Body: b}
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+`
+
+const goEscapePath = `
+// escapePath escapes the segments of a catch-all parameter.
+func escapePath(p string) string {
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+`
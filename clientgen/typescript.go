@@ -0,0 +1,189 @@
+package clientgen
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pooulad/cherry"
+)
+
+// TypeScript writes a TypeScript client module of the routes, which uses
+// fetch. The Package option is not used.
+func TypeScript(w io.Writer, routes []cherry.RouteInfo, opts Options) error {
+	eps, err := endpoints(routes, opts)
+	if err != nil {
+		return err
+	}
+	m, err := collectModels(eps)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("// Code generated by cherry clientgen. DO NOT EDIT.\n")
+	for _, name := range m.names() {
+		b.WriteString("\n" + tsModel(m.types[name]))
+	}
+
+	b.WriteString(`
+export class APIError extends Error {
+  constructor(public status: number, public body: string) {
+    super(status + ": " + body);
+  }
+}
+
+export class Client {
+  constructor(
+    private baseURL: string,
+    private headers: Record<string, string> = {},
+    private fetchFn: typeof fetch = (input, init) => fetch(input, init),
+  ) {
+    this.baseURL = baseURL.replace(/\/$/, "");
+  }
+`)
+	for _, ep := range eps {
+		b.WriteString("\n" + tsEndpoint(ep))
+	}
+	data := "res.json()"
+	if opts.Envelope {
+		data = "(await res.json()).data"
+	}
+	fmt.Fprintf(&b, `
+  private async request<T>(method: string, path: string, body?: unknown): Promise<T> {
+    const headers: Record<string, string> = { Accept: "application/json", ...this.headers };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    const res = await this.fetchFn(this.baseURL + path, {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (!res.ok) {
+      throw new APIError(res.status, await res.text());
+    }
+    if (res.status === 204) {
+      return undefined as T;
+    }
+    return %s;
+  }
+}
+`, data)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func tsModel(t reflect.Type) string {
+	var extends []string
+	var b strings.Builder
+	for _, f := range fields(t) {
+		if f.embedded {
+			extends = append(extends, tsType(f.typ))
+			continue
+		}
+		opt := ""
+		if f.omitEmpty {
+			opt = "?"
+		}
+		fmt.Fprintf(&b, "  %s%s: %s;\n", tsProperty(f.jsonName), opt, tsType(f.typ))
+	}
+	head := "export interface " + t.Name()
+	if len(extends) > 0 {
+		head += " extends " + strings.Join(extends, ", ")
+	}
+	return head + " {\n" + b.String() + "}\n"
+}
+
+// tsProperty quotes the names that are not identifiers.
+func tsProperty(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
+
+// tsType returns the TypeScript type of t, named structs are models.
+func tsType(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "string"
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return "unknown"
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return tsType(t.Elem()) + " | null"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		elem := tsType(t.Elem())
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<string, " + tsType(t.Elem()) + ">"
+	case reflect.Struct:
+		if t.Name() != "" {
+			return t.Name()
+		}
+		var parts []string
+		for _, f := range fields(t) {
+			if !f.embedded {
+				parts = append(parts, tsProperty(f.jsonName)+": "+tsType(f.typ))
+			}
+		}
+		return "{ " + strings.Join(parts, "; ") + " }"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "unknown"
+}
+
+// tsReserved are the reserved words of TypeScript that are not Go keywords.
+var tsReserved = map[string]bool{
+	"body": true, "class": true, "delete": true, "enum": true, "export": true, "extends": true,
+	"in": true, "new": true, "this": true, "typeof": true, "void": true, "with": true,
+}
+
+func tsEndpoint(ep endpoint) string {
+	var args []string
+	path := ep.path
+	for _, p := range ep.params {
+		arg := unexported(p.name)
+		if tsReserved[arg] {
+			arg += "Param"
+		}
+		args = append(args, arg+": string")
+		marker, escape := ":"+p.name, "encodeURIComponent("+arg+")"
+		if p.catchAll {
+			marker = "*" + p.name
+			escape = arg + `.replace(/^\//, "").split("/").map(encodeURIComponent).join("/")`
+		}
+		path = strings.Replace(path, marker, "${"+escape+"}", 1)
+	}
+	body := ""
+	if ep.request != nil {
+		args = append(args, "body: "+tsType(ep.request))
+		body = ", body"
+	}
+	result := "void"
+	if ep.response != nil {
+		result = tsType(ep.response)
+	}
+	name := strings.ToLower(ep.name[:1]) + ep.name[1:]
+	return fmt.Sprintf("  /** %s calls %s %s. */\n  %s(%s): Promise<%s> {\n    return this.request<%s>(%q, `%s`%s);\n  }\n",
+		name, ep.method, ep.path, name, strings.Join(args, ", "), result, result, ep.method, path, body)
+}
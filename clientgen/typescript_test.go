@@ -0,0 +1,45 @@
+package clientgen
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTypeScript(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := TypeScript(buf, testApp().Routes(), Options{Envelope: true}); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	for _, expect := range []string{
+		"export interface User extends Audit {\n  id: number;\n  name: string;\n  email?: string;\n  address: Address | null;\n  labels?: Record<string, string>;\n}",
+		"export interface Audit {\n  created_at: string;\n}",
+		"listUsers(): Promise<User[]> {",
+		"getUser(id: string): Promise<User> {\n    return this.request<User>(\"GET\", `/users/${encodeURIComponent(id)}`);",
+		"createUser(body: User): Promise<User> {\n    return this.request<User>(\"POST\", `/users`, body);",
+		"deleteUsersByID(id: string): Promise<void> {",
+		"getFilesByPath(path: string): Promise<string> {",
+		"return (await res.json()).data;",
+	} {
+		if !strings.Contains(src, expect) {
+			t.Errorf("expecting the client to contain %s got\n%s", expect, src)
+		}
+	}
+}
+
+func TestTSType(t *testing.T) {
+	var v struct {
+		Tags   []*string
+		Raw    []byte
+		Nested struct {
+			A int `json:"a-b"`
+		}
+		Any any
+	}
+	expect := `{ Tags: (string | null)[]; Raw: string; Nested: { "a-b": number }; Any: unknown }`
+	if typ := tsType(reflect.TypeOf(v)); typ != expect {
+		t.Errorf("expecting %s got %s", expect, typ)
+	}
+}
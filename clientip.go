@@ -0,0 +1,77 @@
+package cherry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies sets the proxies, as IP addresses or CIDR ranges, whose
+// X-Forwarded-For and X-Real-IP headers are trusted by Context.ClientIP.
+//
+// app.SetTrustedProxies("10.0.0.0/8", "127.0.0.1").
+func (c *Cherry) SetTrustedProxies(proxies ...string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return fmt.Errorf("cherry: invalid trusted proxy %q", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return fmt.Errorf("cherry: invalid trusted proxy %q: %w", p, err)
+		}
+		nets = append(nets, n)
+	}
+	c.trustedProxies = nets
+	return nil
+}
+
+func (c *Cherry) isTrustedProxy(ip net.IP) bool {
+	for _, n := range c.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client. When the request comes from
+// a trusted proxy the X-Forwarded-For header is walked from right to left
+// and the first address that is not a trusted proxy is returned, falling
+// back to the X-Real-IP header.
+func (c *Context) ClientIP() string {
+	r := c.Request()
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || c.cherry == nil || !c.cherry.isTrustedProxy(ip) {
+		return remote
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				break
+			}
+			if i == 0 || !c.cherry.isTrustedProxy(hop) {
+				return hop.String()
+			}
+		}
+	}
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil {
+		return real.String()
+	}
+	return remote
+}
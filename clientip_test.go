@@ -0,0 +1,50 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	c := New()
+	if err := c.SetTrustedProxies("10.0.0.0/8", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.ClientIP())
+	})
+
+	tests := []struct {
+		remote string
+		xff    string
+		real   string
+		expect string
+	}{
+		{"1.2.3.4:1000", "5.6.7.8", "", "1.2.3.4"},
+		{"127.0.0.1:1000", "", "", "127.0.0.1"},
+		{"127.0.0.1:1000", "5.6.7.8", "", "5.6.7.8"},
+		{"127.0.0.1:1000", "9.9.9.9, 5.6.7.8, 10.0.0.2", "", "5.6.7.8"},
+		{"10.1.1.1:1000", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"127.0.0.1:1000", "", "5.6.7.8", "5.6.7.8"},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remote
+		if test.xff != "" {
+			r.Header.Set("X-Forwarded-For", test.xff)
+		}
+		if test.real != "" {
+			r.Header.Set("X-Real-IP", test.real)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Body.String() != test.expect {
+			t.Errorf("%s %q: expecting %s got %s", test.remote, test.xff, test.expect, rw.Body.String())
+		}
+	}
+
+	if err := c.SetTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("expecting an error for an invalid CIDR")
+	}
+}
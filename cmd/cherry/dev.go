@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// watchedExts are the extensions of the files that rebuild the application
+// when they change, with go.mod and go.sum.
+var watchedExts = map[string]bool{
+	".go": true, ".html": true, ".gohtml": true, ".tmpl": true, ".tpl": true,
+	".yaml": true, ".yml": true, ".toml": true, ".json": true,
+}
+
+// devServer builds and runs the application of a directory and proxies the
+// requests to it. While the application is rebuilt or restarted, requests
+// wait for it instead of being refused.
+type devServer struct {
+	dir     string
+	appPort int
+	out     io.Writer
+	bin     string
+	proxy   *httputil.ReverseProxy
+
+	// building serializes the builds.
+	building sync.Mutex
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	ready    chan struct{}
+	buildErr string
+}
+
+func newDevServer(out io.Writer, dir string, appPort int) (*devServer, error) {
+	tmp, err := os.MkdirTemp("", "cherry-dev")
+	if err != nil {
+		return nil, err
+	}
+	target := &url.URL{Scheme: "http", Host: net.JoinHostPort("127.0.0.1", strconv.Itoa(appPort))}
+	d := &devServer{
+		dir:     dir,
+		appPort: appPort,
+		out:     out,
+		bin:     filepath.Join(tmp, "app"),
+		proxy:   httputil.NewSingleHostReverseProxy(target),
+		ready:   make(chan struct{}),
+	}
+	d.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		d.errorPage(w, http.StatusBadGateway, "The application is not running", err.Error())
+	}
+	return d, nil
+}
+
+// ServeHTTP waits for the application and proxies the request to it, or
+// responds with the errors of the last build.
+func (d *devServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	ready := d.ready
+	d.mu.Unlock()
+	select {
+	case <-ready:
+	case <-r.Context().Done():
+		return
+	}
+	d.mu.Lock()
+	buildErr := d.buildErr
+	d.mu.Unlock()
+	if buildErr != "" {
+		d.errorPage(w, http.StatusInternalServerError, "Build failed", buildErr)
+		return
+	}
+	d.proxy.ServeHTTP(w, r)
+}
+
+func (d *devServer) errorPage(w http.ResponseWriter, code int, title, detail string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "<!DOCTYPE html><title>%s</title><h1>%s</h1><pre>%s</pre>",
+		html.EscapeString(title), html.EscapeString(title), html.EscapeString(detail))
+}
+
+// rebuild builds the application and restarts it when the build succeeds.
+// Requests wait until the new application accepts connections.
+func (d *devServer) rebuild() {
+	d.building.Lock()
+	defer d.building.Unlock()
+	d.mu.Lock()
+	select {
+	case <-d.ready:
+		d.ready = make(chan struct{})
+	default:
+	}
+	ready := d.ready
+	d.mu.Unlock()
+
+	fmt.Fprintln(d.out, "building...")
+	build := exec.Command("go", "build", "-o", d.bin, ".")
+	build.Dir = d.dir
+	output, err := build.CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(d.out, "build failed: %s\n%s", err, output)
+		d.stop()
+		d.mu.Lock()
+		d.buildErr = string(output)
+		d.mu.Unlock()
+		close(ready)
+		return
+	}
+
+	d.stop()
+	d.mu.Lock()
+	d.buildErr = ""
+	d.mu.Unlock()
+	if err := d.start(); err != nil {
+		d.mu.Lock()
+		d.buildErr = err.Error()
+		d.mu.Unlock()
+		close(ready)
+		return
+	}
+	d.waitListening()
+	close(ready)
+}
+
+// start starts the built application on the port of the application, it
+// is passed in the PORT and CHERRY_PORT environment variables.
+func (d *devServer) start() error {
+	port := strconv.Itoa(d.appPort)
+	cmd := exec.Command(d.bin)
+	cmd.Dir = d.dir
+	cmd.Env = append(os.Environ(), "PORT="+port, "CHERRY_PORT="+port)
+	cmd.Stdout = d.out
+	cmd.Stderr = d.out
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	d.mu.Lock()
+	d.cmd, d.exited = cmd, exited
+	d.mu.Unlock()
+	fmt.Fprintf(d.out, "running on port %s\n", port)
+	return nil
+}
+
+// waitListening waits until the application accepts connections or exits.
+func (d *devServer) waitListening() {
+	d.mu.Lock()
+	exited := d.exited
+	d.mu.Unlock()
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(d.appPort))
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		select {
+		case <-exited:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// stop interrupts the application and kills it when it did not exit after
+// 5 seconds.
+func (d *devServer) stop() {
+	d.mu.Lock()
+	cmd, exited := d.cmd, d.exited
+	d.cmd = nil
+	d.mu.Unlock()
+	if cmd == nil {
+		return
+	}
+	cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// snapshot returns the modification times of the watched files of dir.
+func snapshot(dir string) map[string]time.Time {
+	files := map[string]time.Time{}
+	filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := e.Name()
+		if e.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, "_test.go") || !(watchedExts[filepath.Ext(name)] || name == "go.mod" || name == "go.sum") {
+			return nil
+		}
+		if info, err := e.Info(); err == nil {
+			files[path] = info.ModTime()
+		}
+		return nil
+	})
+	return files
+}
+
+func changed(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for path, t := range a {
+		if !b[path].Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch rebuilds the application whenever the watched files change, until
+// ctx is done.
+func (d *devServer) watch(ctx context.Context, interval time.Duration) {
+	files := snapshot(d.dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if current := snapshot(d.dir); changed(files, current) {
+			files = current
+			d.rebuild()
+		}
+	}
+}
+
+// close stops the application and removes its binary.
+func (d *devServer) close() {
+	d.stop()
+	os.RemoveAll(filepath.Dir(d.bin))
+}
+
+// devCommand runs the application of dir behind a proxy on addr, and
+// rebuilds and restarts it when its files change.
+func devCommand(out io.Writer, dir, addr string, appPort int, interval time.Duration) error {
+	d, err := newDevServer(out, dir, appPort)
+	if err != nil {
+		return err
+	}
+	defer d.close()
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: d}
+	go srv.Serve(l)
+	fmt.Fprintf(out, "cherry dev server listening on %s\n", l.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go d.rebuild()
+	d.watch(ctx, interval)
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":              "package main",
+		"main_test.go":         "package main",
+		"views/index.html":     "<h1>cherry</h1>",
+		"config.yaml":          "port: 3000",
+		"README.md":            "# app",
+		".git/HEAD":            "ref: refs/heads/main",
+		"node_modules/x/x.js":  "",
+		"testdata/golden.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := snapshot(dir)
+	if len(before) != 3 {
+		t.Errorf("expecting main.go, index.html and config.yaml got %v", before)
+	}
+	if changed(before, snapshot(dir)) {
+		t.Error("expecting no change")
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(filepath.Join(dir, "main_test.go"), later, later)
+	if changed(before, snapshot(dir)) {
+		t.Error("expecting tests to not be watched")
+	}
+	os.Chtimes(filepath.Join(dir, "views/index.html"), later, later)
+	if !changed(before, snapshot(dir)) {
+		t.Error("expecting a change of a template")
+	}
+}
+
+const devApp = `package main
+
+import (
+	"net/http"
+	"os"
+)
+
+func main() {
+	http.ListenAndServe("127.0.0.1:"+os.Getenv("PORT"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(VERSION))
+	}))
+}
+`
+
+func TestDevServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds an application")
+	}
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module devapp\n\ngo 1.21\n")
+	write("main.go", strings.Replace(devApp, "VERSION", `"v1"`, 1))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	d, err := newDevServer(io.Discard, dir, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(d.close)
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	get := func() (int, string) {
+		res, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+	// requests wait for the first build.
+	go d.rebuild()
+	if code, body := get(); code != http.StatusOK || body != "v1" {
+		t.Fatalf("expecting v1 got %d %s", code, body)
+	}
+
+	write("main.go", strings.Replace(devApp, "VERSION", `"v2"`, 1))
+	d.rebuild()
+	if code, body := get(); code != http.StatusOK || body != "v2" {
+		t.Errorf("expecting v2 got %d %s", code, body)
+	}
+
+	write("main.go", strings.Replace(devApp, "VERSION", "v3", 1))
+	d.rebuild()
+	if code, body := get(); code != http.StatusInternalServerError || !strings.Contains(body, "Build failed") || !strings.Contains(body, "undefined: v3") {
+		t.Errorf("expecting the build error got %d %s", code, body)
+	}
+}
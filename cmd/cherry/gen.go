@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// routesMarker is the line of handlers/routes.go before which the routes of
+// the generated resources are registered.
+const routesMarker = "// cherry:routes"
+
+var resourceName = regexp.MustCompile(`^[a-z][a-z0-9]*([_-][a-z0-9]+)*$`)
+
+// resource is the data of the templates of a generated resource, for the
+// users resource Path is users, Plural Users, Type User and Var user.
+type resource struct {
+	Path   string
+	Plural string
+	Type   string
+	Var    string
+}
+
+func newResource(name string) (resource, error) {
+	if !resourceName.MatchString(name) {
+		return resource{}, fmt.Errorf("invalid resource name %q, use lower case words like users or blog-posts", name)
+	}
+	plural := camelCase(name)
+	typ := singular(plural)
+	return resource{
+		Path:   name,
+		Plural: plural,
+		Type:   typ,
+		Var:    strings.ToLower(typ[:1]) + typ[1:],
+	}, nil
+}
+
+// camelCase returns blog-posts and blog_posts as BlogPosts.
+func camelCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' })
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, "")
+}
+
+// singular returns the singular of an English plural, which is good enough
+// for the names of resources.
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "xes"), strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") && len(s) > 1:
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// genHandler adds the handlers and tests of the resource name to the
+// handlers package of the project in dir and registers its routes.
+func genHandler(out io.Writer, dir, name string) error {
+	res, err := newResource(name)
+	if err != nil {
+		return err
+	}
+	pkg := filepath.Join(dir, "handlers")
+	if _, err := os.Stat(pkg); err != nil {
+		return fmt.Errorf("no handlers package in %s, run cherry gen in a project created by cherry new", dir)
+	}
+	file := strings.ReplaceAll(name, "-", "_")
+	for tmpl, path := range map[string]string{
+		"templates/gen/handler.go.tmpl":      filepath.Join(pkg, file+".go"),
+		"templates/gen/handler_test.go.tmpl": filepath.Join(pkg, file+"_test.go"),
+	} {
+		b, err := render(tmpl, res)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(path, b); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "create", path)
+	}
+
+	register := fmt.Sprintf("register%s(app.Group(\"/api\"), new%sStore())", res.Plural, res.Type)
+	routes := filepath.Join(pkg, "routes.go")
+	b, err := os.ReadFile(routes)
+	if err == nil && bytes.Contains(b, []byte(routesMarker)) {
+		i := bytes.Index(b, []byte(routesMarker))
+		indent := i - bytes.LastIndexByte(b[:i], '\n') - 1
+		line := string(b[i-indent:i]) + register + "\n"
+		b = append(b[:i-indent:i-indent], append([]byte(line), b[i-indent:]...)...)
+		if err := os.WriteFile(routes, b, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "update", routes)
+		return nil
+	}
+	fmt.Fprintf(out, "\nRegister the routes of the resource with:\n\n\t%s\n", register)
+	return nil
+}
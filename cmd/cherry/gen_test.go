@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewResource(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect resource
+	}{
+		{"users", resource{"users", "Users", "User", "user"}},
+		{"blog-posts", resource{"blog-posts", "BlogPosts", "BlogPost", "blogPost"}},
+		{"categories", resource{"categories", "Categories", "Category", "category"}},
+		{"addresses", resource{"addresses", "Addresses", "Address", "address"}},
+		{"staff", resource{"staff", "Staff", "Staff", "staff"}},
+	}
+	for _, test := range tests {
+		res, err := newResource(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != test.expect {
+			t.Errorf("%s: expecting %+v got %+v", test.name, test.expect, res)
+		}
+	}
+	for _, name := range []string{"Users", "1users", "users/", "blog--posts", ""} {
+		if _, err := newResource(name); err == nil {
+			t.Errorf("expecting an error for %q", name)
+		}
+	}
+}
+
+func TestGenHandler(t *testing.T) {
+	dir := t.TempDir()
+	if err := newProject(io.Discard, "example.com/shop", dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := genHandler(io.Discard, dir, "blog-posts"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"blog_posts.go", "blog_posts_test.go"} {
+		if _, err := os.Stat(filepath.Join(dir, "handlers", name)); err != nil {
+			t.Error(err)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "handlers", "routes.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "\tregisterBlogPosts(app.Group(\"/api\"), newBlogPostStore())\n\t" + routesMarker
+	if !strings.Contains(string(b), expect) {
+		t.Errorf("expecting the routes to be registered got %s", b)
+	}
+	if err := genHandler(io.Discard, dir, "blog-posts"); err == nil {
+		t.Error("expecting an error for an existing resource")
+	}
+}
+
+func TestGenHandlerOutsideProject(t *testing.T) {
+	if err := genHandler(io.Discard, t.TempDir(), "users"); err == nil {
+		t.Error("expecting an error outside of a project")
+	}
+}
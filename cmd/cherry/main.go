@@ -0,0 +1,92 @@
+// Command cherry scaffolds Cherry applications.
+//
+//	cherry new github.com/me/myapp    creates the myapp project
+//	cherry gen handler users          adds the users resource to a project
+//	cherry dev                        runs the project and restarts it on changes
+//	cherry routes                     prints the routes of the project
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const usage = `Usage:
+
+	cherry new [-dir dir] <module>    create a project in dir, the last element of the module by default
+	cherry gen handler <name>         add a resource with its routes and tests to the project
+	cherry dev [-addr addr] [-app-port port] [dir]
+	                                  run the project, rebuild and restart it when its files change
+	cherry routes [-json] [dir]       print the routes of the project as a table or JSON
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "cherry:", err)
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("invalid arguments")
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+		return errUsage
+	}
+	switch args[0] {
+	case "new":
+		flags := flag.NewFlagSet("new", flag.ContinueOnError)
+		dir := flags.String("dir", "", "directory of the project")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			fmt.Fprint(out, usage)
+			return errUsage
+		}
+		return newProject(out, flags.Arg(0), *dir)
+	case "gen":
+		if len(args) != 3 || args[1] != "handler" {
+			fmt.Fprint(out, usage)
+			return errUsage
+		}
+		return genHandler(out, ".", args[2])
+	case "dev":
+		flags := flag.NewFlagSet("dev", flag.ContinueOnError)
+		addr := flags.String("addr", ":3000", "address of the dev server")
+		appPort := flags.Int("app-port", 3001, "port of the application, passed in PORT and CHERRY_PORT")
+		interval := flags.Duration("interval", 500*time.Millisecond, "interval of checking the files for changes")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		dir := "."
+		if flags.NArg() > 0 {
+			dir = flags.Arg(0)
+		}
+		return devCommand(out, dir, *addr, *appPort, *interval)
+	case "routes":
+		flags := flag.NewFlagSet("routes", flag.ContinueOnError)
+		asJSON := flags.Bool("json", false, "print the routes as JSON")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		dir, format := ".", "table"
+		if flags.NArg() > 0 {
+			dir = flags.Arg(0)
+		}
+		if *asJSON {
+			format = "json"
+		}
+		return routesCommand(out, dir, format)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	}
+	fmt.Fprint(out, usage)
+	return fmt.Errorf("unknown command %q", args[0])
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{nil, false},
+		{[]string{"help"}, true},
+		{[]string{"new"}, false},
+		{[]string{"gen", "model", "users"}, false},
+		{[]string{"deploy"}, false},
+	}
+	for _, test := range tests {
+		out := &strings.Builder{}
+		err := run(test.args, out)
+		if (err == nil) != test.ok {
+			t.Errorf("%v: unexpected error %v", test.args, err)
+		}
+		if !strings.Contains(out.String(), "Usage:") {
+			t.Errorf("%v: expecting the usage got %s", test.args, out.String())
+		}
+	}
+}
+
+func TestRunNew(t *testing.T) {
+	dir := t.TempDir()
+	if err := run([]string{"new", "-dir", dir, "example.com/shop"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"new", "-dir", dir, "example.com/shop"}, io.Discard); err == nil {
+		t.Error("expecting an error for a directory that is not empty")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// project is the data of the templates of a new project.
+type project struct {
+	Module string
+	Name   string
+}
+
+// newProject creates a project for module in dir from templates/new.
+func newProject(out io.Writer, module, dir string) error {
+	if module == "" || strings.ContainsAny(module, " \t\\") || strings.HasSuffix(module, "/") {
+		return fmt.Errorf("invalid module path %q", module)
+	}
+	p := project{Module: module, Name: path.Base(module)}
+	if dir == "" {
+		dir = p.Name
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", dir)
+	}
+
+	err := fs.WalkDir(templates, "templates/new", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := render(name, p)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(name, "templates/new/"), ".tmpl")
+		fmt.Fprintln(out, "create", filepath.Join(dir, rel))
+		return writeFile(filepath.Join(dir, filepath.FromSlash(rel)), b)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nRun your application with:\n\n\tcd %s\n\tgo mod tidy\n\tgo run .\n", dir)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewProject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	if err := newProject(io.Discard, "example.com/shop", dir); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"go.mod":                   "module example.com/shop",
+		"main.go":                  `"example.com/shop/handlers"`,
+		"handlers/routes.go":       routesMarker,
+		"handlers/routes_test.go":  "func TestIndex",
+		"middleware/middleware.go": "func SecureHeaders",
+		"config.yaml":              "port: 3000",
+		"Dockerfile":               "go build -o /shop .",
+		".gitignore":               "/shop",
+	}
+	for name, content := range expect {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("expecting %s: %v", name, err)
+			continue
+		}
+		if !strings.Contains(string(b), content) {
+			t.Errorf("expecting %s to contain %s got %s", name, content, b)
+		}
+	}
+}
+
+func TestNewProjectInvalidModule(t *testing.T) {
+	for _, module := range []string{"", "my app", "example.com/shop/"} {
+		if err := newProject(io.Discard, module, t.TempDir()); err == nil {
+			t.Errorf("expecting an error for %q", module)
+		}
+	}
+}
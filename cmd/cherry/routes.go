@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// routesCommand runs the application of dir with the -routes flag, which
+// the main of a project created by cherry new handles by printing its routes
+// in format, table or json, instead of serving.
+func routesCommand(out io.Writer, dir, format string) error {
+	cmd := exec.Command("go", "run", ".", "-routes", format)
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running the application: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoutesCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds an application")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := newProject(io.Discard, "example.com/shop", dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := genHandler(io.Discard, dir, "users"); err != nil {
+		t.Fatal(err)
+	}
+	// builds the project with this version of cherry.
+	gomod := "module example.com/shop\n\ngo 1.21\n\nrequire github.com/pooulad/cherry v0.0.0\n\nreplace github.com/pooulad/cherry => " + root + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), sum, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	out := &bytes.Buffer{}
+	if err := routesCommand(out, dir, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var routes []struct {
+		Method     string
+		Path       string
+		Handler    string
+		Middleware int
+	}
+	if err := json.Unmarshal(out.Bytes(), &routes); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if len(routes) != 9 {
+		t.Fatalf("expecting 9 routes got %+v", routes)
+	}
+	last := routes[len(routes)-1]
+	if last.Method != "DELETE" || last.Path != "/api/users/:id" || last.Handler != "handlers.(*userStore).delete" || last.Middleware != 1 {
+		t.Errorf("unexpected route %+v", last)
+	}
+}
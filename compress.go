@@ -0,0 +1,332 @@
+package cherry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressMinSize is the minimum size of a response body before it gets
+// compressed. Smaller bodies are not worth the overhead of compression.
+const compressMinSize = 1024
+
+// incompressibleTypes are content types that are already compressed and
+// would only grow by compressing them again.
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-brotli",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+}
+
+// CompressConfig holds the configuration of the Compress middleware.
+// Zero values are replaced by sensible defaults.
+type CompressConfig struct {
+	// Encodings lists the supported encodings in order of preference when the
+	// client accepts more than one with the same quality. The supported
+	// encodings are "br", "zstd" and "gzip". Defaults to all of them.
+	Encodings []string
+
+	// GzipLevel is the gzip compression level, from gzip.HuffmanOnly (-2) to
+	// gzip.BestCompression (9). Defaults to gzip.DefaultCompression, which 0
+	// and the levels out of range also mean. Use Gzip for
+	// gzip.NoCompression.
+	GzipLevel int
+
+	// BrotliQuality is the brotli quality (1-11). Defaults to 4, which is a
+	// good trade-off for dynamic content.
+	BrotliQuality int
+
+	// ZstdLevel is the zstd level as used by the zstd command line tool
+	// (1-22). Defaults to 3.
+	ZstdLevel int
+
+	// MinSize is the minimum body size in bytes before the response is
+	// compressed. Defaults to 1KB.
+	MinSize int
+
+	// gzipLevelSet tells that GzipLevel was chosen by Gzip, where 0 is
+	// gzip.NoCompression rather than the default.
+	gzipLevelSet bool
+}
+
+// Gzip returns a middleware Handler that compresses the response body with
+// gzip when the client accepts it. Responses smaller than 1KB and content
+// types that are already compressed are written as is. The level is any of
+// the levels of compress/gzip, gzip.NoCompression included, a level out of
+// range falls back to gzip.DefaultCompression.
+//
+// app.Use(cherry.Gzip(gzip.DefaultCompression)).
+func Gzip(level int) Handler {
+	return Compress(CompressConfig{
+		Encodings:    []string{"gzip"},
+		GzipLevel:    level,
+		gzipLevelSet: true,
+	})
+}
+
+// Compress returns a middleware Handler that negotiates the content-encoding
+// of the response with the client based on the Accept-Encoding header.
+//
+// app.Use(cherry.Compress(cherry.CompressConfig{BrotliQuality: 5})).
+func Compress(cfg CompressConfig) Handler {
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{"br", "zstd", "gzip"}
+	}
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = compressMinSize
+	}
+	var codecs []*codec
+	for _, name := range cfg.Encodings {
+		codecs = append(codecs, newCodec(name, cfg))
+	}
+
+	return func(ctx *Context) error {
+		r := ctx.Request()
+		if r.Method == http.MethodHead {
+			return nil
+		}
+		ctx.Response().Header().Add("Vary", "Accept-Encoding")
+		codec := negotiateCodec(r.Header.Get("Accept-Encoding"), codecs)
+		if codec == nil {
+			return nil
+		}
+		cw := &compressWriter{
+			ResponseWriter: ctx.Response(),
+			codec:          codec,
+			minSize:        cfg.MinSize,
+		}
+		ctx.response = cw
+		ctx.onFinish(cw.close)
+		return nil
+	}
+}
+
+// codec creates pooled encoders for a single content-encoding.
+type codec struct {
+	name string
+	pool sync.Pool
+}
+
+func newCodec(name string, cfg CompressConfig) *codec {
+	c := &codec{name: name}
+	switch name {
+	case "gzip":
+		level := cfg.GzipLevel
+		if (level == 0 && !cfg.gzipLevelSet) || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			level = gzip.DefaultCompression
+		}
+		c.pool.New = func() any {
+			w, _ := gzip.NewWriterLevel(nil, level)
+			return w
+		}
+	case "br":
+		quality := cfg.BrotliQuality
+		if quality <= 0 || quality > brotli.BestCompression {
+			quality = 4
+		}
+		c.pool.New = func() any {
+			return brotli.NewWriterLevel(nil, quality)
+		}
+	case "zstd":
+		level := cfg.ZstdLevel
+		if level <= 0 {
+			level = 3
+		}
+		c.pool.New = func() any {
+			w, _ := zstd.NewWriter(nil,
+				zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+				zstd.WithEncoderConcurrency(1),
+			)
+			return w
+		}
+	default:
+		panic("cherry: unsupported compression encoding " + name)
+	}
+	return c
+}
+
+func (c *codec) get(w io.Writer) encoder {
+	e := c.pool.Get().(encoder)
+	e.Reset(w)
+	return e
+}
+
+func (c *codec) put(e encoder) {
+	c.pool.Put(e)
+}
+
+// negotiateCodec returns the codec with the highest quality in the
+// Accept-Encoding header. Ties are won by the codec that comes first.
+func negotiateCodec(header string, codecs []*codec) *codec {
+	if header == "" {
+		return nil
+	}
+	var (
+		best  *codec
+		bestQ float64
+	)
+	elems := parseAccept([]string{header})
+	for _, c := range codecs {
+		q := encodingQuality(elems, c.name)
+		if q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+// encoder is implemented by the pooled compression writers.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter buffers the start of the response until it knows enough
+// to decide whether the body should be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	codec   *codec
+	minSize int
+
+	buf     bytes.Buffer
+	code    int
+	decided bool
+	enc     encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if !w.decided {
+		w.buf.Write(p)
+		if w.buf.Len() < w.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush compresses whatever is buffered and flushes it to the client.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		w.decide()
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide chooses between a compressed and a plain response, writes the
+// header and everything buffered so far.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && w.buf.Len() > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+	if w.shouldCompress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.codec.name)
+		w.ResponseWriter.WriteHeader(w.code)
+		w.enc = w.codec.get(w.ResponseWriter)
+		_, err := w.enc.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return err
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if w.code < http.StatusOK || w.code == http.StatusNoContent || w.code == http.StatusNotModified {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < w.minSize {
+			return false
+		}
+	}
+	return isCompressible(h.Get("Content-Type"))
+}
+
+// close finishes the response, it is called after the handler returned.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.code == 0 && w.buf.Len() == 0 {
+			// nothing written at all, let net/http handle the defaults.
+			return
+		}
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		// the body is smaller than the minimum size.
+		w.decided = true
+		w.ResponseWriter.WriteHeader(w.code)
+		w.ResponseWriter.Write(w.buf.Bytes())
+		return
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.codec.put(w.enc)
+		w.enc = nil
+	}
+}
+
+func isCompressible(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(ct, t) {
+			return ct == "image/svg+xml" || strings.HasPrefix(ct, "image/svg+xml;")
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package cherry
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestGzip(t *testing.T) {
+	c := New()
+	c.Use(Gzip(gzip.DefaultCompression))
+	body := strings.Repeat("cherry ", 500)
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, body)
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expecting Content-Encoding gzip got %s", rw.Header().Get("Content-Encoding"))
+	}
+	if rw.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expecting Vary Accept-Encoding got %s", rw.Header().Get("Vary"))
+	}
+	gr, err := gzip.NewReader(rw.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(gr)
+	if string(b) != body {
+		t.Error("decompressed body does not match")
+	}
+}
+
+func TestGzipLevels(t *testing.T) {
+	body := strings.Repeat("cherry ", 500)
+	compress := func(level int) string {
+		var b strings.Builder
+		w, _ := gzip.NewWriterLevel(&b, level)
+		w.Write([]byte(body))
+		w.Close()
+		return b.String()
+	}
+	tests := []struct {
+		h        Handler
+		level    int
+		expected int
+	}{
+		{Gzip(gzip.NoCompression), gzip.NoCompression, gzip.NoCompression},
+		{Gzip(gzip.HuffmanOnly), gzip.HuffmanOnly, gzip.HuffmanOnly},
+		{Gzip(gzip.BestCompression), gzip.BestCompression, gzip.BestCompression},
+		{Gzip(gzip.HuffmanOnly - 1), gzip.HuffmanOnly - 1, gzip.DefaultCompression},
+		{Gzip(gzip.BestCompression + 1), gzip.BestCompression + 1, gzip.DefaultCompression},
+		{Compress(CompressConfig{Encodings: []string{"gzip"}}), 0, gzip.DefaultCompression},
+	}
+	for _, test := range tests {
+		c := New()
+		c.Use(test.h)
+		c.Get("/", func(ctx *Context) error {
+			return ctx.Text(http.StatusOK, body)
+		})
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Body.String() != compress(test.expected) {
+			t.Errorf("expecting level %d to compress with level %d", test.level, test.expected)
+		}
+	}
+}
+
+func TestGzipSkipsSmallAndCompressed(t *testing.T) {
+	c := New()
+	c.Use(Gzip(gzip.BestSpeed))
+	c.Get("/small", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "small")
+	})
+	c.Get("/image", func(ctx *Context) error {
+		ctx.Response().Header().Set("Content-Type", "image/png")
+		ctx.Response().Write(make([]byte, 4096))
+		return nil
+	})
+
+	for _, route := range []string{"/small", "/image"} {
+		r, _ := http.NewRequest("GET", route, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		isHTTPStatusOK(t, rw.Code)
+		if enc := rw.Header().Get("Content-Encoding"); enc != "" {
+			t.Errorf("%s: expecting no Content-Encoding got %s", route, enc)
+		}
+	}
+	code, body := doRequest(t, "GET", "/small", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "small" {
+		t.Errorf("expecting small got %s", body)
+	}
+}
+
+func TestCompressNegotiation(t *testing.T) {
+	c := New()
+	c.Use(Compress(CompressConfig{}))
+	body := strings.Repeat(`{"name":"cherry"}`, 200)
+	c.Get("/", func(ctx *Context) error {
+		ctx.Response().Header().Set("Content-Type", "application/json")
+		_, err := ctx.Response().Write([]byte(body))
+		return err
+	})
+
+	tests := []struct {
+		accept string
+		expect string
+	}{
+		{"gzip, deflate, br, zstd", "br"},
+		{"gzip, zstd", "zstd"},
+		{"gzip;q=1, br;q=0.5", "gzip"},
+		{"br;q=0, *", "zstd"},
+		{"identity", ""},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", test.accept)
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		enc := rw.Header().Get("Content-Encoding")
+		if enc != test.expect {
+			t.Errorf("%q: expecting encoding %q got %q", test.accept, test.expect, enc)
+			continue
+		}
+		var rd io.Reader
+		switch enc {
+		case "br":
+			rd = brotli.NewReader(rw.Body)
+		case "zstd":
+			zr, err := zstd.NewReader(rw.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			rd = zr
+		case "gzip":
+			gr, err := gzip.NewReader(rw.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rd = gr
+		default:
+			rd = rw.Body
+		}
+		b, err := io.ReadAll(rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != body {
+			t.Errorf("%q: decompressed body does not match", test.accept)
+		}
+	}
+}
+
+func TestEncodingQuality(t *testing.T) {
+	tests := []struct {
+		header string
+		expect float64
+	}{
+		{"gzip", 1},
+		{"deflate, gzip;q=0.5", 0.5},
+		{"gzip;q=0", 0},
+		{"*", 1},
+		{"gzip;q=0, *", 0},
+		{"br", 0},
+	}
+	for _, test := range tests {
+		if got := encodingQuality(parseAccept([]string{test.header}), "gzip"); got != test.expect {
+			t.Errorf("%q: expecting %v got %v", test.header, test.expect, got)
+		}
+	}
+}
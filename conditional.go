@@ -0,0 +1,109 @@
+package cherry
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotModified sets the Last-Modified header and evaluates the conditional
+// headers of a GET or HEAD request against it. If the client's copy is still
+// fresh a 304 Not Modified is written and true is returned, the handler
+// should return without writing a body.
+//
+//	if ctx.NotModified(post.UpdatedAt) {
+//	    return nil
+//	}
+func (c *Context) NotModified(lastModified time.Time) bool {
+	if !lastModified.IsZero() {
+		c.Response().Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	return c.checkNotModified(c.Response().Header().Get("ETag"), lastModified)
+}
+
+// NotModifiedETag sets the ETag header and writes a 304 Not Modified when it
+// matches the If-None-Match header of a GET or HEAD request.
+func (c *Context) NotModifiedETag(etag string) bool {
+	etag = quoteETag(etag)
+	c.Response().Header().Set("ETag", etag)
+	return c.checkNotModified(etag, time.Time{})
+}
+
+// PreconditionFailed evaluates the If-Match and If-Unmodified-Since headers
+// against the current state of the resource. If a precondition fails a 412
+// Precondition Failed is written and true is returned. This is typically used
+// before modifying a resource with PUT, PATCH or DELETE.
+//
+//	if ctx.PreconditionFailed(doc.ETag, doc.UpdatedAt) {
+//	    return nil
+//	}
+func (c *Context) PreconditionFailed(etag string, lastModified time.Time) bool {
+	if !c.preconditionsPass(quoteETag(etag), lastModified) {
+		c.Response().WriteHeader(http.StatusPreconditionFailed)
+		return true
+	}
+	return false
+}
+
+func (c *Context) checkNotModified(etag string, lastModified time.Time) bool {
+	r := c.Request()
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		// If-None-Match takes precedence over If-Modified-Since.
+		if !ifNoneMatch(inm, etag) {
+			return false
+		}
+		writeNotModified(c.Response())
+		return true
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.Truncate(time.Second).After(ims) {
+		return false
+	}
+	writeNotModified(c.Response())
+	return true
+}
+
+func (c *Context) preconditionsPass(etag string, lastModified time.Time) bool {
+	r := c.Request()
+	if im := r.Header.Get("If-Match"); im != "" {
+		// If-Match takes precedence over If-Unmodified-Since.
+		return ifMatch(im, etag)
+	}
+	if lastModified.IsZero() {
+		return true
+	}
+	ius, err := http.ParseTime(r.Header.Get("If-Unmodified-Since"))
+	if err != nil {
+		return true
+	}
+	return !lastModified.Truncate(time.Second).After(ius)
+}
+
+// ifMatch reports whether the If-Match header matches etag using the strong
+// comparison function.
+func ifMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" && etag != "" {
+			return true
+		}
+		if etag != "" && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteETag quotes etag when the caller passed a bare value.
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, "\"") || strings.HasPrefix(etag, "W/\"") {
+		return etag
+	}
+	return "\"" + etag + "\""
+}
@@ -0,0 +1,89 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContextNotModified(t *testing.T) {
+	modified := time.Date(2023, 12, 21, 16, 36, 29, 0, time.UTC)
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		if ctx.NotModified(modified) {
+			return nil
+		}
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+		t.Errorf("unexpected Last-Modified %s", rw.Header().Get("Last-Modified"))
+	}
+
+	r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting code 304 got %d", rw.Code)
+	}
+
+	r.Header.Set("If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+}
+
+func TestContextNotModifiedETag(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		if ctx.NotModifiedETag("v2") {
+			return nil
+		}
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"v2"`)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting code 304 got %d", rw.Code)
+	}
+}
+
+func TestContextPreconditionFailed(t *testing.T) {
+	modified := time.Date(2023, 12, 21, 16, 36, 29, 0, time.UTC)
+	c := New()
+	c.Put("/", func(ctx *Context) error {
+		if ctx.PreconditionFailed("v2", modified) {
+			return nil
+		}
+		return ctx.Text(http.StatusOK, "updated")
+	})
+
+	tests := []struct {
+		header string
+		value  string
+		expect int
+	}{
+		{"If-Match", `"v2"`, http.StatusOK},
+		{"If-Match", `"v1"`, http.StatusPreconditionFailed},
+		{"If-Match", `W/"v2"`, http.StatusPreconditionFailed},
+		{"If-Unmodified-Since", modified.Format(http.TimeFormat), http.StatusOK},
+		{"If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), http.StatusPreconditionFailed},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("PUT", "/", nil)
+		r.Header.Set(test.header, test.value)
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.expect {
+			t.Errorf("%s %s: expecting code %d got %d", test.header, test.value, test.expect, rw.Code)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package cherry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configEnvPrefix is the prefix of the environment variables read by
+// LoadConfig.
+const configEnvPrefix = "CHERRY_"
+
+// Config holds the configuration of an application that can be loaded from
+// a file and the environment with LoadConfig. Durations are written like
+// "5s" or "1m30s".
+type Config struct {
+	// Port the server listens on. It is read from CHERRY_PORT, or PORT as
+	// set by most platforms.
+	Port int `json:"port" yaml:"port" toml:"port" env:"PORT"`
+
+	// CertFile and KeyFile enable TLS.
+	CertFile string `json:"cert_file" yaml:"cert_file" toml:"cert_file" env:"CERT_FILE"`
+	KeyFile  string `json:"key_file" yaml:"key_file" toml:"key_file" env:"KEY_FILE"`
+
+	ReadTimeout       Duration `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout" yaml:"read_header_timeout" toml:"read_header_timeout" env:"READ_HEADER_TIMEOUT"`
+	WriteTimeout      Duration `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout       Duration `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	ShutdownTimeout   Duration `json:"shutdown_timeout" yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+
+	// AccessLog enables the access-log in LogFormat, which is "common",
+	// "combined", "json" or a LogTemplate.
+	AccessLog bool   `json:"access_log" yaml:"access_log" toml:"access_log" env:"ACCESS_LOG"`
+	LogFormat string `json:"log_format" yaml:"log_format" toml:"log_format" env:"LOG_FORMAT"`
+
+	// TrustedProxies are IP addresses or CIDR ranges, see SetTrustedProxies.
+	// In the environment they are separated by commas.
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	// Static maps URL prefixes to directories that are served with Static.
+	// In the environment they are written like "/assets=public,/docs=docs".
+	Static map[string]string `json:"static" yaml:"static" toml:"static" env:"STATIC"`
+}
+
+// Duration is a time.Duration that is written as a string like "5s" in
+// configuration files.
+type Duration time.Duration
+
+// UnmarshalText parses a duration like "5s".
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText writes the duration like "5s".
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfig reads the configuration file at path, which is YAML, TOML or
+// JSON depending on its extension, and overrides it with the CHERRY_
+// environment variables, like CHERRY_READ_TIMEOUT=10s. When path is empty
+// only the environment is read.
+//
+// cfg, err := cherry.LoadConfig("config.yaml").
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(b, cfg)
+		case ".toml":
+			err = toml.Unmarshal(b, cfg)
+		case ".json":
+			err = json.Unmarshal(b, cfg)
+		default:
+			err = fmt.Errorf("unsupported config format %q", ext)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cherry: loading %s: %w", path, err)
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadEnv overrides the fields with an env tag by the environment.
+func (cfg *Config) loadEnv() error {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("env")
+		value, ok := os.LookupEnv(configEnvPrefix + name)
+		if !ok && name == "PORT" {
+			value, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("cherry: %s%s: %w", configEnvPrefix, name, err)
+		}
+	}
+	return nil
+}
+
+func setField(f reflect.Value, value string) error {
+	switch f.Interface().(type) {
+	case Duration:
+		var d Duration
+		if err := d.UnmarshalText([]byte(value)); err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(d))
+	case string:
+		f.SetString(value)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case []string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		f.Set(reflect.ValueOf(list))
+	case map[string]string:
+		m := map[string]string{}
+		for _, pair := range strings.Split(value, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expecting prefix=dir got %q", pair)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		f.Set(reflect.ValueOf(m))
+	}
+	return nil
+}
+
+// NewFromConfig returns a new Cherry object configured by cfg and the
+// options, which are applied after the configuration. Like New it panics
+// when the configuration is invalid. Run serves the application on the
+// configured port.
+//
+// app := cherry.NewFromConfig(cfg)
+// app.Get("/", index)
+// app.Run().
+func NewFromConfig(cfg *Config, opts ...Option) *Cherry {
+	return New(append([]Option{withConfig(cfg)}, opts...)...)
+}
+
+func withConfig(cfg *Config) Option {
+	return func(c *Cherry) error {
+		if cfg.Port < 0 || cfg.Port > 65535 {
+			return fmt.Errorf("invalid port %d", cfg.Port)
+		}
+		if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+			return fmt.Errorf("both cert_file and key_file are required for TLS")
+		}
+		setDuration(&c.ReadTimeout, cfg.ReadTimeout)
+		setDuration(&c.ReadHeaderTimeout, cfg.ReadHeaderTimeout)
+		setDuration(&c.WriteTimeout, cfg.WriteTimeout)
+		setDuration(&c.IdleTimeout, cfg.IdleTimeout)
+		setDuration(&c.ShutdownTimeout, cfg.ShutdownTimeout)
+
+		c.HasAccessLog = cfg.AccessLog
+		switch cfg.LogFormat {
+		case "", "common":
+		case "combined":
+			c.AccessLogFormat = LogCombined
+		case "json":
+			c.AccessLogFormat = LogJSON
+		default:
+			format, err := LogTemplate(cfg.LogFormat)
+			if err != nil {
+				return err
+			}
+			c.AccessLogFormat = format
+		}
+		if err := c.SetTrustedProxies(cfg.TrustedProxies...); err != nil {
+			return err
+		}
+		for prefix, dir := range cfg.Static {
+			c.Static(prefix, dir)
+		}
+		c.config = cfg
+		return nil
+	}
+}
+
+// setDuration sets dst to d unless d is zero, keeping the defaults of New.
+func setDuration(dst *time.Duration, d Duration) {
+	if d != 0 {
+		*dst = time.Duration(d)
+	}
+}
+
+// Run serves the application on the port of the configuration given to
+// NewFromConfig, with TLS when the certificate files are configured.
+func (c *Cherry) Run() error {
+	if c.config == nil {
+		return fmt.Errorf("cherry: Run requires an application created by NewFromConfig")
+	}
+	if c.config.CertFile != "" {
+		return c.ServeTLS(c.config.Port, c.config.CertFile, c.config.KeyFile)
+	}
+	return c.Serve(c.config.Port)
+}
@@ -0,0 +1,102 @@
+package cherry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": "port: 8080\nread_timeout: 3s\naccess_log: true\nlog_format: json\ntrusted_proxies: [10.0.0.0/8]\nstatic:\n  /assets: public\n",
+		"config.toml": "port = 8080\nread_timeout = \"3s\"\naccess_log = true\nlog_format = \"json\"\ntrusted_proxies = [\"10.0.0.0/8\"]\n[static]\n\"/assets\" = \"public\"\n",
+		"config.json": `{"port":8080,"read_timeout":"3s","access_log":true,"log_format":"json","trusted_proxies":["10.0.0.0/8"],"static":{"/assets":"public"}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.Port != 8080 || time.Duration(cfg.ReadTimeout) != 3*time.Second || !cfg.AccessLog || cfg.LogFormat != "json" {
+			t.Errorf("%s: unexpected config %+v", name, cfg)
+		}
+		if len(cfg.TrustedProxies) != 1 || cfg.Static["/assets"] != "public" {
+			t.Errorf("%s: unexpected config %+v", name, cfg)
+		}
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "config.ini")); err == nil {
+		t.Error("expecting an error for a missing file")
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	t.Setenv("PORT", "9000")
+	t.Setenv("CHERRY_WRITE_TIMEOUT", "1m")
+	t.Setenv("CHERRY_TRUSTED_PROXIES", "10.0.0.1, 192.168.0.0/16")
+	t.Setenv("CHERRY_STATIC", "/assets=public,/docs=docs")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("expecting port 9000 got %d", cfg.Port)
+	}
+	if time.Duration(cfg.WriteTimeout) != time.Minute {
+		t.Errorf("expecting write timeout 1m got %v", time.Duration(cfg.WriteTimeout))
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[1] != "192.168.0.0/16" {
+		t.Errorf("unexpected trusted proxies %v", cfg.TrustedProxies)
+	}
+	if len(cfg.Static) != 2 || cfg.Static["/docs"] != "docs" {
+		t.Errorf("unexpected static dirs %v", cfg.Static)
+	}
+
+	t.Setenv("CHERRY_PORT", "9001")
+	cfg, _ = LoadConfig("")
+	if cfg.Port != 9001 {
+		t.Errorf("expecting CHERRY_PORT to win got %d", cfg.Port)
+	}
+
+	t.Setenv("CHERRY_READ_TIMEOUT", "soon")
+	if _, err := LoadConfig(""); err == nil {
+		t.Error("expecting an error for an invalid duration")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0o644)
+	c := NewFromConfig(&Config{
+		WriteTimeout:   Duration(time.Minute),
+		TrustedProxies: []string{"127.0.0.1"},
+		Static:         map[string]string{"/files": dir},
+	})
+	if c.WriteTimeout != time.Minute {
+		t.Errorf("expecting write timeout 1m got %v", c.WriteTimeout)
+	}
+	if c.ReadTimeout != 5*time.Second {
+		t.Errorf("expecting the default read timeout got %v", c.ReadTimeout)
+	}
+	if len(c.trustedProxies) != 1 {
+		t.Errorf("expecting 1 trusted proxy got %d", len(c.trustedProxies))
+	}
+	code, body := doRequest(t, "GET", "/files/hello.txt", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "hello" {
+		t.Errorf("expecting hello got %s", body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic for an invalid trusted proxy")
+		}
+	}()
+	NewFromConfig(&Config{TrustedProxies: []string{"proxy"}})
+}
@@ -0,0 +1,104 @@
+package cherry
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ContentTypes sets the Content-Type of the responses written by the
+// helpers of the Context.
+type ContentTypes struct {
+	// Charset is added to the text, JSON, XML and JavaScript types that do
+	// not have one. The default is "utf-8", "-" leaves it out
+	Charset string
+
+	// Text is the type of Context.Text. The default is text/plain
+	Text string
+
+	// JSON is the type of Context.JSON, like application/vnd.api+json. The
+	// default is application/json
+	JSON string
+
+	// Sniff detects the type of Blob and Stream responses without a type,
+	// and of files whose extension is unknown, from their first bytes. The
+	// default is true, without it they are application/octet-stream
+	Sniff bool
+}
+
+// defaultContentTypes are the ContentTypes of New and of a Context without
+// an application.
+var defaultContentTypes = ContentTypes{
+	Charset: "utf-8",
+	Text:    "text/plain",
+	JSON:    "application/json",
+	Sniff:   true,
+}
+
+func (c *Context) contentTypes() ContentTypes {
+	if c.cherry == nil {
+		return defaultContentTypes
+	}
+	return c.cherry.ContentTypes
+}
+
+// contentType returns the media type with the Charset added when it is a
+// text type without a charset.
+func (c *Context) contentType(mediaType string) string {
+	charset := c.contentTypes().Charset
+	if charset == "" || charset == "-" || !isTextType(mediaType) ||
+		strings.Contains(strings.ToLower(mediaType), "charset=") {
+		return mediaType
+	}
+	return mediaType + "; charset=" + charset
+}
+
+// isTextType reports whether the media type is text that a charset applies
+// to.
+func isTextType(mediaType string) bool {
+	mt, _, _ := strings.Cut(strings.ToLower(mediaType), ";")
+	mt = strings.TrimSpace(mt)
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		mt == "application/json", strings.HasSuffix(mt, "+json"),
+		mt == "application/xml", strings.HasSuffix(mt, "+xml"),
+		mt == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// Blob writes data with the given content type. Without a type, it is
+// detected from data, see ContentTypes.Sniff.
+//
+// return ctx.Blob(http.StatusOK, "image/png", png).
+func (c *Context) Blob(code int, contentType string, data []byte) error {
+	if contentType == "" {
+		contentType = c.sniff(data)
+	}
+	c.Response().Header().Set("Content-Type", c.contentType(contentType))
+	c.Response().WriteHeader(code)
+	_, err := c.Response().Write(data)
+	return err
+}
+
+// sniff returns the detected type of data, or application/octet-stream when
+// sniffing is disabled.
+func (c *Context) sniff(data []byte) string {
+	if !c.contentTypes().Sniff {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(data)
+}
+
+// sniffReader returns the detected type of the first bytes of r and a reader
+// that still returns them.
+func (c *Context) sniffReader(r io.Reader) (string, io.Reader) {
+	if !c.contentTypes().Sniff {
+		return "application/octet-stream", r
+	}
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	return http.DetectContentType(head), br
+}
@@ -0,0 +1,75 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentTypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	routes := map[string]Handler{
+		"/text":   func(ctx *Context) error { return ctx.Text(http.StatusOK, "hi") },
+		"/json":   func(ctx *Context) error { return ctx.JSON(http.StatusOK, 1) },
+		"/blob":   func(ctx *Context) error { return ctx.Blob(http.StatusOK, "", png) },
+		"/csv":    func(ctx *Context) error { return ctx.Blob(http.StatusOK, "text/csv; charset=latin1", nil) },
+		"/stream": func(ctx *Context) error { return ctx.Stream(http.StatusOK, "", strings.NewReader("<html>")) },
+	}
+	tests := []struct {
+		name   string
+		types  ContentTypes
+		expect map[string]string
+	}{
+		{"default", defaultContentTypes, map[string]string{
+			"/text":   "text/plain; charset=utf-8",
+			"/json":   "application/json; charset=utf-8",
+			"/blob":   "image/png",
+			"/csv":    "text/csv; charset=latin1",
+			"/stream": "text/html; charset=utf-8",
+		}},
+		{"custom", ContentTypes{Charset: "-", Text: "text/markdown", JSON: "application/vnd.api+json"}, map[string]string{
+			"/text":   "text/markdown",
+			"/json":   "application/vnd.api+json",
+			"/blob":   "application/octet-stream",
+			"/stream": "application/octet-stream",
+		}},
+	}
+	for _, test := range tests {
+		c := New()
+		c.ContentTypes = test.types
+		for route, h := range routes {
+			c.Get(route, h)
+		}
+		for route, expect := range test.expect {
+			rw := httptest.NewRecorder()
+			c.ServeHTTP(rw, httptest.NewRequest("GET", route, nil))
+			if ct := rw.Header().Get("Content-Type"); ct != expect {
+				t.Errorf("%s %s: expecting %q got %q", test.name, route, expect, ct)
+			}
+		}
+	}
+}
+
+func TestFileSniffing(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.unknown"), []byte("<html><body>"), 0o644)
+	for _, sniff := range []bool{true, false} {
+		c := New()
+		c.ContentTypes.Sniff = sniff
+		c.Get("/", func(ctx *Context) error {
+			return ctx.File(filepath.Join(dir, "page.unknown"))
+		})
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+		expect := "text/html; charset=utf-8"
+		if !sniff {
+			expect = "application/octet-stream"
+		}
+		if ct := rw.Header().Get("Content-Type"); ct != expect {
+			t.Errorf("sniff %v: expecting %q got %q", sniff, expect, ct)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package cherry
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stats holds the runtime statistics that are exposed by the debug endpoint.
+// It is shared by an application and all of its groups.
+type stats struct {
+	conns    atomic.Int64
+	inflight atomic.Int64
+	enabled  atomic.Bool
+	// connsQueued counts the connections that had to wait for a free slot
+	// and connsRejected the ones that were closed because of MaxConns.
+	connsQueued   atomic.Int64
+	connsRejected atomic.Int64
+
+	mu    sync.Mutex
+	names map[uintptr]string
+	mw    map[string]*middlewareTiming
+}
+
+type middlewareTiming struct {
+	Calls uint64        `json:"calls"`
+	Total time.Duration `json:"total_ns"`
+}
+
+func newStats() *stats {
+	return &stats{
+		names: map[uintptr]string{},
+		mw:    map[string]*middlewareTiming{},
+	}
+}
+
+// observeMiddleware records how long a single middleware took to run.
+func (s *stats) observeMiddleware(h Handler, d time.Duration) {
+	pc := reflect.ValueOf(h).Pointer()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.names[pc]
+	if !ok {
+		name = "unknown"
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			name = fn.Name()
+		}
+		s.names[pc] = name
+	}
+	t, ok := s.mw[name]
+	if !ok {
+		t = &middlewareTiming{}
+		s.mw[name] = t
+	}
+	t.Calls++
+	t.Total += d
+}
+
+// runMiddleware runs a middleware, timing it when the debug endpoint is
+// enabled.
+func (s *stats) runMiddleware(h Handler, ctx *Context) error {
+	if !s.enabled.Load() {
+		return h(ctx)
+	}
+	start := time.Now()
+	err := h(ctx)
+	s.observeMiddleware(h, time.Since(start))
+	return err
+}
+
+// snapshot returns the cherry specific statistics.
+func (s *stats) snapshot() map[string]interface{} {
+	s.mu.Lock()
+	mw := make(map[string]middlewareTiming, len(s.mw))
+	for name, t := range s.mw {
+		mw[name] = *t
+	}
+	s.mu.Unlock()
+	return map[string]interface{}{
+		"open_connections":     s.conns.Load(),
+		"queued_connections":   s.connsQueued.Load(),
+		"rejected_connections": s.connsRejected.Load(),
+		"inflight":             s.inflight.Load(),
+		"goroutines":           runtime.NumGoroutine(),
+		"middleware":           mw,
+	}
+}
+
+// collect exports the connection statistics as metrics.
+func (s *stats) collect(m *Metrics) {
+	m.Gauge("cherry_connections_open").Set(float64(s.conns.Load()))
+	m.Counter("cherry_connections_queued_total").set(float64(s.connsQueued.Load()))
+	m.Counter("cherry_connections_rejected_total").set(float64(s.connsRejected.Load()))
+}
+
+// Debug registers a GET route on path that serves the expvar variables
+// together with cherry specific statistics: open connections, requests in
+// flight, goroutines and the time spent in each middleware. Middleware timings
+// are only collected once Debug is called. The auth handlers run before the
+// endpoint and can be used to protect it.
+//
+// app.Debug("/debug/vars", cherry.BasicAuth(...)).
+func (c *Cherry) Debug(path string, auth ...Handler) {
+	c.stats.enabled.Store(true)
+	c.Get(path, chain(c.debugHandler, auth...))
+}
+
+func (c *Cherry) debugHandler(ctx *Context) error {
+	w := ctx.Response()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	var vars []expvar.KeyValue
+	expvar.Do(func(kv expvar.KeyValue) {
+		vars = append(vars, kv)
+	})
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+
+	b, err := json.Marshal(c.stats.snapshot())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "{\n%q: %s", "cherry", b)
+	for _, kv := range vars {
+		fmt.Fprintf(w, ",\n%q: %s", kv.Key, kv.Value)
+	}
+	_, err = fmt.Fprint(w, "\n}\n")
+	return err
+}
@@ -0,0 +1,59 @@
+package cherry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	c := New()
+	c.Use(func(ctx *Context) error { return nil })
+	c.Debug("/debug/vars", func(ctx *Context) error {
+		if ctx.Header("X-Token") != "secret" {
+			return NewHTTPError(http.StatusUnauthorized)
+		}
+		return nil
+	})
+
+	code, _ := doRequest(t, "GET", "/debug/vars", nil, c)
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expecting status 401 got %d", code)
+	}
+
+	r, _ := http.NewRequest("GET", "/debug/vars", nil)
+	r.Header.Set("X-Token", "secret")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+
+	var vars struct {
+		Cherry struct {
+			Goroutines int                         `json:"goroutines"`
+			Inflight   int                         `json:"inflight"`
+			Middleware map[string]middlewareTiming `json:"middleware"`
+		} `json:"cherry"`
+		Memstats map[string]interface{} `json:"memstats"`
+	}
+	if err := json.Unmarshal(rw.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("expecting valid json got %v: %s", err, rw.Body.String())
+	}
+	if vars.Cherry.Goroutines == 0 {
+		t.Error("expecting goroutines to be reported")
+	}
+	if vars.Cherry.Inflight != 1 {
+		t.Errorf("expecting 1 request in flight got %d", vars.Cherry.Inflight)
+	}
+	if len(vars.Cherry.Middleware) != 1 {
+		t.Errorf("expecting timings for 1 middleware got %v", vars.Cherry.Middleware)
+	}
+	for name, timing := range vars.Cherry.Middleware {
+		if timing.Calls != 2 {
+			t.Errorf("%s: expecting 2 calls got %d", name, timing.Calls)
+		}
+	}
+	if vars.Memstats == nil {
+		t.Error("expecting expvar memstats")
+	}
+}
@@ -0,0 +1,101 @@
+package cherry
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// decompressMaxSize is the default limit of a decompressed request body.
+const decompressMaxSize = 10 << 20
+
+// Decompress returns a middleware Handler that transparently decompresses
+// request bodies sent with a Content-Encoding of gzip, deflate, br or zstd,
+// so DecodeJSON and friends read the plain body. Reading more than maxSize
+// decompressed bytes fails with a 413 HTTPError, which protects against
+// decompression bombs. A maxSize of zero defaults to 10MB.
+//
+// app.Use(cherry.Decompress(1 << 20)).
+func Decompress(maxSize int64) Handler {
+	if maxSize <= 0 {
+		maxSize = decompressMaxSize
+	}
+	return func(ctx *Context) error {
+		r := ctx.Request()
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+			return nil
+		}
+		body, err := newDecoder(encoding, r.Body, maxSize)
+		if err != nil {
+			return err
+		}
+		r.Body = &limitedBody{
+			r:      io.LimitReader(body, maxSize+1),
+			closer: body,
+			orig:   r.Body,
+			max:    maxSize,
+		}
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		return nil
+	}
+}
+
+func newDecoder(encoding string, body io.Reader, maxSize int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, NewHTTPError(http.StatusBadRequest, "invalid gzip request body")
+		}
+		return zr, nil
+	case "deflate":
+		zr, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, NewHTTPError(http.StatusBadRequest, "invalid deflate request body")
+		}
+		return zr, nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(body)), nil
+	case "zstd":
+		zr, err := zstd.NewReader(body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(maxSize)),
+		)
+		if err != nil {
+			return nil, NewHTTPError(http.StatusBadRequest, "invalid zstd request body")
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, NewHTTPError(http.StatusUnsupportedMediaType, "unsupported content encoding: "+encoding)
+}
+
+// limitedBody reads a decompressed request body up to max bytes.
+type limitedBody struct {
+	r      io.Reader
+	closer io.Closer
+	orig   io.Closer
+	max    int64
+	n      int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if b.n > b.max {
+		return n - int(b.n-b.max), NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	b.closer.Close()
+	return b.orig.Close()
+}
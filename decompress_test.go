@@ -0,0 +1,73 @@
+package cherry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipBody(t *testing.T, s string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	return buf
+}
+
+func TestDecompress(t *testing.T) {
+	c := New()
+	c.Use(Decompress(0))
+	c.Post("/", func(ctx *Context) error {
+		v := map[string]string{}
+		if err := ctx.DecodeJSON(&v); err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, v["name"])
+	})
+
+	r, _ := http.NewRequest("POST", "/", gzipBody(t, `{"name":"cherry"}`))
+	r.Header.Set("Content-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Body.String() != "cherry" {
+		t.Errorf("expecting cherry got %s", rw.Body.String())
+	}
+
+	code, body := doRequest(t, "POST", "/", strings.NewReader(`{"name":"plain"}`), c)
+	isHTTPStatusOK(t, code)
+	if body != "plain" {
+		t.Errorf("expecting plain got %s", body)
+	}
+}
+
+func TestDecompressLimits(t *testing.T) {
+	c := New()
+	c.Use(Decompress(64))
+	c.Post("/", func(ctx *Context) error {
+		var v interface{}
+		return ctx.DecodeJSON(&v)
+	})
+
+	payload := `{"data":"` + strings.Repeat("a", 1024) + `"}`
+	r, _ := http.NewRequest("POST", "/", gzipBody(t, payload))
+	r.Header.Set("Content-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting code 413 got %d", rw.Code)
+	}
+
+	r, _ = http.NewRequest("POST", "/", strings.NewReader("{}"))
+	r.Header.Set("Content-Encoding", "compress")
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expecting code 415 got %d", rw.Code)
+	}
+}
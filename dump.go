@@ -0,0 +1,179 @@
+package cherry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DumpConfig configures the Dump middleware.
+type DumpConfig struct {
+	// Header, when set, only dumps the requests that have the header, like
+	// X-Debug-Dump. The default dumps every request
+	Header string
+
+	// Redact are the headers whose values are hidden. The default is
+	// Authorization, Proxy-Authorization, Cookie and Set-Cookie
+	Redact []string
+
+	// MaxBody is the maximum number of bytes of a body that are dumped. The
+	// default is 4KB
+	MaxBody int
+
+	// Output receives the dumps. The default is the Output of the
+	// application
+	Output io.Writer
+}
+
+var defaultRedact = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Dump returns a middleware Handler that writes the requests and their
+// responses, with their headers and bodies, to the Output, for diagnosing
+// integrations. It is enabled for single routes with Chain or Tagged, or for
+// the requests with a debug header. The values of credential headers are
+// redacted and the bodies are cut to MaxBody.
+//
+// app.Use(cherry.Dump(cherry.DumpConfig{Header: "X-Debug-Dump"})).
+func Dump(cfg DumpConfig) Handler {
+	if cfg.Redact == nil {
+		cfg.Redact = defaultRedact
+	}
+	if cfg.MaxBody == 0 {
+		cfg.MaxBody = 4 << 10
+	}
+	redact := map[string]bool{}
+	for _, name := range cfg.Redact {
+		redact[http.CanonicalHeaderKey(name)] = true
+	}
+	return func(ctx *Context) error {
+		r := ctx.Request()
+		if cfg.Header != "" && r.Header.Get(cfg.Header) == "" {
+			return nil
+		}
+		out := cfg.Output
+		if out == nil && ctx.cherry != nil {
+			out = ctx.cherry.Output
+		}
+		if out == nil {
+			out = os.Stdout
+		}
+
+		var dump bytes.Buffer
+		fmt.Fprintf(&dump, "> %s %s %s\n", r.Method, r.URL.RequestURI(), r.Proto)
+		dumpHeader(&dump, "> ", r.Header, redact, "Host", r.Host)
+		if r.Body != nil && r.Body != http.NoBody {
+			// the body is read up to the cap and put back for the handler.
+			head, err := io.ReadAll(io.LimitReader(r.Body, int64(cfg.MaxBody)+1))
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+			if err == nil {
+				dumpBody(&dump, "> ", r.Header.Get("Content-Type"), head, cfg.MaxBody, len(head) > cfg.MaxBody)
+			}
+		}
+
+		dw := &dumpWriter{ResponseWriter: ctx.Response(), max: cfg.MaxBody}
+		ctx.response = dw
+		ctx.onFinish(func() {
+			ctx.response = dw.ResponseWriter
+			code := dw.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+			fmt.Fprintf(&dump, "< %s %d %s\n", r.Proto, code, http.StatusText(code))
+			dumpHeader(&dump, "< ", dw.Header(), redact)
+			dumpBody(&dump, "< ", dw.Header().Get("Content-Type"), dw.body.Bytes(), cfg.MaxBody, dw.size > cfg.MaxBody)
+			out.Write(dump.Bytes())
+		})
+		return nil
+	}
+}
+
+// readCloser reads from a Reader and closes a Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// dumpHeader writes the headers in alphabetical order, the extra key value
+// pairs are written first.
+func dumpHeader(w *bytes.Buffer, prefix string, h http.Header, redact map[string]bool, extra ...string) {
+	for i := 0; i+1 < len(extra); i += 2 {
+		fmt.Fprintf(w, "%s%s: %s\n", prefix, extra[i], extra[i+1])
+	}
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range h[name] {
+			if redact[name] {
+				value = "[redacted]"
+			}
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, name, value)
+		}
+	}
+	w.WriteString(strings.TrimSpace(prefix) + "\n")
+}
+
+// dumpBody writes a text body up to max bytes, binary bodies are only
+// described.
+func dumpBody(w *bytes.Buffer, prefix, contentType string, body []byte, max int, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+	if len(body) > max {
+		body = body[:max]
+	}
+	if !isTextType(contentType) && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		fmt.Fprintf(w, "%s[binary body of type %q]\n", prefix, contentType)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(body), "\n"), "\n") {
+		w.WriteString(prefix + line + "\n")
+	}
+	if truncated {
+		fmt.Fprintf(w, "%s[body truncated to %d bytes]\n", prefix, max)
+	}
+}
+
+// dumpWriter keeps the status code and the first bytes of the response it
+// writes.
+type dumpWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+	size int
+	max  int
+}
+
+func (w *dumpWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *dumpWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if n := w.max + 1 - w.body.Len(); n > 0 {
+		w.body.Write(p[:min(n, len(p))])
+	}
+	w.size += len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *dumpWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *dumpWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
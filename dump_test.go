@@ -0,0 +1,81 @@
+package cherry
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	var out bytes.Buffer
+	c := New()
+	c.Use(Dump(DumpConfig{Header: "X-Debug-Dump", MaxBody: 16, Output: &out}))
+	c.Post("/users", func(ctx *Context) error {
+		b, _ := io.ReadAll(ctx.Request().Body)
+		http.SetCookie(ctx.Response(), &http.Cookie{Name: "session", Value: "secret"})
+		return ctx.Text(http.StatusCreated, "created "+string(b))
+	})
+	c.Get("/logo.png", func(ctx *Context) error {
+		return ctx.Blob(http.StatusOK, "image/png", []byte("\x89PNG"))
+	})
+
+	body := `{"name":"Ann","email":"ann@example.com"}`
+	r := httptest.NewRequest("POST", "/users?x=1", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Debug-Dump", "1")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Body.String() != "created "+body {
+		t.Errorf("expecting the handler to read the whole body got %q", rw.Body)
+	}
+	expect := `> POST /users?x=1 HTTP/1.1
+> Host: example.com
+> Authorization: [redacted]
+> Content-Type: application/json
+> X-Debug-Dump: 1
+>
+> {"name":"Ann","e
+> [body truncated to 16 bytes]
+< HTTP/1.1 201 Created
+< Content-Type: text/plain; charset=utf-8
+< Server: Cherry🍒/1.0
+< Set-Cookie: [redacted]
+<
+< created {"name":
+< [body truncated to 16 bytes]
+`
+	if out.String() != expect {
+		t.Errorf("expecting the dump\n%s\ngot\n%s", expect, out.String())
+	}
+
+	out.Reset()
+	r = httptest.NewRequest("GET", "/logo.png", nil)
+	c.ServeHTTP(httptest.NewRecorder(), r)
+	if out.Len() != 0 {
+		t.Errorf("expecting no dump without the debug header got %s", out.String())
+	}
+	r.Header.Set("X-Debug-Dump", "1")
+	c.ServeHTTP(httptest.NewRecorder(), r)
+	if !strings.Contains(out.String(), `< [binary body of type "image/png"]`) {
+		t.Errorf("expecting binary bodies to be described got %s", out.String())
+	}
+}
+
+func TestDumpRoute(t *testing.T) {
+	var out bytes.Buffer
+	c := New()
+	c.Get("/dumped", Chain(noopHandler, Dump(DumpConfig{Output: &out})))
+	c.Get("/other", noopHandler)
+	doRequest(t, "GET", "/other", nil, c)
+	if out.Len() != 0 {
+		t.Errorf("expecting no dump of other routes got %s", out.String())
+	}
+	doRequest(t, "GET", "/dumped", nil, c)
+	if !strings.HasPrefix(out.String(), "> GET /dumped HTTP/1.1\n") || !strings.Contains(out.String(), "< HTTP/1.1 200 OK\n") {
+		t.Errorf("expecting the dump of the route got %s", out.String())
+	}
+}
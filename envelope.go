@@ -0,0 +1,107 @@
+package cherry
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Envelope is the body of the responses of Context.OK, Context.Created and
+// Context.Fail, before it is shaped by the EnvelopeFunc of the application.
+type Envelope struct {
+	Data   any             `json:"data,omitempty"`
+	Meta   map[string]any  `json:"meta,omitempty"`
+	Errors []EnvelopeError `json:"errors,omitempty"`
+}
+
+// EnvelopeError is an error of an Envelope. The Field and Code of a
+// FieldError are set, Code is its tag.
+type EnvelopeError struct {
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// EnvelopeFunc returns the body of an envelope response with the status
+// code, it shapes all of the envelopes of an application.
+//
+//	app.Envelope = func(ctx *cherry.Context, code int, e *cherry.Envelope) any {
+//		return map[string]any{"success": code < 400, "result": e.Data, "errors": e.Errors}
+//	}
+type EnvelopeFunc func(ctx *Context, code int, e *Envelope) any
+
+// SetMeta sets a meta value of the envelope of the response, like the total
+// of a paginated list.
+func (c *Context) SetMeta(key string, value any) {
+	if c.meta == nil {
+		c.meta = map[string]any{}
+	}
+	c.meta[key] = value
+}
+
+// OK responds with data in the envelope with 200 OK.
+//
+// return ctx.OK(users).
+func (c *Context) OK(data any) error {
+	return c.envelope(http.StatusOK, &Envelope{Data: data})
+}
+
+// Created responds with data in the envelope with 201 Created and the
+// Location of the created resource, unless it is empty.
+//
+// return ctx.Created(user, "/users/"+user.ID).
+func (c *Context) Created(data any, location string) error {
+	if location != "" {
+		c.Response().Header().Set("Location", location)
+	}
+	return c.envelope(http.StatusCreated, &Envelope{Data: data})
+}
+
+// Fail responds with the errors of err in the envelope. A zero code uses
+// the code of an HTTPError, 422 for a FieldError or ValidationErrors and 500
+// for other errors. Like in the default error handler, the messages of
+// server errors are hidden outside of DebugMode unless they are HTTPErrors.
+// The messages of FieldErrors are in the language of the request, see
+// Context.ErrorMessage.
+//
+// return ctx.Fail(http.StatusConflict, errors.New("the email is taken")).
+func (c *Context) Fail(code int, err error) error {
+	var (
+		he   *HTTPError
+		fe   *FieldError
+		errs ValidationErrors
+	)
+	e := &Envelope{}
+	switch {
+	case errors.As(err, &errs):
+		for _, fe := range errs {
+			e.Errors = append(e.Errors, EnvelopeError{Message: c.fieldMessage(fe), Field: fe.Field, Code: fe.Tag})
+		}
+	case errors.As(err, &fe):
+		e.Errors = []EnvelopeError{{Message: c.fieldMessage(fe), Field: fe.Field, Code: fe.Tag}}
+	}
+	if code == 0 {
+		code = statusCode(err)
+		if e.Errors != nil && !errors.As(err, &he) {
+			code = http.StatusUnprocessableEntity
+		}
+	}
+	if e.Errors == nil {
+		msg := err.Error()
+		if !IsDebug() && !errors.As(err, &he) && code >= http.StatusInternalServerError {
+			msg = http.StatusText(code)
+		}
+		e.Errors = []EnvelopeError{{Message: msg}}
+	}
+	if code >= http.StatusInternalServerError && c.cherry != nil {
+		c.cherry.logError(c, err)
+	}
+	return c.envelope(code, e)
+}
+
+func (c *Context) envelope(code int, e *Envelope) error {
+	e.Meta = c.meta
+	if c.cherry != nil && c.cherry.Envelope != nil {
+		return c.JSON(code, c.cherry.Envelope(c, code, e))
+	}
+	return c.JSON(code, e)
+}
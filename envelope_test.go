@@ -0,0 +1,78 @@
+package cherry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	c := New()
+	c.Get("/ok", func(ctx *Context) error {
+		ctx.SetMeta("total", 2)
+		return ctx.OK([]string{})
+	})
+	c.Post("/created", func(ctx *Context) error {
+		return ctx.Created(map[string]int{"id": 7}, "/users/7")
+	})
+	c.Get("/conflict", func(ctx *Context) error {
+		return ctx.Fail(http.StatusConflict, errors.New("the email is taken"))
+	})
+	c.Get("/invalid", func(ctx *Context) error {
+		return ctx.Fail(0, ValidationErrors{{Field: "email", Tag: "required"}, {Field: "age", Tag: "min", Param: "18"}})
+	})
+	c.Get("/http", func(ctx *Context) error {
+		return ctx.Fail(0, NewHTTPError(http.StatusNotFound, "no such user"))
+	})
+	tests := []struct {
+		method string
+		route  string
+		code   int
+		body   string
+	}{
+		{"GET", "/ok", http.StatusOK, `{"data":[],"meta":{"total":2}}`},
+		{"POST", "/created", http.StatusCreated, `{"data":{"id":7}}`},
+		{"GET", "/conflict", http.StatusConflict, `{"errors":[{"message":"the email is taken"}]}`},
+		{"GET", "/invalid", http.StatusUnprocessableEntity, `{"errors":[{"message":"email is required","field":"email","code":"required"},{"message":"age must be at least 18","field":"age","code":"min"}]}`},
+		{"GET", "/http", http.StatusNotFound, `{"errors":[{"message":"no such user"}]}`},
+	}
+	for _, test := range tests {
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, httptest.NewRequest(test.method, test.route, nil))
+		if rw.Code != test.code || strings.TrimSpace(rw.Body.String()) != test.body {
+			t.Errorf("%s: expecting %d %s got %d %s", test.route, test.code, test.body, rw.Code, rw.Body)
+		}
+	}
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("POST", "/created", nil))
+	if loc := rw.Header().Get("Location"); loc != "/users/7" {
+		t.Errorf("expecting the location /users/7 got %q", loc)
+	}
+}
+
+func TestEnvelopeFunc(t *testing.T) {
+	c := New()
+	c.Envelope = func(ctx *Context, code int, e *Envelope) any {
+		return map[string]any{"success": code < 400, "result": e.Data}
+	}
+	c.Get("/", func(ctx *Context) error { return ctx.OK(1) })
+	c.Get("/fail", func(ctx *Context) error { return ctx.Fail(0, errors.New("database is down")) })
+	if _, body := doRequest(t, "GET", "/", nil, c); strings.TrimSpace(body) != `{"result":1,"success":true}` {
+		t.Errorf("unexpected body %s", body)
+	}
+	if code, body := doRequest(t, "GET", "/fail", nil, c); code != http.StatusInternalServerError || strings.TrimSpace(body) != `{"result":null,"success":false}` {
+		t.Errorf("unexpected response %d %s", code, body)
+	}
+}
+
+func TestFailHidesServerErrors(t *testing.T) {
+	setMode(t, ReleaseMode)
+	c := New()
+	c.Output = &strings.Builder{}
+	c.Get("/", func(ctx *Context) error { return ctx.Fail(0, errors.New("database is down")) })
+	if code, body := doRequest(t, "GET", "/", nil, c); code != http.StatusInternalServerError || !strings.Contains(body, `"Internal Server Error"`) {
+		t.Errorf("expecting the status text got %d %s", code, body)
+	}
+}
@@ -0,0 +1,38 @@
+package cherry
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error that carries the HTTP status code it should be
+// responded with. The default error handler writes the Code and Message of an
+// HTTPError instead of responding with a 500.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+// NewHTTPError returns a new HTTPError. When no message is given the status
+// text of the code is used.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	msg := http.StatusText(code)
+	if len(message) > 0 {
+		msg = message[0]
+	}
+	return &HTTPError{Code: code, Message: msg}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// statusCode returns the status code for err, which is the code of an
+// HTTPError or 500 for any other error.
+func statusCode(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,175 @@
+package cherry
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ETag returns a middleware Handler that buffers successful GET and HEAD
+// responses, tags them with an ETag computed over the body and responds with
+// 304 Not Modified when it matches the If-None-Match request header. An ETag
+// set by the handler itself is honored instead of computing one. HEAD
+// responses have no body to compute it over, so only an ETag set by the
+// handler is sent for them. Event streams, upgrades and responses that the
+// handler flushes are not tagged.
+//
+// app.Use(cherry.ETag(false)).
+func ETag(weak bool) Handler {
+	return func(ctx *Context) error {
+		r := ctx.Request()
+		if r.Method != http.MethodGet && r.Method != http.MethodHead || isStreamRequest(r) {
+			return nil
+		}
+		bw := &bufferWriter{ResponseWriter: ctx.Response()}
+		ctx.response = bw
+		ctx.onFinish(func() {
+			if bw.passthrough {
+				return
+			}
+			if bw.code != http.StatusOK {
+				bw.flush()
+				return
+			}
+			h := bw.Header()
+			etag := h.Get("ETag")
+			if etag == "" && r.Method == http.MethodHead {
+				bw.flush()
+				return
+			}
+			if etag == "" {
+				etag = computeETag(bw.buf.Bytes(), weak)
+				h.Set("ETag", etag)
+			}
+			if ifNoneMatch(r.Header.Get("If-None-Match"), etag) {
+				writeNotModified(bw.ResponseWriter)
+				return
+			}
+			bw.flush()
+		})
+		return nil
+	}
+}
+
+// computeETag returns a quoted entity-tag for b.
+func computeETag(b []byte, weak bool) string {
+	h := fnv.New64a()
+	h.Write(b)
+	etag := fmt.Sprintf("\"%x-%x\"", len(b), h.Sum64())
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// ifNoneMatch reports whether the If-None-Match header matches etag using
+// the weak comparison function.
+func ifNoneMatch(header, etag string) bool {
+	if header == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified writes a 304 response, dropping the headers that describe
+// a body.
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// bufferWriter holds the complete response in memory until flush is called.
+// Event streams, and responses that are flushed or hijacked by the handler,
+// are passed through instead, they are not complete until the handler
+// returns.
+type bufferWriter struct {
+	http.ResponseWriter
+	buf  bytes.Buffer
+	code int
+	// passthrough is set once the writer stopped buffering.
+	passthrough bool
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+	if isEventStream(w.Header()) {
+		w.Flush()
+	}
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+		if w.passthrough {
+			return w.ResponseWriter.Write(p)
+		}
+	}
+	return w.buf.Write(p)
+}
+
+// Flush writes what is buffered and stops buffering, the rest of the
+// response is written through.
+func (w *bufferWriter) Flush() {
+	if !w.passthrough {
+		w.passthrough = true
+		if w.code != 0 {
+			w.ResponseWriter.WriteHeader(w.code)
+			w.ResponseWriter.Write(w.buf.Bytes())
+			w.buf.Reset()
+		}
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the handler take over the connection, nothing buffered is
+// written.
+func (w *bufferWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *bufferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flush writes the buffered status code and body to the underlying writer.
+func (w *bufferWriter) flush() {
+	if w.code == 0 || w.passthrough {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	w.ResponseWriter.Write(w.buf.Bytes())
+}
+
+// isStreamRequest reports whether r asks for an event stream or a protocol
+// upgrade like a WebSocket, whose responses are never buffered.
+func isStreamRequest(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func isEventStream(h http.Header) bool {
+	return strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+}
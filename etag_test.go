@@ -0,0 +1,207 @@
+package cherry
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	c := New()
+	c.Use(ETag(false))
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+	c.Get("/custom", func(ctx *Context) error {
+		ctx.Response().Header().Set("ETag", `"v1"`)
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	etag := rw.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expecting an ETag header")
+	}
+	if rw.Body.String() != "cherry" {
+		t.Errorf("expecting cherry got %s", rw.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", "W/"+etag)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting code 304 got %d", rw.Code)
+	}
+	if rw.Body.Len() != 0 {
+		t.Errorf("expecting empty body got %s", rw.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/custom", nil)
+	r.Header.Set("If-None-Match", `"v0", "v1"`)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting code 304 got %d", rw.Code)
+	}
+	if rw.Header().Get("ETag") != `"v1"` {
+		t.Errorf("expecting ETag \"v1\" got %s", rw.Header().Get("ETag"))
+	}
+}
+
+func TestETagHead(t *testing.T) {
+	c := New()
+	c.Use(ETag(false))
+	c.Head("/", func(ctx *Context) error {
+		ctx.Response().WriteHeader(http.StatusOK)
+		return nil
+	})
+	c.Head("/custom", func(ctx *Context) error {
+		ctx.Response().Header().Set("ETag", `"v1"`)
+		ctx.Response().WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	r, _ := http.NewRequest("HEAD", "/", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if etag := rw.Header().Get("ETag"); etag != "" {
+		t.Errorf("expecting no ETag for the empty HEAD body got %s", etag)
+	}
+
+	r, _ = http.NewRequest("HEAD", "/custom", nil)
+	r.Header.Set("If-None-Match", `"v1"`)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting code 304 got %d", rw.Code)
+	}
+}
+
+func TestETagWeak(t *testing.T) {
+	c := New()
+	c.Use(ETag(true))
+	c.Get("/", noopHandler)
+	c.Get("/text", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+
+	r, _ := http.NewRequest("GET", "/text", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if etag := rw.Header().Get("ETag"); len(etag) < 2 || etag[:2] != "W/" {
+		t.Errorf("expecting a weak ETag got %s", etag)
+	}
+
+	code, _ := doRequest(t, "GET", "/", nil, c)
+	isHTTPStatusOK(t, code)
+}
+
+func TestJSONETag(t *testing.T) {
+	c := New()
+	c.JSONETag = true
+	c.Get("/", func(ctx *Context) error {
+		return ctx.JSON(http.StatusOK, map[string]string{"name": "cherry"})
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	etag := rw.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expecting an ETag header")
+	}
+	if rw.Body.String() != "{\"name\":\"cherry\"}\n" {
+		t.Errorf("unexpected body %s", rw.Body.String())
+	}
+
+	r.Header.Set("If-None-Match", etag)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting code 304 got %d", rw.Code)
+	}
+	if rw.Body.Len() != 0 {
+		t.Errorf("expecting empty body got %s", rw.Body.String())
+	}
+}
+
+func TestETagStreaming(t *testing.T) {
+	release := make(chan struct{})
+	c := New()
+	c.Use(ETag(false))
+	c.Get("/events", func(ctx *Context) error {
+		if err := ctx.SSE(Event{Data: "hello"}); err != nil {
+			return err
+		}
+		<-release
+		return nil
+	})
+	c.Get("/stream", func(ctx *Context) error {
+		sent := false
+		return ctx.StreamWriter(func(w *bufio.Writer) bool {
+			if !sent {
+				sent = true
+				w.WriteString("chunk\n")
+				return true
+			}
+			<-release
+			return false
+		})
+	})
+	c.Get("/raw", func(ctx *Context) error {
+		conn, brw, err := http.NewResponseController(ctx.Response()).Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 6\r\nConnection: close\r\n\r\nhijack")
+		return brw.Flush()
+	})
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	// the handlers are released before the server is closed.
+	defer close(release)
+
+	for path, expect := range map[string]string{"/events": "data: hello\n", "/stream": "chunk\n"} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line := make(chan string, 1)
+		go func() {
+			s, _ := bufio.NewReader(res.Body).ReadString('\n')
+			line <- s
+		}()
+		select {
+		case s := <-line:
+			if s != expect {
+				t.Errorf("expecting %q from %s got %q", expect, path, s)
+			}
+			if res.Header.Get("ETag") != "" {
+				t.Errorf("expecting no ETag on the stream of %s", path)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("expecting %s to be streamed before the handler returns", path)
+		}
+		res.Body.Close()
+	}
+
+	res, err := http.Get(srv.URL + "/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(b) != "hijack" {
+		t.Errorf("expecting the connection to be hijacked got %q", b)
+	}
+}
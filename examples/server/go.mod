@@ -2,10 +2,16 @@ module cherry/example
 
 go 1.21.4
 
-require golang.org/x/net v0.19.0
+require github.com/pooulad/cherry v0.0.0-20231221163629-d2e4cd6db14e
 
 require (
-	github.com/bradfitz/http2 v0.0.0-20160116213329-aa7658c0e990 // indirect
+	github.com/BurntSushi/toml v1.3.2 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/julienschmidt/httprouter v1.3.0 // indirect
-	github.com/pooulad/cherry v0.0.0-20231221163629-d2e4cd6db14e // indirect
+	github.com/klauspost/compress v1.17.4 // indirect
+	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/pooulad/cherry => ../..
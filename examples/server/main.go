@@ -8,11 +8,10 @@ import (
 	"net/http"
 
 	"github.com/pooulad/cherry"
-	"golang.org/x/net/context"
 )
 
-// Simpel example how to use cherry with a "datastore" by making use
-// of cherry.Context to pass information between middleware and handlers
+// Simpel example how to use cherry with a "datastore" by registering it once
+// in the state of the application and using it in the handlers.
 
 func main() {
 	listen := flag.Int("listen", 3000, "listen address of the application")
@@ -23,8 +22,10 @@ func main() {
 	// centralizing our errors returned from middleware and request handlers
 	app.SetErrorHandler(errorHandler)
 
+	// long-lived dependencies are registered once
+	app.State().Set("datastore", &datastore{"mydatabase"})
+
 	app.Get("/hello/:name", greetingHandler)
-	app.Use(dbContextHandler)
 
 	// make a subrouter and register some middleware for it
 	admin := app.Group("/admin")
@@ -38,28 +39,6 @@ type datastore struct {
 	name string
 }
 
-type dbContext struct {
-	context.Context
-	ds *datastore
-}
-
-func (c *dbContext) Value(key interface{}) interface{} {
-	if key == "datastore" {
-		return c.ds
-	}
-	return c.Context.Value(key)
-}
-
-func newDatastoreContext(parent context.Context, ds *datastore) context.Context {
-	return &dbContext{parent, ds}
-}
-
-func dbContextHandler(ctx *cherry.Context) error {
-	db := datastore{"mydatabase"}
-	ctx.Context = newDatastoreContext(ctx.Context, &db)
-	return nil
-}
-
 // Only the powerfull have access to the admin routes.
 func authenticate(ctx *cherry.Context) error {
 	admins := []string{"toby", "master iy", "c.froome"}
@@ -73,21 +52,22 @@ func authenticate(ctx *cherry.Context) error {
 	return errors.New("access forbidden")
 }
 
-// context helper function to stay lean and mean in your handlers.
-func datastoreFromContext(ctx context.Context) *datastore {
-	return ctx.Value("datastore").(*datastore)
+// state helper function to stay lean and mean in your handlers.
+func datastoreFromContext(ctx *cherry.Context) *datastore {
+	db, _ := cherry.StateValue[*datastore](ctx, "datastore")
+	return db
 }
 
 func greetingHandler(ctx *cherry.Context) error {
 	name := ctx.Param("name")
-	db := datastoreFromContext(ctx.Context)
+	db := datastoreFromContext(ctx)
 	greeting := fmt.Sprintf("Greetings, %s\nYour database %s is ready", name, db.name)
 	return ctx.Text(http.StatusOK, greeting)
 }
 
 func adminGreetingHandler(ctx *cherry.Context) error {
 	name := ctx.Param("name")
-	db := datastoreFromContext(ctx.Context)
+	db := datastoreFromContext(ctx)
 	greeting := fmt.Sprintf("Greetings powerfull admin, %s\nYour database %s is ready", name, db.name)
 	return ctx.Text(http.StatusOK, greeting)
 }
@@ -0,0 +1,177 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileOption configures the responses of File and Stream.
+type FileOption func(*fileConfig)
+
+type fileConfig struct {
+	bandwidth int64
+}
+
+// WithBandwidth limits the rate at which the response is written to the
+// client to bytesPerSecond, which protects shared egress from a few large
+// downloads.
+//
+// ctx.File("exports/report.zip", cherry.WithBandwidth(1<<20)).
+func WithBandwidth(bytesPerSecond int64) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.bandwidth = bytesPerSecond
+	}
+}
+
+// File writes the named file to the client. Range requests, with multiple
+// ranges and If-Range, conditional and HEAD requests are handled by
+// http.ServeContent. The file is tagged with a strong ETag derived from its
+// size and modification time, unless the handler set one. Relative names
+// are resolved against the base directory of the application. A file that
+// does not exist is answered with a 404 HTTPError.
+func (c *Context) File(name string, opts ...FileOption) error {
+	return c.serveFile(name, "", opts)
+}
+
+// Attachment writes the named file like File, with a Content-Disposition
+// that makes browsers download it as filename. An empty filename uses the
+// base name of the file.
+//
+// ctx.Attachment("invoices/2024-001.pdf", "invoice.pdf").
+func (c *Context) Attachment(name, filename string, opts ...FileOption) error {
+	if filename == "" {
+		filename = filepath.Base(name)
+	}
+	return c.serveFile(name, filename, opts)
+}
+
+// serveFile writes the named file, as an attachment when filename is set.
+func (c *Context) serveFile(name, filename string, opts []FileOption) error {
+	if c.cherry != nil {
+		name = c.cherry.resolve(name)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return fileError(err)
+	}
+	if stat.IsDir() {
+		return NewHTTPError(http.StatusNotFound)
+	}
+	w := c.fileWriter(opts)
+	if filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	if w.Header().Get("ETag") == "" {
+		w.Header().Set("ETag", fmt.Sprintf("\"%x-%x\"", stat.ModTime().UnixNano(), stat.Size()))
+	}
+	if w.Header().Get("Content-Type") == "" && !c.contentTypes().Sniff &&
+		mime.TypeByExtension(filepath.Ext(name)) == "" {
+		// http.ServeContent sniffs the files of unknown extensions.
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	http.ServeContent(w, c.Request(), filepath.Base(name), stat.ModTime(), f)
+	return nil
+}
+
+// Stream copies r to the client with the given status and content type.
+// Without a type, it is detected from the first bytes of r, see
+// ContentTypes.Sniff.
+//
+// ctx.Stream(http.StatusOK, "text/csv", rows, cherry.WithBandwidth(256<<10)).
+func (c *Context) Stream(code int, contentType string, r io.Reader, opts ...FileOption) error {
+	if contentType == "" {
+		contentType, r = c.sniffReader(r)
+	}
+	w := c.fileWriter(opts)
+	w.Header().Set("Content-Type", c.contentType(contentType))
+	w.WriteHeader(code)
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// fileWriter returns the writer of the response with the options applied.
+func (c *Context) fileWriter(opts []FileOption) http.ResponseWriter {
+	var cfg fileConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.bandwidth <= 0 {
+		return c.Response()
+	}
+	return &throttledWriter{
+		ResponseWriter: c.Response(),
+		rate:           cfg.bandwidth,
+		start:          time.Now(),
+		ctx:            c.Request().Context(),
+		extend:         c.extendWriteDeadline,
+	}
+}
+
+func fileError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NewHTTPError(http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		return NewHTTPError(http.StatusForbidden)
+	}
+	return err
+}
+
+// throttledWriter writes at most rate bytes per second. It writes in chunks
+// of a tenth of the rate and flushes each of them before it waits, so the
+// client receives a steady stream. The write deadline of the connection is
+// extended before every chunk, a slow download outlives the WriteTimeout of
+// the server.
+type throttledWriter struct {
+	http.ResponseWriter
+	rate    int64
+	start   time.Time
+	written int64
+	ctx     context.Context
+	extend  func()
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	chunk := int(max(w.rate/10, 1))
+	var n int
+	for len(p) > 0 {
+		w.extend()
+		m, err := w.ResponseWriter.Write(p[:min(len(p), chunk)])
+		n += m
+		w.written += int64(m)
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+		flush(w.ResponseWriter)
+		due := w.start.Add(time.Duration(float64(w.written) / float64(w.rate) * float64(time.Second)))
+		if d := time.Until(due); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-w.ctx.Done():
+				t.Stop()
+				return n, w.ctx.Err()
+			}
+		}
+	}
+	return n, nil
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,185 @@
+package cherry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "report.txt"), []byte("0123456789"), 0o644)
+	c := New()
+	c.SetBaseDir(dir)
+	c.Get("/:name", func(ctx *Context) error {
+		return ctx.File(ctx.Param("name"))
+	})
+
+	code, body := doRequest(t, "GET", "/report.txt", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "0123456789" {
+		t.Errorf("expecting the file got %q", body)
+	}
+
+	r := httptest.NewRequest("GET", "/report.txt", nil)
+	r.Header.Set("Range", "bytes=2-4")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusPartialContent || rw.Body.String() != "234" {
+		t.Errorf("expecting 206 with 234 got %d %q", rw.Code, rw.Body.String())
+	}
+
+	if code, _ := doRequest(t, "GET", "/missing.txt", nil, c); code != http.StatusNotFound {
+		t.Errorf("expecting 404 got %d", code)
+	}
+}
+
+func TestFileRanges(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "video.mp4")
+	os.WriteFile(name, []byte("0123456789"), 0o644)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(name, modTime, modTime)
+	c := New()
+	c.Get("/video", func(ctx *Context) error {
+		return ctx.File(name)
+	})
+	c.Head("/video", func(ctx *Context) error {
+		return ctx.File(name)
+	})
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/video", nil))
+	etag := rw.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || rw.Header().Get("Accept-Ranges") != "bytes" {
+		t.Fatalf("expecting a strong ETag and Accept-Ranges got %v", rw.Header())
+	}
+
+	tests := []struct {
+		name    string
+		header  map[string]string
+		code    int
+		body    string
+		partial bool
+	}{
+		{"single range", map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "789", false},
+		{"multiple ranges", map[string]string{"Range": "bytes=0-1,5-6"}, http.StatusPartialContent, "", true},
+		{"if-range etag", map[string]string{"Range": "bytes=0-1", "If-Range": etag}, http.StatusPartialContent, "01", false},
+		{"stale if-range etag", map[string]string{"Range": "bytes=0-1", "If-Range": `"old"`}, http.StatusOK, "0123456789", false},
+		{"if-range date", map[string]string{"Range": "bytes=0-1", "If-Range": modTime.Format(http.TimeFormat)}, http.StatusPartialContent, "01", false},
+		{"stale if-range date", map[string]string{"Range": "bytes=0-1", "If-Range": modTime.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK, "0123456789", false},
+		{"unsatisfiable", map[string]string{"Range": "bytes=20-30"}, http.StatusRequestedRangeNotSatisfiable, "", false},
+		{"if-none-match", map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/video", nil)
+		for k, v := range test.header {
+			r.Header.Set(k, v)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code {
+			t.Errorf("%s: expecting %d got %d", test.name, test.code, rw.Code)
+			continue
+		}
+		if test.partial {
+			ct := rw.Header().Get("Content-Type")
+			body := rw.Body.String()
+			if !strings.HasPrefix(ct, "multipart/byteranges") || !strings.Contains(body, "01") || !strings.Contains(body, "56") {
+				t.Errorf("%s: expecting a multipart body got %s %q", test.name, ct, body)
+			}
+			continue
+		}
+		if test.body != "" && rw.Body.String() != test.body {
+			t.Errorf("%s: expecting %q got %q", test.name, test.body, rw.Body.String())
+		}
+	}
+
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("HEAD", "/video", nil))
+	if rw.Code != http.StatusOK || rw.Body.Len() != 0 || rw.Header().Get("Content-Length") != "10" {
+		t.Errorf("expecting a HEAD response without body got %d %v %q", rw.Code, rw.Header(), rw.Body.String())
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "2024-001.pdf"), []byte("%PDF"), 0o644)
+	c := New()
+	c.SetBaseDir(dir)
+	c.Get("/invoice", func(ctx *Context) error {
+		return ctx.Attachment("2024-001.pdf", "facture été.pdf")
+	})
+	c.Get("/missing", func(ctx *Context) error {
+		return ctx.Attachment("missing.pdf", "")
+	})
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/invoice", nil))
+	isHTTPStatusOK(t, rw.Code)
+	if cd := rw.Header().Get("Content-Disposition"); cd != "attachment; filename*=utf-8''facture%20%C3%A9t%C3%A9.pdf" {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/missing", nil))
+	if rw.Code != http.StatusNotFound || rw.Header().Get("Content-Disposition") != "" {
+		t.Errorf("expecting a 404 without Content-Disposition got %d %v", rw.Code, rw.Header())
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Stream(http.StatusAccepted, "text/csv", strings.NewReader("a,b\n"))
+	})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if rw.Code != http.StatusAccepted || rw.Header().Get("Content-Type") != "text/csv; charset=utf-8" || rw.Body.String() != "a,b\n" {
+		t.Errorf("unexpected response %d %v %q", rw.Code, rw.Header(), rw.Body.String())
+	}
+}
+
+func TestWithBandwidth(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Stream(http.StatusOK, "text/plain", strings.NewReader(body), WithBandwidth(10<<10))
+	})
+	start := time.Now()
+	code, got := doRequest(t, "GET", "/", nil, c)
+	isHTTPStatusOK(t, code)
+	if got != body {
+		t.Error("expecting the complete body")
+	}
+	// 2KB at 10KB per second.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expecting the download to be throttled, it took %s", elapsed)
+	}
+}
+
+func TestWithBandwidthOutlivesWriteTimeout(t *testing.T) {
+	body := strings.Repeat("a", 6<<10)
+	c := New()
+	c.WriteTimeout = 200 * time.Millisecond
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Stream(http.StatusOK, "text/plain", strings.NewReader(body), WithBandwidth(10<<10))
+	})
+	addr := serveWriteTimeout(t, c)
+
+	// 6KB at 10KB per second, three times the WriteTimeout.
+	res, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil || string(b) != body {
+		t.Errorf("expecting the complete body got %v after %d bytes", err, len(b))
+	}
+}
@@ -3,7 +3,12 @@ module github.com/pooulad/cherry
 go 1.21.4
 
 require (
-	github.com/bradfitz/http2 v0.0.0-20160116213329-aa7658c0e990
+	github.com/BurntSushi/toml v1.3.2
+	github.com/andybalholm/brotli v1.1.0
 	github.com/julienschmidt/httprouter v1.3.0
+	github.com/klauspost/compress v1.17.4
+	golang.org/x/crypto v0.16.0
 	golang.org/x/net v0.19.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
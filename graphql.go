@@ -0,0 +1,74 @@
+package cherry
+
+import (
+	"html/template"
+	"net/http"
+	"path"
+)
+
+// GraphQLOption configures the endpoint registered with GraphQL.
+type GraphQLOption func(*graphqlConfig)
+
+type graphqlConfig struct {
+	playground string
+	middleware []Handler
+}
+
+// WithPlayground serves the GraphiQL playground for the endpoint on route.
+func WithPlayground(route string) GraphQLOption {
+	return func(cfg *graphqlConfig) {
+		cfg.playground = route
+	}
+}
+
+// WithGraphQLMiddleware runs the middleware before the queries, in addition
+// to the middleware of the application, for example to require a login.
+func WithGraphQLMiddleware(middleware ...Handler) GraphQLOption {
+	return func(cfg *graphqlConfig) {
+		cfg.middleware = append(cfg.middleware, middleware...)
+	}
+}
+
+// GraphQL serves the GET and POST queries on route with schema, an
+// http.Handler like the ones of gqlgen or graphql-go. The queries go through
+// the middleware of the application, so they are logged, authenticated and
+// measured like any other route.
+//
+// app.GraphQL("/graphql", srv, cherry.WithPlayground("/playground")).
+func (c *Cherry) GraphQL(route string, schema http.Handler, opts ...GraphQLOption) {
+	var cfg graphqlConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	h := chain(WrapHandler(schema), cfg.middleware...)
+	c.Get(route, h)
+	c.Post(route, h)
+	if cfg.playground != "" {
+		endpoint := path.Join(c.prefix, route)
+		c.Get(cfg.playground, func(ctx *Context) error {
+			ctx.Response().Header().Set("Content-Type", "text/html; charset=utf-8")
+			return playgroundTemplate.Execute(ctx.Response(), endpoint)
+		})
+	}
+}
+
+var playgroundTemplate = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>GraphiQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+  <style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+</head>
+<body>
+  <div id="graphiql">Loading...</div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: {{.}} });
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher }));
+  </script>
+</body>
+</html>
+`))
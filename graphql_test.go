@@ -0,0 +1,63 @@
+package cherry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	schema := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		if r.Method == http.MethodPost {
+			var req struct {
+				Query string `json:"query"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			query = req.Query
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"query": query}})
+	})
+
+	c := New()
+	api := c.Group("/api")
+	api.GraphQL("/graphql", schema,
+		WithPlayground("/playground"),
+		WithGraphQLMiddleware(func(ctx *Context) error {
+			if ctx.Header("Authorization") == "" {
+				return NewHTTPError(http.StatusUnauthorized)
+			}
+			return nil
+		}),
+	)
+
+	r, _ := http.NewRequest("POST", "/api/graphql", strings.NewReader(`{"query":"{ me { name } }"}`))
+	r.Header.Set("Authorization", "Bearer token")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if body := rw.Body.String(); !strings.Contains(body, `"query":"{ me { name } }"`) {
+		t.Errorf("unexpected response %s", body)
+	}
+
+	r, _ = http.NewRequest("GET", "/api/graphql?query=%7B+me+%7D", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if body := rw.Body.String(); !strings.Contains(body, `"query":"{ me }"`) {
+		t.Errorf("unexpected response %s", body)
+	}
+
+	if code, _ := doRequest(t, "POST", "/api/graphql", nil, c); code != http.StatusUnauthorized {
+		t.Errorf("expecting 401 got %d", code)
+	}
+
+	code, body := doRequest(t, "GET", "/api/playground", nil, c)
+	isHTTPStatusOK(t, code)
+	if !strings.Contains(body, `createFetcher({ url: "/api/graphql" })`) {
+		t.Errorf("expecting the playground to query /api/graphql got %s", body)
+	}
+}
@@ -0,0 +1,48 @@
+package cherry
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// GRPC serves the gRPC requests on the same port as the application. h is
+// usually a *grpc.Server, which implements http.Handler. Requests are
+// recognized as gRPC by their HTTP2 protocol and application/grpc
+// content-type, and bypass the router and the middleware. GRPC enables
+// HTTP2, and H2C for clients that connect without TLS.
+//
+// app.GRPC(grpcServer).
+func (c *Cherry) GRPC(h http.Handler) {
+	c.grpc = h
+	c.HTTP2 = true
+	c.H2C = true
+}
+
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// mountMethods are the methods Mount registers.
+var mountMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// Mount serves h, like the mux generated by grpc-gateway, for every path
+// under prefix and every method, with the middleware and error handler of
+// the application. The request path is passed on unchanged, use
+// http.StripPrefix when h expects paths relative to prefix. No other route
+// may be registered under prefix.
+//
+// app.Mount("/v1", gatewayMux).
+func (c *Cherry) Mount(prefix string, h http.Handler) {
+	handler := WrapHandler(h)
+	root := path.Join(c.prefix, prefix) == "/"
+	for _, method := range mountMethods {
+		if !root {
+			c.add(method, prefix, handler)
+		}
+		c.add(method, path.Join(prefix, "*path"), handler)
+	}
+}
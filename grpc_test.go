@@ -0,0 +1,101 @@
+package cherry
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestGRPC(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	c.GRPC(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Write([]byte("grpc " + r.URL.Path))
+	}))
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "http "+ctx.Request().Proto)
+	})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Serve(0)
+	}()
+	addr := waitForServer(t, c)
+	defer func() {
+		c.Close()
+		<-errc
+	}()
+	_, port, _ := net.SplitHostPort(addr)
+	url := "http://127.0.0.1:" + port
+
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+
+	tests := []struct {
+		client      *http.Client
+		method      string
+		path        string
+		contentType string
+		expect      string
+	}{
+		{h2c, "POST", "/helloworld.Greeter/SayHello", "application/grpc+proto", "grpc /helloworld.Greeter/SayHello"},
+		{h2c, "GET", "/", "", "http HTTP/2.0"},
+		{http.DefaultClient, "GET", "/", "", "http HTTP/1.1"},
+		{http.DefaultClient, "POST", "/", "application/grpc", "Method Not Allowed\n"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, url+test.path, strings.NewReader(""))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		resp, err := test.client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != test.expect {
+			t.Errorf("%s %s: expecting %q got %q", test.method, test.path, test.expect, b)
+		}
+	}
+}
+
+func TestMount(t *testing.T) {
+	c := New()
+	c.Use(func(ctx *Context) error {
+		ctx.Response().Header().Set("X-Middleware", "yes")
+		return nil
+	})
+	gateway := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+	c.Mount("/v1", gateway)
+	c.Get("/health", func(ctx *Context) error { return nil })
+
+	for _, method := range []string{"GET", "POST", "DELETE"} {
+		for _, p := range []string{"/v1", "/v1/users/42"} {
+			code, body := doRequest(t, method, p, nil, c)
+			isHTTPStatusOK(t, code)
+			if body != method+" "+p {
+				t.Errorf("expecting %s %s got %s", method, p, body)
+			}
+		}
+	}
+
+	root := New()
+	root.Group("/").Mount("/", gateway)
+	if _, body := doRequest(t, "GET", "/anything", nil, root); body != "GET /anything" {
+		t.Errorf("expecting GET /anything got %s", body)
+	}
+}
@@ -0,0 +1,26 @@
+package cherry
+
+import "net/http"
+
+// H adapts fn to a Handler. The request is bound into an In with Bind, fn is
+// called with it and the Out it returns is written as JSON with a 200
+// status. Errors of Bind and fn go through the error handler like the errors
+// of any other Handler. Since fn is a plain function of its input it is
+// easy to unit test.
+//
+//	app.Post("/users/:id", cherry.H(func(ctx *cherry.Context, in updateUser) (*User, error) {
+//		return store.Update(in.ID, in.Name)
+//	}))
+func H[In, Out any](fn func(ctx *Context, in In) (Out, error)) Handler {
+	return func(ctx *Context) error {
+		var in In
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			return err
+		}
+		return ctx.JSON(http.StatusOK, out)
+	}
+}
@@ -0,0 +1,60 @@
+package cherry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type greetInput struct {
+	Name  string `param:"name"`
+	Times int    `query:"times"`
+}
+
+type greetOutput struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(ctx *Context, in greetInput) (greetOutput, error) {
+	if in.Name == "nobody" {
+		return greetOutput{}, NewHTTPError(http.StatusNotFound)
+	}
+	if in.Times > 3 {
+		return greetOutput{}, errors.New("too many greetings")
+	}
+	return greetOutput{strings.Repeat("hello "+in.Name+" ", in.Times)}, nil
+}
+
+func TestH(t *testing.T) {
+	c := New()
+	c.Get("/greet/:name", H(greet))
+
+	code, body := doRequest(t, "GET", "/greet/toby?times=2", nil, c)
+	isHTTPStatusOK(t, code)
+	var out greetOutput
+	if err := json.Unmarshal([]byte(body), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Greeting != "hello toby hello toby " {
+		t.Errorf("unexpected greeting %q", out.Greeting)
+	}
+
+	tests := map[string]int{
+		"/greet/toby?times=x": http.StatusBadRequest,
+		"/greet/nobody":       http.StatusNotFound,
+		"/greet/toby?times=4": http.StatusInternalServerError,
+	}
+	for route, expect := range tests {
+		if code, _ := doRequest(t, "GET", route, nil, c); code != expect {
+			t.Errorf("%s: expecting %d got %d", route, expect, code)
+		}
+	}
+
+	// the function is tested without a router.
+	out, err := greet(nil, greetInput{Name: "cherry", Times: 1})
+	if err != nil || out.Greeting != "hello cherry " {
+		t.Errorf("unexpected result %q %v", out.Greeting, err)
+	}
+}
@@ -0,0 +1,135 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthCheckTimeout bounds the time a single health check may take.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck reports whether a dependency of the application is healthy by
+// returning nil.
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// health holds the registered checks, it is shared by an application and all
+// of its groups.
+type health struct {
+	mu           sync.Mutex
+	liveness     []namedCheck
+	readiness    []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// CheckResult is the outcome of a single health check.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthReport is the JSON body returned by the health endpoints.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// AddLivenessCheck registers a check that tells whether the process is
+// running correctly. A failing liveness check usually means the process
+// should be restarted.
+func (c *Cherry) AddLivenessCheck(name string, check HealthCheck) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	c.health.liveness = append(c.health.liveness, namedCheck{name, check})
+}
+
+// AddReadinessCheck registers a check that tells whether the application
+// can serve traffic, like a reachable database.
+//
+// app.AddReadinessCheck("db", func(ctx context.Context) error { return db.PingContext(ctx) }).
+func (c *Cherry) AddReadinessCheck(name string, check HealthCheck) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	c.health.readiness = append(c.health.readiness, namedCheck{name, check})
+}
+
+// Health registers the health endpoints under path:
+//
+//	path/live   runs the liveness checks
+//	path/ready  runs the liveness and readiness checks
+//	path        same as path/ready
+//
+// The endpoints respond with 200 and a JSON HealthReport when all checks
+// pass and 503 otherwise. Once the server starts a graceful shutdown the
+// readiness endpoints report not ready, so load balancers stop sending
+// traffic.
+func (c *Cherry) Health(p string) {
+	ready := func(ctx *Context) error {
+		return c.health.serve(ctx, true)
+	}
+	c.Get(p, ready)
+	c.Get(path.Join(p, "ready"), ready)
+	c.Get(path.Join(p, "live"), func(ctx *Context) error {
+		return c.health.serve(ctx, false)
+	})
+}
+
+func (h *health) serve(ctx *Context, readiness bool) error {
+	h.mu.Lock()
+	checks := append([]namedCheck(nil), h.liveness...)
+	if readiness {
+		checks = append(checks, h.readiness...)
+	}
+	h.mu.Unlock()
+
+	report := HealthReport{Status: "ok", Checks: runChecks(ctx.Request().Context(), checks)}
+	for _, res := range report.Checks {
+		if res.Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	if readiness && h.shuttingDown.Load() {
+		report.Status = "shutting down"
+	}
+	code := http.StatusOK
+	if report.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	return ctx.JSON(code, report)
+}
+
+// runChecks runs the checks concurrently.
+func runChecks(ctx context.Context, checks []namedCheck) map[string]CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]CheckResult, len(checks))
+	)
+	for _, nc := range checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			res := CheckResult{Status: "ok"}
+			if err := nc.check(ctx); err != nil {
+				res = CheckResult{Status: "fail", Error: err.Error()}
+			}
+			mu.Lock()
+			results[nc.name] = res
+			mu.Unlock()
+		}(nc)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,85 @@
+package cherry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	c := New()
+	c.Health("/healthz")
+	c.AddLivenessCheck("loop", func(ctx context.Context) error { return nil })
+	c.AddReadinessCheck("db", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	code, body := doRequest(t, "GET", "/healthz/live", nil, c)
+	isHTTPStatusOK(t, code)
+	var report HealthReport
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != "ok" || len(report.Checks) != 1 {
+		t.Errorf("expecting a passing liveness report got %+v", report)
+	}
+
+	for _, route := range []string{"/healthz", "/healthz/ready"} {
+		code, body = doRequest(t, "GET", route, nil, c)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expecting status 503 got %d", route, code)
+		}
+		report = HealthReport{}
+		json.Unmarshal([]byte(body), &report)
+		if report.Checks["db"].Error != "connection refused" {
+			t.Errorf("%s: expecting db check to fail got %+v", route, report.Checks["db"])
+		}
+	}
+}
+
+func TestHealthShuttingDown(t *testing.T) {
+	c := New()
+	c.Health("/healthz")
+	code, _ := doRequest(t, "GET", "/healthz", nil, c)
+	isHTTPStatusOK(t, code)
+
+	c.health.shuttingDown.Store(true)
+	code, body := doRequest(t, "GET", "/healthz/ready", nil, c)
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "shutting down") {
+		t.Errorf("expecting not ready during shutdown got %d %s", code, body)
+	}
+	code, _ = doRequest(t, "GET", "/healthz/live", nil, c)
+	isHTTPStatusOK(t, code)
+}
+
+func TestDeregistrationDelay(t *testing.T) {
+	c := New()
+	c.Health("/healthz")
+	c.DeregistrationDelay = 100 * time.Millisecond
+	addr := serveTest(t, c)
+
+	done := make(chan struct{})
+	go func() {
+		c.Shutdown(context.Background())
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/healthz/ready", addr))
+	if err != nil {
+		t.Fatalf("expecting the server to keep serving during the delay got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expecting not ready during the delay got %d", resp.StatusCode)
+	}
+	if !resp.Close {
+		t.Error("expecting keep-alives to be disabled during the delay")
+	}
+	<-done
+}
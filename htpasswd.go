@@ -0,0 +1,220 @@
+package cherry
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdCheck is the interval at which an htpasswd file is checked for
+// changes.
+const htpasswdCheck = time.Second
+
+// HtpasswdAuth returns a middleware Handler that requires HTTP basic
+// authentication with the users of an htpasswd file, which is reloaded when
+// it changes. The passwords must be hashed with bcrypt (htpasswd -B) or
+// MD5-APR1 (htpasswd -m). It is meant to quickly protect a staging
+// environment or an internal dashboard.
+//
+// auth, err := cherry.HtpasswdAuth("/etc/app/.htpasswd").
+func HtpasswdAuth(path string) (Handler, error) {
+	h := &htpasswd{path: path}
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	return func(ctx *Context) error {
+		if err := h.reload(); err != nil && ctx.cherry != nil {
+			// the users of the previous version stay valid.
+			ctx.cherry.logError(ctx, err)
+		}
+		user, password, ok := ctx.Request().BasicAuth()
+		if ok && h.verify(user, password) {
+			return nil
+		}
+		ctx.Response().Header().Set("WWW-Authenticate", `Basic realm="Restricted", charset="UTF-8"`)
+		return NewHTTPError(http.StatusUnauthorized)
+	}, nil
+}
+
+// htpasswd holds the users of an htpasswd file.
+type htpasswd struct {
+	path string
+
+	mu      sync.RWMutex
+	users   map[string]string
+	modTime time.Time
+	size    int64
+	checked time.Time
+	// verified caches the hashes of the credentials that matched, bcrypt is
+	// slow on purpose and browsers send the credentials with every request.
+	verified map[[32]byte]string
+}
+
+func (h *htpasswd) load() error {
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return fmt.Errorf("cherry: loading %s: %w", h.path, err)
+	}
+	b, err := os.ReadFile(h.path)
+	if err != nil {
+		return fmt.Errorf("cherry: loading %s: %w", h.path, err)
+	}
+	users, err := parseHtpasswd(b)
+	if err != nil {
+		return fmt.Errorf("cherry: loading %s: %w", h.path, err)
+	}
+	h.mu.Lock()
+	h.users, h.modTime, h.size, h.checked = users, info.ModTime(), info.Size(), time.Now()
+	h.verified = map[[32]byte]string{}
+	h.mu.Unlock()
+	return nil
+}
+
+// reload loads the file again when it changed, it is checked at most once
+// per htpasswdCheck.
+func (h *htpasswd) reload() error {
+	h.mu.Lock()
+	if time.Since(h.checked) < htpasswdCheck {
+		h.mu.Unlock()
+		return nil
+	}
+	h.checked = time.Now()
+	modTime, size := h.modTime, h.size
+	h.mu.Unlock()
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return fmt.Errorf("cherry: loading %s: %w", h.path, err)
+	}
+	if info.ModTime().Equal(modTime) && info.Size() == size {
+		return nil
+	}
+	return h.load()
+}
+
+func (h *htpasswd) verify(user, password string) bool {
+	key := sha256.Sum256([]byte(user + "\x00" + password))
+	h.mu.RLock()
+	hash, ok := h.users[user]
+	cached := h.verified[key]
+	h.mu.RUnlock()
+	if !ok {
+		// the time of a bcrypt comparison is spent anyway, so unknown users
+		// cannot be told apart by the time of the response.
+		bcrypt.CompareHashAndPassword(dummyBcrypt, []byte(password))
+		return false
+	}
+	if cached != "" && subtle.ConstantTimeCompare([]byte(cached), []byte(hash)) == 1 {
+		return true
+	}
+	if !checkHtpasswd(hash, password) {
+		return false
+	}
+	h.mu.Lock()
+	if len(h.verified) >= 1000 {
+		h.verified = map[[32]byte]string{}
+	}
+	h.verified[key] = hash
+	h.mu.Unlock()
+	return true
+}
+
+// dummyBcrypt is the bcrypt hash compared for unknown users, with the
+// default cost of htpasswd.
+var dummyBcrypt = []byte("$2a$05$kFwWuHQszyFl6mnoZ9eR8ecqyYajDdCaB1HnLgz1o8B.T2CREo64S")
+
+// parseHtpasswd parses the user:hash lines of an htpasswd file.
+func parseHtpasswd(b []byte) (map[string]string, error) {
+	users := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("line %d: expecting user:hash", n)
+		}
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "$apr1$") {
+			return nil, fmt.Errorf("line %d: the password of %s is not hashed with bcrypt or MD5-APR1", n, user)
+		}
+		users[user] = hash
+	}
+	return users, s.Err()
+}
+
+func checkHtpasswd(hash, password string) bool {
+	if strings.HasPrefix(hash, "$apr1$") {
+		salt, _, _ := strings.Cut(hash[len("$apr1$"):], "$")
+		return subtle.ConstantTimeCompare([]byte(apr1(password, salt)), []byte(hash)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// apr1 hashes a password with the MD5 based algorithm of Apache.
+func apr1(password, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+	d := md5.New()
+	d.Write([]byte(password + magic + salt))
+	alt := md5.Sum([]byte(password + salt + password))
+	for i := len(pw); i > 0; i -= 16 {
+		d.Write(alt[:min(i, 16)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			d.Write([]byte{0})
+		} else {
+			d.Write(pw[:1])
+		}
+	}
+	final := d.Sum(nil)
+	for i := 0; i < 1000; i++ {
+		d := md5.New()
+		if i&1 != 0 {
+			d.Write(pw)
+		} else {
+			d.Write(final)
+		}
+		if i%3 != 0 {
+			d.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			d.Write(pw)
+		}
+		if i&1 != 0 {
+			d.Write(final)
+		} else {
+			d.Write(pw)
+		}
+		final = d.Sum(nil)
+	}
+
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	var b strings.Builder
+	b.WriteString(magic + salt + "$")
+	encode := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			b.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, i := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint32(final[i[0]])<<16|uint32(final[i[1]])<<8|uint32(final[i[2]]), 4)
+	}
+	encode(uint32(final[11]), 2)
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package cherry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestApr1(t *testing.T) {
+	if h := apr1("myPassword", "r31....."); h != "$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/" {
+		t.Errorf("expecting the hash of Apache got %s", h)
+	}
+	if !checkHtpasswd("$apr1$salt$VEpBc9VHGUKwI9.yg13Iu0", "secret") || checkHtpasswd("$apr1$salt$VEpBc9VHGUKwI9.yg13Iu0", "Secret") {
+		t.Error("expecting only the right password to match")
+	}
+}
+
+func TestHtpasswdAuth(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	content := "# staging\nann:" + string(hash) + "\nbob:$apr1$salt$VEpBc9VHGUKwI9.yg13Iu0\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := HtpasswdAuth(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Output = io.Discard
+	staging := c.Group("/staging")
+	staging.Use(auth)
+	staging.Get("/dashboard", noopHandler)
+
+	tests := []struct {
+		user, password string
+		code           int
+	}{
+		{"ann", "s3cret", http.StatusOK},
+		{"ann", "s3cret", http.StatusOK},
+		{"bob", "secret", http.StatusOK},
+		{"ann", "secret", http.StatusUnauthorized},
+		{"eve", "s3cret", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/staging/dashboard", nil)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.password)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code {
+			t.Errorf("expecting %d for %s:%s got %d", test.code, test.user, test.password, rw.Code)
+		}
+		if rw.Code == http.StatusUnauthorized && rw.Header().Get("WWW-Authenticate") == "" {
+			t.Error("expecting a basic authentication challenge")
+		}
+	}
+}
+
+func TestHtpasswdReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	os.WriteFile(path, []byte("bob:$apr1$salt$VEpBc9VHGUKwI9.yg13Iu0\n"), 0o600)
+	h := &htpasswd{path: path}
+	if err := h.load(); err != nil {
+		t.Fatal(err)
+	}
+	if !h.verify("bob", "secret") {
+		t.Fatal("expecting bob to be allowed")
+	}
+
+	os.WriteFile(path, []byte("ann:$apr1$salt$VEpBc9VHGUKwI9.yg13Iu0\n"), 0o600)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	if err := h.reload(); err != nil || !h.verify("bob", "secret") {
+		t.Errorf("expecting the file to be checked at most once a second got %v", err)
+	}
+	h.checked = time.Time{}
+	if err := h.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if h.verify("bob", "secret") || !h.verify("ann", "secret") {
+		t.Error("expecting the users of the new file")
+	}
+
+	os.WriteFile(path, []byte("carl:plain\n"), 0o600)
+	os.Chtimes(path, future.Add(time.Minute), future.Add(time.Minute))
+	h.checked = time.Time{}
+	if err := h.reload(); err == nil || !h.verify("ann", "secret") {
+		t.Errorf("expecting an invalid file to keep the previous users got %v", err)
+	}
+
+	if _, err := HtpasswdAuth(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expecting an error for a missing file")
+	}
+}
@@ -0,0 +1,341 @@
+// Package i18n translates the messages of a Cherry application with
+// catalogs in JSON or TOML, one per language, with plural forms.
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	bundle := i18n.New(language.English)
+//	if err := bundle.LoadFS(locales, "locales"); err != nil {
+//		log.Fatal(err)
+//	}
+//	app.Use(bundle.Middleware())
+//	app.Get("/checkout", func(ctx *cherry.Context) error {
+//		return ctx.Text(http.StatusOK, ctx.T("cart.items", "count", 3))
+//	})
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pooulad/cherry"
+	"golang.org/x/text/language"
+)
+
+// Message is a translated message. Messages without plural forms only have
+// Other. The forms are the CLDR plural categories.
+type Message struct {
+	Zero  string
+	One   string
+	Two   string
+	Few   string
+	Many  string
+	Other string
+}
+
+// Bundle holds the catalogs of all languages.
+type Bundle struct {
+	fallback language.Tag
+
+	mu       sync.RWMutex
+	catalogs map[language.Tag]map[string]Message
+	matcher  language.Matcher
+}
+
+// New returns an empty Bundle. Messages that are missing from the catalog
+// of a language are taken from the catalog of fallback.
+func New(fallback language.Tag) *Bundle {
+	return &Bundle{
+		fallback: fallback,
+		catalogs: map[language.Tag]map[string]Message{},
+	}
+}
+
+// Fallback returns the fallback language of the bundle.
+func (b *Bundle) Fallback() language.Tag {
+	return b.fallback
+}
+
+// Languages returns the languages of the catalogs, the fallback first.
+func (b *Bundle) Languages() []language.Tag {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.languages()
+}
+
+func (b *Bundle) languages() []language.Tag {
+	tags := []language.Tag{b.fallback}
+	for tag := range b.catalogs {
+		if tag != b.fallback {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags[1:], func(i, j int) bool { return tags[i+1].String() < tags[j+1].String() })
+	return tags
+}
+
+// AddMessages adds messages to the catalog of a language, replacing the
+// messages with the same keys.
+func (b *Bundle) AddMessages(tag language.Tag, messages map[string]Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog, ok := b.catalogs[tag]
+	if !ok {
+		catalog = map[string]Message{}
+		b.catalogs[tag] = catalog
+	}
+	for k, m := range messages {
+		catalog[k] = m
+	}
+	b.matcher = language.NewMatcher(b.languages())
+}
+
+// Parse adds the messages of a catalog in JSON or TOML to the catalog of a
+// language. Nested objects are flattened into keys joined by dots, objects
+// whose keys are all plural categories are the forms of a message.
+//
+//	{"cart": {"title": "Cart", "items": {"one": "{count} item", "other": "{count} items"}}}
+func (b *Bundle) Parse(tag language.Tag, data []byte, format string) error {
+	var raw map[string]any
+	var err error
+	switch strings.TrimPrefix(strings.ToLower(format), ".") {
+	case "json":
+		err = json.Unmarshal(data, &raw)
+	case "toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("i18n: unsupported catalog format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("i18n: %s catalog: %w", tag, err)
+	}
+	messages := map[string]Message{}
+	if err := flatten("", raw, messages); err != nil {
+		return fmt.Errorf("i18n: %s catalog: %w", tag, err)
+	}
+	b.AddMessages(tag, messages)
+	return nil
+}
+
+// LoadFile adds a catalog file. The language is the name of the file
+// without extension, like pt-BR.toml, or its last part, like
+// messages.fr.json.
+func (b *Bundle) LoadFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return b.parseFile(filepath.Base(name), data)
+}
+
+// LoadFS adds all of the catalog files in the directory dir of fsys, like
+// an embed.FS.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := b.parseFile(e.Name(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bundle) parseFile(name string, data []byte) error {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if i := strings.LastIndexByte(base, '.'); i >= 0 {
+		base = base[i+1:]
+	}
+	tag, err := language.Parse(base)
+	if err != nil {
+		return fmt.Errorf("i18n: no language in the name of %s: %w", name, err)
+	}
+	return b.Parse(tag, data, ext)
+}
+
+// Match returns the language of the bundle that matches the preferred
+// languages best, or the fallback.
+func (b *Bundle) Match(preferred ...language.Tag) language.Tag {
+	b.mu.RLock()
+	matcher, languages := b.matcher, b.languages()
+	b.mu.RUnlock()
+	if matcher == nil || len(preferred) == 0 {
+		return b.fallback
+	}
+	_, i, conf := matcher.Match(preferred...)
+	if conf == language.No {
+		return b.fallback
+	}
+	return languages[i]
+}
+
+// Localizer returns a Localizer for the language that matches the preferred
+// languages best.
+func (b *Bundle) Localizer(preferred ...language.Tag) *Localizer {
+	return &Localizer{bundle: b, tag: b.Match(preferred...)}
+}
+
+// Middleware returns a middleware Handler that resolves the language of the
+// request with ctx.Locale and stores a Localizer for it, which ctx.T uses.
+func (b *Bundle) Middleware() cherry.Handler {
+	return func(ctx *cherry.Context) error {
+		l := &Localizer{bundle: b, tag: ctx.Locale(b.Languages()...)}
+		cherry.WithValue(ctx, l)
+		cherry.WithValue[cherry.Translator](ctx, l)
+		return nil
+	}
+}
+
+// FromContext returns the Localizer stored by the middleware, or a
+// Localizer for the language of ctx.Locale.
+func (b *Bundle) FromContext(ctx *cherry.Context) *Localizer {
+	if l, ok := cherry.Value[*Localizer](ctx); ok {
+		return l
+	}
+	return b.Localizer(ctx.Locale())
+}
+
+// Localizer translates messages to a single language.
+type Localizer struct {
+	bundle *Bundle
+	tag    language.Tag
+}
+
+// Language returns the language of the Localizer.
+func (l *Localizer) Language() language.Tag {
+	return l.tag
+}
+
+// Translate returns the message key in the language of the Localizer, or in
+// the fallback language when it is missing. The args are pairs of names and
+// values, or a single map[string]any, that fill the {name} placeholders. A
+// "count" arg selects the plural form. Unknown keys are returned as is.
+func (l *Localizer) Translate(key string, args ...any) string {
+	m, tag, ok := l.bundle.lookup(l.tag, key)
+	if !ok {
+		return cherry.Interpolate(key, args...)
+	}
+	text := m.Other
+	if len(args) > 0 {
+		if count, ok := cherry.Args(args...)["count"]; ok {
+			text = m.form(pluralForm(tag, count))
+		}
+	}
+	return cherry.Interpolate(text, args...)
+}
+
+// lookup finds the message in the catalog of tag, of its parent languages
+// and then of the fallback. It returns the language the message was found
+// in, whose plural rules apply.
+func (b *Bundle) lookup(tag language.Tag, key string) (Message, language.Tag, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for t := tag; ; t = t.Parent() {
+		if m, ok := b.catalogs[t][key]; ok {
+			return m, t, true
+		}
+		if t == language.Und {
+			break
+		}
+	}
+	m, ok := b.catalogs[b.fallback][key]
+	return m, b.fallback, ok
+}
+
+// form returns the text of a plural form, or Other when the message does
+// not have it.
+func (m Message) form(form string) string {
+	var s string
+	switch form {
+	case "zero":
+		s = m.Zero
+	case "one":
+		s = m.One
+	case "two":
+		s = m.Two
+	case "few":
+		s = m.Few
+	case "many":
+		s = m.Many
+	}
+	if s == "" {
+		return m.Other
+	}
+	return s
+}
+
+// flatten adds the messages of a parsed catalog to messages.
+func flatten(prefix string, raw map[string]any, messages map[string]Message) error {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			messages[key] = Message{Other: v}
+		case map[string]any:
+			if m, ok := pluralMessage(v); ok {
+				messages[key] = m
+				continue
+			}
+			if err := flatten(key, v, messages); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q is a %T, not a string or an object", key, v)
+		}
+	}
+	return nil
+}
+
+// pluralMessage returns the Message of an object whose keys are all plural
+// categories.
+func pluralMessage(raw map[string]any) (Message, bool) {
+	var m Message
+	if len(raw) == 0 {
+		return m, false
+	}
+	for k, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			return m, false
+		}
+		switch k {
+		case "zero":
+			m.Zero = s
+		case "one":
+			m.One = s
+		case "two":
+			m.Two = s
+		case "few":
+			m.Few = s
+		case "many":
+			m.Many = s
+		case "other":
+			m.Other = s
+		default:
+			return m, false
+		}
+	}
+	return m, true
+}
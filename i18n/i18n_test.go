@@ -0,0 +1,144 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pooulad/cherry"
+	"golang.org/x/text/language"
+)
+
+var locales = fstest.MapFS{
+	"locales/en.json": {Data: []byte(`{
+		"greeting": "Hello {name}",
+		"checkout": {"title": "Checkout"},
+		"cart": {"items": {"one": "{count} item", "other": "{count} items"}}
+	}`)},
+	"locales/fr.toml": {Data: []byte(`
+greeting = "Bonjour {name}"
+
+[cart.items]
+one = "{count} article"
+other = "{count} articles"
+`)},
+	"locales/messages.ru.json": {Data: []byte(`{
+		"cart": {"items": {"one": "{count} товар", "few": "{count} товара", "many": "{count} товаров", "other": "{count} товара"}}
+	}`)},
+	"locales/README.md": {Data: []byte("not a catalog")},
+}
+
+func newBundle(t *testing.T) *Bundle {
+	b := New(language.English)
+	if err := b.LoadFS(locales, "locales"); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTranslate(t *testing.T) {
+	b := newBundle(t)
+	tests := []struct {
+		lang   language.Tag
+		key    string
+		args   []any
+		expect string
+	}{
+		{language.English, "greeting", []any{"name", "Ada"}, "Hello Ada"},
+		{language.French, "greeting", []any{map[string]any{"name": "Ada"}}, "Bonjour Ada"},
+		{language.French, "checkout.title", nil, "Checkout"},
+		{language.English, "cart.items", []any{"count", 1}, "1 item"},
+		{language.English, "cart.items", []any{"count", 2}, "2 items"},
+		{language.English, "cart.items", []any{"count", 1.5}, "1.5 items"},
+		{language.French, "cart.items", []any{"count", 0}, "0 article"},
+		{language.French, "cart.items", []any{"count", 1.5}, "1.5 article"},
+		{language.Russian, "cart.items", []any{"count", 1}, "1 товар"},
+		{language.Russian, "cart.items", []any{"count", 3}, "3 товара"},
+		{language.Russian, "cart.items", []any{"count", 5}, "5 товаров"},
+		{language.Russian, "cart.items", []any{"count", 21}, "21 товар"},
+		{language.English, "missing.{name}", []any{"name", "key"}, "missing.key"},
+	}
+	for _, test := range tests {
+		if got := b.Localizer(test.lang).Translate(test.key, test.args...); got != test.expect {
+			t.Errorf("%s %s %v: expecting %q got %q", test.lang, test.key, test.args, test.expect, got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := newBundle(t)
+	tests := []struct {
+		preferred []language.Tag
+		expect    language.Tag
+	}{
+		{nil, language.English},
+		{[]language.Tag{language.MustParse("fr-CA")}, language.French},
+		{[]language.Tag{language.Japanese}, language.English},
+		{[]language.Tag{language.Japanese, language.Russian}, language.Russian},
+	}
+	for _, test := range tests {
+		if got := b.Match(test.preferred...); got != test.expect {
+			t.Errorf("%v: expecting %s got %s", test.preferred, test.expect, got)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	b := newBundle(t)
+	app := cherry.New()
+	app.Use(b.Middleware())
+	app.Get("/", func(ctx *cherry.Context) error {
+		return ctx.Text(http.StatusOK, ctx.T("cart.items", "count", 2))
+	})
+
+	tests := []struct {
+		target string
+		cookie string
+		accept string
+		expect string
+	}{
+		{"/", "", "", "2 items"},
+		{"/", "", "fr-FR,fr;q=0.9,en;q=0.8", "2 articles"},
+		{"/", "", "de;q=0.9, ru;q=0.5", "2 товара"},
+		{"/", "ru", "fr", "2 товара"},
+		{"/?lang=en", "ru", "fr", "2 items"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			r.Header.Set("Accept-Language", test.accept)
+		}
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "lang", Value: test.cookie})
+		}
+		rw := httptest.NewRecorder()
+		app.ServeHTTP(rw, r)
+		if rw.Body.String() != test.expect {
+			t.Errorf("%s %q %q: expecting %q got %q", test.target, test.cookie, test.accept, test.expect, rw.Body.String())
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "pt-BR.json")
+	os.WriteFile(name, []byte(`{"greeting": "Olá {name}"}`), 0o644)
+	b := New(language.English)
+	if err := b.LoadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Localizer(language.MustParse("pt-BR")).Translate("greeting", "name", "Ada"); got != "Olá Ada" {
+		t.Errorf("expecting Olá Ada got %q", got)
+	}
+
+	os.WriteFile(name, []byte(`{"greeting": 1}`), 0o644)
+	if err := b.LoadFile(name); err == nil {
+		t.Error("expecting an error for a message that is not a string")
+	}
+	if err := b.LoadFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expecting an error for a missing file")
+	}
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// pluralForm returns the CLDR plural category of count in the language. A
+// count that is not a number is "other".
+func pluralForm(tag language.Tag, count any) string {
+	var s string
+	switch n := count.(type) {
+	case int:
+		s = strconv.Itoa(n)
+	case int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s = strconv.FormatInt(toInt64(n), 10)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'f', -1, 32)
+	case float64:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	case string:
+		if _, err := strconv.ParseFloat(n, 64); err != nil {
+			return "other"
+		}
+		s = n
+	default:
+		return "other"
+	}
+	i, v, w, f, t := operands(s)
+	switch plural.Cardinal.MatchPlural(tag, i, v, w, f, t) {
+	case plural.Zero:
+		return "zero"
+	case plural.One:
+		return "one"
+	case plural.Two:
+		return "two"
+	case plural.Few:
+		return "few"
+	case plural.Many:
+		return "many"
+	}
+	return "other"
+}
+
+// operands returns the CLDR plural operands of a decimal number: the
+// integer digits i, the number of visible fraction digits v, without
+// trailing zeros w, and the fraction digits f, without trailing zeros t.
+func operands(s string) (i, v, w, f, t int) {
+	s = strings.TrimPrefix(s, "-")
+	ip, fp, _ := strings.Cut(s, ".")
+	i = atoi(ip)
+	v = len(fp)
+	f = atoi(fp)
+	tp := strings.TrimRight(fp, "0")
+	w = len(tp)
+	t = atoi(tp)
+	return
+}
+
+// atoi parses digits, values that overflow are capped, which keeps their
+// plural category in all languages.
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil && s != "" {
+		return math.MaxInt32
+	}
+	return n
+}
+
+func toInt64(n any) int64 {
+	switch n := n.(type) {
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case uint:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,261 @@
+// Package jsonrpc serves JSON-RPC 2.0 methods on a Cherry route.
+//
+//	d := jsonrpc.New()
+//	jsonrpc.Register(d, "add", func(ctx *cherry.Context, p [2]int) (int, error) {
+//		return p[0] + p[1], nil
+//	})
+//	app.Post("/rpc", d.Handler())
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/pooulad/cherry"
+)
+
+// The error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeServerError is used for the HTTPErrors returned by methods.
+	CodeServerError = -32000
+)
+
+// maxBodySize limits the size of a request.
+const maxBodySize = 4 << 20
+
+// Error is a JSON-RPC error. Methods return an *Error to choose the code
+// the client receives.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// NewError returns a new Error.
+func NewError(code int, message string, data ...any) *Error {
+	e := &Error{Code: code, Message: message}
+	if len(data) > 0 {
+		e.Data = data[0]
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+// Method is a registered method, which is called with the raw params of
+// the request.
+type Method func(ctx *cherry.Context, params json.RawMessage) (any, error)
+
+// Dispatcher routes the requests to the registered methods. It is safe for
+// concurrent use.
+type Dispatcher struct {
+	mu      sync.RWMutex
+	methods map[string]Method
+
+	// MapError maps the errors returned by methods to the error sent to the
+	// client. The default keeps an *Error, maps a *cherry.HTTPError to
+	// CodeServerError with the status in the data and any other error to
+	// CodeInternalError, whose message is only sent in debug mode.
+	MapError func(err error) *Error
+}
+
+// New returns an empty Dispatcher.
+func New() *Dispatcher {
+	return &Dispatcher{methods: map[string]Method{}, MapError: mapError}
+}
+
+// Handle registers m under name, replacing a method of the same name.
+func (d *Dispatcher) Handle(name string, m Method) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.methods[name] = m
+}
+
+// Register registers fn under name. The params of the request are bound
+// into a P, by name from an object or by position from an array. An array
+// is bound to the exported fields of a struct P in order.
+func Register[P, R any](d *Dispatcher, name string, fn func(ctx *cherry.Context, params P) (R, error)) {
+	d.Handle(name, func(ctx *cherry.Context, raw json.RawMessage) (any, error) {
+		var p P
+		if err := bindParams(raw, &p); err != nil {
+			return nil, NewError(CodeInvalidParams, "Invalid params", err.Error())
+		}
+		return fn(ctx, p)
+	})
+}
+
+func (d *Dispatcher) method(name string) (Method, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	m, ok := d.methods[name]
+	return m, ok
+}
+
+// Handler returns the cherry.Handler that serves the dispatcher, usually
+// registered with Post. Single and batch requests are supported.
+// Notifications, requests without an id, get no response. Errors that
+// prevent a JSON-RPC response from being written go through the error
+// handler of the application.
+func (d *Dispatcher) Handler() cherry.Handler {
+	return func(ctx *cherry.Context) error {
+		body, err := io.ReadAll(io.LimitReader(ctx.Request().Body, maxBodySize+1))
+		if err != nil {
+			return err
+		}
+		if len(body) > maxBodySize {
+			return cherry.NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			return d.serveBatch(ctx, body)
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return write(ctx, errorResponse(nil, NewError(CodeParseError, "Parse error")))
+		}
+		resp := d.call(ctx, &req)
+		if resp == nil {
+			ctx.Response().WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return write(ctx, resp)
+	}
+}
+
+func (d *Dispatcher) serveBatch(ctx *cherry.Context, body []byte) error {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return write(ctx, errorResponse(nil, NewError(CodeParseError, "Parse error")))
+	}
+	if len(batch) == 0 {
+		return write(ctx, errorResponse(nil, NewError(CodeInvalidRequest, "Invalid Request")))
+	}
+	responses := make([]*response, 0, len(batch))
+	for _, raw := range batch {
+		var req request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			responses = append(responses, errorResponse(nil, NewError(CodeInvalidRequest, "Invalid Request")))
+			continue
+		}
+		if resp := d.call(ctx, &req); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		ctx.Response().WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return write(ctx, responses)
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// call runs the method of req and returns its response, which is nil for a
+// notification.
+func (d *Dispatcher) call(ctx *cherry.Context, req *request) *response {
+	notification := req.ID == nil
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, NewError(CodeInvalidRequest, "Invalid Request"))
+	}
+	m, ok := d.method(req.Method)
+	if !ok {
+		if notification {
+			return nil
+		}
+		return errorResponse(req.ID, NewError(CodeMethodNotFound, "Method not found"))
+	}
+	result, err := m(ctx, req.Params)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, d.MapError(err))
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", Error: err, ID: id}
+}
+
+func write(ctx *cherry.Context, v any) error {
+	return ctx.JSON(http.StatusOK, v)
+}
+
+func mapError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var he *cherry.HTTPError
+	if errors.As(err, &he) {
+		return NewError(CodeServerError, he.Message, map[string]int{"status": he.Code})
+	}
+	if cherry.IsDebug() {
+		return NewError(CodeInternalError, err.Error())
+	}
+	return NewError(CodeInternalError, "Internal error")
+}
+
+// bindParams decodes raw into p. Positional params are bound to the fields
+// of a struct in order.
+func bindParams(raw json.RawMessage, p any) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	v := reflect.ValueOf(p).Elem()
+	if raw[0] != '[' || v.Kind() != reflect.Struct {
+		return json.Unmarshal(raw, p)
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+	var fields []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() {
+			fields = append(fields, v.Field(i))
+		}
+	}
+	if len(values) > len(fields) {
+		return fmt.Errorf("expecting at most %d params got %d", len(fields), len(values))
+	}
+	for i, value := range values {
+		if err := json.Unmarshal(value, fields[i].Addr().Interface()); err != nil {
+			return fmt.Errorf("param %d: %w", i, err)
+		}
+	}
+	return nil
+}
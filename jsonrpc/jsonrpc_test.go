@@ -0,0 +1,123 @@
+package jsonrpc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pooulad/cherry"
+)
+
+type transfer struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int    `json:"amount"`
+}
+
+func newApp() *cherry.Cherry {
+	d := New()
+	Register(d, "add", func(ctx *cherry.Context, p [2]int) (int, error) {
+		return p[0] + p[1], nil
+	})
+	Register(d, "transfer", func(ctx *cherry.Context, p transfer) (string, error) {
+		if p.Amount <= 0 {
+			return "", NewError(CodeInvalidParams, "amount must be positive")
+		}
+		return p.From + "->" + p.To, nil
+	})
+	Register(d, "forbidden", func(ctx *cherry.Context, p any) (any, error) {
+		return nil, cherry.NewHTTPError(http.StatusForbidden)
+	})
+	Register(d, "fail", func(ctx *cherry.Context, p any) (any, error) {
+		return nil, errors.New("database is down")
+	})
+	app := cherry.New()
+	app.Post("/rpc", d.Handler())
+	return app
+}
+
+func call(t *testing.T, app *cherry.Cherry, body string) (int, string) {
+	r, _ := http.NewRequest("POST", "/rpc", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	app.ServeHTTP(rw, r)
+	return rw.Code, strings.TrimSpace(rw.Body.String())
+}
+
+func TestDispatcher(t *testing.T) {
+	app := newApp()
+	tests := []struct {
+		body   string
+		expect string
+	}{
+		{`{"jsonrpc":"2.0","method":"add","params":[1,2],"id":1}`,
+			`{"jsonrpc":"2.0","result":3,"id":1}`},
+		{`{"jsonrpc":"2.0","method":"transfer","params":{"from":"a","to":"b","amount":5},"id":"x"}`,
+			`{"jsonrpc":"2.0","result":"a-\u003eb","id":"x"}`},
+		{`{"jsonrpc":"2.0","method":"transfer","params":["a","b",5],"id":2}`,
+			`{"jsonrpc":"2.0","result":"a-\u003eb","id":2}`},
+		{`{"jsonrpc":"2.0","method":"transfer","params":["a","b",0],"id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"amount must be positive"},"id":3}`},
+		{`{"jsonrpc":"2.0","method":"add","params":{"a":1},"id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"json: cannot unmarshal object into Go value of type [2]int"},"id":4}`},
+		{`{"jsonrpc":"2.0","method":"missing","id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":5}`},
+		{`{"jsonrpc":"2.0","method":"forbidden","id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"Forbidden","data":{"status":403}},"id":6}`},
+		{`{"jsonrpc":"2.0","method":"fail","id":7}`,
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"database is down"},"id":7}`},
+		{`{"method":"add","id":8}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":8}`},
+		{`{"jsonrpc":"2.0",`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{`[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+	}
+	for _, test := range tests {
+		code, body := call(t, app, test.body)
+		if code != http.StatusOK {
+			t.Errorf("%s: expecting 200 got %d", test.body, code)
+		}
+		if body != test.expect {
+			t.Errorf("%s:\nexpecting %s\ngot       %s", test.body, test.expect, body)
+		}
+	}
+}
+
+func TestBatchAndNotifications(t *testing.T) {
+	app := newApp()
+	code, body := call(t, app, `[
+		{"jsonrpc":"2.0","method":"add","params":[1,2],"id":1},
+		{"jsonrpc":"2.0","method":"add","params":[3,4]},
+		1,
+		{"jsonrpc":"2.0","method":"add","params":[5,6],"id":2}
+	]`)
+	if code != http.StatusOK {
+		t.Fatalf("expecting 200 got %d", code)
+	}
+	expect := `[{"jsonrpc":"2.0","result":3,"id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},{"jsonrpc":"2.0","result":11,"id":2}]`
+	if body != expect {
+		t.Errorf("expecting %s got %s", expect, body)
+	}
+
+	code, body = call(t, app, `{"jsonrpc":"2.0","method":"add","params":[1,2]}`)
+	if code != http.StatusNoContent || body != "" {
+		t.Errorf("expecting no response for a notification got %d %s", code, body)
+	}
+	code, _ = call(t, app, `[{"jsonrpc":"2.0","method":"add","params":[1,2]}]`)
+	if code != http.StatusNoContent {
+		t.Errorf("expecting no response for a batch of notifications got %d", code)
+	}
+}
+
+func TestReleaseModeHidesErrors(t *testing.T) {
+	cherry.SetMode(cherry.ReleaseMode)
+	defer cherry.SetMode(cherry.DebugMode)
+	_, body := call(t, newApp(), `{"jsonrpc":"2.0","method":"fail","id":1}`)
+	expect := `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`
+	if body != expect {
+		t.Errorf("expecting %s got %s", expect, body)
+	}
+}
@@ -0,0 +1,250 @@
+package cherry
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Handler is the name of the handler, like main.listUsers.
+	Handler string `json:"handler"`
+	// Middleware is the number of middleware that run before the handler,
+	// routes of Handle have none.
+	Middleware int `json:"middleware"`
+	// Name is the name of the route, see Route.Name.
+	Name string `json:"name,omitempty"`
+	// Meta and Tags are the metadata of the route, see Route.Meta.
+	Meta map[string]any `json:"meta,omitempty"`
+	Tags []string       `json:"tags,omitempty"`
+	// Request and Response are the types of the documented models of the
+	// route, see Route.Request.
+	Request  reflect.Type `json:"-"`
+	Response reflect.Type `json:"-"`
+}
+
+// registeredRoute is a route with the application or group it was
+// registered on, which holds its middleware.
+type registeredRoute struct {
+	RouteInfo
+	app *Cherry
+}
+
+// info returns the route with the middleware that are registered by now,
+// which includes the middleware added after the route.
+func (r registeredRoute) info() RouteInfo {
+	info := r.RouteInfo
+	if r.app != nil {
+		info.Middleware = len(r.app.middleware)
+	}
+	return info
+}
+
+// hooks holds the lifecycle hooks of an application, it is shared by the
+// application and all of its groups.
+type hooks struct {
+	mu       sync.Mutex
+	start    []func(net.Addr)
+	stop     []func()
+	shutdown []func(context.Context)
+	route    []func(RouteInfo)
+	routes   []registeredRoute
+	errors   []func(*Context, error, []byte)
+
+	stopOnce     sync.Once
+	shutdownOnce sync.Once
+}
+
+// OnStart registers fn to run each time a server starts listening, with the
+// address the listener is bound to.
+func (c *Cherry) OnStart(fn func(addr net.Addr)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.start = append(c.hooks.start, fn)
+}
+
+// OnListen is the same as OnStart, it registers fn to run once a listener is
+// bound, which is the moment the address of a server on port 0 is known.
+func (c *Cherry) OnListen(fn func(addr net.Addr)) {
+	c.OnStart(fn)
+}
+
+// OnStop registers fn to run once when a shutdown begins, before the
+// servers stop accepting connections.
+func (c *Cherry) OnStop(fn func()) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.stop = append(c.hooks.stop, fn)
+}
+
+// OnShutdown registers fn to run after the servers are shut down, to release
+// resources like database pools or to flush queues. The hooks run once, in
+// the order they were registered, with the context of the shutdown.
+func (c *Cherry) OnShutdown(fn func(ctx context.Context)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.shutdown = append(c.hooks.shutdown, fn)
+}
+
+// OnRouteRegistered registers fn to run for every route of the application.
+// Routes registered before the hook are replayed, so the order of
+// registration does not matter.
+func (c *Cherry) OnRouteRegistered(fn func(RouteInfo)) {
+	c.hooks.mu.Lock()
+	c.hooks.route = append(c.hooks.route, fn)
+	c.hooks.mu.Unlock()
+	for _, r := range c.Routes() {
+		fn(r)
+	}
+}
+
+// OnError registers fn to run for the errors returned by middleware and
+// handlers and for the panics they recover from, before the error handler
+// responds. The stack is the stack of a panic and nil for errors. It wires
+// error reporting, like Sentry, once for the whole application.
+//
+//	app.OnError(func(ctx *cherry.Context, err error, stack []byte) {
+//		sentry.CaptureException(err)
+//	})
+func (c *Cherry) OnError(fn func(ctx *Context, err error, stack []byte)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.errors = append(c.hooks.errors, fn)
+}
+
+// Routes returns the registered routes in the order they were registered.
+func (c *Cherry) Routes() []RouteInfo {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.routes.mu.RLock()
+	defer c.routes.mu.RUnlock()
+	routes := make([]RouteInfo, len(c.hooks.routes))
+	for i, r := range c.hooks.routes {
+		routes[i] = r.info()
+		if m := c.routes.meta[routeKey{r.Method, r.Path}]; m != nil {
+			m.apply(&routes[i])
+		}
+	}
+	return routes
+}
+
+// Param is a url parameter of a route.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the url parameters of a route, in the order of the pattern.
+type Params []Param
+
+// ByName returns the value of the parameter name, or "".
+func (ps Params) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Lookup returns the route that serves a request with the method and path,
+// and its url parameters, without calling its handler. The last result is
+// false when no route matches.
+//
+// route, params, ok := app.Lookup("GET", "/users/7").
+func (c *Cherry) Lookup(method, path string) (RouteInfo, Params, bool) {
+	handle, ps, _ := c.router.Lookup(method, path)
+	if handle == nil {
+		return RouteInfo{}, nil, false
+	}
+	var params Params
+	for _, p := range ps {
+		params = append(params, Param{Key: p.Key, Value: p.Value})
+	}
+	for _, r := range c.Routes() {
+		if r.Method == method && matchPattern(r.Path, path) {
+			return r, params, true
+		}
+	}
+	return RouteInfo{Method: method}, params, true
+}
+
+// matchPattern reports whether the path matches the route pattern.
+func matchPattern(pattern, p string) bool {
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, "*") {
+			return true
+		}
+		if i >= len(parts) {
+			return false
+		}
+		if strings.HasPrefix(s, ":") {
+			if parts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if s != parts[i] {
+			return false
+		}
+	}
+	return len(parts) == len(segments)
+}
+
+func (h *hooks) routeRegistered(app *Cherry, r RouteInfo) {
+	route := registeredRoute{RouteInfo: r, app: app}
+	h.mu.Lock()
+	h.routes = append(h.routes, route)
+	fns := append([]func(RouteInfo){}, h.route...)
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn(route.info())
+	}
+}
+
+func (h *hooks) runStart(addr net.Addr) {
+	h.mu.Lock()
+	fns := append([]func(net.Addr){}, h.start...)
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn(addr)
+	}
+}
+
+func (h *hooks) runStop() {
+	h.stopOnce.Do(func() {
+		h.mu.Lock()
+		fns := append([]func(){}, h.stop...)
+		h.mu.Unlock()
+		for _, fn := range fns {
+			fn()
+		}
+	})
+}
+
+func (h *hooks) runShutdown(ctx context.Context) {
+	h.shutdownOnce.Do(func() {
+		h.mu.Lock()
+		fns := append([]func(context.Context){}, h.shutdown...)
+		h.mu.Unlock()
+		for _, fn := range fns {
+			fn(ctx)
+		}
+	})
+}
+
+func (h *hooks) runError(ctx *Context, err error, stack []byte) {
+	h.mu.Lock()
+	fns := h.errors
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn(ctx, err, stack)
+	}
+}
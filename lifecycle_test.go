@@ -0,0 +1,117 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	c := New()
+	c.Get("/", noopHandler)
+
+	var (
+		events []string
+		routes []RouteInfo
+	)
+	c.OnRouteRegistered(func(r RouteInfo) { routes = append(routes, r) })
+	c.Group("/api").Post("/users", noopHandler)
+
+	started := make(chan net.Addr, 1)
+	c.OnStart(func(addr net.Addr) { started <- addr })
+	c.OnStop(func() { events = append(events, "stop") })
+	c.OnShutdown(func(ctx context.Context) { events = append(events, "shutdown") })
+
+	expect := []RouteInfo{
+		{Method: "GET", Path: "/", Handler: funcName(noopHandler)},
+		{Method: "POST", Path: "/api/users", Handler: funcName(noopHandler)},
+	}
+	if !reflect.DeepEqual(routes, expect) {
+		t.Errorf("expecting routes %v got %v", expect, routes)
+	}
+	if !reflect.DeepEqual(c.Routes(), expect) {
+		t.Errorf("expecting route table %v got %v", expect, c.Routes())
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	addr := <-started
+	if addr.String() != waitForServer(t, c) {
+		t.Errorf("expecting OnStart with the bound address got %s", addr)
+	}
+	c.Shutdown(context.Background())
+	<-errc
+	if !reflect.DeepEqual(events, []string{"stop", "shutdown"}) {
+		t.Errorf("expecting stop and shutdown events got %v", events)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	c := New()
+	var reported []string
+	c.OnError(func(ctx *Context, err error, stack []byte) {
+		if stack != nil {
+			t.Error("expecting no stack for an error")
+		}
+		reported = append(reported, ctx.FullPath()+" "+err.Error())
+	})
+	c.Use(func(ctx *Context) error {
+		if ctx.Query("token") == "" {
+			return NewHTTPError(http.StatusUnauthorized)
+		}
+		return nil
+	})
+	c.Get("/users/:id", func(ctx *Context) error {
+		return errors.New("database is down")
+	})
+	c.Get("/ok", noopHandler)
+
+	doRequest(t, "GET", "/users/1", nil, c)
+	code, _ := doRequest(t, "GET", "/users/1?token=1", nil, c)
+	doRequest(t, "GET", "/ok?token=1", nil, c)
+	expect := []string{"/users/:id Unauthorized", "/users/:id database is down"}
+	if code != http.StatusInternalServerError || !reflect.DeepEqual(reported, expect) {
+		t.Errorf("expecting %d and reported errors %v got %d %v", http.StatusInternalServerError, expect, code, reported)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c := New()
+	c.Get("/users/:id", noopHandler)
+	c.Get("/users/:id/orders/:order", noopHandler)
+	c.Get("/signup", noopHandler)
+	c.Group("/static").Get("/*path", noopHandler)
+	c.Delete("/users/:id", noopHandler)
+
+	tests := []struct {
+		method string
+		path   string
+		route  string
+		params Params
+	}{
+		{"GET", "/users/7", "/users/:id", Params{{"id", "7"}}},
+		{"GET", "/signup", "/signup", nil},
+		{"GET", "/users/7/orders/9", "/users/:id/orders/:order", Params{{"id", "7"}, {"order", "9"}}},
+		{"DELETE", "/users/7", "/users/:id", Params{{"id", "7"}}},
+		{"GET", "/static/css/app.css", "/static/*path", Params{{"path", "/css/app.css"}}},
+	}
+	for _, test := range tests {
+		route, params, ok := c.Lookup(test.method, test.path)
+		if !ok || route.Method != test.method || route.Path != test.route || !reflect.DeepEqual(params, test.params) {
+			t.Errorf("%s %s: expecting %s %v got %v %v %v", test.method, test.path, test.route, test.params, route, params, ok)
+		}
+	}
+	if _, params, _ := c.Lookup("GET", "/users/7/orders/9"); params.ByName("order") != "9" {
+		t.Errorf("expecting the order param got %v", params)
+	}
+	for _, miss := range [][2]string{{"POST", "/users/7"}, {"GET", "/unknown"}, {"GET", "/users/7/"}} {
+		if route, _, ok := c.Lookup(miss[0], miss[1]); ok {
+			t.Errorf("%v: expecting no route got %v", miss, route)
+		}
+	}
+}
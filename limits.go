@@ -0,0 +1,167 @@
+package cherry
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// limitListener accepts at most cap(sem) connections at the same time. The
+// remaining connections wait in the backlog of the listener, or, with a
+// timeout, are accepted and closed when no connection is freed in time.
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	timeout   time.Duration
+	stats     *stats
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newLimitListener(l net.Listener, n int, timeout time.Duration, st *stats) *limitListener {
+	return &limitListener{
+		Listener: l,
+		sem:      make(chan struct{}, n),
+		timeout:  timeout,
+		stats:    st,
+		done:     make(chan struct{}),
+	}
+}
+
+// acquire takes a slot, waiting at most timeout when it is not zero.
+func (l *limitListener) acquire(timeout time.Duration) bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+	}
+	l.stats.connsQueued.Add(1)
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-expired:
+		return false
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	if l.timeout <= 0 {
+		if !l.acquire(0) {
+			return nil, net.ErrClosed
+		}
+		c, err := l.Listener.Accept()
+		if err != nil {
+			<-l.sem
+			return nil, err
+		}
+		return &limitConn{Conn: c, release: func() { <-l.sem }}, nil
+	}
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.acquire(l.timeout) {
+			return &limitConn{Conn: c, release: func() { <-l.sem }}, nil
+		}
+		c.Close()
+		l.stats.connsRejected.Add(1)
+	}
+}
+
+func (l *limitListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+type limitConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// deadlineListener gives every accepted connection a read deadline, so a
+// client that opens a connection and stays silent is disconnected.
+type deadlineListener struct {
+	net.Listener
+	timeout time.Duration
+}
+
+func (l *deadlineListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	dc := &deadlineConn{Conn: c, deadline: time.Now().Add(l.timeout)}
+	c.SetReadDeadline(dc.deadline)
+	return dc, nil
+}
+
+// deadlineConn enforces the initial read deadline until the first bytes are
+// read. Deadlines set by net/http in the meantime are capped by it, after
+// that they apply as is.
+type deadlineConn struct {
+	net.Conn
+	mu        sync.Mutex
+	deadline  time.Time
+	requested time.Time
+	read      bool
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		if !c.read {
+			c.read = true
+			c.Conn.SetReadDeadline(c.requested)
+		}
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requested = t
+	if !c.read && (t.IsZero() || t.After(c.deadline)) {
+		t = c.deadline
+	}
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *deadlineConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// wrapListener passes every accepted connection through wrap.
+type wrapListener struct {
+	net.Listener
+	wrap func(net.Conn) net.Conn
+}
+
+func (l *wrapListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.wrap(c), nil
+}
@@ -0,0 +1,165 @@
+package cherry
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func serveTest(t *testing.T, c *Cherry) string {
+	c.DisableSignalHandling()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	addr := waitForServer(t, c)
+	t.Cleanup(func() {
+		c.Close()
+		<-errc
+	})
+	return addr
+}
+
+func TestMaxConns(t *testing.T) {
+	c := New()
+	c.MaxConns = 1
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	addr := serveTest(t, c)
+
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		Timeout:   200 * time.Millisecond,
+	}
+	url := fmt.Sprintf("http://%s/", addr)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(url); err == nil {
+		t.Error("expecting the request to wait for a free connection")
+	}
+	conn.Close()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	isHTTPStatusOK(t, resp.StatusCode)
+}
+
+func TestConnReadTimeout(t *testing.T) {
+	c := New()
+	c.ReadTimeout = 0
+	c.ConnReadTimeout = 50 * time.Millisecond
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	addr := serveTest(t, c)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Errorf("expecting the server to close the silent connection got %v", err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	isHTTPStatusOK(t, resp.StatusCode)
+}
+
+func TestMaxConnsWait(t *testing.T) {
+	c := New()
+	c.MaxConns = 1
+	c.MaxConnsWait = 50 * time.Millisecond
+	addr := serveTest(t, c)
+
+	held, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+	// make sure the first connection is accepted before the second.
+	time.Sleep(20 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Errorf("expecting the connection above the limit to be closed got %v", err)
+	}
+	if n := c.stats.connsRejected.Load(); n != 1 {
+		t.Errorf("expecting 1 rejected connection got %d", n)
+	}
+	if n := c.stats.connsQueued.Load(); n != 1 {
+		t.Errorf("expecting 1 queued connection got %d", n)
+	}
+
+	b := &strings.Builder{}
+	c.Metrics().WriteTo(b)
+	if !strings.Contains(b.String(), "cherry_connections_rejected_total 1\n") {
+		t.Errorf("expecting the rejected connections metric got\n%s", b.String())
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func TestListenerHooks(t *testing.T) {
+	var (
+		read       atomic.Int64
+		controlled bool
+	)
+	c := New()
+	c.KeepAlive = -1
+	c.ListenControl = func(network, address string, rc syscall.RawConn) error {
+		controlled = true
+		return nil
+	}
+	c.WrapConn = func(conn net.Conn) net.Conn {
+		return &countingConn{Conn: conn, read: &read}
+	}
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	addr := serveTest(t, c)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	isHTTPStatusOK(t, resp.StatusCode)
+	if !controlled {
+		t.Error("expecting ListenControl to be called")
+	}
+	if read.Load() == 0 {
+		t.Error("expecting the wrapped connection to be used")
+	}
+}
@@ -0,0 +1,206 @@
+package cherry
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Route is a registered route. Naming it lets URLFor build its URLs.
+//
+// app.Get("/users/:id", showUser).Name("user").
+type Route struct {
+	Method string
+	Path   string
+
+	routes *routes
+}
+
+// routes holds the named routes of an application and the metadata of its
+// routes, it is shared by the application and all of its groups.
+type routes struct {
+	mu    sync.RWMutex
+	names map[string]string
+	meta  map[routeKey]*routeMeta
+}
+
+func (c *Cherry) route(method, path string) *Route {
+	return &Route{Method: method, Path: path, routes: c.routes}
+}
+
+// Name names the route for URLFor. Naming another route with the same name
+// replaces it.
+func (r *Route) Name(name string) *Route {
+	r.routes.mu.Lock()
+	defer r.routes.mu.Unlock()
+	if r.routes.names == nil {
+		r.routes.names = map[string]string{}
+	}
+	r.routes.names[name] = r.Path
+	r.routes.metaOf(r.Method, r.Path).name = name
+	return r
+}
+
+// URLFor returns the path of the named route with its :name and *name
+// segments filled by the params, pairs of names and values. The params that
+// are not in the path are added to the query string.
+//
+// app.URLFor("user", "id", 7, "tab", "orders") => /users/7?tab=orders.
+func (c *Cherry) URLFor(name string, params ...any) (string, error) {
+	c.routes.mu.RLock()
+	pattern, ok := c.routes.names[name]
+	c.routes.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("cherry: no route named %q", name)
+	}
+	values := map[string]any{}
+	for k, v := range Args(params...) {
+		values[k] = v
+	}
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		v, ok := values[seg[1:]]
+		if !ok {
+			return "", fmt.Errorf("cherry: missing parameter %q of route %q", seg[1:], name)
+		}
+		delete(values, seg[1:])
+		s := fmt.Sprint(v)
+		if seg[0] == ':' {
+			segments[i] = url.PathEscape(s)
+			continue
+		}
+		// a catch-all keeps its slashes.
+		parts := strings.Split(strings.TrimPrefix(s, "/"), "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+	u := strings.Join(segments, "/")
+	if len(values) > 0 {
+		query := url.Values{}
+		for k, v := range values {
+			query.Set(k, fmt.Sprint(v))
+		}
+		u += "?" + query.Encode()
+	}
+	return u, nil
+}
+
+// URLFor returns the absolute URL of the named route, on the scheme and
+// host of the request, see Cherry.URLFor.
+func (c *Context) URLFor(name string, params ...any) (string, error) {
+	if c.cherry == nil {
+		return "", fmt.Errorf("cherry: no route named %q", name)
+	}
+	p, err := c.cherry.URLFor(name, params...)
+	if err != nil {
+		return "", err
+	}
+	return c.BaseURL() + p, nil
+}
+
+// AbsoluteURL resolves ref against the URL of the request, on the scheme
+// and host of the request. An empty ref returns the URL of the request.
+//
+// next := ctx.AbsoluteURL("?page=3").
+func (c *Context) AbsoluteURL(ref string) string {
+	base, _ := url.Parse(c.BaseURL())
+	u := *c.Request().URL
+	u.Scheme, u.Host = base.Scheme, base.Host
+	r, err := url.Parse(ref)
+	if err != nil {
+		return u.String()
+	}
+	return u.ResolveReference(r).String()
+}
+
+// BaseURL returns the scheme and host of the request, like
+// https://example.com. Behind a trusted proxy, see SetTrustedProxies, they
+// are taken from the Forwarded or X-Forwarded-Proto and X-Forwarded-Host
+// headers.
+func (c *Context) BaseURL() string {
+	return c.Scheme() + "://" + c.Host()
+}
+
+// Scheme returns the scheme of the request, http or https.
+func (c *Context) Scheme() string {
+	r := c.Request()
+	if c.fromTrustedProxy() {
+		if proto := forwardedParam(r.Header.Get("Forwarded"), "proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host of the request, with its port when it has one.
+func (c *Context) Host() string {
+	r := c.Request()
+	if c.fromTrustedProxy() {
+		if host := forwardedParam(r.Header.Get("Forwarded"), "host"); host != "" {
+			return host
+		}
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			return strings.TrimSpace(strings.Split(host, ",")[0])
+		}
+	}
+	return r.Host
+}
+
+// fromTrustedProxy reports whether the request comes from a trusted proxy.
+func (c *Context) fromTrustedProxy() bool {
+	if c.cherry == nil {
+		return false
+	}
+	remote, _, err := net.SplitHostPort(c.Request().RemoteAddr)
+	if err != nil {
+		remote = c.Request().RemoteAddr
+	}
+	ip := net.ParseIP(remote)
+	return ip != nil && c.cherry.isTrustedProxy(ip)
+}
+
+// forwardedParam returns a parameter of the first element of a Forwarded
+// header (RFC 7239).
+func forwardedParam(header, name string) string {
+	first, _, _ := strings.Cut(header, ",")
+	for _, pair := range strings.Split(first, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(k, name) {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// Link is a link of the Link header (RFC 8288).
+type Link struct {
+	URL string
+	Rel string
+}
+
+// SetLinkHeader sets the Link header to the links.
+//
+//	ctx.SetLinkHeader(
+//		cherry.Link{URL: ctx.AbsoluteURL("?page=3"), Rel: "next"},
+//		cherry.Link{URL: ctx.AbsoluteURL("?page=1"), Rel: "prev"},
+//	)
+func (c *Context) SetLinkHeader(links ...Link) {
+	parts := make([]string, len(links))
+	for i, l := range links {
+		parts[i] = fmt.Sprintf("<%s>; rel=%q", l.URL, l.Rel)
+	}
+	c.Response().Header().Set("Link", strings.Join(parts, ", "))
+}
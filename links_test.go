@@ -0,0 +1,83 @@
+package cherry
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURLFor(t *testing.T) {
+	c := New()
+	c.Get("/users/:id", func(ctx *Context) error { return nil }).Name("user")
+	api := c.Group("/api")
+	api.Get("/files/*path", func(ctx *Context) error { return nil }).Name("file")
+
+	tests := []struct {
+		name   string
+		params []any
+		expect string
+	}{
+		{"user", []any{"id", 7}, "/users/7"},
+		{"user", []any{"id", "a b", "tab", "orders", "page", 2}, "/users/a%20b?page=2&tab=orders"},
+		{"user", []any{map[string]any{"id": 1}}, "/users/1"},
+		{"file", []any{"path", "docs/read me.txt"}, "/api/files/docs/read%20me.txt"},
+	}
+	for _, test := range tests {
+		got, err := c.URLFor(test.name, test.params...)
+		if err != nil || got != test.expect {
+			t.Errorf("%s %v: expecting %q got %q %v", test.name, test.params, test.expect, got, err)
+		}
+	}
+	if _, err := c.URLFor("user"); err == nil {
+		t.Error("expecting an error for a missing parameter")
+	}
+	if _, err := c.URLFor("unknown"); err == nil {
+		t.Error("expecting an error for an unknown route")
+	}
+}
+
+func TestContextURLs(t *testing.T) {
+	c := New()
+	c.SetTrustedProxies("10.0.0.0/8")
+	c.Get("/users/:id", func(ctx *Context) error {
+		user, err := ctx.URLFor("user", "id", 8)
+		if err != nil {
+			return err
+		}
+		ctx.SetLinkHeader(Link{URL: user, Rel: "next"}, Link{URL: ctx.AbsoluteURL("?page=1"), Rel: "first"})
+		return ctx.Text(http.StatusOK, ctx.AbsoluteURL(""))
+	}).Name("user")
+
+	tests := []struct {
+		remote string
+		tls    bool
+		header map[string]string
+		expect string
+	}{
+		{"192.0.2.1:1234", false, nil, "http://example.com"},
+		{"192.0.2.1:1234", true, nil, "https://example.com"},
+		{"192.0.2.1:1234", false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"}, "http://example.com"},
+		{"10.0.0.1:1234", false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com, proxy"}, "https://api.example.com"},
+		{"10.0.0.1:1234", false, map[string]string{"Forwarded": `proto=https;host="shop.example.com:8443", proto=http`}, "https://shop.example.com:8443"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/users/7?tab=x", nil)
+		r.RemoteAddr = test.remote
+		if test.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		for k, v := range test.header {
+			r.Header.Set(k, v)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if body := rw.Body.String(); body != test.expect+"/users/7?tab=x" {
+			t.Errorf("%v: expecting %s got %s", test.header, test.expect, body)
+		}
+		link := "<" + test.expect + `/users/8>; rel="next", <` + test.expect + `/users/7?page=1>; rel="first"`
+		if got := rw.Header().Get("Link"); got != link {
+			t.Errorf("%v: expecting Link %s got %s", test.header, link, got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cherry
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterOp is the operator of a Filter.
+type FilterOp string
+
+// The operators of filters, the operator of filter[status]=open is OpEq.
+const (
+	OpEq  FilterOp = "eq"
+	OpNe  FilterOp = "ne"
+	OpGt  FilterOp = "gt"
+	OpGte FilterOp = "gte"
+	OpLt  FilterOp = "lt"
+	OpLte FilterOp = "lte"
+	OpIn  FilterOp = "in"
+)
+
+// FieldType is the type of the values of a filtered field.
+type FieldType int
+
+// The types of filter values, a Filter holds a string, an int64, a float64,
+// a bool or a time.Time. Times are RFC 3339 or dates like 2006-01-02.
+const (
+	StringField FieldType = iota
+	IntField
+	FloatField
+	BoolField
+	TimeField
+)
+
+// QueryField allows a field in the query of ParseListQuery.
+type QueryField struct {
+	// Column is the name of the field in the database, which handlers use
+	// instead of the name of the query. The default is the name
+	Column string
+
+	// Type is the type of the filter values. The default is StringField
+	Type FieldType
+
+	// Sortable allows sorting by the field
+	Sortable bool
+
+	// Ops are the allowed filter operators, none means the field can not be
+	// filtered
+	Ops []FilterOp
+}
+
+// QuerySpec is the allowlist of the fields of ParseListQuery, by their name
+// in the query. Fields that are not in the spec are rejected.
+type QuerySpec map[string]QueryField
+
+// SortField is an element of the sort order.
+type SortField struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Filter is a condition on a field. Value is of the Type of the field, and
+// a slice of it for OpIn.
+type Filter struct {
+	Field  string
+	Column string
+	Op     FilterOp
+	Value  any
+}
+
+// ListQuery is the parsed sort order and filters of a list request.
+type ListQuery struct {
+	Sort    []SortField
+	Filters []Filter
+}
+
+// ListQuery parses the sort order and the filters of the query string for
+// the fields allowed by spec. Fields and operators that are not allowed and
+// invalid values are answered with a 400 HTTPError.
+//
+//	// ?sort=-created_at,name&filter[status]=open&filter[age][gte]=18
+//	q, err := ctx.ListQuery(cherry.QuerySpec{
+//		"created_at": {Type: cherry.TimeField, Sortable: true},
+//		"name":       {Sortable: true},
+//		"status":     {Ops: []cherry.FilterOp{cherry.OpEq, cherry.OpIn}},
+//		"age":        {Type: cherry.IntField, Ops: []cherry.FilterOp{cherry.OpGte, cherry.OpLte}},
+//	})
+func (c *Context) ListQuery(spec QuerySpec) (*ListQuery, error) {
+	q, err := ParseListQuery(c.queryValues(), spec)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, c.ErrorMessage(err))
+	}
+	return q, nil
+}
+
+// ParseListQuery parses the sort parameter, a comma separated list of
+// fields that are prefixed with - for a descending order, and the
+// filter[field] and filter[field][op] parameters. Errors are FieldErrors,
+// see Context.ErrorMessage. The filters are sorted by field.
+func ParseListQuery(values url.Values, spec QuerySpec) (*ListQuery, error) {
+	q := &ListQuery{}
+	for _, s := range values["sort"] {
+		for _, name := range strings.Split(s, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			sf := SortField{Field: strings.TrimLeft(name, "+-"), Desc: name[0] == '-'}
+			field, ok := spec[sf.Field]
+			if !ok || !field.Sortable {
+				return nil, &FieldError{Field: sf.Field, Tag: "sort"}
+			}
+			sf.Column = field.column(sf.Field)
+			q.Sort = append(q.Sort, sf)
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, op, err := parseFilterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		field, ok := spec[name]
+		if !ok || !field.allows(op) {
+			return nil, &FieldError{Field: name, Tag: "filter", Param: string(op)}
+		}
+		for _, raw := range values[key] {
+			value, err := field.parse(op, raw)
+			if err != nil {
+				return nil, &FieldError{Field: name, Tag: "type", Param: field.Type.String(), Value: raw}
+			}
+			q.Filters = append(q.Filters, Filter{Field: name, Column: field.column(name), Op: op, Value: value})
+		}
+	}
+	return q, nil
+}
+
+// parseFilterKey parses filter[field] and filter[field][op].
+func parseFilterKey(key string) (string, FilterOp, error) {
+	rest := strings.TrimPrefix(key, "filter[")
+	name, rest, ok := strings.Cut(rest, "]")
+	if !ok || name == "" {
+		return "", "", &FieldError{Field: key, Tag: "invalid"}
+	}
+	if rest == "" {
+		return name, OpEq, nil
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") {
+		return "", "", &FieldError{Field: key, Tag: "invalid"}
+	}
+	return name, FilterOp(rest[1 : len(rest)-1]), nil
+}
+
+func (f QueryField) column(name string) string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return name
+}
+
+func (f QueryField) allows(op FilterOp) bool {
+	for _, o := range f.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parse returns the value of a filter, a slice of values for OpIn.
+func (f QueryField) parse(op FilterOp, raw string) (any, error) {
+	if op != OpIn {
+		return f.Type.parse(raw)
+	}
+	parts := strings.Split(raw, ",")
+	values := make([]any, len(parts))
+	for i, part := range parts {
+		v, err := f.Type.parse(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (t FieldType) parse(raw string) (any, error) {
+	switch t {
+	case IntField:
+		return strconv.ParseInt(raw, 10, 64)
+	case FloatField:
+		return strconv.ParseFloat(raw, 64)
+	case BoolField:
+		return strconv.ParseBool(raw)
+	case TimeField:
+		if tm, err := time.Parse(time.RFC3339, raw); err == nil {
+			return tm, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	}
+	return raw, nil
+}
+
+func (t FieldType) String() string {
+	switch t {
+	case StringField:
+		return "string"
+	case IntField:
+		return "integer"
+	case FloatField:
+		return "number"
+	case BoolField:
+		return "boolean"
+	case TimeField:
+		return "time"
+	}
+	return fmt.Sprintf("FieldType(%d)", t)
+}
@@ -0,0 +1,74 @@
+package cherry
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testQuerySpec = QuerySpec{
+	"created_at": {Column: "users.created_at", Type: TimeField, Sortable: true, Ops: []FilterOp{OpGte, OpLt}},
+	"name":       {Sortable: true},
+	"status":     {Ops: []FilterOp{OpEq, OpIn}},
+	"age":        {Type: IntField, Ops: []FilterOp{OpGte, OpLte}},
+}
+
+func TestParseListQuery(t *testing.T) {
+	values, _ := url.ParseQuery("sort=-created_at,+name&filter[status][in]=open,closed&filter[age][gte]=18&filter[created_at][lt]=2024-03-01&page=2")
+	q, err := ParseListQuery(values, testQuerySpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &ListQuery{
+		Sort: []SortField{{Field: "created_at", Column: "users.created_at", Desc: true}, {Field: "name", Column: "name"}},
+		Filters: []Filter{
+			{Field: "age", Column: "age", Op: OpGte, Value: int64(18)},
+			{Field: "created_at", Column: "users.created_at", Op: OpLt, Value: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+			{Field: "status", Column: "status", Op: OpIn, Value: []any{"open", "closed"}},
+		},
+	}
+	if !reflect.DeepEqual(q, expect) {
+		t.Errorf("expecting %+v got %+v", expect, q)
+	}
+}
+
+func TestParseListQueryErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		expect string
+	}{
+		{"sort=age", "age is not a sortable field"},
+		{"sort=password", "password is not a sortable field"},
+		{"filter[name]=ada", "name can not be filtered with eq"},
+		{"filter[status][gt]=open", "status can not be filtered with gt"},
+		{"filter[age][gte]=old", "age must be a valid integer"},
+		{"filter[created_at][gte]=yesterday", "created_at must be a valid time"},
+		{"filter[age]gte=1", "filter[age]gte is invalid"},
+	}
+	for _, test := range tests {
+		values, _ := url.ParseQuery(test.query)
+		if _, err := ParseListQuery(values, testQuerySpec); err == nil || err.Error() != test.expect {
+			t.Errorf("%s: expecting %q got %v", test.query, test.expect, err)
+		}
+	}
+}
+
+func TestContextListQuery(t *testing.T) {
+	c := New()
+	c.Get("/users", func(ctx *Context) error {
+		q, err := ctx.ListQuery(testQuerySpec)
+		if err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, q.Filters[0].Column)
+	})
+	if code, body := doRequest(t, "GET", "/users?filter[status]=open", nil, c); code != http.StatusOK || body != "status" {
+		t.Errorf("expecting 200 got %d %q", code, body)
+	}
+	if code, body := doRequest(t, "GET", "/users?sort=secret", nil, c); code != http.StatusBadRequest || !strings.Contains(body, "secret is not a sortable field") {
+		t.Errorf("expecting 400 got %d %q", code, body)
+	}
+}
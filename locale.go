@@ -0,0 +1,127 @@
+package cherry
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Locale returns the supported language that matches the preferences of the
+// client best, or the first supported language when none matches. The
+// preferences are the LocaleKey query parameter, then the LocaleKey cookie
+// and then the Accept-Language header with its q-values. The result is
+// cached on the Context, so that a later Locale without arguments, from the
+// templates or the i18n package, returns it. Without a resolved language,
+// Locale without arguments returns the language the client prefers most,
+// or language.Und.
+//
+// lang := ctx.Locale(language.English, language.French, language.German).
+func (c *Context) Locale(supported ...language.Tag) language.Tag {
+	if len(supported) == 0 {
+		if c.locale != nil {
+			return *c.locale
+		}
+		if preferred := c.preferredLanguages(); len(preferred) > 0 {
+			return preferred[0]
+		}
+		return language.Und
+	}
+	tag := supported[0]
+	if preferred := c.preferredLanguages(); len(preferred) > 0 {
+		if _, i, conf := language.NewMatcher(supported).Match(preferred...); conf != language.No {
+			tag = supported[i]
+		}
+	}
+	c.SetLocale(tag)
+	return tag
+}
+
+// SetLocale sets the language that Locale without arguments returns, for
+// middleware that resolves it another way, like from the profile of a user.
+func (c *Context) SetLocale(tag language.Tag) {
+	c.locale = &tag
+}
+
+// preferredLanguages returns the languages the client prefers, in order.
+func (c *Context) preferredLanguages() []language.Tag {
+	if c.preferred != nil {
+		return c.preferred
+	}
+	key := "lang"
+	if c.cherry != nil && c.cherry.LocaleKey != "" {
+		key = c.cherry.LocaleKey
+	}
+	preferred := []language.Tag{}
+	if tag, err := language.Parse(c.Query(key)); err == nil {
+		preferred = append(preferred, tag)
+	}
+	if cookie, err := c.Request().Cookie(key); err == nil {
+		if tag, err := language.Parse(cookie.Value); err == nil {
+			preferred = append(preferred, tag)
+		}
+	}
+	accept, _, _ := language.ParseAcceptLanguage(c.Header("Accept-Language"))
+	c.preferred = append(preferred, accept...)
+	return c.preferred
+}
+
+// languageOf returns the language of the request, or language.Und without
+// a Context.
+func languageOf(c *Context) language.Tag {
+	if c == nil {
+		return language.Und
+	}
+	return c.Locale()
+}
+
+// dateLayouts are the numeric date layouts of languages and regions, the
+// region takes precedence.
+var dateLayouts = map[string]string{
+	"en": "1/2/2006", "GB": "02/01/2006", "AU": "02/01/2006", "IE": "02/01/2006",
+	"NZ": "02/01/2006", "IN": "02/01/2006", "CA": "2006-01-02",
+	"fr": "02/01/2006", "es": "02/01/2006", "it": "02/01/2006", "pt": "02/01/2006",
+	"de": "02.01.2006", "ru": "02.01.2006", "pl": "02.01.2006", "tr": "02.01.2006",
+	"nb": "02.01.2006", "da": "02.01.2006", "fi": "2.1.2006", "uk": "02.01.2006",
+	"nl": "02-01-2006", "ja": "2006/01/02", "zh": "2006/01/02", "ko": "2006. 01. 02.",
+	"sv": "2006-01-02", "lt": "2006-01-02",
+}
+
+// formatDate formats t, a time.Time, with the layout or the numeric date
+// layout of the language.
+func formatDate(tag language.Tag, t any, layout ...string) (string, error) {
+	var tm time.Time
+	switch v := t.(type) {
+	case time.Time:
+		tm = v
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		tm = *v
+	default:
+		return "", fmt.Errorf("cherry: date of a %T", t)
+	}
+	if len(layout) > 0 {
+		return tm.Format(layout[0]), nil
+	}
+	if tag == language.Und {
+		return tm.Format("2006-01-02"), nil
+	}
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+	l, ok := dateLayouts[region.String()]
+	if !ok {
+		if l, ok = dateLayouts[base.String()]; !ok {
+			l = "2006-01-02"
+		}
+	}
+	return tm.Format(l), nil
+}
+
+// formatNumber formats a number with the separators of the language.
+func formatNumber(tag language.Tag, n any) string {
+	return message.NewPrinter(tag).Sprint(number.Decimal(n))
+}
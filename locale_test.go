@@ -0,0 +1,71 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestLocale(t *testing.T) {
+	supported := []language.Tag{language.English, language.French, language.MustParse("pt-BR")}
+	tests := []struct {
+		target string
+		cookie string
+		accept string
+		expect language.Tag
+	}{
+		{"/", "", "", language.English},
+		{"/", "", "fr-CH, fr;q=0.9, en;q=0.8", language.French},
+		{"/", "", "de, pt;q=0.5", language.MustParse("pt-BR")},
+		{"/", "", "en;q=0.1, fr;q=0.9", language.French},
+		{"/", "", "ja", language.English},
+		{"/", "fr", "en", language.French},
+		{"/?locale=pt-BR", "fr", "en", language.MustParse("pt-BR")},
+		{"/?locale=invalid!", "", "fr", language.French},
+	}
+	c := New()
+	c.LocaleKey = "locale"
+	var got, cached language.Tag
+	c.Get("/", func(ctx *Context) error {
+		got = ctx.Locale(supported...)
+		cached = ctx.Locale()
+		return nil
+	})
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			r.Header.Set("Accept-Language", test.accept)
+		}
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "locale", Value: test.cookie})
+		}
+		c.ServeHTTP(httptest.NewRecorder(), r)
+		if got != test.expect || cached != test.expect {
+			t.Errorf("%s %q %q: expecting %s got %s (cached %s)", test.target, test.cookie, test.accept, test.expect, got, cached)
+		}
+	}
+}
+
+func TestLocaleWithoutSupported(t *testing.T) {
+	c := New()
+	var tags []language.Tag
+	c.Get("/", func(ctx *Context) error {
+		tags = append(tags, ctx.Locale())
+		ctx.SetLocale(language.German)
+		tags = append(tags, ctx.Locale())
+		return nil
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "nl;q=0.5, fr")
+	c.ServeHTTP(httptest.NewRecorder(), r)
+	c.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	expect := []language.Tag{language.French, language.German, language.Und, language.German}
+	for i, tag := range expect {
+		if tags[i] != tag {
+			t.Errorf("expecting %v got %v", expect, tags)
+			break
+		}
+	}
+}
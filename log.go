@@ -0,0 +1,113 @@
+package cherry
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (c *Cherry) logListening(addr string, tls bool) {
+	if c.Logger != nil {
+		c.Logger.Info("cherry listening", slog.String("addr", addr), slog.Bool("tls", tls))
+		return
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "0.0.0.0" + addr
+	}
+	if tls {
+		fmt.Fprintf(c.Output, "Cherry🍒 listening TLS on %s\n", addr)
+		return
+	}
+	fmt.Fprintf(c.Output, "Cherry🍒 listening on %s\n", addr)
+}
+
+// logError logs an error returned by a handler. Errors are only logged when
+// a Logger is set, the plain output keeps quiet about them.
+func (c *Cherry) logError(ctx *Context, err error) {
+	var pe *PanicError
+	if c.Logger == nil || errors.As(err, &pe) {
+		// panics are logged with their report when they are recovered.
+		return
+	}
+	r := ctx.Request()
+	c.Logger.LogAttrs(r.Context(), slog.LevelError, "handler error",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("error", err.Error()),
+	)
+}
+
+// logFailure logs an error that happened outside of a request, like a
+// failing background task.
+func (c *Cherry) logFailure(msg string, err error) {
+	if c.Logger != nil {
+		c.Logger.Error(msg, slog.String("error", err.Error()))
+		return
+	}
+	fmt.Fprintf(c.Output, "Cherry🍒 %s: %s\n", msg, err)
+}
+
+func (c *Cherry) writeLog(r *http.Request, start time.Time, l *responseLogger) {
+	e := newAccessLogEntry(r, start, l)
+	if c.Logger != nil {
+		c.Logger.LogAttrs(r.Context(), slog.LevelInfo, "access",
+			slog.String("host", e.Host),
+			slog.String("remote_addr", e.RemoteAddr),
+			slog.String("user", e.User),
+			slog.String("method", e.Method),
+			slog.String("uri", e.URI),
+			slog.String("route", e.Route),
+			slog.String("proto", e.Proto),
+			slog.Int("status", e.Status),
+			slog.Int("size", e.Size),
+			slog.Duration("latency", e.Latency),
+			slog.String("request_id", e.RequestID),
+			slog.String("user_agent", e.UserAgent),
+			slog.String("referer", e.Referer),
+		)
+		return
+	}
+	format := c.AccessLogFormat
+	if format == nil {
+		format = LogCommon
+	}
+	format(c.Output, e)
+}
+
+// LogSlowRequests logs a warning for every request that takes longer than
+// threshold, with its route and the names of its url parameters. The values
+// of the parameters are redacted as they may hold personal data. A threshold
+// of zero disables the warnings.
+//
+// app.LogSlowRequests(500 * time.Millisecond).
+func (c *Cherry) LogSlowRequests(threshold time.Duration) {
+	c.slowAfter = threshold
+}
+
+func (c *Cherry) logSlowRequest(r *http.Request, start time.Time, l *responseLogger) {
+	latency := time.Since(start)
+	route := l.route
+	if route == "" {
+		route = r.URL.Path
+	}
+	params := make([]string, len(l.params))
+	for i, p := range l.params {
+		params[i] = p.Key + "=[REDACTED]"
+	}
+	if c.Logger != nil {
+		c.Logger.LogAttrs(r.Context(), slog.LevelWarn, "slow request",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.Any("params", params),
+			slog.Int("status", l.Status()),
+			slog.Duration("latency", latency),
+			slog.Duration("threshold", c.slowAfter),
+		)
+		return
+	}
+	fmt.Fprintf(c.Output, "WARN slow request %s %s [%s] status %d took %s (threshold %s)\n",
+		r.Method, route, strings.Join(params, " "), l.Status(), latency, c.slowAfter)
+}
@@ -0,0 +1,154 @@
+package cherry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New()
+	c.Output = buf
+	c.HasAccessLog = true
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "cherry")
+	})
+	doRequest(t, "GET", "/", nil, c)
+	if !strings.Contains(buf.String(), `"GET / HTTP/1.1" 200 6`) {
+		t.Errorf("unexpected access log %s", buf.String())
+	}
+}
+
+func TestSlogAccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New()
+	c.Logger = slog.New(slog.NewJSONHandler(buf, nil))
+	c.HasAccessLog = true
+	c.Get("/", func(ctx *Context) error {
+		return errors.New("boom")
+	})
+	doRequest(t, "GET", "/", nil, c)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expecting 2 records got %d: %s", len(lines), buf.String())
+	}
+	records := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if records[0]["level"] != "ERROR" || records[0]["error"] != "boom" {
+		t.Errorf("unexpected error record %v", records[0])
+	}
+	if records[1]["msg"] != "access" || records[1]["status"] != float64(500) || records[1]["method"] != "GET" {
+		t.Errorf("unexpected access record %v", records[1])
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	custom, err := LogTemplate(`{{.Method}} {{.Route}} {{.Status}} {{.RequestID}} {{.UserAgent}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format AccessLogFormat
+		expect string
+	}{
+		{LogCommon, `example.com - - [`},
+		{LogCombined, `"GET /users/42 HTTP/1.1" 200 6 "https://example.com" "cherry-test"`},
+		{LogCombined, " 10.0.0.1\n"},
+		{LogJSON, `"route":"/users/:id"`},
+		{LogJSON, `"host":"example.com"`},
+		{LogJSON, `"remote_addr":"10.0.0.1"`},
+		{custom, "GET /users/:id 200 abc cherry-test\n"},
+	}
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		c := New()
+		c.Output = buf
+		c.SetAccessLogFormat(test.format)
+		c.Get("/users/:id", func(ctx *Context) error {
+			return ctx.Text(http.StatusOK, "cherry")
+		})
+		r := httptest.NewRequest("GET", "/users/42", nil)
+		r.Host = "example.com:8080"
+		r.RemoteAddr = "10.0.0.1:4321"
+		r.Header.Set("User-Agent", "cherry-test")
+		r.Header.Set("Referer", "https://example.com")
+		r.Header.Set("X-Request-ID", "abc")
+		c.ServeHTTP(httptest.NewRecorder(), r)
+		if !strings.Contains(buf.String(), test.expect) {
+			t.Errorf("expecting log containing %s got %s", test.expect, buf.String())
+		}
+	}
+}
+
+func TestCommonLogFormat(t *testing.T) {
+	e := &AccessLogEntry{
+		Time:       time.Date(2024, 3, 9, 14, 5, 7, 0, time.FixedZone("", 3600)),
+		Host:       "example.com",
+		RemoteAddr: "10.0.0.1",
+		User:       "-",
+		Method:     "GET",
+		URI:        "/users/42?tab=orders",
+		Proto:      "HTTP/1.1",
+		Status:     200,
+		Size:       6,
+		UserAgent:  `cherry "test"`,
+		Latency:    1500 * time.Microsecond,
+	}
+	buf := &bytes.Buffer{}
+	LogCommon(buf, e)
+	LogCombined(buf, e)
+	expect := `example.com - - [09/Mar/2024:14:05:07 +0100] "GET /users/42?tab=orders HTTP/1.1" 200 6` + "\n" +
+		`example.com - - [09/Mar/2024:14:05:07 +0100] "GET /users/42?tab=orders HTTP/1.1" 200 6 "-" "cherry \"test\"" 1.500 10.0.0.1` + "\n"
+	if buf.String() != expect {
+		t.Errorf("expecting %s got %s", expect, buf.String())
+	}
+}
+
+func TestContextFullPath(t *testing.T) {
+	c := New()
+	g := c.Group("/api")
+	g.Get("/users/:id", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.FullPath())
+	})
+	_, body := doRequest(t, "GET", "/api/users/42", nil, c)
+	if body != "/api/users/:id" {
+		t.Errorf("expecting /api/users/:id got %s", body)
+	}
+}
+
+func TestLogSlowRequests(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New()
+	c.Output = buf
+	c.LogSlowRequests(10 * time.Millisecond)
+	c.Get("/fast", noopHandler)
+	c.Get("/slow/:token", func(ctx *Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	doRequest(t, "GET", "/fast", nil, c)
+	if buf.Len() != 0 {
+		t.Errorf("expecting no warning got %s", buf.String())
+	}
+	doRequest(t, "GET", "/slow/secret", nil, c)
+	log := buf.String()
+	if !strings.Contains(log, "WARN slow request GET /slow/:token [token=[REDACTED]]") {
+		t.Errorf("unexpected warning %s", log)
+	}
+	if strings.Contains(log, "secret") {
+		t.Errorf("expecting the parameter value to be redacted got %s", log)
+	}
+}
@@ -0,0 +1,327 @@
+package cherry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultBuckets are the upper bounds of the histogram buckets, tuned for
+// request durations in seconds.
+var defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics is a registry of counters, gauges and histograms that can be
+// exported in the Prometheus text format. Every application has a registry
+// that is shared by the framework and the application, see Cherry.Metrics.
+type Metrics struct {
+	mu         sync.Mutex
+	metrics    map[string]*metricFamily
+	collectors []func(*Metrics)
+	enabled    atomic.Bool
+}
+
+type metricFamily struct {
+	name   string
+	kind   string
+	series map[string]interface{}
+}
+
+// NewMetrics returns an empty registry.
+func NewMetrics() *Metrics {
+	return &Metrics{metrics: map[string]*metricFamily{}}
+}
+
+// Counter returns the counter with the given name and label pairs, creating
+// it if needed.
+//
+// m.Counter("orders_created_total", "method", "card").Inc()
+func (m *Metrics) Counter(name string, labels ...string) *Counter {
+	return m.get(name, "counter", labels, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+// Gauge returns the gauge with the given name and label pairs, creating it if
+// needed.
+func (m *Metrics) Gauge(name string, labels ...string) *Gauge {
+	return m.get(name, "gauge", labels, func() interface{} { return &Gauge{} }).(*Gauge)
+}
+
+// Histogram returns the histogram with the given name and label pairs,
+// creating it with buckets suitable for durations in seconds if needed.
+func (m *Metrics) Histogram(name string, labels ...string) *Histogram {
+	return m.get(name, "histogram", labels, func() interface{} {
+		return &Histogram{bounds: defaultBuckets, counts: make([]uint64, len(defaultBuckets))}
+	}).(*Histogram)
+}
+
+func (m *Metrics) get(name, kind string, labels []string, create func() interface{}) interface{} {
+	if len(labels)%2 != 0 {
+		panic("cherry: metric labels must be key value pairs")
+	}
+	key := formatLabels(labels)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.metrics[name]
+	if !ok {
+		f = &metricFamily{name: name, kind: kind, series: map[string]interface{}{}}
+		m.metrics[name] = f
+	}
+	if f.kind != kind {
+		panic(fmt.Sprintf("cherry: metric %s registered as %s, not %s", name, f.kind, kind))
+	}
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+	}
+	return s
+}
+
+// formatLabels renders the label pairs sorted by name, like {a="1",b="2"}.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Collect registers a custom collector, fn runs before every export to
+// update metrics whose values live elsewhere, like the size of a pool.
+//
+// m.Collect(func(m *cherry.Metrics) { m.Gauge("db_open_connections").Set(float64(db.Stats().OpenConnections)) }).
+func (m *Metrics) Collect(fn func(*Metrics)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.collectors = append(m.collectors, fn)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	collectors := append([]func(*Metrics){}, m.collectors...)
+	m.mu.Unlock()
+	for _, fn := range collectors {
+		fn(m)
+	}
+
+	m.mu.Lock()
+	names := make([]string, 0, len(m.metrics))
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		f := m.metrics[name]
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.kind)
+		for _, k := range keys {
+			switch s := f.series[k].(type) {
+			case *Counter:
+				fmt.Fprintf(&b, "%s%s %s\n", name, k, formatFloat(s.Value()))
+			case *Gauge:
+				fmt.Fprintf(&b, "%s%s %s\n", name, k, formatFloat(s.Value()))
+			case *Histogram:
+				s.write(&b, name, k)
+			}
+		}
+	}
+	m.mu.Unlock()
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Handler returns a Handler that serves the metrics in the Prometheus text
+// format.
+//
+// app.Get("/metrics", app.Metrics().Handler()).
+func (m *Metrics) Handler() Handler {
+	return func(ctx *Context) error {
+		ctx.Response().Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, err := m.WriteTo(ctx.Response())
+		return err
+	}
+}
+
+// observeRequest records the framework metrics of a request.
+func (m *Metrics) observeRequest(method, route string, status int, start time.Time) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	m.Counter("cherry_requests_total", "method", method, "route", route, "status", strconv.Itoa(status)).Inc()
+	m.Histogram("cherry_request_duration_seconds", "method", method, "route", route).Observe(time.Since(start).Seconds())
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a metric that only goes up.
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds v, which must not be negative, to the counter.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("cherry: counter cannot decrease")
+	}
+	addFloat(&c.bits, v)
+}
+
+// set sets the counter to a value that is counted elsewhere.
+func (c *Counter) set(v float64) {
+	c.bits.Store(math.Float64bits(v))
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// Gauge is a metric that can go up and down.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Inc increments the gauge by one.
+func (g *Gauge) Inc() {
+	addFloat(&g.bits, 1)
+}
+
+// Dec decrements the gauge by one.
+func (g *Gauge) Dec() {
+	addFloat(&g.bits, -1)
+}
+
+// Add adds v to the gauge.
+func (g *Gauge) Add(v float64) {
+	addFloat(&g.bits, v)
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		if bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds an observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// write renders the cumulative buckets, sum and count of the histogram.
+func (h *Histogram) write(b *strings.Builder, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	withLe := func(le string) string {
+		if labels == "" {
+			return "{le=\"" + le + "\"}"
+		}
+		return labels[:len(labels)-1] + ",le=\"" + le + "\"}"
+	}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, withLe(formatFloat(bound)), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket%s %d\n", name, withLe("+Inf"), h.count)
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// Metrics returns the metrics registry of the application, which is shared
+// with all of its groups. The first call enables the framework metrics:
+// cherry_requests_total and cherry_request_duration_seconds, labeled with
+// the method and route pattern, and the connection metrics of the servers.
+func (c *Cherry) Metrics() *Metrics {
+	c.metrics.enabled.Store(true)
+	return c.metrics
+}
+
+// RouteMetrics gives access to the metrics registry with the route of the
+// current request as a label.
+type RouteMetrics struct {
+	m     *Metrics
+	route string
+}
+
+// Metrics returns the metrics of the application labeled with the route
+// pattern of the request.
+//
+// ctx.Metrics().Counter("orders_created_total").Inc()
+func (c *Context) Metrics() *RouteMetrics {
+	return &RouteMetrics{m: c.cherry.metrics, route: c.route}
+}
+
+// Counter returns the counter with the given name for the route.
+func (rm *RouteMetrics) Counter(name string, labels ...string) *Counter {
+	return rm.m.Counter(name, rm.labels(labels)...)
+}
+
+// Gauge returns the gauge with the given name for the route.
+func (rm *RouteMetrics) Gauge(name string, labels ...string) *Gauge {
+	return rm.m.Gauge(name, rm.labels(labels)...)
+}
+
+// Histogram returns the histogram with the given name for the route.
+func (rm *RouteMetrics) Histogram(name string, labels ...string) *Histogram {
+	return rm.m.Histogram(name, rm.labels(labels)...)
+}
+
+// labels appends the route label to a copy of labels, so the spare capacity
+// of the caller's slice is never written.
+func (rm *RouteMetrics) labels(labels []string) []string {
+	return append(labels[:len(labels):len(labels)], "route", rm.route)
+}
@@ -0,0 +1,75 @@
+package cherry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	c := New()
+	c.Get("/metrics", c.Metrics().Handler())
+	c.Post("/orders/:id", func(ctx *Context) error {
+		ctx.Metrics().Counter("orders_created_total", "method", "card").Inc()
+		ctx.Metrics().Gauge("orders_pending").Add(2)
+		return ctx.Text(http.StatusCreated, "created")
+	})
+
+	doRequest(t, "POST", "/orders/1", nil, c)
+	doRequest(t, "POST", "/orders/2", nil, c)
+	code, body := doRequest(t, "GET", "/metrics", nil, c)
+	isHTTPStatusOK(t, code)
+
+	for _, line := range []string{
+		"# TYPE orders_created_total counter",
+		`orders_created_total{method="card",route="/orders/:id"} 2`,
+		`orders_pending{route="/orders/:id"} 4`,
+		`cherry_requests_total{method="POST",route="/orders/:id",status="201"} 2`,
+		"# TYPE cherry_request_duration_seconds histogram",
+		`cherry_request_duration_seconds_bucket{method="POST",route="/orders/:id",le="+Inf"} 2`,
+		`cherry_request_duration_seconds_count{method="POST",route="/orders/:id"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expecting metrics to contain %s got\n%s", line, body)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	m := NewMetrics()
+	h := m.Histogram("latency")
+	h.Observe(0.003)
+	h.Observe(0.2)
+	h.Observe(20)
+	b := &strings.Builder{}
+	m.WriteTo(b)
+	for _, line := range []string{
+		`latency_bucket{le="0.005"} 1`,
+		`latency_bucket{le="0.25"} 2`,
+		`latency_bucket{le="10"} 2`,
+		`latency_bucket{le="+Inf"} 3`,
+		`latency_sum 20.203`,
+		`latency_count 3`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("expecting metrics to contain %s got\n%s", line, b.String())
+		}
+	}
+}
+
+func TestRouteMetricsLabels(t *testing.T) {
+	c := New()
+	labels := make([]string, 2, 4)
+	labels[0], labels[1] = "method", "card"
+	spare := labels[:4]
+	spare[2], spare[3] = "kept", "kept"
+	c.Get("/orders", func(ctx *Context) error {
+		ctx.Metrics().Counter("orders_total", labels...).Inc()
+		return nil
+	})
+
+	doRequest(t, "GET", "/orders", nil, c)
+	if spare[2] != "kept" || spare[3] != "kept" {
+		t.Errorf("expecting the spare capacity of the labels untouched got %v", spare)
+	}
+}
@@ -0,0 +1,115 @@
+package cherry
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pooulad/cherry/utils"
+)
+
+// Mode switches the behaviour that differs between development and
+// production in one place.
+type Mode int32
+
+const (
+	// DebugMode shows the errors of handlers in the responses, colors the
+	// output and prints the routes when the server starts. It is the
+	// default.
+	DebugMode Mode = iota
+	// ReleaseMode hides the messages of internal errors from clients and
+	// keeps the output plain.
+	ReleaseMode
+	// TestMode behaves like DebugMode but keeps the output quiet, no banner
+	// and no routes are printed.
+	TestMode
+)
+
+// modeEnv is the environment variable that sets the initial mode.
+const modeEnv = "CHERRY_MODE"
+
+var mode atomic.Int32
+
+// modeErr is the error of an unknown CHERRY_MODE, which is logged when a
+// server starts.
+var modeErr error
+
+func init() {
+	modeErr = setModeFromEnv(os.Getenv(modeEnv))
+}
+
+// setModeFromEnv sets the mode of the CHERRY_MODE variable. An unknown
+// value falls back to ReleaseMode, falling back to DebugMode would show the
+// errors of a production server to its clients because of a typo.
+func setModeFromEnv(value string) error {
+	m, err := ParseMode(value)
+	if err != nil {
+		SetMode(ReleaseMode)
+		return fmt.Errorf("%w in %s, using release mode", err, modeEnv)
+	}
+	SetMode(m)
+	return nil
+}
+
+// SetMode sets the mode of all applications. The initial mode is read from
+// the CHERRY_MODE environment variable and defaults to DebugMode, an unknown
+// value of the variable is ReleaseMode and is logged when a server starts.
+//
+// cherry.SetMode(cherry.ReleaseMode).
+func SetMode(m Mode) {
+	mode.Store(int32(m))
+}
+
+// CurrentMode returns the mode set with SetMode.
+func CurrentMode() Mode {
+	return Mode(mode.Load())
+}
+
+// ParseMode parses "debug", "release" or "test". An empty string is
+// DebugMode.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "debug", "dev", "development":
+		return DebugMode, nil
+	case "release", "prod", "production":
+		return ReleaseMode, nil
+	case "test":
+		return TestMode, nil
+	}
+	return DebugMode, fmt.Errorf("cherry: unknown mode %q", s)
+}
+
+func (m Mode) String() string {
+	switch m {
+	case DebugMode:
+		return "debug"
+	case ReleaseMode:
+		return "release"
+	case TestMode:
+		return "test"
+	}
+	return fmt.Sprintf("Mode(%d)", int32(m))
+}
+
+// IsDebug reports whether the current mode shows details meant for
+// developers, which is true in DebugMode and TestMode.
+func IsDebug() bool {
+	return CurrentMode() != ReleaseMode
+}
+
+// printStartup prints the banner, and the routes in DebugMode, before the
+// first server starts. It is silent when a Logger is set.
+func (c *Cherry) printStartup() {
+	if c.Logger != nil {
+		return
+	}
+	switch CurrentMode() {
+	case TestMode:
+	case ReleaseMode:
+		fmt.Fprint(c.Output, string(banner)+"\n")
+	default:
+		fmt.Fprint(c.Output, utils.Colorize(utils.ColorRed, string(banner))+"\n")
+		c.PrintRoutes(c.Output)
+	}
+}
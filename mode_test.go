@@ -0,0 +1,118 @@
+package cherry
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func setMode(t *testing.T, m Mode) {
+	old := CurrentMode()
+	SetMode(m)
+	t.Cleanup(func() { SetMode(old) })
+}
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]Mode{
+		"":           DebugMode,
+		"debug":      DebugMode,
+		"Release":    ReleaseMode,
+		"production": ReleaseMode,
+		"test":       TestMode,
+	}
+	for s, expect := range tests {
+		m, err := ParseMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != expect {
+			t.Errorf("%q: expecting %s got %s", s, expect, m)
+		}
+	}
+	if _, err := ParseMode("staging"); err == nil {
+		t.Error("expecting an error for an unknown mode")
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	setMode(t, DebugMode)
+	if err := setModeFromEnv("release"); err != nil || CurrentMode() != ReleaseMode {
+		t.Errorf("expecting release mode got %s %v", CurrentMode(), err)
+	}
+
+	SetMode(DebugMode)
+	err := setModeFromEnv("prodution")
+	if err == nil || !strings.Contains(err.Error(), `"prodution"`) || !strings.Contains(err.Error(), "CHERRY_MODE") {
+		t.Errorf("expecting an error with the invalid value got %v", err)
+	}
+	if CurrentMode() != ReleaseMode {
+		t.Errorf("expecting an invalid value to fall back to release mode got %s", CurrentMode())
+	}
+
+	modeErr = err
+	defer func() { modeErr = nil }()
+	var out bytes.Buffer
+	c := New()
+	c.Output = &out
+	serveTest(t, c)
+	if !strings.Contains(out.String(), "prodution") {
+		t.Errorf("expecting the invalid mode to be logged on start got %q", out.String())
+	}
+}
+
+func TestReleaseModeErrors(t *testing.T) {
+	c := New()
+	c.Get("/internal", func(ctx *Context) error {
+		return errors.New("database password is wrong")
+	})
+	c.Get("/http", func(ctx *Context) error {
+		return NewHTTPError(http.StatusBadRequest, "name is required")
+	})
+
+	setMode(t, DebugMode)
+	_, body := doRequest(t, "GET", "/internal", nil, c)
+	if !strings.Contains(body, "database password is wrong") {
+		t.Errorf("expecting the error in debug mode got %s", body)
+	}
+
+	SetMode(ReleaseMode)
+	code, body := doRequest(t, "GET", "/internal", nil, c)
+	if code != http.StatusInternalServerError {
+		t.Errorf("expecting 500 got %d", code)
+	}
+	if strings.TrimSpace(body) != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expecting the status text in release mode got %s", body)
+	}
+	_, body = doRequest(t, "GET", "/http", nil, c)
+	if strings.TrimSpace(body) != "name is required" {
+		t.Errorf("expecting the HTTPError message got %s", body)
+	}
+}
+
+func TestPrintStartup(t *testing.T) {
+	var out bytes.Buffer
+	c := New(WithOutput(&out))
+	c.Get("/foo", func(ctx *Context) error { return nil })
+
+	setMode(t, DebugMode)
+	c.printStartup()
+	if !strings.Contains(out.String(), "GET     /foo") {
+		t.Errorf("expecting the routes in debug mode got %s", out.String())
+	}
+
+	out.Reset()
+	SetMode(ReleaseMode)
+	c.printStartup()
+	if strings.Contains(out.String(), "/foo") || strings.Contains(out.String(), "\u001b[") {
+		t.Errorf("expecting a plain banner in release mode got %s", out.String())
+	}
+
+	out.Reset()
+	SetMode(TestMode)
+	c.printStartup()
+	if out.Len() != 0 {
+		t.Errorf("expecting no output in test mode got %s", out.String())
+	}
+}
@@ -0,0 +1,423 @@
+package cherry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI is an OpenAPI 3 document of an application, which validates the
+// requests and responses of the operations it describes, see
+// OpenAPI.Validate. The paths of the document are matched against the route
+// patterns, /users/{id} is the route /users/:id.
+type OpenAPI struct {
+	doc openAPIDoc
+	ops map[routeKey]*openAPIOperation
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                      `yaml:"openapi"`
+	Paths      map[string]*openAPIPathItem `yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*openAPISchema      `yaml:"schemas"`
+		Parameters    map[string]*openAPIParameter   `yaml:"parameters"`
+		RequestBodies map[string]*openAPIRequestBody `yaml:"requestBodies"`
+		Responses     map[string]*openAPIResponse    `yaml:"responses"`
+		Examples      map[string]*openAPIExample     `yaml:"examples"`
+	} `yaml:"components"`
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation   `yaml:"get"`
+	Put        *openAPIOperation   `yaml:"put"`
+	Post       *openAPIOperation   `yaml:"post"`
+	Delete     *openAPIOperation   `yaml:"delete"`
+	Options    *openAPIOperation   `yaml:"options"`
+	Head       *openAPIOperation   `yaml:"head"`
+	Patch      *openAPIOperation   `yaml:"patch"`
+}
+
+func (p *openAPIPathItem) operations() map[string]*openAPIOperation {
+	return map[string]*openAPIOperation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"OPTIONS": p.Options, "HEAD": p.Head, "PATCH": p.Patch,
+	}
+}
+
+type openAPIOperation struct {
+	OperationID string                      `yaml:"operationId"`
+	Parameters  []*openAPIParameter         `yaml:"parameters"`
+	RequestBody *openAPIRequestBody         `yaml:"requestBody"`
+	Responses   map[string]*openAPIResponse `yaml:"responses"`
+}
+
+type openAPIParameter struct {
+	Ref      string         `yaml:"$ref"`
+	Name     string         `yaml:"name"`
+	In       string         `yaml:"in"`
+	Required bool           `yaml:"required"`
+	Explode  *bool          `yaml:"explode"`
+	Schema   *openAPISchema `yaml:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Ref      string                       `yaml:"$ref"`
+	Required bool                         `yaml:"required"`
+	Content  map[string]*openAPIMediaType `yaml:"content"`
+}
+
+type openAPIResponse struct {
+	Ref     string                       `yaml:"$ref"`
+	Content map[string]*openAPIMediaType `yaml:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema             `yaml:"schema"`
+	Example  any                        `yaml:"example"`
+	Examples map[string]*openAPIExample `yaml:"examples"`
+}
+
+type openAPIExample struct {
+	Ref   string `yaml:"$ref"`
+	Value any    `yaml:"value"`
+}
+
+// openAPISchema is the subset of JSON Schema used by OpenAPI 3.0 and 3.1.
+type openAPISchema struct {
+	Ref                  string                    `yaml:"$ref"`
+	Type                 openAPITypes              `yaml:"type"`
+	Format               string                    `yaml:"format"`
+	Nullable             bool                      `yaml:"nullable"`
+	Enum                 []any                     `yaml:"enum"`
+	Properties           map[string]*openAPISchema `yaml:"properties"`
+	Required             []string                  `yaml:"required"`
+	AdditionalProperties *openAPIAdditional        `yaml:"additionalProperties"`
+	Items                *openAPISchema            `yaml:"items"`
+	Minimum              *float64                  `yaml:"minimum"`
+	Maximum              *float64                  `yaml:"maximum"`
+	// ExclusiveMinimum and ExclusiveMaximum are booleans in OpenAPI 3.0 and
+	// numbers in 3.1.
+	ExclusiveMinimum any              `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum any              `yaml:"exclusiveMaximum"`
+	MinLength        *int             `yaml:"minLength"`
+	MaxLength        *int             `yaml:"maxLength"`
+	Pattern          string           `yaml:"pattern"`
+	MinItems         *int             `yaml:"minItems"`
+	MaxItems         *int             `yaml:"maxItems"`
+	UniqueItems      bool             `yaml:"uniqueItems"`
+	AllOf            []*openAPISchema `yaml:"allOf"`
+	AnyOf            []*openAPISchema `yaml:"anyOf"`
+	OneOf            []*openAPISchema `yaml:"oneOf"`
+	Not              *openAPISchema   `yaml:"not"`
+	Example          any              `yaml:"example"`
+	Default          any              `yaml:"default"`
+	ReadOnly         bool             `yaml:"readOnly"`
+	WriteOnly        bool             `yaml:"writeOnly"`
+
+	pattern *regexp.Regexp
+}
+
+// openAPITypes is the type of a schema, a list of types in OpenAPI 3.1.
+type openAPITypes []string
+
+func (t *openAPITypes) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*t = openAPITypes{n.Value}
+		return nil
+	}
+	var types []string
+	if err := n.Decode(&types); err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+func (t openAPITypes) has(typ string) bool {
+	for _, s := range t {
+		if s == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// openAPIAdditional is the additionalProperties of a schema, which is a
+// boolean or a schema of the additional properties.
+type openAPIAdditional struct {
+	allowed bool
+	schema  *openAPISchema
+}
+
+func (a *openAPIAdditional) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&a.allowed)
+	}
+	a.allowed = true
+	a.schema = &openAPISchema{}
+	return n.Decode(a.schema)
+}
+
+// LoadOpenAPI reads the OpenAPI 3 document at path, which is YAML or JSON.
+//
+// doc, err := cherry.LoadOpenAPI("openapi.yaml").
+func LoadOpenAPI(path string) (*OpenAPI, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ParseOpenAPI(b)
+	if err != nil {
+		return nil, fmt.Errorf("cherry: loading %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// ParseOpenAPI parses an OpenAPI 3 document in YAML or JSON. References are
+// resolved within the components of the document, references to other
+// documents are not supported.
+func ParseOpenAPI(data []byte) (*OpenAPI, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		// JSON documents are converted, YAML does not allow the tabs that
+		// JSON is often indented with.
+		var v any
+		if err := json.Unmarshal(trimmed, &v); err != nil {
+			return nil, fmt.Errorf("cherry: parsing the OpenAPI document: %w", err)
+		}
+		data, _ = yaml.Marshal(v)
+	}
+	d := &OpenAPI{ops: map[routeKey]*openAPIOperation{}}
+	if err := yaml.Unmarshal(data, &d.doc); err != nil {
+		return nil, fmt.Errorf("cherry: parsing the OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(d.doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("cherry: unsupported OpenAPI version %q", d.doc.OpenAPI)
+	}
+	for _, name := range sortedNames(d.doc.Components.Schemas) {
+		if err := d.prepareSchema(d.doc.Components.Schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range sortedNames(d.doc.Paths) {
+		item := d.doc.Paths[path]
+		for method, op := range item.operations() {
+			if op == nil {
+				continue
+			}
+			if err := d.prepareOperation(op, item.Parameters); err != nil {
+				return nil, fmt.Errorf("%w in %s %s", err, method, path)
+			}
+			d.ops[routeKey{method, openAPIRoute(path)}] = op
+		}
+	}
+	return d, nil
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openAPIRoute returns the route pattern of a path of the document.
+func openAPIRoute(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}' {
+			segments[i] = ":" + s[1:len(s)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operation returns the operation of a route, nil when the document does
+// not describe it.
+func (d *OpenAPI) operation(method, route string) *openAPIOperation {
+	return d.ops[routeKey{method, route}]
+}
+
+// prepareOperation resolves the references of an operation and merges the
+// parameters of its path into its own.
+func (d *OpenAPI) prepareOperation(op *openAPIOperation, shared []*openAPIParameter) error {
+	var params []*openAPIParameter
+	for _, list := range [][]*openAPIParameter{shared, op.Parameters} {
+		for _, p := range list {
+			p, err := d.resolveParameter(p)
+			if err != nil {
+				return err
+			}
+			if err := d.prepareSchema(p.Schema); err != nil {
+				return err
+			}
+			replaced := false
+			for i, prev := range params {
+				if prev.Name == p.Name && prev.In == p.In {
+					params[i], replaced = p, true
+				}
+			}
+			if !replaced {
+				params = append(params, p)
+			}
+		}
+	}
+	op.Parameters = params
+
+	if op.RequestBody != nil && op.RequestBody.Ref != "" {
+		name, err := refName(op.RequestBody.Ref, "requestBodies")
+		if err != nil {
+			return err
+		}
+		if op.RequestBody = d.doc.Components.RequestBodies[name]; op.RequestBody == nil {
+			return fmt.Errorf("cherry: unknown OpenAPI reference %q", name)
+		}
+	}
+	if op.RequestBody != nil {
+		if err := d.prepareContent(op.RequestBody.Content); err != nil {
+			return err
+		}
+	}
+	for code, res := range op.Responses {
+		if res != nil && res.Ref != "" {
+			name, err := refName(res.Ref, "responses")
+			if err != nil {
+				return err
+			}
+			if res = d.doc.Components.Responses[name]; res == nil {
+				return fmt.Errorf("cherry: unknown OpenAPI reference %q", name)
+			}
+			op.Responses[code] = res
+		}
+		if res != nil {
+			if err := d.prepareContent(res.Content); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *OpenAPI) resolveParameter(p *openAPIParameter) (*openAPIParameter, error) {
+	if p == nil || p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	if p = d.doc.Components.Parameters[name]; p == nil {
+		return nil, fmt.Errorf("cherry: unknown OpenAPI reference %q", name)
+	}
+	return p, nil
+}
+
+func (d *OpenAPI) prepareContent(content map[string]*openAPIMediaType) error {
+	for _, mt := range content {
+		if mt == nil {
+			continue
+		}
+		if err := d.prepareSchema(mt.Schema); err != nil {
+			return err
+		}
+		for key, ex := range mt.Examples {
+			if ex == nil || ex.Ref == "" {
+				continue
+			}
+			name, err := refName(ex.Ref, "examples")
+			if err != nil {
+				return err
+			}
+			if mt.Examples[key] = d.doc.Components.Examples[name]; mt.Examples[key] == nil {
+				return fmt.Errorf("cherry: unknown OpenAPI reference %q", ex.Ref)
+			}
+		}
+	}
+	return nil
+}
+
+// prepareSchema checks the references of a schema, compiles its patterns
+// and converts its enums to the values that encoding/json decodes.
+func (d *OpenAPI) prepareSchema(s *openAPISchema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			return err
+		}
+		if _, ok := d.doc.Components.Schemas[name]; !ok {
+			return fmt.Errorf("cherry: unknown OpenAPI reference %q", s.Ref)
+		}
+		return nil
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("cherry: OpenAPI pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for i, v := range s.Enum {
+		s.Enum[i] = jsonValue(v)
+	}
+	children := append(append(append([]*openAPISchema{s.Items, s.Not}, s.AllOf...), s.AnyOf...), s.OneOf...)
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.schema)
+	}
+	for _, name := range sortedNames(s.Properties) {
+		children = append(children, s.Properties[name])
+	}
+	for _, child := range children {
+		if err := d.prepareSchema(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the schema a reference refers to.
+func (d *OpenAPI) resolve(s *openAPISchema) *openAPISchema {
+	// a chain of references ends after a few hops unless it is a cycle.
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		name, _ := refName(s.Ref, "schemas")
+		s = d.doc.Components.Schemas[name]
+	}
+	if s != nil && s.Ref != "" {
+		return nil
+	}
+	return s
+}
+
+var refEscapes = strings.NewReplacer("~1", "/", "~0", "~")
+
+// refName returns the name of a reference to the components of a kind, like
+// #/components/schemas/User.
+func refName(ref, kind string) (string, error) {
+	name, ok := strings.CutPrefix(ref, "#/components/"+kind+"/")
+	if !ok {
+		return "", fmt.Errorf("cherry: unsupported OpenAPI reference %q", ref)
+	}
+	return refEscapes.Replace(name), nil
+}
+
+// jsonValue returns v, decoded from YAML, as encoding/json decodes it into
+// an any, numbers are float64s.
+func jsonValue(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	json.Unmarshal(b, &out)
+	return out
+}
@@ -0,0 +1,230 @@
+package cherry
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openAPIMethods are the methods of the operations of a path, in the order
+// they are mocked.
+var openAPIMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// Mock registers a route for every operation of the document that the
+// application has no route for yet, which responds with the example of the
+// operation, so clients can be developed before the backend exists. Call it
+// after the routes are registered. The mocked routes are tagged "mock".
+//
+// A mocked route responds with the first successful response of the
+// operation, its example or the first of its examples, or a value generated
+// from its schema. The Prefer request header picks another response or
+// example, like Prefer: code=404, example=missing.
+//
+// app.Mock(doc).
+func (c *Cherry) Mock(doc *OpenAPI) {
+	existing := map[routeKey]bool{}
+	for _, r := range c.Routes() {
+		existing[routeKey{r.Method, r.Path}] = true
+	}
+	for _, path := range sortedNames(doc.doc.Paths) {
+		route := openAPIRoute(path)
+		ops := doc.doc.Paths[path].operations()
+		for _, method := range openAPIMethods {
+			op := ops[method]
+			if op == nil || existing[routeKey{method, route}] {
+				continue
+			}
+			c.add(method, route, doc.mockHandler(op)).Tag("mock")
+		}
+	}
+}
+
+func (d *OpenAPI) mockHandler(op *openAPIOperation) Handler {
+	return func(ctx *Context) error {
+		prefer := parsePrefer(ctx.Request().Header.Get("Prefer"))
+		code, res := mockResponse(op.Responses, prefer["code"])
+		if res == nil {
+			return NewHTTPError(http.StatusNotImplemented, "the operation has no response to mock")
+		}
+		if len(res.Content) == 0 {
+			ctx.Response().WriteHeader(code)
+			return nil
+		}
+		types := sortedNames(res.Content)
+		// JSON is preferred when the client accepts any type.
+		sort.SliceStable(types, func(i, j int) bool { return isJSONType(types[i]) && !isJSONType(types[j]) })
+		ct := ctx.Accepts(types...)
+		if ct == "" {
+			return NewHTTPError(http.StatusNotAcceptable)
+		}
+		mt := res.Content[ct]
+		if mt == nil {
+			mt = &openAPIMediaType{}
+		}
+		example := d.mockExample(mt, prefer["example"])
+		if s, ok := example.(string); ok && !isJSONType(ct) {
+			return ctx.Blob(code, ct, []byte(s))
+		}
+		b, err := json.Marshal(example)
+		if err != nil {
+			return err
+		}
+		return ctx.Blob(code, ct, b)
+	}
+}
+
+// mockResponse returns the response of a code, or the first successful
+// response of the operation when code is empty.
+func mockResponse(responses map[string]*openAPIResponse, code string) (int, *openAPIResponse) {
+	if n, err := strconv.Atoi(code); err == nil {
+		return n, openAPIStatus(responses, n)
+	}
+	var fallback string
+	for _, key := range sortedNames(responses) {
+		if n, err := strconv.Atoi(key); err == nil && n >= 200 && n < 300 {
+			return n, responses[key]
+		}
+		if fallback == "" && (strings.EqualFold(key, "2XX") || key == "default") {
+			fallback = key
+		}
+	}
+	if fallback != "" {
+		return http.StatusOK, responses[fallback]
+	}
+	return 0, nil
+}
+
+// parsePrefer parses the preferences of a Prefer header, like
+// code=404, example=missing.
+func parsePrefer(header string) map[string]string {
+	prefs := map[string]string{}
+	for _, pref := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pref), "=")
+		prefs[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return prefs
+}
+
+// mockExample returns the named example of a media type, its example or a
+// value generated from its schema.
+func (d *OpenAPI) mockExample(mt *openAPIMediaType, name string) any {
+	if ex := mt.Examples[name]; ex != nil {
+		return ex.Value
+	}
+	if mt.Example != nil {
+		return mt.Example
+	}
+	if names := sortedNames(mt.Examples); len(names) > 0 && mt.Examples[names[0]] != nil {
+		return mt.Examples[names[0]].Value
+	}
+	v, _ := d.generate(mt.Schema, map[*openAPISchema]bool{})
+	return v
+}
+
+// generate returns a value of a schema, its example or default, or else the
+// zero value of its type with the properties of objects and one item in
+// arrays. It returns false for a schema that is already being generated,
+// recursive properties, like the parent of a tree node, are left out.
+func (d *OpenAPI) generate(s *openAPISchema, visiting map[*openAPISchema]bool) (any, bool) {
+	s = d.resolve(s)
+	if s == nil {
+		return nil, true
+	}
+	if visiting[s] {
+		return nil, false
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+	switch {
+	case s.Example != nil:
+		return s.Example, true
+	case s.Default != nil:
+		return s.Default, true
+	case len(s.Enum) > 0:
+		return s.Enum[0], true
+	case len(s.AllOf) > 0:
+		obj := map[string]any{}
+		for _, sub := range s.AllOf {
+			v, _ := d.generate(sub, visiting)
+			if m, ok := v.(map[string]any); ok {
+				for k, v := range m {
+					obj[k] = v
+				}
+			}
+		}
+		for k, v := range d.generateProperties(s, visiting) {
+			obj[k] = v
+		}
+		return obj, true
+	case len(s.OneOf) > 0:
+		return d.generate(s.OneOf[0], visiting)
+	case len(s.AnyOf) > 0:
+		return d.generate(s.AnyOf[0], visiting)
+	}
+	var typ string
+	for _, t := range s.Type {
+		if t != "null" {
+			typ = t
+			break
+		}
+	}
+	switch {
+	case typ == "object" || (typ == "" && s.Properties != nil):
+		return d.generateProperties(s, visiting), true
+	case typ == "array":
+		if item, ok := d.generate(s.Items, visiting); ok && s.Items != nil {
+			return []any{item}, true
+		}
+		return []any{}, true
+	case typ == "integer" || typ == "number":
+		if min, exclusive, ok := bound(s.Minimum, s.ExclusiveMinimum); ok {
+			if exclusive {
+				min++
+			}
+			return min, true
+		}
+		return 0, true
+	case typ == "boolean":
+		return false, true
+	case typ == "string":
+		return mockString(s), true
+	}
+	return nil, true
+}
+
+func (d *OpenAPI) generateProperties(s *openAPISchema, visiting map[*openAPISchema]bool) map[string]any {
+	obj := map[string]any{}
+	for _, name := range sortedNames(s.Properties) {
+		if v, ok := d.generate(s.Properties[name], visiting); ok {
+			obj[name] = v
+		}
+	}
+	return obj
+}
+
+// mockFormats are the examples of the string formats.
+var mockFormats = map[string]string{
+	"date-time": "2024-01-01T00:00:00Z",
+	"date":      "2024-01-01",
+	"email":     "user@example.com",
+	"uri":       "https://example.com",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+}
+
+func mockString(s *openAPISchema) string {
+	if ex, ok := mockFormats[s.Format]; ok {
+		return ex
+	}
+	str := "string"
+	if s.MinLength != nil && len(str) < *s.MinLength {
+		str += strings.Repeat("s", *s.MinLength-len(str))
+	}
+	if s.MaxLength != nil && len(str) > *s.MaxLength {
+		str = str[:*s.MaxLength]
+	}
+	return str
+}
@@ -0,0 +1,157 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testMockOpenAPI = `
+openapi: 3.0.3
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: '#/components/schemas/User'}
+    post:
+      responses:
+        "201":
+          content:
+            application/json:
+              examples:
+                ann: {$ref: '#/components/examples/ann'}
+                bob: {value: {id: 2, name: Bob}}
+        "409":
+          content:
+            application/json:
+              example: {error: the email is taken}
+  /users/{id}:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              example: {id: 1, name: Ann}
+            text/plain:
+              example: Ann
+        "404":
+          description: not found
+    delete:
+      responses:
+        "204":
+          description: deleted
+  /health:
+    get:
+      responses:
+        "200":
+          content:
+            text/plain:
+              schema: {type: string, example: ok}
+components:
+  examples:
+    ann:
+      value: {id: 1, name: Ann}
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: integer, minimum: 1}
+        email: {type: string, format: email}
+        tags: {type: array, items: {type: string, enum: [new, vip]}}
+        active: {type: boolean}
+        parent: {$ref: '#/components/schemas/User'}
+`
+
+func TestMock(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(testMockOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Get("/health", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "healthy")
+	})
+	c.Mock(doc)
+
+	tests := []struct {
+		method string
+		route  string
+		header map[string]string
+		code   int
+		body   string
+	}{
+		{"GET", "/health", nil, http.StatusOK, "healthy"},
+		{"GET", "/users/7", nil, http.StatusOK, `{"id":1,"name":"Ann"}`},
+		{"GET", "/users/7", map[string]string{"Accept": "text/plain"}, http.StatusOK, "Ann"},
+		{"GET", "/users/7", map[string]string{"Accept": "image/png"}, http.StatusNotAcceptable, ""},
+		{"GET", "/users/7", map[string]string{"Prefer": "code=404"}, http.StatusNotFound, ""},
+		{"GET", "/users/7", map[string]string{"Prefer": "code=500"}, http.StatusNotImplemented, ""},
+		{"DELETE", "/users/7", nil, http.StatusNoContent, ""},
+		{"POST", "/users", nil, http.StatusCreated, `{"id":1,"name":"Ann"}`},
+		{"POST", "/users", map[string]string{"Prefer": "example=bob"}, http.StatusCreated, `{"id":2,"name":"Bob"}`},
+		{"POST", "/users", map[string]string{"Prefer": `code=409, example="none"`}, http.StatusConflict, `{"error":"the email is taken"}`},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.route, nil)
+		for k, v := range test.header {
+			r.Header.Set(k, v)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code || (test.body != "" && strings.TrimSpace(rw.Body.String()) != test.body) {
+			t.Errorf("%s %s %v: expecting %d %s got %d %s", test.method, test.route, test.header, test.code, test.body, rw.Code, rw.Body)
+		}
+	}
+
+	mocked := 0
+	for _, r := range c.Routes() {
+		if len(r.Tags) == 1 && r.Tags[0] == "mock" {
+			mocked++
+		}
+	}
+	if mocked != 4 {
+		t.Errorf("expecting 4 mocked routes got %d", mocked)
+	}
+}
+
+func TestMockGenerate(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(testMockOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Mock(doc)
+	code, body := doRequest(t, "GET", "/users", nil, c)
+	if code != http.StatusOK || !strings.HasPrefix(body, `[{"active":false,"email":"user@example.com","id":1,"tags":["new"]}]`) {
+		t.Errorf("expecting users generated from the schema got %d %s", code, body)
+	}
+
+	// generated values are valid against their schemas, without the
+	// recursive parent.
+	user, _ := doc.generate(&openAPISchema{Ref: "#/components/schemas/User"}, map[*openAPISchema]bool{})
+	v := &schemaValidator{doc: doc}
+	v.validate(&openAPISchema{Ref: "#/components/schemas/User"}, jsonValue(user), "")
+	if len(v.errs) > 0 {
+		t.Errorf("expecting a valid user got %v", v.errs)
+	}
+	min := 3
+	if s := mockString(&openAPISchema{MaxLength: &min}); s != "str" {
+		t.Errorf("expecting the string to be cut to its max length got %q", s)
+	}
+}
+
+func TestParsePrefer(t *testing.T) {
+	prefs := parsePrefer(`code=404, Example="missing", respond-async`)
+	if prefs["code"] != "404" || prefs["example"] != "missing" {
+		t.Errorf("unexpected preferences %v", prefs)
+	}
+	if _, ok := prefs["respond-async"]; !ok {
+		t.Errorf("expecting the preference without a value got %v", prefs)
+	}
+}
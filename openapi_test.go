@@ -0,0 +1,177 @@
+package cherry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOpenAPI = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: "1.0"
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - $ref: '#/components/parameters/limit'
+        - name: tags
+          in: query
+          schema:
+            type: array
+            items: {type: string}
+            maxItems: 2
+      responses:
+        200:
+          description: the users
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: '#/components/schemas/User'}
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        "201":
+          description: the user
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+        4XX:
+          $ref: '#/components/responses/Error'
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema: {type: integer, minimum: 1}
+    get:
+      responses:
+        "200":
+          description: the user
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+components:
+  parameters:
+    limit:
+      name: limit
+      in: query
+      schema: {type: integer, maximum: 100}
+  responses:
+    Error:
+      description: an error
+      content:
+        text/plain: {}
+  schemas:
+    User:
+      type: object
+      required: [id, name, email]
+      additionalProperties: false
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string, minLength: 2, pattern: '^[A-Z]'}
+        email: {type: string, format: email}
+        role: {type: string, enum: [admin, member]}
+        age: {type: integer, exclusiveMinimum: true, minimum: 0}
+        address: {$ref: '#/components/schemas/Address'}
+        nickname: {type: string, nullable: true}
+    Address:
+      type: object
+      required: [city]
+      properties:
+        city: {type: string}
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(testOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []routeKey{{"GET", "/users"}, {"POST", "/users"}, {"GET", "/users/:id"}} {
+		if doc.operation(key.method, key.path) == nil {
+			t.Errorf("expecting an operation for %s %s", key.method, key.path)
+		}
+	}
+	list := doc.operation("GET", "/users")
+	if list.OperationID != "listUsers" || len(list.Parameters) != 2 || list.Parameters[0].Name != "limit" {
+		t.Errorf("expecting the parameters of listUsers to be resolved got %+v", list.Parameters)
+	}
+	if list.Responses["200"] == nil {
+		t.Error("expecting the response of a numeric status code")
+	}
+	show := doc.operation("GET", "/users/:id")
+	if len(show.Parameters) != 1 || show.Parameters[0].Name != "id" {
+		t.Errorf("expecting the parameters of the path got %+v", show.Parameters)
+	}
+	if res := doc.operation("POST", "/users").Responses["4XX"]; res == nil || res.Content["text/plain"] == nil {
+		t.Errorf("expecting the referenced response got %+v", res)
+	}
+	user := doc.resolve(&openAPISchema{Ref: "#/components/schemas/User"})
+	if user == nil || user.Properties["name"].pattern == nil || user.Properties["role"].Enum[0] != "admin" {
+		t.Errorf("expecting the prepared User schema got %+v", user)
+	}
+}
+
+func TestParseOpenAPIJSON(t *testing.T) {
+	src := "{\n\t\"openapi\": \"3.1.0\",\n\t\"paths\": {\"/ping\": {\"get\": {\"responses\": {\"204\": {\"description\": \"pong\"}}}}}\n}"
+	doc, err := ParseOpenAPI([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.operation("GET", "/ping") == nil {
+		t.Error("expecting the operation of the JSON document")
+	}
+}
+
+func TestParseOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"swagger: '2.0'", "unsupported OpenAPI version"},
+		{"openapi: 3.0.0\ncomponents:\n  schemas:\n    A: {$ref: '#/components/schemas/B'}", "unknown OpenAPI reference"},
+		{"openapi: 3.0.0\ncomponents:\n  schemas:\n    A: {$ref: 'other.yaml#/A'}", "unsupported OpenAPI reference"},
+		{"openapi: 3.0.0\ncomponents:\n  schemas:\n    A: {type: string, pattern: '('}", "OpenAPI pattern"},
+		{"openapi: 3.0.0\npaths:\n  /a:\n    get:\n      parameters: [{$ref: '#/components/parameters/x'}]", "in GET /a"},
+		{"openapi: [", "parsing the OpenAPI document"},
+	}
+	for _, test := range tests {
+		if _, err := ParseOpenAPI([]byte(test.src)); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expecting an error with %q got %v", test.src, test.err, err)
+		}
+	}
+}
+
+func TestLoadOpenAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(path, []byte(testOpenAPI), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOpenAPI(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOpenAPI(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expecting an error for a missing document")
+	}
+}
+
+func TestOpenAPIRoute(t *testing.T) {
+	tests := map[string]string{
+		"/users":                  "/users",
+		"/users/{id}":             "/users/:id",
+		"/users/{id}/posts/{pid}": "/users/:id/posts/:pid",
+	}
+	for path, route := range tests {
+		if got := openAPIRoute(path); got != route {
+			t.Errorf("%s: expecting %s got %s", path, route, got)
+		}
+	}
+}
@@ -0,0 +1,530 @@
+package cherry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OpenAPIOptions configure the validation of OpenAPI.Validate.
+type OpenAPIOptions struct {
+	// Responses validates the responses as well, which buffers them. A
+	// response that does not match the document is answered with a 500
+	// instead. The default is to only validate the requests
+	Responses bool
+}
+
+// openAPIError is a validation error of a request or a response, it is an
+// HTTPError and ValidationErrors, so Context.Fail lists its fields.
+type openAPIError struct {
+	*HTTPError
+	errs ValidationErrors
+}
+
+func (e *openAPIError) Unwrap() []error {
+	return []error{e.HTTPError, e.errs}
+}
+
+// Validate returns a middleware Handler that validates the parameters and
+// bodies of the requests to the routes described by the document. A request
+// that does not match is answered with a 400 that lists the invalid fields,
+// or with a 415 for a content type that is not in the document. Routes that
+// are not in the document are not validated.
+//
+// app.Use(doc.Validate(cherry.OpenAPIOptions{Responses: cherry.IsDebug()})).
+func (d *OpenAPI) Validate(opts OpenAPIOptions) Handler {
+	return func(ctx *Context) error {
+		op := d.operation(ctx.Request().Method, ctx.FullPath())
+		if op == nil {
+			return nil
+		}
+		if err := d.validateRequest(ctx, op); err != nil {
+			return err
+		}
+		if !opts.Responses {
+			return nil
+		}
+		bw := &bufferWriter{ResponseWriter: ctx.Response()}
+		ctx.response = bw
+		ctx.onFinish(func() {
+			ctx.response = bw.ResponseWriter
+			if bw.code == 0 || bw.passthrough {
+				return
+			}
+			if err := d.validateResponse(op, bw); err != nil {
+				ctx.err = err
+				bw.Header().Del("Content-Length")
+				if ctx.cherry != nil {
+					ctx.cherry.ErrorHandler(ctx, err)
+				} else {
+					errorHandler(ctx, err)
+				}
+				return
+			}
+			bw.flush()
+		})
+		return nil
+	}
+}
+
+func (d *OpenAPI) validateRequest(ctx *Context, op *openAPIOperation) error {
+	v := &schemaValidator{doc: d, request: true}
+	r := ctx.Request()
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			values = []string{ctx.Param(p.Name)}
+		case "query":
+			values = ctx.queryValues()[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		case "cookie":
+			if c, err := r.Cookie(p.Name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+		v.validateParameter(p, values)
+	}
+	if rb := op.RequestBody; rb != nil {
+		if err := d.validateRequestBody(v, r, rb); err != nil {
+			return err
+		}
+	}
+	if len(v.errs) > 0 {
+		return &openAPIError{HTTPError: NewHTTPError(http.StatusBadRequest, ctx.ErrorMessage(v.errs)), errs: v.errs}
+	}
+	return nil
+}
+
+// validateRequestBody validates the body of r, which can be read again by
+// the handler.
+func (d *OpenAPI) validateRequestBody(v *schemaValidator, r *http.Request, rb *openAPIRequestBody) error {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				return NewHTTPError(http.StatusRequestEntityTooLarge)
+			}
+			return NewHTTPError(http.StatusBadRequest, "reading the body: "+err.Error())
+		}
+		body = b
+	}
+	if len(body) == 0 {
+		if rb.Required {
+			v.fail("", "required", "", nil)
+		}
+		return nil
+	}
+	ct, mt := openAPIMedia(rb.Content, r.Header.Get("Content-Type"))
+	if mt == nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	if mt.Schema == nil || !isJSONType(ct) {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return NewHTTPError(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+	}
+	v.validate(mt.Schema, value, "")
+	return nil
+}
+
+func (d *OpenAPI) validateResponse(op *openAPIOperation, bw *bufferWriter) error {
+	res := openAPIStatus(op.Responses, bw.code)
+	if res == nil {
+		return NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("the response status %d is not in the OpenAPI document", bw.code))
+	}
+	if len(res.Content) == 0 || bw.buf.Len() == 0 {
+		return nil
+	}
+	contentType := bw.Header().Get("Content-Type")
+	ct, mt := openAPIMedia(res.Content, contentType)
+	if mt == nil {
+		return NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("the response content type %q is not in the OpenAPI document", contentType))
+	}
+	if mt.Schema == nil || !isJSONType(ct) {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(bw.buf.Bytes(), &value); err != nil {
+		return NewHTTPError(http.StatusInternalServerError, "the response is not valid JSON: "+err.Error())
+	}
+	v := &schemaValidator{doc: d}
+	v.validate(mt.Schema, value, "")
+	if len(v.errs) > 0 {
+		return &openAPIError{
+			HTTPError: NewHTTPError(http.StatusInternalServerError, "the response does not match the OpenAPI document: "+v.errs.Error()),
+			errs:      v.errs,
+		}
+	}
+	return nil
+}
+
+// openAPIStatus returns the response of a status code, which is the
+// response of the code, of its range like 4XX or the default response.
+func openAPIStatus(responses map[string]*openAPIResponse, code int) *openAPIResponse {
+	status := strconv.Itoa(code)
+	for _, key := range []string{status, status[:1] + "XX", status[:1] + "xx", "default"} {
+		if res, ok := responses[key]; ok && res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// openAPIMedia returns the media type of content that matches a Content-Type
+// header, a range like image/* matches image/png.
+func openAPIMedia(content map[string]*openAPIMediaType, contentType string) (string, *openAPIMediaType) {
+	ct, _, _ := mime.ParseMediaType(contentType)
+	typ, _, _ := strings.Cut(ct, "/")
+	for _, key := range []string{ct, typ + "/*", "*/*"} {
+		if mt, ok := content[key]; ok {
+			if mt == nil {
+				mt = &openAPIMediaType{}
+			}
+			return ct, mt
+		}
+	}
+	return ct, nil
+}
+
+func isJSONType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// schemaValidator collects the errors of the values validated against the
+// schemas of a document.
+type schemaValidator struct {
+	doc *OpenAPI
+	// request is true for requests, in which the readOnly properties are
+	// not required, and false for responses, in which the writeOnly
+	// properties are not required.
+	request bool
+	errs    ValidationErrors
+}
+
+func (v *schemaValidator) fail(field, tag, param string, value any) {
+	if field == "" {
+		field = "body"
+	}
+	v.errs = append(v.errs, &FieldError{Field: field, Tag: tag, Param: param, Value: value})
+}
+
+// validateParameter validates the values of a parameter, which are
+// converted to the type of its schema.
+func (v *schemaValidator) validateParameter(p *openAPIParameter, values []string) {
+	if len(values) == 0 || (p.In == "path" && values[0] == "") {
+		if p.Required {
+			v.fail(p.Name, "required", "", nil)
+		}
+		return
+	}
+	s := v.doc.resolve(p.Schema)
+	if s == nil {
+		return
+	}
+	if s.Type.has("array") {
+		if len(values) == 1 && (p.In != "query" || (p.Explode != nil && !*p.Explode)) {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]any, len(values))
+		for i, raw := range values {
+			value, ok := v.convert(v.doc.resolve(s.Items), raw, fmt.Sprintf("%s[%d]", p.Name, i))
+			if !ok {
+				return
+			}
+			items[i] = value
+		}
+		v.validate(s, items, p.Name)
+		return
+	}
+	if value, ok := v.convert(s, values[0], p.Name); ok {
+		v.validate(s, value, p.Name)
+	}
+}
+
+// convert converts the string value of a parameter to the type of s.
+func (v *schemaValidator) convert(s *openAPISchema, raw, field string) (any, bool) {
+	if s == nil {
+		return raw, true
+	}
+	switch {
+	case s.Type.has("string") || len(s.Type) == 0:
+		return raw, true
+	case s.Type.has("integer"):
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			v.fail(field, "type", "integer", raw)
+			return nil, false
+		}
+		return float64(n), true
+	case s.Type.has("number"):
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			v.fail(field, "type", "number", raw)
+			return nil, false
+		}
+		return f, true
+	case s.Type.has("boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			v.fail(field, "type", "boolean", raw)
+			return nil, false
+		}
+		return b, true
+	}
+	return raw, true
+}
+
+// validate validates a value decoded by encoding/json against s.
+func (v *schemaValidator) validate(s *openAPISchema, value any, field string) {
+	s = v.doc.resolve(s)
+	if s == nil {
+		return
+	}
+	for _, sub := range s.AllOf {
+		v.validate(sub, value, field)
+	}
+	if len(s.AnyOf) > 0 && v.matches(s.AnyOf, value) == 0 {
+		v.fail(field, "invalid", "", value)
+	}
+	if len(s.OneOf) > 0 && v.matches(s.OneOf, value) != 1 {
+		v.fail(field, "invalid", "", value)
+	}
+	if s.Not != nil && v.matches([]*openAPISchema{s.Not}, value) == 1 {
+		v.fail(field, "invalid", "", value)
+	}
+	if value == nil {
+		if len(s.Type) > 0 && !s.Nullable && !s.Type.has("null") {
+			v.fail(field, "type", strings.Join(s.Type, " or "), nil)
+		}
+		return
+	}
+	if len(s.Type) > 0 && !s.accepts(value) {
+		v.fail(field, "type", strings.Join(s.Type, " or "), value)
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			opts := make([]string, len(s.Enum))
+			for i, e := range s.Enum {
+				opts[i] = fmt.Sprint(e)
+			}
+			v.fail(field, "oneof", strings.Join(opts, " "), value)
+		}
+	}
+	switch x := value.(type) {
+	case string:
+		v.validateString(s, x, field)
+	case float64:
+		v.validateNumber(s, x, field)
+	case []any:
+		v.validateArray(s, x, field)
+	case map[string]any:
+		v.validateObject(s, x, field)
+	}
+}
+
+// matches returns the number of schemas that value is valid against.
+func (v *schemaValidator) matches(schemas []*openAPISchema, value any) int {
+	n := 0
+	for _, s := range schemas {
+		sub := &schemaValidator{doc: v.doc, request: v.request}
+		sub.validate(s, value, "")
+		if len(sub.errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// accepts reports whether the JSON type of value is a type of s, integers
+// are numbers too.
+func (s *openAPISchema) accepts(value any) bool {
+	var typ string
+	switch x := value.(type) {
+	case bool:
+		typ = "boolean"
+	case float64:
+		typ = "number"
+		if x == math.Trunc(x) && !s.Type.has("number") {
+			typ = "integer"
+		}
+	case string:
+		typ = "string"
+	case []any:
+		typ = "array"
+	case map[string]any:
+		typ = "object"
+	}
+	return s.Type.has(typ)
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func (v *schemaValidator) validateString(s *openAPISchema, x, field string) {
+	n := utf8.RuneCountInString(x)
+	if s.MinLength != nil && n < *s.MinLength {
+		v.fail(field, "min", strconv.Itoa(*s.MinLength), x)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		v.fail(field, "max", strconv.Itoa(*s.MaxLength), x)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(x) {
+		v.fail(field, "pattern", s.Pattern, x)
+	}
+	valid := true
+	switch s.Format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, x)
+		valid = err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, x)
+		valid = err == nil
+	case "email":
+		if a, err := mail.ParseAddress(x); err != nil || a.Address != x {
+			v.fail(field, "email", "", x)
+		}
+	case "uri":
+		if u, err := url.Parse(x); err != nil || !u.IsAbs() {
+			v.fail(field, "url", "", x)
+		}
+	case "uuid":
+		valid = uuidPattern.MatchString(x)
+	case "ipv4":
+		ip := net.ParseIP(x)
+		valid = ip != nil && ip.To4() != nil
+	case "ipv6":
+		ip := net.ParseIP(x)
+		valid = ip != nil && ip.To4() == nil
+	}
+	if !valid {
+		v.fail(field, "type", s.Format, x)
+	}
+}
+
+func (v *schemaValidator) validateNumber(s *openAPISchema, x float64, field string) {
+	if min, exclusive, ok := bound(s.Minimum, s.ExclusiveMinimum); ok {
+		switch {
+		case exclusive && x <= min:
+			v.fail(field, "gt", formatFloat(min), x)
+		case x < min:
+			v.fail(field, "min", formatFloat(min), x)
+		}
+	}
+	if max, exclusive, ok := bound(s.Maximum, s.ExclusiveMaximum); ok {
+		switch {
+		case exclusive && x >= max:
+			v.fail(field, "lt", formatFloat(max), x)
+		case x > max:
+			v.fail(field, "max", formatFloat(max), x)
+		}
+	}
+}
+
+// bound returns a minimum or maximum, exclusive is a boolean in OpenAPI 3.0
+// and the bound itself in 3.1.
+func bound(limit *float64, exclusive any) (float64, bool, bool) {
+	switch e := exclusive.(type) {
+	case int:
+		return float64(e), true, true
+	case float64:
+		return e, true, true
+	case bool:
+		if limit != nil {
+			return *limit, e, true
+		}
+	}
+	if limit != nil {
+		return *limit, false, true
+	}
+	return 0, false, false
+}
+
+func (v *schemaValidator) validateArray(s *openAPISchema, x []any, field string) {
+	if s.MinItems != nil && len(x) < *s.MinItems {
+		v.fail(field, "min", strconv.Itoa(*s.MinItems), nil)
+	}
+	if s.MaxItems != nil && len(x) > *s.MaxItems {
+		v.fail(field, "max", strconv.Itoa(*s.MaxItems), nil)
+	}
+	if s.UniqueItems {
+	unique:
+		for i := range x {
+			for j := i + 1; j < len(x); j++ {
+				if reflect.DeepEqual(x[i], x[j]) {
+					v.fail(field, "unique", "", nil)
+					break unique
+				}
+			}
+		}
+	}
+	if s.Items != nil {
+		prefix := field
+		if prefix == "" {
+			prefix = "body"
+		}
+		for i, item := range x {
+			v.validate(s.Items, item, fmt.Sprintf("%s[%d]", prefix, i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(s *openAPISchema, x map[string]any, field string) {
+	for _, name := range s.Required {
+		if _, ok := x[name]; ok {
+			continue
+		}
+		if prop := v.doc.resolve(s.Properties[name]); prop != nil && (v.request && prop.ReadOnly || !v.request && prop.WriteOnly) {
+			continue
+		}
+		v.fail(joinField(field, name), "required", "", nil)
+	}
+	for _, name := range sortedNames(x) {
+		if prop, ok := s.Properties[name]; ok {
+			v.validate(prop, x[name], joinField(field, name))
+			continue
+		}
+		if a := s.AdditionalProperties; a != nil {
+			if !a.allowed {
+				v.fail(joinField(field, name), "notallowed", "", nil)
+			} else if a.schema != nil {
+				v.validate(a.schema, x[name], joinField(field, name))
+			}
+		}
+	}
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
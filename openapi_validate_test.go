@@ -0,0 +1,199 @@
+package cherry
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func openAPIApp(t *testing.T, opts OpenAPIOptions) *Cherry {
+	doc, err := ParseOpenAPI([]byte(testOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Use(doc.Validate(opts))
+	c.Get("/users", func(ctx *Context) error {
+		return ctx.JSON(http.StatusOK, []map[string]any{{"id": 1, "name": "Ann", "email": "ann@example.com"}})
+	})
+	c.Post("/users", func(ctx *Context) error {
+		var user map[string]any
+		if err := ctx.DecodeJSON(&user); err != nil {
+			return err
+		}
+		user["id"] = 2
+		return ctx.JSON(http.StatusCreated, user)
+	})
+	c.Get("/users/:id", func(ctx *Context) error {
+		if ctx.Param("id") == "2" {
+			return ctx.JSON(http.StatusOK, map[string]any{"id": 2, "name": "bob"})
+		}
+		if ctx.Param("id") == "3" {
+			return ctx.Text(http.StatusTeapot, "short and stout")
+		}
+		return ctx.JSON(http.StatusOK, map[string]any{"id": 1, "name": "Ann", "email": "ann@example.com"})
+	})
+	c.Get("/health", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	return c
+}
+
+func TestOpenAPIValidateRequests(t *testing.T) {
+	c := openAPIApp(t, OpenAPIOptions{})
+	tests := []struct {
+		method string
+		route  string
+		body   string
+		code   int
+		errors string
+	}{
+		{"GET", "/users?limit=10&tags=a&tags=b", "", http.StatusOK, ""},
+		{"GET", "/users?limit=ten", "", http.StatusBadRequest, "limit must be a valid integer"},
+		{"GET", "/users?limit=1000&tags=a&tags=b&tags=c", "", http.StatusBadRequest, "limit must be at most 100; tags must be at most 2"},
+		{"GET", "/users/0", "", http.StatusBadRequest, "id must be at least 1"},
+		{"GET", "/users/1", "", http.StatusOK, ""},
+		{"GET", "/health", "", http.StatusOK, ""},
+		{"POST", "/users", `{"name":"Ann","email":"ann@example.com","address":{"city":"Oslo"},"nickname":null}`, http.StatusCreated, ""},
+		{"POST", "/users", "", http.StatusBadRequest, "body is required"},
+		{"POST", "/users", `{"name":"a","email":"nope","role":"owner","age":0,"address":{},"extra":1}`, http.StatusBadRequest,
+			"address.city is required; age must be greater than 0; email must be a valid email address; extra is not allowed; " +
+				"name must be at least 2; name must match ^[A-Z]; role must be one of admin member"},
+		{"POST", "/users", `{"name":`, http.StatusBadRequest, "invalid JSON body"},
+		{"POST", "/users", `[]`, http.StatusBadRequest, "body must be a valid object"},
+	}
+	for _, test := range tests {
+		var body io.Reader
+		if test.body != "" {
+			body = strings.NewReader(test.body)
+		}
+		r := httptest.NewRequest(test.method, test.route, body)
+		r.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code || !strings.Contains(rw.Body.String(), test.errors) {
+			t.Errorf("%s %s %s: expecting %d %q got %d %q", test.method, test.route, test.body, test.code, test.errors, rw.Code, rw.Body)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/users", strings.NewReader("name=Ann"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expecting 415 for a content type that is not in the document got %d", rw.Code)
+	}
+}
+
+func TestOpenAPIValidateFail(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(testOpenAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.SetErrorHandler(func(ctx *Context, err error) {
+		ctx.Fail(0, err)
+	})
+	c.Use(doc.Validate(OpenAPIOptions{}))
+	c.Get("/users", noopHandler)
+	code, body := doRequest(t, "GET", "/users?limit=ten", nil, c)
+	want := `{"errors":[{"message":"limit must be a valid integer","field":"limit","code":"type"}]}`
+	if code != http.StatusBadRequest || strings.TrimSpace(body) != want {
+		t.Errorf("expecting 400 %s got %d %s", want, code, body)
+	}
+
+	var errs ValidationErrors
+	var he *HTTPError
+	err = &openAPIError{HTTPError: NewHTTPError(http.StatusBadRequest), errs: ValidationErrors{{Field: "a", Tag: "required"}}}
+	if !errors.As(err, &errs) || !errors.As(err, &he) {
+		t.Error("expecting the error to be ValidationErrors and an HTTPError")
+	}
+}
+
+func TestOpenAPIValidateResponses(t *testing.T) {
+	c := openAPIApp(t, OpenAPIOptions{Responses: true})
+	tests := []struct {
+		route string
+		code  int
+		body  string
+	}{
+		{"/users", http.StatusOK, `"name":"Ann"`},
+		{"/users/1", http.StatusOK, `"name":"Ann"`},
+		{"/users/2", http.StatusInternalServerError, "the response does not match the OpenAPI document: email is required; name must match ^[A-Z]"},
+		{"/users/3", http.StatusInternalServerError, "the response status 418 is not in the OpenAPI document"},
+		{"/health", http.StatusOK, "ok"},
+	}
+	for _, test := range tests {
+		code, body := doRequest(t, "GET", test.route, nil, c)
+		if code != test.code || !strings.Contains(body, test.body) {
+			t.Errorf("%s: expecting %d %q got %d %q", test.route, test.code, test.body, code, body)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Ann","email":"ann@example.com"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	var user map[string]any
+	if err := json.Unmarshal(rw.Body.Bytes(), &user); rw.Code != http.StatusCreated || err != nil || user["id"] != 2.0 {
+		t.Errorf("expecting the created user got %d %s", rw.Code, rw.Body)
+	}
+}
+
+func TestSchemaValidator(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(`
+openapi: 3.1.0
+components:
+  schemas:
+    Pet:
+      oneOf:
+        - {type: object, required: [bark], properties: {bark: {type: boolean}}}
+        - {type: object, required: [meow], properties: {meow: {type: boolean}}}
+    Score: {type: [number, "null"], exclusiveMaximum: 10}
+    Tags: {type: array, uniqueItems: true, items: {type: string, format: uuid}}
+    Name: {not: {type: string, enum: [root]}}
+    Secret:
+      type: object
+      required: [password]
+      properties: {password: {type: string, writeOnly: true}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := func(name string) *openAPISchema { return &openAPISchema{Ref: "#/components/schemas/" + name} }
+	tests := []struct {
+		schema  string
+		value   any
+		request bool
+		errors  string
+	}{
+		{"Pet", map[string]any{"bark": true}, true, ""},
+		{"Pet", map[string]any{"bark": true, "meow": true}, true, "body is invalid"},
+		{"Pet", map[string]any{}, true, "body is invalid"},
+		{"Score", nil, true, ""},
+		{"Score", 9.5, true, ""},
+		{"Score", 10.0, true, "body must be less than 10"},
+		{"Score", "1", true, "body must be a valid number or null"},
+		{"Tags", []any{"0b4e7ca9-5f5e-4b35-a2c1-6f1f2e9d8a10"}, true, ""},
+		{"Tags", []any{"x", "x"}, true, "body must not have duplicate items; body[0] must be a valid uuid; body[1] must be a valid uuid"},
+		{"Name", "ann", true, ""},
+		{"Name", "root", true, "body is invalid"},
+		{"Secret", map[string]any{}, false, ""},
+		{"Secret", map[string]any{}, true, "password is required"},
+	}
+	for _, test := range tests {
+		v := &schemaValidator{doc: doc, request: test.request}
+		v.validate(ref(test.schema), test.value, "")
+		got := ""
+		if len(v.errs) > 0 {
+			got = v.errs.Error()
+		}
+		if got != test.errors {
+			t.Errorf("%s %v: expecting %q got %q", test.schema, test.value, test.errors, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cherry
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Option configures a Cherry object in New.
+type Option func(*Cherry) error
+
+// WithOutput sets the writer of the access-log and startup messages.
+func WithOutput(w io.Writer) Option {
+	return func(c *Cherry) error {
+		if w == nil {
+			return errors.New("output must not be nil")
+		}
+		c.Output = w
+		return nil
+	}
+}
+
+// WithLogger sets the structured logger, see Cherry.Logger.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Cherry) error {
+		if l == nil {
+			return errors.New("logger must not be nil")
+		}
+		c.Logger = l
+		return nil
+	}
+}
+
+// WithAccessLog enables the access-log. The format is optional and defaults
+// to LogCommon.
+func WithAccessLog(format ...AccessLogFormat) Option {
+	return func(c *Cherry) error {
+		c.HasAccessLog = true
+		if len(format) > 0 {
+			c.AccessLogFormat = format[0]
+		}
+		return nil
+	}
+}
+
+// WithErrorHandler sets the handler of the errors returned by Handlers.
+func WithErrorHandler(h ErrorHandlerFunc) Option {
+	return func(c *Cherry) error {
+		if h == nil {
+			return errors.New("error handler must not be nil")
+		}
+		c.ErrorHandler = h
+		return nil
+	}
+}
+
+// WithTimeouts sets the read, write and idle timeouts of the server. Zero
+// means no timeout.
+func WithTimeouts(read, write, idle time.Duration) Option {
+	return func(c *Cherry) error {
+		if read < 0 || write < 0 || idle < 0 {
+			return errors.New("timeouts must not be negative")
+		}
+		c.ReadTimeout, c.WriteTimeout, c.IdleTimeout = read, write, idle
+		return nil
+	}
+}
+
+// WithShutdownTimeout sets the maximum duration of a graceful shutdown.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(c *Cherry) error {
+		if d < 0 {
+			return errors.New("shutdown timeout must not be negative")
+		}
+		c.ShutdownTimeout = d
+		return nil
+	}
+}
+
+// WithMaxConns limits the number of simultaneous connections.
+func WithMaxConns(n int) Option {
+	return func(c *Cherry) error {
+		if n < 0 {
+			return errors.New("max connections must not be negative")
+		}
+		c.MaxConns = n
+		return nil
+	}
+}
+
+// WithHTTP2 enables the HTTP2 protocol, the options are optional.
+func WithHTTP2(opts ...HTTP2Options) Option {
+	return func(c *Cherry) error {
+		c.HTTP2 = true
+		if len(opts) > 0 {
+			c.HTTP2Options = opts[0]
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package cherry
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New(
+		WithOutput(buf),
+		WithAccessLog(LogJSON),
+		WithTimeouts(time.Second, 2*time.Second, 3*time.Second),
+		WithShutdownTimeout(4*time.Second),
+		WithMaxConns(10),
+		WithHTTP2(HTTP2Options{MaxConcurrentStreams: 5}),
+	)
+	if c.ReadTimeout != time.Second || c.WriteTimeout != 2*time.Second || c.IdleTimeout != 3*time.Second {
+		t.Errorf("expecting the configured timeouts got %s %s %s", c.ReadTimeout, c.WriteTimeout, c.IdleTimeout)
+	}
+	if c.ShutdownTimeout != 4*time.Second || c.MaxConns != 10 {
+		t.Errorf("expecting shutdown timeout 4s and 10 connections got %s and %d", c.ShutdownTimeout, c.MaxConns)
+	}
+	if !c.HTTP2 || c.HTTP2Options.MaxConcurrentStreams != 5 {
+		t.Errorf("expecting HTTP2 with 5 streams got %v %+v", c.HTTP2, c.HTTP2Options)
+	}
+
+	c.Get("/", noopHandler)
+	code, _ := doRequest(t, "GET", "/", nil, c)
+	isHTTPStatusOK(t, code)
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("expecting a JSON access-log got %q", buf.String())
+	}
+}
+
+func TestNewWithErrorHandler(t *testing.T) {
+	c := New(WithErrorHandler(func(ctx *Context, err error) {
+		ctx.Response().WriteHeader(http.StatusTeapot)
+	}))
+	c.Get("/", func(ctx *Context) error {
+		return NewHTTPError(http.StatusBadRequest)
+	})
+	code, _ := doRequest(t, "GET", "/", nil, c)
+	if code != http.StatusTeapot {
+		t.Errorf("expecting status 418 got %d", code)
+	}
+}
+
+func TestNewWithInvalidOption(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expecting New to panic on an invalid option")
+		}
+	}()
+	New(WithTimeouts(-1, 0, 0))
+}
@@ -0,0 +1,48 @@
+package cherry
+
+// Phase orders middleware. Middleware of a lower phase runs before the
+// middleware of a higher phase, whatever the order of registration, and
+// middleware of the same phase runs in the order it was registered. Phases
+// are plain numbers, so libraries can pick a priority between the named
+// phases, like PhaseAuth+10.
+type Phase int
+
+const (
+	// PhaseEarly is for middleware that must see every request first, like
+	// request ids, recovery and tracing.
+	PhaseEarly Phase = 100
+	// PhaseAuth is for authentication.
+	PhaseAuth Phase = 200
+	// PhasePostAuth is for middleware that needs the authenticated user,
+	// like authorization and rate limiting per user.
+	PhasePostAuth Phase = 300
+	// PhaseDefault is the phase of the middleware registered with Use.
+	PhaseDefault Phase = 400
+	// PhaseLate is for middleware that must run right before the handler.
+	PhaseLate Phase = 500
+)
+
+// UsePhase adds middleware to the given phase.
+//
+// app.UsePhase(cherry.PhaseAuth, authenticate).
+func (c *Cherry) UsePhase(phase Phase, handlers ...Handler) {
+	// find the position after the last middleware of the same or a lower
+	// phase.
+	i := len(c.phases)
+	for i > 0 && c.phases[i-1] > phase {
+		i--
+	}
+	// new slices are built, since groups share the backing arrays of the
+	// middleware they inherited.
+	middleware := make([]Handler, 0, len(c.middleware)+len(handlers))
+	middleware = append(middleware, c.middleware[:i]...)
+	middleware = append(middleware, handlers...)
+	middleware = append(middleware, c.middleware[i:]...)
+	phases := make([]Phase, 0, len(middleware))
+	phases = append(phases, c.phases[:i]...)
+	for range handlers {
+		phases = append(phases, phase)
+	}
+	phases = append(phases, c.phases[i:]...)
+	c.middleware, c.phases = middleware, phases
+}
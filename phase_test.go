@@ -0,0 +1,40 @@
+package cherry
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUsePhase(t *testing.T) {
+	buf := &bytes.Buffer{}
+	write := func(s string) Handler {
+		return func(ctx *Context) error {
+			buf.WriteString(s)
+			return nil
+		}
+	}
+	c := New()
+	c.Use(write("d1"))
+	c.UsePhase(PhaseAuth, write("a"))
+	c.Use(write("d2"))
+	c.UsePhase(PhaseLate, write("l"))
+	c.UsePhase(PhaseEarly, write("e"))
+	c.UsePhase(PhaseAuth+10, write("p"))
+
+	admin := c.Group("/admin")
+	admin.UsePhase(PhasePostAuth, write("g"))
+	c.UsePhase(PhaseEarly, write("x"))
+
+	c.Get("/", write("h"))
+	admin.Get("/", write("h"))
+
+	doRequest(t, "GET", "/", nil, c)
+	if buf.String() != "exapd1d2lh" {
+		t.Errorf("expecting exapd1d2lh got %s", buf.String())
+	}
+	buf.Reset()
+	doRequest(t, "GET", "/admin", nil, c)
+	if buf.String() != "eapgd1d2lh" {
+		t.Errorf("expecting eapgd1d2lh got %s", buf.String())
+	}
+}
@@ -0,0 +1,54 @@
+package cherry
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"path"
+	"strings"
+)
+
+// Pprof registers the net/http/pprof handlers under prefix. The middleware
+// handlers run before each profile and can be used to protect them.
+//
+// app.Pprof("/debug/pprof", cherry.BasicAuth("admin", "secret")).
+func (c *Cherry) Pprof(prefix string, middleware ...Handler) {
+	h := chain(pprofHandler, middleware...)
+	route := path.Join(prefix, "*name")
+	c.Get(route, h)
+	c.Post(route, h)
+}
+
+func pprofHandler(ctx *Context) error {
+	w, r := ctx.Response(), ctx.Request()
+	switch name := strings.Trim(ctx.Param("name"), "/"); name {
+	case "":
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+	return nil
+}
+
+// BasicAuth returns a middleware Handler that requires HTTP basic
+// authentication with the given user and password.
+func BasicAuth(user, password string) Handler {
+	return func(ctx *Context) error {
+		u, p, ok := ctx.Request().BasicAuth()
+		if ok &&
+			subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1 {
+			return nil
+		}
+		ctx.Response().Header().Set("WWW-Authenticate", `Basic realm="Restricted", charset="UTF-8"`)
+		return NewHTTPError(http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,64 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPprof(t *testing.T) {
+	c := New()
+	c.Pprof("/admin/pprof", BasicAuth("admin", "secret"))
+
+	code, _ := doRequest(t, "GET", "/admin/pprof/", nil, c)
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expecting status 401 got %d", code)
+	}
+
+	tests := []struct {
+		route  string
+		expect string
+	}{
+		{"/admin/pprof/", "goroutine"},
+		{"/admin/pprof/cmdline", ""},
+		{"/admin/pprof/goroutine?debug=1", "goroutine profile:"},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", test.route, nil)
+		r.SetBasicAuth("admin", "secret")
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		isHTTPStatusOK(t, rw.Code)
+		if !strings.Contains(rw.Body.String(), test.expect) {
+			t.Errorf("%s: expecting body to contain %q", test.route, test.expect)
+		}
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	c := New()
+	c.Use(BasicAuth("admin", "secret"))
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, "ok")
+	})
+
+	for _, test := range []struct {
+		user, password string
+		expect         int
+	}{
+		{"admin", "secret", http.StatusOK},
+		{"admin", "wrong", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.password)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.expect {
+			t.Errorf("%s:%s: expecting status %d got %d", test.user, test.password, test.expect, rw.Code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cherry
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	contextPtrType = reflect.TypeOf((*Context)(nil))
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+	cleanupType    = reflect.TypeOf(func(error) {})
+)
+
+// providers holds the dependency providers registered with Provide, it is
+// shared by the application and all of its groups.
+type providers struct {
+	mu sync.RWMutex
+	m  map[reflect.Type]reflect.Value
+}
+
+func newProviders() *providers {
+	return &providers{m: map[reflect.Type]reflect.Value{}}
+}
+
+func (p *providers) get(t reflect.Type) (reflect.Value, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	fn, ok := p.m[t]
+	return fn, ok
+}
+
+// Provide registers fn as the provider of a request-scoped dependency. fn is
+// one of
+//
+//	func(ctx *cherry.Context) (T, error)
+//	func(ctx *cherry.Context) (T, func(err error), error)
+//
+// and is called the first time Resolve asks for a T during a request, the
+// result is cached for the rest of the request. The cleanup function of the
+// second form is called once the request has finished with the error
+// returned by the middleware or handler, which lets a transaction commit or
+// roll back. Provide panics when fn has another signature.
+//
+// app.Provide(func(ctx *cherry.Context) (*sql.Tx, func(error), error) { .. }).
+func (c *Cherry) Provide(fn any) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	valid := t.Kind() == reflect.Func && t.NumIn() == 1 && t.In(0) == contextPtrType &&
+		(t.NumOut() == 2 || (t.NumOut() == 3 && t.Out(1) == cleanupType)) &&
+		t.Out(t.NumOut()-1) == errorType
+	if !valid {
+		panic(fmt.Sprintf("cherry: invalid provider %s", t))
+	}
+	c.providers.mu.Lock()
+	defer c.providers.mu.Unlock()
+	c.providers.m[t.Out(0)] = v
+}
+
+// Resolve returns the dependency of type T for the request, constructing
+// it with its provider on first use. It fails when no provider of T is
+// registered or when the provider fails.
+//
+// tx, err := cherry.Resolve[*sql.Tx](ctx).
+func Resolve[T any](ctx *Context) (T, error) {
+	var zero T
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if v, ok := ctx.deps[t]; ok {
+		if _, ok := v.(resolving); ok {
+			return zero, fmt.Errorf("cherry: dependency cycle resolving %s", t)
+		}
+		// a nil interface returned by the provider is the zero T.
+		v, _ := v.(T)
+		return v, nil
+	}
+	if ctx.cherry == nil {
+		return zero, fmt.Errorf("cherry: no provider for %s", t)
+	}
+	fn, ok := ctx.cherry.providers.get(t)
+	if !ok {
+		return zero, fmt.Errorf("cherry: no provider for %s", t)
+	}
+	if ctx.deps == nil {
+		ctx.deps = map[any]any{}
+	}
+	ctx.deps[t] = resolving{}
+	out := fn.Call([]reflect.Value{reflect.ValueOf(ctx)})
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		delete(ctx.deps, t)
+		return zero, err
+	}
+	if len(out) == 3 && !out[1].IsNil() {
+		cleanup := out[1].Interface().(func(error))
+		ctx.onFinish(func() { cleanup(ctx.err) })
+	}
+	v, _ := out[0].Interface().(T)
+	ctx.deps[t] = v
+	return v, nil
+}
+
+// resolving marks a dependency as being constructed.
+type resolving struct{}
@@ -0,0 +1,120 @@
+package cherry
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type testTx struct {
+	committed  bool
+	rolledBack bool
+}
+
+type testRepo struct {
+	tx *testTx
+}
+
+func TestProvide(t *testing.T) {
+	c := New()
+	var (
+		txs   []*testTx
+		calls int
+	)
+	c.Provide(func(ctx *Context) (*testTx, func(error), error) {
+		calls++
+		tx := &testTx{}
+		txs = append(txs, tx)
+		return tx, func(err error) {
+			if err != nil {
+				tx.rolledBack = true
+				return
+			}
+			tx.committed = true
+		}, nil
+	})
+	c.Provide(func(ctx *Context) (*testRepo, error) {
+		tx, err := Resolve[*testTx](ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &testRepo{tx}, nil
+	})
+
+	api := c.Group("/api")
+	api.Get("/ok", func(ctx *Context) error {
+		repo, err := Resolve[*testRepo](ctx)
+		if err != nil {
+			return err
+		}
+		tx, _ := Resolve[*testTx](ctx)
+		if repo.tx != tx {
+			t.Error("expecting the transaction to be cached for the request")
+		}
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	api.Get("/fail", func(ctx *Context) error {
+		if _, err := Resolve[*testTx](ctx); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+
+	code, _ := doRequest(t, "GET", "/api/ok", nil, c)
+	isHTTPStatusOK(t, code)
+	if calls != 1 || !txs[0].committed {
+		t.Errorf("expecting one committed transaction got %d calls %+v", calls, txs[0])
+	}
+	doRequest(t, "GET", "/api/fail", nil, c)
+	if calls != 2 || !txs[1].rolledBack {
+		t.Errorf("expecting the second transaction to roll back got %+v", txs[1])
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	c := New()
+	c.Provide(func(ctx *Context) (*testRepo, error) {
+		return nil, errors.New("no database")
+	})
+	c.Get("/", func(ctx *Context) error {
+		if _, err := Resolve[*testTx](ctx); err == nil {
+			t.Error("expecting an error without a provider")
+		}
+		_, err := Resolve[*testRepo](ctx)
+		return err
+	})
+	code, body := doRequest(t, "GET", "/", nil, c)
+	if code != http.StatusInternalServerError || body != "no database\n" {
+		t.Errorf("expecting the provider error got %d %s", code, body)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic for an invalid provider")
+		}
+	}()
+	c.Provide(func() *testTx { return nil })
+}
+
+func TestResolveNilInterface(t *testing.T) {
+	c := New()
+	var calls int
+	c.Provide(func(ctx *Context) (io.Writer, error) {
+		calls++
+		return nil, nil
+	})
+	c.Get("/", func(ctx *Context) error {
+		for i := 0; i < 2; i++ {
+			if v, err := Resolve[io.Writer](ctx); v != nil || err != nil {
+				t.Errorf("expecting the nil value of the provider got %v %v", v, err)
+			}
+		}
+		return nil
+	})
+	code, _ := doRequest(t, "GET", "/", nil, c)
+	isHTTPStatusOK(t, code)
+	if calls != 1 {
+		t.Errorf("expecting the nil value to be kept for the request got %d calls", calls)
+	}
+}
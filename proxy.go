@@ -0,0 +1,140 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// ProxyOptions configures a reverse proxy registered with Proxy.
+type ProxyOptions struct {
+	// RewritePrefix replaces the part of the path before the wildcard of
+	// the route. With a route of /api/legacy/*path and a RewritePrefix of
+	// /v1, /api/legacy/users is proxied to /v1/users. The default is the
+	// path of the target.
+	RewritePrefix string
+
+	// PreserveHost sends the Host header of the client to the upstream
+	// instead of the host of the target.
+	PreserveHost bool
+
+	// SetHeaders are set on the requests to the upstream, RemoveHeaders
+	// are removed from them, like cookies that must not leak to it.
+	SetHeaders    map[string]string
+	RemoveHeaders []string
+
+	// Transport is used for the requests to the upstream. The default is
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// ModifyResponse may change the response of the upstream, see
+	// httputil.ReverseProxy.
+	ModifyResponse func(*http.Response) error
+
+	// Retry retries the requests that fail, see ProxyRetry. Retries are
+	// disabled by default.
+	Retry ProxyRetry
+}
+
+// Proxy forwards the requests of every method on route to target, behind
+// the middleware of the application. The value of the wildcard the route
+// ends in is appended to the RewritePrefix. The X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto headers are set, responses are
+// streamed to the client as they arrive and failing upstreams are answered
+// with a 502 HTTPError through the error handler. Proxy panics when target
+// is not an absolute url.
+//
+// app.Proxy("/api/legacy/*path", "http://internal:8080", cherry.ProxyOptions{RewritePrefix: "/v1"}).
+func (c *Cherry) Proxy(route, target string, opts ProxyOptions) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic("cherry: invalid proxy target " + target)
+	}
+	wildcard := ""
+	if i := strings.LastIndex(route, "*"); i >= 0 {
+		wildcard = route[i+1:]
+	}
+	prefix := opts.RewritePrefix
+	if prefix == "" {
+		prefix = u.Path
+	}
+	transport := opts.Transport
+	if opts.Retry.Attempts > 1 {
+		transport = newRetryTransport(transport, opts.Retry)
+	}
+	p := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			state := pr.In.Context().Value(proxyStateKey{}).(*proxyState)
+			out := pr.Out
+			out.URL.Scheme = u.Scheme
+			out.URL.Host = u.Host
+			out.URL.Path = joinProxyPath(prefix, state.rest)
+			out.URL.RawPath = ""
+			if u.RawQuery != "" && out.URL.RawQuery != "" {
+				out.URL.RawQuery = u.RawQuery + "&" + out.URL.RawQuery
+			} else if u.RawQuery != "" {
+				out.URL.RawQuery = u.RawQuery
+			}
+			pr.SetXForwarded()
+			if opts.PreserveHost {
+				out.Host = pr.In.Host
+			} else {
+				out.Host = ""
+			}
+			for k, v := range opts.SetHeaders {
+				out.Header.Set(k, v)
+			}
+			for _, k := range opts.RemoveHeaders {
+				out.Header.Del(k)
+			}
+		},
+		Transport:      transport,
+		FlushInterval:  -1,
+		ModifyResponse: opts.ModifyResponse,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			r.Context().Value(proxyStateKey{}).(*proxyState).err = err
+		},
+	}
+	h := func(ctx *Context) error {
+		state := &proxyState{}
+		if wildcard != "" {
+			state.rest = ctx.Param(wildcard)
+		}
+		r := ctx.Request()
+		r = r.WithContext(context.WithValue(r.Context(), proxyStateKey{}, state))
+		p.ServeHTTP(ctx.Response(), r)
+		if state.err == nil || r.Context().Err() != nil {
+			// the client went away, there is nobody to respond to.
+			return nil
+		}
+		return NewHTTPError(http.StatusBadGateway)
+	}
+	for _, method := range mountMethods {
+		c.add(method, route, h)
+	}
+}
+
+type proxyStateKey struct{}
+
+// proxyState passes the path of a request to the upstream and the error of
+// the upstream back to the handler.
+type proxyState struct {
+	rest string
+	err  error
+}
+
+// joinProxyPath joins the prefix and the path of the wildcard, keeping a
+// trailing slash.
+func joinProxyPath(prefix, rest string) string {
+	switch {
+	case rest == "" && prefix == "":
+		return "/"
+	case rest == "":
+		return prefix
+	case prefix == "" || prefix == "/":
+		return rest
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(rest, "/")
+}
@@ -0,0 +1,235 @@
+package cherry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRetryBody is the largest request body that is buffered so it can be
+// sent again on a retry. Requests with larger bodies are not retried.
+const maxRetryBody = 64 << 10
+
+// ProxyRetry configures the retries of a reverse proxy. Retries are spread
+// out by an exponential backoff with jitter and limited by a budget, so
+// retries cannot multiply the load on an upstream that is already failing.
+type ProxyRetry struct {
+	// Attempts is the maximum number of attempts per request, including
+	// the first one. Zero or one disables the retries.
+	Attempts int
+
+	// Methods are the methods that are retried. The default is the
+	// idempotent methods GET, HEAD, OPTIONS, PUT, DELETE and TRACE.
+	Methods []string
+
+	// Backoff is the delay before the first retry, which doubles with every
+	// retry up to MaxBackoff. The defaults are 50ms and 1s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// PerTryTimeout limits the duration of a single attempt. The default is
+	// no limit besides the context of the request.
+	PerTryTimeout time.Duration
+
+	// BudgetRatio is the number of retries that may be made per request on
+	// average and BudgetSize the number of retries that may be made in a
+	// burst. Every request adds BudgetRatio to the budget, up to BudgetSize,
+	// and every retry takes one from it. The defaults are 0.2 and 10.
+	BudgetRatio float64
+	BudgetSize  int
+
+	// RetryOn decides whether an attempt failed. The default retries
+	// transport errors and the 502, 503 and 504 status codes.
+	RetryOn func(resp *http.Response, err error) bool
+}
+
+var defaultRetryMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions,
+	http.MethodPut, http.MethodDelete, http.MethodTrace,
+}
+
+func defaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryTransport retries the requests of a reverse proxy.
+type retryTransport struct {
+	next    http.RoundTripper
+	cfg     ProxyRetry
+	methods map[string]bool
+	budget  *retryBudget
+}
+
+func newRetryTransport(next http.RoundTripper, cfg ProxyRetry) *retryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = defaultRetryMethods
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 50 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Second
+	}
+	if cfg.BudgetRatio <= 0 {
+		cfg.BudgetRatio = 0.2
+	}
+	if cfg.BudgetSize <= 0 {
+		cfg.BudgetSize = 10
+	}
+	if cfg.RetryOn == nil {
+		cfg.RetryOn = defaultRetryOn
+	}
+	methods := map[string]bool{}
+	for _, m := range cfg.Methods {
+		methods[m] = true
+	}
+	return &retryTransport{
+		next:    next,
+		cfg:     cfg,
+		methods: methods,
+		budget:  newRetryBudget(cfg.BudgetRatio, cfg.BudgetSize),
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.budget.deposit()
+	if !t.methods[req.Method] || !rewindable(req) {
+		return t.try(req)
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := t.try(req)
+		last := attempt >= t.cfg.Attempts || req.Context().Err() != nil
+		if last || !t.cfg.RetryOn(resp, err) || !t.budget.withdraw() {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxRetryBody))
+			resp.Body.Close()
+		}
+		if !sleep(req.Context(), t.backoff(attempt)) {
+			return nil, req.Context().Err()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// try makes a single attempt, limited by the PerTryTimeout. The timeout
+// is cancelled once the body of the response is closed.
+func (t *retryTransport) try(req *http.Request) (*http.Response, error) {
+	if t.cfg.PerTryTimeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.cfg.PerTryTimeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the delay before the retry after attempt, a random
+// duration up to the exponential backoff.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.cfg.Backoff << (attempt - 1)
+	if d <= 0 || d > t.cfg.MaxBackoff {
+		d = t.cfg.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// rewindable reports whether the body of req can be sent again, buffering
+// small bodies that have no GetBody.
+func rewindable(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return true
+	}
+	if req.ContentLength < 0 || req.ContentLength > maxRetryBody {
+		return false
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		req.Body = io.NopCloser(bytes.NewReader(nil))
+		return false
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return true
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// retryBudget allows ratio retries per request and at most size retries in
+// a burst. Every request deposits ratio tokens, every retry withdraws one.
+type retryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+	ratio  float64
+	max    float64
+}
+
+func newRetryBudget(ratio float64, size int) *retryBudget {
+	return &retryBudget{tokens: float64(size), ratio: ratio, max: float64(size)}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
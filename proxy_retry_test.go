@@ -0,0 +1,98 @@
+package cherry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProxyRetry(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if n%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + string(b)))
+	}))
+	defer upstream.Close()
+
+	c := New()
+	c.Proxy("/api/*path", upstream.URL, ProxyOptions{Retry: ProxyRetry{
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+	}})
+
+	code, body := doRequest(t, "PUT", "/api/users/1", strings.NewReader("toby"), c)
+	isHTTPStatusOK(t, code)
+	if body != "PUT toby" || calls.Load() != 3 {
+		t.Errorf("expecting PUT toby after 3 calls got %q after %d", body, calls.Load())
+	}
+
+	// POST is not idempotent and not retried.
+	calls.Store(0)
+	code, _ = doRequest(t, "POST", "/api/users", strings.NewReader("toby"), c)
+	if code != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("expecting a single 503 got %d after %d calls", code, calls.Load())
+	}
+}
+
+func TestProxyRetryBudget(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	c := New()
+	c.Proxy("/api/*path", upstream.URL, ProxyOptions{Retry: ProxyRetry{
+		Attempts:    5,
+		Backoff:     time.Millisecond,
+		BudgetRatio: 0.1,
+		BudgetSize:  2,
+	}})
+	for i := 0; i < 10; i++ {
+		if code, _ := doRequest(t, "GET", "/api/x", nil, c); code != http.StatusServiceUnavailable {
+			t.Fatalf("expecting 503 got %d", code)
+		}
+	}
+	// 10 requests and the burst of 2 retries, the other 9 requests add 0.9
+	// to the budget which is not enough for another retry.
+	if n := calls.Load(); n != 12 {
+		t.Errorf("expecting 12 calls got %d", n)
+	}
+}
+
+func TestProxyPerTryTimeout(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer upstream.Close()
+
+	c := New()
+	c.Proxy("/api/*path", upstream.URL, ProxyOptions{Retry: ProxyRetry{
+		Attempts:      2,
+		Backoff:       time.Millisecond,
+		PerTryTimeout: 50 * time.Millisecond,
+	}})
+	code, body := doRequest(t, "GET", "/api/x", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "fast" {
+		t.Errorf("expecting fast got %s", body)
+	}
+}
@@ -0,0 +1,95 @@
+package cherry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "yes")
+		w.Write([]byte(strings.Join([]string{
+			r.Method,
+			r.URL.RequestURI(),
+			r.Host,
+			r.Header.Get("X-Forwarded-Host"),
+			r.Header.Get("X-Api-Key"),
+			r.Header.Get("Cookie"),
+		}, " ")))
+	}))
+	defer upstream.Close()
+	upstreamHost := strings.TrimPrefix(upstream.URL, "http://")
+
+	c := New()
+	c.Proxy("/api/legacy/*path", upstream.URL, ProxyOptions{
+		RewritePrefix: "/v1",
+		SetHeaders:    map[string]string{"X-Api-Key": "secret"},
+		RemoveHeaders: []string{"Cookie"},
+	})
+	c.Proxy("/same/*path", upstream.URL+"/base", ProxyOptions{PreserveHost: true})
+	c.Proxy("/status", upstream.URL+"/health", ProxyOptions{})
+
+	tests := []struct {
+		method string
+		target string
+		expect string
+	}{
+		{"GET", "/api/legacy/users?limit=5", "GET /v1/users?limit=5 " + upstreamHost + " example.com secret "},
+		{"DELETE", "/api/legacy/users/42/", "DELETE /v1/users/42/ " + upstreamHost + " example.com secret "},
+		{"POST", "/same/orders", "POST /base/orders example.com example.com  session=1"},
+		{"GET", "/status", "GET /health " + upstreamHost + " example.com  session=1"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "http://example.com"+test.target, nil)
+		r.Header.Set("Cookie", "session=1")
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		isHTTPStatusOK(t, rw.Code)
+		if rw.Body.String() != test.expect {
+			t.Errorf("%s %s:\nexpecting %q\ngot       %q", test.method, test.target, test.expect, rw.Body.String())
+		}
+		if rw.Header().Get("X-Upstream") != "yes" {
+			t.Errorf("%s: expecting the headers of the upstream", test.target)
+		}
+	}
+}
+
+func TestProxyStreamsAndFails(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	c := New(WithOutput(io.Discard), WithAccessLog())
+	c.Proxy("/stream", upstream.URL, ProxyOptions{})
+	c.Proxy("/down/*path", "http://127.0.0.1:1", ProxyOptions{})
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "first" {
+		t.Errorf("expecting the first chunk to be streamed got %q %v", buf, err)
+	}
+	resp.Body.Close()
+
+	code, _ := doRequest(t, "GET", "/down/x", nil, c)
+	if code != http.StatusBadGateway {
+		t.Errorf("expecting 502 got %d", code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic for an invalid target")
+		}
+	}()
+	c.Proxy("/invalid", "internal:8080", ProxyOptions{})
+}
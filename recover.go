@@ -0,0 +1,153 @@
+package cherry
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PanicError is the error of a panic that a middleware or a handler
+// recovered from. It is answered with a 500 by the error handler and
+// reported to the OnError hooks with the stack of the panic.
+type PanicError struct {
+	// Value is the value the middleware or handler panicked with.
+	Value any
+	// Report describes the request that panicked.
+	Report *PanicReport
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value of the panic when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// PanicReport is the structured report of a panic, which is logged and
+// reported to the OnError hooks with the PanicError.
+type PanicReport struct {
+	Time   time.Time
+	Method string
+	// Path is the path of the request and Route its pattern.
+	Path   string
+	Route  string
+	Params Params
+	// Header are the headers of the request, without the credentials.
+	Header http.Header
+	Stack  []byte
+	// Goroutines is the dump of all the goroutines, when PanicGoroutines
+	// is enabled.
+	Goroutines []byte
+}
+
+// String formats the report as text.
+func (r *PanicReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", r.Method, r.Path)
+	if r.Route != "" && r.Route != r.Path {
+		fmt.Fprintf(&b, " (route %s)", r.Route)
+	}
+	b.WriteString("\n")
+	for _, p := range r.Params {
+		fmt.Fprintf(&b, "param %s=%s\n", p.Key, p.Value)
+	}
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "header %s: %s\n", name, strings.Join(r.Header[name], ", "))
+	}
+	b.Write(r.Stack)
+	if len(r.Goroutines) > 0 {
+		b.WriteString("\ngoroutines:\n")
+		b.Write(r.Goroutines)
+	}
+	return b.String()
+}
+
+// recoverPanic recovers from a panic of the middleware or the handler of a
+// request. http.ErrAbortHandler is panicked again, it aborts the response on
+// purpose.
+func (c *Cherry) recoverPanic(ctx *Context) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	report := c.panicReport(ctx, debug.Stack())
+	err := &PanicError{Value: v, Report: report}
+	c.logPanic(ctx, err)
+	c.handleError(ctx, err, report.Stack)
+}
+
+func (c *Cherry) panicReport(ctx *Context, stack []byte) *PanicReport {
+	r := ctx.Request()
+	report := &PanicReport{
+		Time:   time.Now(),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Route:  ctx.route,
+		Header: r.Header.Clone(),
+		Stack:  stack,
+	}
+	for _, p := range ctx.vars {
+		report.Params = append(report.Params, Param{Key: p.Key, Value: p.Value})
+	}
+	for _, name := range defaultRedact {
+		report.Header.Del(name)
+	}
+	if c.PanicGoroutines {
+		report.Goroutines = allStacks()
+	}
+	return report
+}
+
+// allStacks returns the stacks of all the goroutines, up to 16MB.
+func allStacks() []byte {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 16<<20 {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// logPanic logs the report of a panic to the Logger, or to the Output.
+func (c *Cherry) logPanic(ctx *Context, err *PanicError) {
+	report := err.Report
+	if c.Logger == nil {
+		fmt.Fprintf(c.Output, "Cherry🍒 %s\n%s\n", err, report)
+		return
+	}
+	params := make([]any, len(report.Params))
+	for i, p := range report.Params {
+		params[i] = slog.String(p.Key, p.Value)
+	}
+	attrs := []slog.Attr{
+		slog.String("error", err.Error()),
+		slog.String("method", report.Method),
+		slog.String("path", report.Path),
+		slog.String("route", report.Route),
+		slog.Group("params", params...),
+		slog.Any("header", report.Header),
+		slog.String("stack", string(report.Stack)),
+	}
+	if report.Goroutines != nil {
+		attrs = append(attrs, slog.String("goroutines", string(report.Goroutines)))
+	}
+	c.Logger.LogAttrs(ctx.Request().Context(), slog.LevelError, "panic", attrs...)
+}
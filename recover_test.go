@@ -0,0 +1,130 @@
+package cherry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	setMode(t, ReleaseMode)
+	c := New()
+	c.Output = io.Discard
+	var (
+		reported error
+		stack    []byte
+	)
+	c.OnError(func(ctx *Context, err error, s []byte) {
+		reported, stack = err, s
+	})
+	c.Get("/panic", func(ctx *Context) error {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	})
+	code, body := doRequest(t, "GET", "/panic", nil, c)
+	if code != http.StatusInternalServerError || strings.TrimSpace(body) != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expecting a 500 got %d %q", code, body)
+	}
+	var pe *PanicError
+	if !errors.As(reported, &pe) || !strings.Contains(pe.Error(), "assignment to entry in nil map") {
+		t.Errorf("expecting the panic to be reported got %v", reported)
+	}
+	if !strings.Contains(string(stack), "recover_test.go") {
+		t.Errorf("expecting the stack of the panic got %s", stack)
+	}
+
+	// the next request gets a clean context.
+	c.Get("/ok", func(ctx *Context) error { return ctx.Text(http.StatusOK, "ok") })
+	if code, body := doRequest(t, "GET", "/ok", nil, c); code != http.StatusOK || body != "ok" {
+		t.Errorf("expecting 200 ok got %d %q", code, body)
+	}
+}
+
+func TestRecoverPanicError(t *testing.T) {
+	err := &PanicError{Value: io.ErrUnexpectedEOF}
+	if !errors.Is(err, io.ErrUnexpectedEOF) || err.Error() != "panic: unexpected EOF" {
+		t.Errorf("expecting the panic to wrap its error got %v", err)
+	}
+	if (&PanicError{Value: 42}).Unwrap() != nil {
+		t.Error("expecting no wrapped error for a value")
+	}
+}
+
+func TestPanicReport(t *testing.T) {
+	var out bytes.Buffer
+	c := New()
+	c.Output = &out
+	c.PanicGoroutines = true
+	var report *PanicReport
+	c.OnError(func(ctx *Context, err error, stack []byte) {
+		var pe *PanicError
+		if errors.As(err, &pe) {
+			report = pe.Report
+		}
+	})
+	c.Get("/users/:id", func(ctx *Context) error {
+		panic("boom")
+	})
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("User-Agent", "test")
+	c.ServeHTTP(httptest.NewRecorder(), r)
+
+	if report == nil {
+		t.Fatal("expecting a panic report")
+	}
+	if report.Method != "GET" || report.Path != "/users/42" || report.Route != "/users/:id" ||
+		report.Params.ByName("id") != "42" || report.Header.Get("User-Agent") != "test" || report.Header.Get("Authorization") != "" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !bytes.Contains(report.Goroutines, []byte("goroutine ")) {
+		t.Error("expecting the stacks of all the goroutines")
+	}
+	logged := out.String()
+	for _, s := range []string{"Cherry🍒 panic: boom\n", "GET /users/42 (route /users/:id)\n", "param id=42\n", "header User-Agent: test\n", "goroutines:\n"} {
+		if !strings.Contains(logged, s) {
+			t.Errorf("expecting %q in the log got %s", s, logged)
+		}
+	}
+	if strings.Contains(logged, "secret") {
+		t.Error("expecting the credentials to be left out of the log")
+	}
+}
+
+func TestPanicReportLogger(t *testing.T) {
+	var out bytes.Buffer
+	c := New()
+	c.Logger = slog.New(slog.NewJSONHandler(&out, nil))
+	c.Get("/users/:id", func(ctx *Context) error {
+		panic("boom")
+	})
+	doRequest(t, "GET", "/users/42", nil, c)
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("expecting a single record got %s", out.String())
+	}
+	if record["msg"] != "panic" || record["route"] != "/users/:id" || record["params"].(map[string]any)["id"] != "42" ||
+		!strings.Contains(record["stack"].(string), "recover_test.go") {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	c := New()
+	c.Get("/abort", func(ctx *Context) error {
+		panic(http.ErrAbortHandler)
+	})
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expecting http.ErrAbortHandler to be panicked again got %v", v)
+		}
+	}()
+	doRequest(t, "GET", "/abort", nil, c)
+}
@@ -0,0 +1,79 @@
+package cherry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acmeChallengePrefix is the path of the ACME HTTP-01 challenges.
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// ServeTLSWithRedirect serves the application with TLS on httpsPort, and
+// runs a server on httpPort that redirects every request to the TLS server.
+// Requests for ACME challenges are passed to ACMEHandler when it is set. Both
+// servers are stopped together by Shutdown and Close.
+//
+// app.ServeTLSWithRedirect(443, 80, cert, key).
+func (c *Cherry) ServeTLSWithRedirect(httpsPort, httpPort int, certFile, keyFile string) error {
+	redirect := c.wrap(&http.Server{
+		Addr:              fmt.Sprintf(":%d", httpPort),
+		Handler:           redirectHandler(httpsPort, c.ACMEHandler),
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}, nil)
+	errc := make(chan error, 1)
+	go func() {
+		// the TLS server handles the signals for both servers.
+		err := c.run(redirect)
+		if !errors.Is(err, ErrServerClosed) {
+			c.logFailure("redirect server failed", err)
+		}
+		errc <- err
+	}()
+
+	err := c.ServeTLS(httpsPort, certFile, keyFile)
+	if errors.Is(err, ErrServerClosed) {
+		<-errc
+		return err
+	}
+	// the TLS server failed to start, the redirect server is of no use.
+	redirect.Close()
+	<-errc
+	return err
+}
+
+// redirectHandler redirects to the same URL on the TLS port, GET and HEAD
+// requests with 301 Moved Permanently and other methods with 308 Permanent
+// Redirect so the method and body are kept.
+func redirectHandler(httpsPort int, acme http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if acme != nil && strings.HasPrefix(r.URL.Path, acmeChallengePrefix) {
+			acme.ServeHTTP(w, r)
+			return
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if httpsPort == 443 {
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+		} else {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
@@ -0,0 +1,64 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	acme := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("token"))
+	})
+	tests := []struct {
+		method, host, uri string
+		port              int
+		code              int
+		location          string
+	}{
+		{"GET", "example.com", "/a?b=c", 443, http.StatusMovedPermanently, "https://example.com/a?b=c"},
+		{"GET", "example.com:8080", "/", 8443, http.StatusMovedPermanently, "https://example.com:8443/"},
+		{"POST", "example.com", "/form", 443, http.StatusPermanentRedirect, "https://example.com/form"},
+		{"GET", "[::1]:80", "/", 443, http.StatusMovedPermanently, "https://[::1]/"},
+		{"GET", "example.com", "/.well-known/acme-challenge/abc", 443, http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.uri, nil)
+		r.Host = test.host
+		rw := httptest.NewRecorder()
+		redirectHandler(test.port, acme).ServeHTTP(rw, r)
+		if rw.Code != test.code {
+			t.Errorf("%s %s: expecting status %d got %d", test.host, test.uri, test.code, rw.Code)
+		}
+		if loc := rw.Header().Get("Location"); loc != test.location {
+			t.Errorf("%s %s: expecting location %q got %q", test.host, test.uri, test.location, loc)
+		}
+	}
+}
+
+func TestServeTLSWithRedirect(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	certFile, keyFile := writeCertificate(t, "a.test")
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeTLSWithRedirect(0, 0, certFile, keyFile)
+	}()
+	for i := 0; i < 100 && len(c.servers.all()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(c.servers.all()); n != 2 {
+		t.Fatalf("expecting 2 servers got %d", n)
+	}
+	c.Shutdown(context.Background())
+	select {
+	case err := <-errc:
+		if err != ErrServerClosed {
+			t.Errorf("expecting ErrServerClosed got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expecting both servers to stop")
+	}
+}
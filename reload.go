@@ -0,0 +1,124 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// listenersEnv holds the addresses of the listeners a reloaded process
+// inherits. The listener of the n-th address is file descriptor 3+n.
+const listenersEnv = "CHERRY_LISTENERS"
+
+var inherited struct {
+	once      sync.Once
+	mu        sync.Mutex
+	listeners map[string][]net.Listener
+}
+
+// inheritedListener returns the listener for addr that was passed on by the
+// previous process, or nil when there is none.
+func inheritedListener(addr string) net.Listener {
+	inherited.once.Do(func() {
+		value := os.Getenv(listenersEnv)
+		os.Unsetenv(listenersEnv)
+		inherited.listeners = parseListeners(value, 3)
+	})
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	ls := inherited.listeners[addr]
+	if len(ls) == 0 {
+		return nil
+	}
+	inherited.listeners[addr] = ls[1:]
+	return ls[0]
+}
+
+// parseListeners turns the comma separated addresses of value into the
+// listeners of the file descriptors starting at fd.
+func parseListeners(value string, fd int) map[string][]net.Listener {
+	listeners := map[string][]net.Listener{}
+	if value == "" {
+		return listeners
+	}
+	for i, addr := range strings.Split(value, ",") {
+		f := os.NewFile(uintptr(fd+i), addr)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		listeners[addr] = append(listeners[addr], l)
+	}
+	return listeners
+}
+
+// reload starts a new process of the same binary that takes over the
+// listeners of the running servers, and gracefully shuts down this process.
+// Connections that arrive meanwhile wait in the backlog of the shared
+// sockets, so no connection is refused.
+func (c *Cherry) reload() error {
+	cmd, err := reloadCommand(c.servers.all())
+	if err == nil {
+		err = cmd.Start()
+		// the new process has its own descriptors once it started.
+		closeFiles(cmd.ExtraFiles)
+	}
+	if err != nil {
+		c.logFailure("reload failed", err)
+		return err
+	}
+	return c.Shutdown(context.Background())
+}
+
+// reloadCommand builds the command that runs the new binary with the
+// listeners of srvs.
+func reloadCommand(srvs []*server) (*exec.Cmd, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		files []*os.File
+		addrs []string
+	)
+	for _, srv := range srvs {
+		select {
+		case <-srv.listening:
+		default:
+			continue
+		}
+		fl, ok := srv.ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			closeFiles(files)
+			return nil, fmt.Errorf("cherry: cannot pass on listener of %s", srv.Addr)
+		}
+		// File returns a duplicate of the descriptor, which must be closed.
+		f, err := fl.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+		files = append(files, f)
+		addrs = append(addrs, srv.Addr)
+	}
+	if len(files) == 0 {
+		return nil, errors.New("cherry: no listeners to pass on")
+	}
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), listenersEnv+"="+strings.Join(addrs, ","))
+	cmd.ExtraFiles = files
+	return cmd, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
@@ -0,0 +1,95 @@
+//go:build !windows
+
+package cherry
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestParseListeners(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// parseListeners closes the descriptors it inherits, closing the one of f
+	// again when f is collected would close a descriptor of another test.
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listeners := parseListeners(":8080", fd)
+	if len(listeners[":8080"]) != 1 {
+		t.Fatalf("expecting an inherited listener for :8080 got %v", listeners)
+	}
+	inherited := listeners[":8080"][0]
+	defer inherited.Close()
+	if inherited.Addr().String() != l.Addr().String() {
+		t.Errorf("expecting listener on %s got %s", l.Addr(), inherited.Addr())
+	}
+}
+
+func TestReloadCommand(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	waitForServer(t, c)
+	defer func() {
+		c.Close()
+		<-errc
+	}()
+
+	cmd, err := reloadCommand(c.servers.all())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd.ExtraFiles) != 1 {
+		t.Fatalf("expecting 1 listener to be passed on got %d", len(cmd.ExtraFiles))
+	}
+	cmd.ExtraFiles[0].Close()
+	env := cmd.Env[len(cmd.Env)-1]
+	if !strings.HasPrefix(env, listenersEnv+"=127.0.0.1:0") {
+		t.Errorf("expecting the listener addresses in the environment got %s", env)
+	}
+}
+
+// fileListener is a listener that cannot pass on its descriptor.
+type fileListener struct{ net.Listener }
+
+func TestReloadCommandClosesFiles(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("counting descriptors needs /proc")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	listening := make(chan struct{})
+	close(listening)
+	srvs := []*server{
+		{Server: &http.Server{Addr: "127.0.0.1:0"}, ln: ln, listening: listening},
+		{Server: &http.Server{Addr: "127.0.0.1:1"}, ln: fileListener{ln}, listening: listening},
+	}
+	fds, _ := os.ReadDir("/proc/self/fd")
+	if _, err := reloadCommand(srvs); err == nil {
+		t.Fatal("expecting an error for a listener without a descriptor")
+	}
+	if after, _ := os.ReadDir("/proc/self/fd"); len(after) != len(fds) {
+		t.Errorf("expecting the descriptors already collected to be closed got %d open, %d before", len(after), len(fds))
+	}
+}
@@ -0,0 +1,314 @@
+package cherry
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionUserKey is the key of the session that holds the id of the logged
+// in user, which Context.Login and the RememberMe middleware set.
+const SessionUserKey = "cherry.user"
+
+// sessionRemembered is the key of the session that tells that the user was
+// logged in by a remember-me token.
+const sessionRemembered = "cherry.remembered"
+
+// rememberGrace is the time a rotated remember-me token stays valid, for the
+// requests that a browser sent in parallel with the old token.
+const rememberGrace = 30 * time.Second
+
+// RememberToken is a remember-me token of a user. A token belongs to a
+// series, which is created when the user logs in with "keep me logged in"
+// and whose token is replaced every time it is used.
+type RememberToken struct {
+	Series string
+	User   string
+	// Hash is the SHA-256 of the token, the token itself is only known to
+	// the browser.
+	Hash string
+	// Previous is the hash of the token before Rotated, which is accepted
+	// for a short time.
+	Previous string
+	Rotated  time.Time
+	Expires  time.Time
+}
+
+// RememberStore is the storage backend of the remember-me tokens.
+type RememberStore interface {
+	// Get returns the token of a series.
+	Get(series string) (*RememberToken, bool)
+	// Set stores a token, replacing the token of its series.
+	Set(t *RememberToken)
+	// Delete removes a series.
+	Delete(series string)
+	// DeleteUser removes all the series of a user.
+	DeleteUser(user string)
+}
+
+// MemoryRememberStore is an in-memory RememberStore.
+type MemoryRememberStore struct {
+	mu     sync.Mutex
+	tokens map[string]RememberToken
+}
+
+// NewMemoryRememberStore returns an empty MemoryRememberStore.
+func NewMemoryRememberStore() *MemoryRememberStore {
+	return &MemoryRememberStore{tokens: map[string]RememberToken{}}
+}
+
+// Get implements RememberStore.
+func (s *MemoryRememberStore) Get(series string) (*RememberToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[series]
+	if !ok || time.Now().After(t.Expires) {
+		return nil, false
+	}
+	return &t, true
+}
+
+// Set implements RememberStore.
+func (s *MemoryRememberStore) Set(t *RememberToken) {
+	s.mu.Lock()
+	s.tokens[t.Series] = *t
+	s.mu.Unlock()
+}
+
+// Delete implements RememberStore.
+func (s *MemoryRememberStore) Delete(series string) {
+	s.mu.Lock()
+	delete(s.tokens, series)
+	s.mu.Unlock()
+}
+
+// DeleteUser implements RememberStore.
+func (s *MemoryRememberStore) DeleteUser(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for series, t := range s.tokens {
+		if t.User == user {
+			delete(s.tokens, series)
+		}
+	}
+}
+
+// RememberConfig configures the RememberMe middleware.
+type RememberConfig struct {
+	// Store keeps the tokens. The default is a MemoryRememberStore
+	Store RememberStore
+
+	// Cookie is the name of the cookie that holds the token. The default is
+	// "remember"
+	Cookie string
+
+	// MaxAge is the lifetime of a series. The default is 30 days
+	MaxAge time.Duration
+
+	// Path and Domain scope the cookie. The default path is "/"
+	Path   string
+	Domain string
+
+	// Secure restricts the cookie to HTTPS. The default is to set it on the
+	// requests served over TLS only
+	Secure bool
+
+	// OnTheft, when set, is called when a token that was already used is
+	// presented again, which means that the cookie was stolen. All the
+	// series of the user are removed before, which logs out the thief and
+	// the user
+	OnTheft func(ctx *Context, user string)
+}
+
+// rememberMe is the RememberMe middleware of a request.
+type rememberMe struct {
+	cfg *RememberConfig
+
+	// series serializes the rotations of a series, the requests that a
+	// browser sends in parallel with the same token would otherwise all
+	// rotate it and the browser would keep a token that is neither the
+	// current nor the previous one.
+	mu     sync.Mutex
+	series map[string]*seriesLock
+}
+
+type seriesLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks a series and returns the function that unlocks it.
+func (rm *rememberMe) lock(series string) func() {
+	rm.mu.Lock()
+	l, ok := rm.series[series]
+	if !ok {
+		l = &seriesLock{}
+		rm.series[series] = l
+	}
+	l.refs++
+	rm.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		rm.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(rm.series, series)
+		}
+		rm.mu.Unlock()
+	}
+}
+
+// RememberMe returns a middleware Handler that logs in the users that come
+// back with the remember-me cookie of Context.Remember, when their session
+// has expired. It must come after the Sessions middleware.
+//
+// The cookie holds a series and a token, the token is replaced every time
+// it logs in a user and only its hash is stored. A stolen cookie is detected
+// when the user or the thief presents the replaced token, then all the
+// series of the user are removed.
+//
+// app.Use(cherry.Sessions(cherry.SessionConfig{}), cherry.RememberMe(cherry.RememberConfig{})).
+func RememberMe(cfg RememberConfig) Handler {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRememberStore()
+	}
+	if cfg.Cookie == "" {
+		cfg.Cookie = "remember"
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 30 * 24 * time.Hour
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	rm := &rememberMe{cfg: &cfg, series: map[string]*seriesLock{}}
+	return func(ctx *Context) error {
+		WithValue(ctx, rm)
+		session := ctx.Session()
+		if session.Get(SessionUserKey) != nil {
+			return nil
+		}
+		cookie, err := ctx.Request().Cookie(cfg.Cookie)
+		if err != nil || cookie.Value == "" {
+			return nil
+		}
+		user, ok := rm.authenticate(ctx, cookie.Value)
+		if !ok {
+			rm.setCookie(ctx, "", -1)
+			return nil
+		}
+		session.Regenerate()
+		session.Set(SessionUserKey, user)
+		session.Set(sessionRemembered, true)
+		return nil
+	}
+}
+
+// authenticate checks the value of a cookie and rotates its token. The
+// check and the rotation of a series are atomic within the process.
+func (rm *rememberMe) authenticate(ctx *Context, value string) (string, bool) {
+	series, token, ok := strings.Cut(value, ":")
+	if !ok {
+		return "", false
+	}
+	defer rm.lock(series)()
+	t, ok := rm.cfg.Store.Get(series)
+	if !ok {
+		return "", false
+	}
+	hash := hashToken(token)
+	switch {
+	case subtle.ConstantTimeCompare([]byte(hash), []byte(t.Hash)) == 1:
+		rm.issue(ctx, t)
+	case t.Previous != "" && time.Since(t.Rotated) < rememberGrace &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(t.Previous)) == 1:
+		// a request sent in parallel with the one that rotated the token.
+	default:
+		rm.cfg.Store.DeleteUser(t.User)
+		if rm.cfg.OnTheft != nil {
+			rm.cfg.OnTheft(ctx, t.User)
+		}
+		return "", false
+	}
+	return t.User, true
+}
+
+// issue gives a series a new token and sends it in the cookie.
+func (rm *rememberMe) issue(ctx *Context, t *RememberToken) {
+	token := randomHex(32)
+	if t.Hash != "" {
+		t.Previous, t.Rotated = t.Hash, time.Now()
+	}
+	t.Hash = hashToken(token)
+	rm.cfg.Store.Set(t)
+	rm.setCookie(ctx, t.Series+":"+token, int(time.Until(t.Expires)/time.Second))
+}
+
+func (rm *rememberMe) setCookie(ctx *Context, value string, maxAge int) {
+	cfg := rm.cfg
+	replaceCookie(ctx.Response(), &http.Cookie{
+		Name:     cfg.Cookie,
+		Value:    value,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		MaxAge:   maxAge,
+		Secure:   cfg.Secure || ctx.Request().TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Remember starts a remember-me series for the user, which logs the user in
+// again with the RememberMe middleware once the session has expired. It is
+// called after a login with "keep me logged in" checked.
+//
+// err := ctx.Remember(user.ID).
+func (c *Context) Remember(user string) error {
+	rm, ok := Value[*rememberMe](c)
+	if !ok {
+		return fmt.Errorf("cherry: Remember requires the RememberMe middleware")
+	}
+	if cookie, err := c.Request().Cookie(rm.cfg.Cookie); err == nil {
+		series, _, _ := strings.Cut(cookie.Value, ":")
+		rm.cfg.Store.Delete(series)
+	}
+	rm.issue(c, &RememberToken{
+		Series:  randomHex(16),
+		User:    user,
+		Expires: time.Now().Add(rm.cfg.MaxAge),
+	})
+	return nil
+}
+
+// Forget ends the remember-me series of the request and expires its cookie,
+// on logout.
+func (c *Context) Forget() {
+	rm, ok := Value[*rememberMe](c)
+	if !ok {
+		return
+	}
+	if cookie, err := c.Request().Cookie(rm.cfg.Cookie); err == nil {
+		series, _, _ := strings.Cut(cookie.Value, ":")
+		rm.cfg.Store.Delete(series)
+		rm.setCookie(c, "", -1)
+	}
+}
+
+// Remembered reports whether the user of the session was logged in by a
+// remember-me token rather than with credentials. Sensitive actions, like
+// changing a password, should ask for the credentials again.
+func (c *Context) Remembered() bool {
+	v, _ := c.Session().Get(sessionRemembered).(bool)
+	return v
+}
@@ -0,0 +1,187 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newRememberApp(store RememberStore, thefts *[]string) *Cherry {
+	c := New()
+	c.Use(Sessions(SessionConfig{}), RememberMe(RememberConfig{
+		Store:   store,
+		OnTheft: func(ctx *Context, user string) { *thefts = append(*thefts, user) },
+	}))
+	c.Post("/login", func(ctx *Context) error {
+		ctx.Session().Regenerate()
+		ctx.Session().Set(SessionUserKey, "ann")
+		return ctx.Remember("ann")
+	})
+	c.Get("/me", func(ctx *Context) error {
+		user, _ := ctx.Session().Get(SessionUserKey).(string)
+		if ctx.Remembered() {
+			user += " (remembered)"
+		}
+		return ctx.Text(http.StatusOK, user)
+	})
+	c.Post("/logout", func(ctx *Context) error {
+		ctx.Forget()
+		ctx.Session().Destroy()
+		return nil
+	})
+	return c
+}
+
+func rememberCookie(t *testing.T, rw *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range rw.Result().Cookies() {
+		if cookie.Name == "remember" {
+			return cookie
+		}
+	}
+	t.Fatalf("expecting a remember cookie got %v", rw.Header().Values("Set-Cookie"))
+	return nil
+}
+
+func me(c *Cherry, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/me", nil)
+	r.AddCookie(cookie)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	return rw
+}
+
+func TestRememberMe(t *testing.T) {
+	store := NewMemoryRememberStore()
+	var thefts []string
+	c := newRememberApp(store, &thefts)
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("POST", "/login", nil))
+	first := rememberCookie(t, rw)
+	series, token, _ := strings.Cut(first.Value, ":")
+	if stored, ok := store.Get(series); !ok || stored.User != "ann" || stored.Hash == token {
+		t.Fatalf("expecting the hash of the token to be stored got %+v", stored)
+	}
+	if first.MaxAge < 29*24*3600 || !first.HttpOnly {
+		t.Errorf("expecting a persistent HttpOnly cookie got %v", first)
+	}
+
+	// the session has expired, the cookie logs the user in again.
+	rw = me(c, first)
+	if rw.Body.String() != "ann (remembered)" {
+		t.Fatalf("expecting the user to be remembered got %q", rw.Body)
+	}
+	second := rememberCookie(t, rw)
+	if !strings.HasPrefix(second.Value, series+":") || second.Value == first.Value {
+		t.Errorf("expecting the token to be rotated in its series got %s", second.Value)
+	}
+
+	// a request sent in parallel with the old token is still accepted.
+	if rw = me(c, first); rw.Body.String() != "ann (remembered)" {
+		t.Errorf("expecting the previous token to be accepted for a while got %q", rw.Body)
+	}
+
+	stored, _ := store.Get(series)
+	stored.Rotated = time.Now().Add(-time.Minute)
+	store.Set(stored)
+	if rw = me(c, first); rw.Body.String() != "" || len(thefts) != 1 || thefts[0] != "ann" {
+		t.Errorf("expecting a replayed token to be reported got %q %v", rw.Body, thefts)
+	}
+	if _, ok := store.Get(series); ok {
+		t.Error("expecting the series of the user to be removed after a theft")
+	}
+	if rw = me(c, second); rw.Body.String() != "" {
+		t.Errorf("expecting the thief to be logged out got %q", rw.Body)
+	}
+}
+
+// slowRememberStore is a RememberStore with the latency of a database.
+type slowRememberStore struct {
+	*MemoryRememberStore
+}
+
+func (s slowRememberStore) Get(series string) (*RememberToken, bool) {
+	t, ok := s.MemoryRememberStore.Get(series)
+	time.Sleep(10 * time.Millisecond)
+	return t, ok
+}
+
+func TestRememberParallel(t *testing.T) {
+	store := slowRememberStore{NewMemoryRememberStore()}
+	var thefts []string
+	c := newRememberApp(store, &thefts)
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("POST", "/login", nil))
+	first := rememberCookie(t, rw)
+
+	// a page that loads its assets in parallel with the same token.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		rotated []*http.Cookie
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rw := me(c, first)
+			if rw.Body.String() != "ann (remembered)" {
+				t.Errorf("expecting every request to be logged in got %q", rw.Body)
+			}
+			for _, cookie := range rw.Result().Cookies() {
+				if cookie.Name == "remember" {
+					mu.Lock()
+					rotated = append(rotated, cookie)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(rotated) != 1 {
+		t.Fatalf("expecting the token to be rotated once got %d", len(rotated))
+	}
+	if rw = me(c, rotated[0]); rw.Body.String() != "ann (remembered)" || len(thefts) != 0 {
+		t.Errorf("expecting the rotated token to stay valid got %q %v", rw.Body, thefts)
+	}
+}
+
+func TestRememberForget(t *testing.T) {
+	store := NewMemoryRememberStore()
+	var thefts []string
+	c := newRememberApp(store, &thefts)
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("POST", "/login", nil))
+	cookie := rememberCookie(t, rw)
+
+	r := httptest.NewRequest("POST", "/logout", nil)
+	r.AddCookie(cookie)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if expired := rememberCookie(t, rw); expired.MaxAge >= 0 {
+		t.Errorf("expecting the cookie to be expired got %v", expired)
+	}
+	series, _, _ := strings.Cut(cookie.Value, ":")
+	if _, ok := store.Get(series); ok {
+		t.Error("expecting the series to be removed")
+	}
+	if rw = me(c, cookie); rw.Body.String() != "" || len(thefts) != 0 {
+		t.Errorf("expecting a forgotten token to be ignored got %q %v", rw.Body, thefts)
+	}
+}
+
+func TestRememberWithoutMiddleware(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Remember("ann")
+	})
+	if code, _ := doRequest(t, "GET", "/", nil, c); code != http.StatusInternalServerError {
+		t.Errorf("expecting Remember to fail without the middleware got %d", code)
+	}
+}
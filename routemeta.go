@@ -0,0 +1,165 @@
+package cherry
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// routeKey identifies a route by its method and pattern.
+type routeKey struct {
+	method string
+	path   string
+}
+
+// routeMeta is the metadata of a route.
+type routeMeta struct {
+	name     string
+	values   map[string]any
+	tags     []string
+	request  reflect.Type
+	response reflect.Type
+}
+
+// apply sets the metadata of info to copies of m.
+func (m *routeMeta) apply(info *RouteInfo) {
+	if m.values != nil {
+		info.Meta = make(map[string]any, len(m.values))
+		for k, v := range m.values {
+			info.Meta[k] = v
+		}
+	}
+	info.Tags = append([]string(nil), m.tags...)
+	info.Name = m.name
+	info.Request, info.Response = m.request, m.response
+}
+
+func (m *routeMeta) hasTag(tag string) bool {
+	for _, t := range m.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Meta sets a metadata value of the route, which middleware read with
+// Context.RouteMeta and tooling with Routes. The OnRouteRegistered hooks run
+// before the metadata is set.
+//
+// app.Post("/charges", charge).Meta("team", "payments").
+func (r *Route) Meta(key string, value any) *Route {
+	r.routes.mu.Lock()
+	defer r.routes.mu.Unlock()
+	m := r.routes.metaOf(r.Method, r.Path)
+	if m.values == nil {
+		m.values = map[string]any{}
+	}
+	m.values[key] = value
+	return r
+}
+
+// Tag adds tags to the route, see Tagged and Context.HasTag.
+//
+// app.Get("/products", listProducts).Tag("public").
+func (r *Route) Tag(tags ...string) *Route {
+	r.routes.mu.Lock()
+	defer r.routes.mu.Unlock()
+	m := r.routes.metaOf(r.Method, r.Path)
+	for _, tag := range tags {
+		if !m.hasTag(tag) {
+			m.tags = append(m.tags, tag)
+		}
+	}
+	return r
+}
+
+// Request documents the model of the request bodies of the route, for the
+// client generator, see the clientgen package.
+//
+// app.Post("/users", createUser).Request(User{}).Response(User{}).
+func (r *Route) Request(model any) *Route {
+	r.routes.mu.Lock()
+	defer r.routes.mu.Unlock()
+	r.routes.metaOf(r.Method, r.Path).request = reflect.TypeOf(model)
+	return r
+}
+
+// Response documents the model of the response bodies of the route, see
+// Request.
+func (r *Route) Response(model any) *Route {
+	r.routes.mu.Lock()
+	defer r.routes.mu.Unlock()
+	r.routes.metaOf(r.Method, r.Path).response = reflect.TypeOf(model)
+	return r
+}
+
+// metaOf returns the metadata of a route, creating it. The lock must be
+// held.
+func (rs *routes) metaOf(method, path string) *routeMeta {
+	if rs.meta == nil {
+		rs.meta = map[routeKey]*routeMeta{}
+	}
+	key := routeKey{method, path}
+	if rs.meta[key] == nil {
+		rs.meta[key] = &routeMeta{}
+	}
+	return rs.meta[key]
+}
+
+// find returns the metadata of a route, or nil. HEAD requests of GET routes
+// have the metadata of the GET route. The lock must be held.
+func (rs *routes) find(method, path string) *routeMeta {
+	if m, ok := rs.meta[routeKey{method, path}]; ok || method != http.MethodHead {
+		return m
+	}
+	return rs.meta[routeKey{http.MethodGet, path}]
+}
+
+// RouteMeta returns a metadata value of the route of the request, see
+// Route.Meta.
+func (c *Context) RouteMeta(key string) (any, bool) {
+	if c.cherry == nil {
+		return nil, false
+	}
+	rs := c.cherry.routes
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if m := rs.find(c.Request().Method, c.route); m != nil {
+		v, ok := m.values[key]
+		return v, ok
+	}
+	return nil, false
+}
+
+// HasTag reports whether the route of the request has the tag.
+func (c *Context) HasTag(tag string) bool {
+	if c.cherry == nil {
+		return false
+	}
+	rs := c.cherry.routes
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	m := rs.find(c.Request().Method, c.route)
+	return m != nil && m.hasTag(tag)
+}
+
+// Tagged returns middleware that runs the middleware only for the routes
+// with the tag.
+//
+// app.Use(cherry.Tagged("public", rateLimit)).
+func Tagged(tag string, middleware ...Handler) Handler {
+	return func(ctx *Context) error {
+		if !ctx.HasTag(tag) {
+			return nil
+		}
+		for _, h := range middleware {
+			if err := h(ctx); err != nil {
+				return err
+			}
+			if ctx.aborted {
+				return nil
+			}
+		}
+		return nil
+	}
+}
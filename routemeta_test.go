@@ -0,0 +1,71 @@
+package cherry
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRouteMeta(t *testing.T) {
+	c := New()
+	api := c.Group("/api")
+	api.Post("/charges", func(ctx *Context) error {
+		team, _ := ctx.RouteMeta("team")
+		return ctx.Text(http.StatusOK, team.(string))
+	}).Meta("team", "payments").Tag("internal", "billing", "internal")
+	c.Get("/health", noopHandler)
+
+	routes := c.Routes()
+	if !reflect.DeepEqual(routes[0].Meta, map[string]any{"team": "payments"}) {
+		t.Errorf("expecting the meta of the route got %v", routes[0].Meta)
+	}
+	if !reflect.DeepEqual(routes[0].Tags, []string{"internal", "billing"}) {
+		t.Errorf("expecting the tags of the route got %v", routes[0].Tags)
+	}
+	if routes[1].Meta != nil || routes[1].Tags != nil {
+		t.Errorf("expecting no metadata got %v %v", routes[1].Meta, routes[1].Tags)
+	}
+	// the metadata of Routes are copies.
+	routes[0].Meta["team"] = "growth"
+	if c.Routes()[0].Meta["team"] != "payments" {
+		t.Error("expecting Routes to return a copy of the metadata")
+	}
+
+	_, body := doRequest(t, "POST", "/api/charges", nil, c)
+	if body != "payments" {
+		t.Errorf("expecting payments got %s", body)
+	}
+}
+
+func TestTagged(t *testing.T) {
+	c := New()
+	limited := 0
+	c.Use(Tagged("public", func(ctx *Context) error {
+		limited++
+		return nil
+	}))
+	c.Get("/products", func(ctx *Context) error {
+		if !ctx.HasTag("public") {
+			t.Error("expecting the route to have the public tag")
+		}
+		return nil
+	}).Tag("public")
+	c.Get("/admin", noopHandler)
+
+	doRequest(t, "GET", "/products", nil, c)
+	doRequest(t, "GET", "/products", nil, c)
+	doRequest(t, "GET", "/admin", nil, c)
+	if limited != 2 {
+		t.Errorf("expecting the middleware for the 2 requests of the tagged route got %d", limited)
+	}
+}
+
+func TestRouteModels(t *testing.T) {
+	type user struct{ Name string }
+	c := New()
+	c.Post("/users", noopHandler).Name("createUser").Request(user{}).Response([]user{})
+	r := c.Routes()[0]
+	if r.Name != "createUser" || r.Request != reflect.TypeOf(user{}) || r.Response != reflect.TypeOf([]user{}) {
+		t.Errorf("unexpected route %+v", r)
+	}
+}
@@ -0,0 +1,75 @@
+package cherry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// PrintRoutes writes the routes as a table of their method, path, handler
+// and number of middleware. It is printed at startup in DebugMode.
+//
+// app.PrintRoutes(os.Stdout).
+func (c *Cherry) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tMIDDLEWARE")
+	for _, r := range c.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", r.Method, r.Path, r.Handler, r.Middleware)
+	}
+	tw.Flush()
+}
+
+// PrintRoutesJSON writes the routes as a JSON array, for tooling.
+func (c *Cherry) PrintRoutesJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.Routes())
+}
+
+// WriteRoutes writes the routes in format, "table" like PrintRoutes or
+// "json" like PrintRoutesJSON. Projects created by cherry new call it for
+// their -routes flag, which the cherry routes command sets.
+//
+// err := app.WriteRoutes(os.Stdout, "json").
+func (c *Cherry) WriteRoutes(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		c.PrintRoutes(w)
+		return nil
+	case "json":
+		return c.PrintRoutesJSON(w)
+	}
+	return fmt.Errorf("cherry: unknown routes format %q, expecting table or json", format)
+}
+
+// httpHandlerName returns the name of the function of an http.HandlerFunc,
+// or the type of other handlers.
+func httpHandlerName(h http.Handler) string {
+	if fn, ok := h.(http.HandlerFunc); ok {
+		return funcName(fn)
+	}
+	t := fmt.Sprintf("%T", h)
+	if i := strings.LastIndexByte(t, '/'); i >= 0 {
+		t = t[i+1:]
+	}
+	return strings.TrimPrefix(t, "*")
+}
+
+// funcName returns the name of a function without the path of its package,
+// like main.listUsers or handlers.(*store).list.
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,77 @@
+package cherry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func routeTableHandler(ctx *Context) error { return nil }
+
+type routeTableStore struct{}
+
+func (s *routeTableStore) list(ctx *Context) error { return nil }
+
+type routeTableHTTPHandler struct{}
+
+func (routeTableHTTPHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func TestPrintRoutes(t *testing.T) {
+	c := New()
+	c.Use(noopHandler)
+	c.Get("/", routeTableHandler)
+	admin := c.Group("/admin")
+	admin.Get("/users", (&routeTableStore{}).list)
+	// middleware added after the route are counted.
+	admin.Use(noopHandler)
+	c.Handle("GET", "/metrics", routeTableHTTPHandler{})
+	c.Handle("GET", "/debug", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	buf := &bytes.Buffer{}
+	c.PrintRoutes(buf)
+	expect := []string{
+		"METHOD  PATH          HANDLER                         MIDDLEWARE",
+		"GET     /             cherry.routeTableHandler        1",
+		"GET     /admin/users  cherry.(*routeTableStore).list  2",
+		"GET     /metrics      cherry.routeTableHTTPHandler    0",
+		"GET     /debug        cherry.TestPrintRoutes.func1    0",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expecting\n%s\ngot\n%s", strings.Join(expect, "\n"), buf.String())
+	}
+}
+
+func TestPrintRoutesJSON(t *testing.T) {
+	c := New()
+	c.Post("/users", routeTableHandler)
+	buf := &bytes.Buffer{}
+	if err := c.PrintRoutesJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var routes []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &routes); err != nil {
+		t.Fatal(err)
+	}
+	expect := []map[string]any{{"method": "POST", "path": "/users", "handler": "cherry.routeTableHandler", "middleware": float64(0)}}
+	if !reflect.DeepEqual(routes, expect) {
+		t.Errorf("expecting %v got %v", expect, routes)
+	}
+}
+
+func TestWriteRoutes(t *testing.T) {
+	c := New()
+	c.Post("/users", routeTableHandler)
+	for format, expect := range map[string]string{"table": "POST    /users", "json": `"path": "/users"`} {
+		buf := &bytes.Buffer{}
+		if err := c.WriteRoutes(buf, format); err != nil || !strings.Contains(buf.String(), expect) {
+			t.Errorf("%s: expecting %s got %v %s", format, expect, err, buf)
+		}
+	}
+	if err := c.WriteRoutes(io.Discard, "yaml"); err == nil {
+		t.Error("expecting an error for an unknown format")
+	}
+}
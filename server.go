@@ -1,128 +1,343 @@
 package cherry
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/bradfitz/http2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
-const useClosedConn = "use of closed network connection"
+var (
+	// ErrServerClosed is returned by Serve after a graceful shutdown.
+	ErrServerClosed = http.ErrServerClosed
+
+	// ErrForcedStop is returned by Serve when the server was stopped with
+	// Close, or when connections were still open after the shutdown timeout
+	// and had to be closed. It wraps ErrServerClosed.
+	ErrForcedStop = fmt.Errorf("cherry: connections closed before they drained: %w", ErrServerClosed)
+)
 
 // Server provides a gracefull shutdown of http server.
 type server struct {
 	*http.Server
-	quit  chan struct{}
-	fquit chan struct{}
-	wg    sync.WaitGroup
+	stats  *stats
+	health *health
+	// shutdown is called when a stop signal is received.
+	shutdown func(context.Context) error
+	// started is called once the listener is bound.
+	started func(net.Addr)
+	// reload is called when a reload signal is received.
+	reload func() error
+	// signals trigger a graceful shutdown, none disables signal handling.
+	signals []os.Signal
+	// certificates and getCertificate are used in addition to the
+	// certificate files of ServeTLS.
+	certificates   []tls.Certificate
+	getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	// maxConns limits the number of simultaneous connections, connections
+	// wait at most maxConnsWait for a free slot when it is set.
+	maxConns     int
+	maxConnsWait time.Duration
+	// connReadTimeout is the read deadline of new connections.
+	connReadTimeout time.Duration
+	// keepAlive, listenControl and wrapConn tune the listener.
+	keepAlive     time.Duration
+	listenControl func(network, address string, c syscall.RawConn) error
+	wrapConn      func(net.Conn) net.Conn
+	// ln is the plain listener, without TLS, that is passed to a new
+	// process on a reload.
+	ln net.Listener
+	// listening is closed once the listener is bound to addr.
+	listening chan struct{}
+	addr      net.Addr
+	// stopped is closed once the server is shut down or closed.
+	stopped chan struct{}
+	once    sync.Once
+	// forced is set when connections were closed instead of drained.
+	forced atomic.Bool
 }
 
-func newServer(addr string, h http.Handler, HTTP2 bool) *http.Server {
+func newServer(addr string, c *Cherry) *http.Server {
 	srv := &http.Server{
-		Addr:         addr,
-		Handler:      h,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:              addr,
+		Handler:           c,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+	if c.TLSConfig != nil {
+		srv.TLSConfig = c.TLSConfig.Clone()
+	} else {
+		srv.TLSConfig = TLSConfig()
+	}
+	h2s := &http2.Server{
+		MaxConcurrentStreams:         c.HTTP2Options.MaxConcurrentStreams,
+		MaxReadFrameSize:             c.HTTP2Options.MaxReadFrameSize,
+		IdleTimeout:                  c.HTTP2Options.IdleTimeout,
+		MaxUploadBufferPerConnection: c.HTTP2Options.MaxUploadBufferPerConnection,
+		MaxUploadBufferPerStream:     c.HTTP2Options.MaxUploadBufferPerStream,
 	}
-	if HTTP2 {
-		http2.ConfigureServer(srv, &http2.Server{})
+	if c.HTTP2 {
+		http2.ConfigureServer(srv, h2s)
+	}
+	if c.H2C {
+		srv.Handler = h2c.NewHandler(srv.Handler, h2s)
 	}
 	return srv
 }
 
+// listen binds the address of the server, or takes over the listener that
+// was passed on by the previous process during a reload.
+func (s *server) listen() (net.Listener, error) {
+	l := inheritedListener(s.Addr)
+	if l == nil {
+		lc := net.ListenConfig{KeepAlive: s.keepAlive, Control: s.listenControl}
+		var err error
+		if l, err = lc.Listen(context.Background(), "tcp", s.Addr); err != nil {
+			return nil, err
+		}
+	}
+	s.ln = l
+	if s.wrapConn != nil {
+		l = &wrapListener{Listener: l, wrap: s.wrapConn}
+	}
+	if s.connReadTimeout > 0 {
+		l = &deadlineListener{Listener: l, timeout: s.connReadTimeout}
+	}
+	if s.maxConns > 0 {
+		l = newLimitListener(l, s.maxConns, s.maxConnsWait, s.stats)
+	}
+	return l, nil
+}
+
 func (s *server) ListenAndServe() error {
-	l, err := net.Listen("tcp", s.Addr)
+	l, err := s.listen()
 	if err != nil {
 		return err
 	}
 	return s.serve(l)
 }
 
+// ListenAndServeTLS serves TLS with the certificate in the cert and key
+// files, followed by the certificates added to the application. Either may be
+// left out. The certificate is chosen by SNI, the first one is the default.
 func (s *server) ListenAndServeTLS(cert, key string) error {
-	var err error
-	config := &tls.Config{}
+	config := TLSConfig()
 	if s.TLSConfig != nil {
-		*config = *s.TLSConfig
+		config = s.TLSConfig.Clone()
 	}
 	if config.NextProtos == nil {
 		config.NextProtos = []string{"http/1.1"}
 	}
-	config.Certificates = make([]tls.Certificate, 1)
-	config.Certificates[0], err = tls.LoadX509KeyPair(cert, key)
-	if err != nil {
-		return err
+	if cert != "" || key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return err
+		}
+		config.Certificates = append([]tls.Certificate{pair}, config.Certificates...)
+	}
+	config.Certificates = append(config.Certificates, s.certificates...)
+	if config.GetCertificate == nil {
+		config.GetCertificate = s.getCertificate
+	}
+	if len(config.Certificates) == 0 && config.GetCertificate == nil {
+		return errors.New("cherry: no TLS certificate configured")
 	}
 
-	l, err := net.Listen("tcp", s.Addr)
+	l, err := s.listen()
 	if err != nil {
 		return err
 	}
-	tlsList := tls.NewListener(l.(*net.TCPListener), config)
+	tlsList := tls.NewListener(l, config)
 	return s.serve(tlsList)
 }
 
-// serve hooks in the Server.ConnState to incr and decr the waitgroup based on
-// the connection state.
+// serve counts the open connections through Server.ConnState and serves l
+// until the server is shut down. After a graceful shutdown it waits for the
+// open connections to drain before returning ErrServerClosed, or
+// ErrForcedStop when connections had to be closed.
 func (s *server) serve(l net.Listener) error {
 	s.Server.ConnState = func(conn net.Conn, state http.ConnState) {
 		switch state {
 		case http.StateNew:
-			s.wg.Add(1)
+			s.stats.conns.Add(1)
 		case http.StateClosed, http.StateHijacked:
-			s.wg.Done()
+			s.stats.conns.Add(-1)
 		}
 	}
-	go s.closeNotify(l)
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.closeNotify(stop)
+
+	s.addr = l.Addr()
+	close(s.listening)
+	s.started(s.addr)
+
+	err := s.Server.Serve(l)
+	if !errors.Is(err, ErrServerClosed) {
+		// a listener closed underneath the server returns net.ErrClosed,
+		// only Shutdown and Close stop the server.
+		return err
+	}
+	<-s.stopped
+	if s.forced.Load() {
+		return ErrForcedStop
+	}
+	return ErrServerClosed
+}
 
-	errChan := make(chan error, 1)
-	go func() {
-		errChan <- s.Server.Serve(l)
-	}()
+// Shutdown gracefully shuts down the server, see http.Server.Shutdown.
+// Connections that are still open when ctx is done are closed.
+func (s *server) Shutdown(ctx context.Context) error {
+	s.health.shuttingDown.Store(true)
+	defer s.stop()
+	err := s.Server.Shutdown(ctx)
+	if err != nil {
+		s.forced.Store(true)
+		s.Server.Close()
+	}
+	return err
+}
+
+// Close immediately closes the listener and all connections.
+func (s *server) Close() error {
+	s.forced.Store(true)
+	defer s.stop()
+	return s.Server.Close()
+}
+
+func (s *server) stop() {
+	s.once.Do(func() { close(s.stopped) })
+}
 
+// closeNotify shuts the server down when one of the configured signals is
+// received.
+func (s *server) closeNotify(stop chan struct{}) {
+	if len(s.signals) == 0 {
+		return
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, s.signals...)
+	defer signal.Stop(sig)
+
+	select {
+	case <-sig:
+		s.shutdown(context.Background())
+	case <-stop:
+	}
+}
+
+// watchReload calls reload for every reload signal until stop is closed.
+// The process keeps running after a failed reload, so the signals are
+// watched until a reload succeeds.
+func watchReload(reload func() error, stop chan struct{}) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, reloadSignals...)
+	defer signal.Stop(sig)
 	for {
 		select {
-		case err := <-errChan:
-			if strings.Contains(err.Error(), useClosedConn) {
-				continue
+		case <-sig:
+			if reload() == nil {
+				return
 			}
-			return err
-		case <-s.quit:
-			s.SetKeepAlivesEnabled(false)
-			s.wg.Wait()
-			return errors.New("server stopped gracefully")
-		case <-s.fquit:
-			return errors.New("server stopped: process killed")
+		case <-stop:
+			return
 		}
 	}
 }
 
-func (s *server) closeNotify(l net.Listener) {
-	sig := make(chan os.Signal, 1)
+// servers tracks the running servers of an application, it is shared by the
+// application and all of its groups.
+type servers struct {
+	mu   sync.Mutex
+	list []*server
+	// stopReload ends the watch of the reload signals, which runs once per
+	// application while it has servers that handle signals.
+	stopReload chan struct{}
+}
+
+func (s *servers) add(srv *server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, srv)
+	if len(srv.signals) > 0 && len(reloadSignals) > 0 && s.stopReload == nil {
+		s.stopReload = make(chan struct{})
+		go watchReload(srv.reload, s.stopReload)
+	}
+}
+
+func (s *servers) remove(srv *server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range s.list {
+		if v == srv {
+			s.list = append(s.list[:i], s.list[i+1:]...)
+			break
+		}
+	}
+	if s.stopReload == nil {
+		return
+	}
+	for _, v := range s.list {
+		if len(v.signals) > 0 {
+			return
+		}
+	}
+	close(s.stopReload)
+	s.stopReload = nil
+}
+
+func (s *servers) all() []*server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*server(nil), s.list...)
+}
+
+// HTTP2Options holds the settings of the HTTP2 protocol. Zero values use the
+// defaults of golang.org/x/net/http2.
+type HTTP2Options struct {
+	// MaxConcurrentStreams is the number of streams a client may have open
+	// at the same time. The default is 250
+	MaxConcurrentStreams uint32
 
-	signal.Notify(
-		sig,
-		syscall.SIGTERM,
-		syscall.SIGQUIT,
-		syscall.SIGUSR2,
-		syscall.SIGINT,
-	)
-	sign := <-sig
-	switch sign {
-	case syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT:
-		l.Close()
-		s.quit <- struct{}{}
-	case syscall.SIGKILL:
-		l.Close()
-		s.fquit <- struct{}{}
-	case syscall.SIGUSR2:
-		panic("USR2 => not implemented")
+	// MaxReadFrameSize is the largest frame the server is willing to read.
+	// The default is 1MB
+	MaxReadFrameSize uint32
+
+	// IdleTimeout closes connections without active streams after this
+	// duration. The default is the IdleTimeout of the server
+	IdleTimeout time.Duration
+
+	// MaxUploadBufferPerConnection is the flow control window of a
+	// connection. The default is 1MB
+	MaxUploadBufferPerConnection int32
+
+	// MaxUploadBufferPerStream is the flow control window of a stream. The
+	// default is 1MB
+	MaxUploadBufferPerStream int32
+}
+
+// addr returns the address of the first server that is listening.
+func (s *servers) addr() net.Addr {
+	for _, srv := range s.all() {
+		select {
+		case <-srv.listening:
+			return srv.addr
+		default:
+		}
 	}
+	return nil
 }
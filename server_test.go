@@ -0,0 +1,192 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// waitForServer waits until c is listening and returns the address.
+func waitForServer(t *testing.T, c *Cherry) string {
+	for i := 0; i < 100; i++ {
+		if addr := c.Addr(); addr != nil {
+			return addr.String()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server did not start")
+	return ""
+}
+
+func TestShutdownDrains(t *testing.T) {
+	c := New()
+	started := make(chan struct{})
+	c.Get("/slow", func(ctx *Context) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return ctx.Text(http.StatusOK, "done")
+	})
+	srv := &http.Server{Addr: "127.0.0.1:0", Handler: c}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(srv)
+	}()
+	addr := waitForServer(t, c)
+	resc := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("http://%s/slow", addr))
+		if err != nil {
+			resc <- 0
+			return
+		}
+		resp.Body.Close()
+		resc <- resp.StatusCode
+	}()
+	<-started
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code := <-resc; code != http.StatusOK {
+		t.Errorf("expecting in-flight request to finish with 200 got %d", code)
+	}
+	if err := <-errc; err != ErrServerClosed {
+		t.Errorf("expecting ErrServerClosed got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := New()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	waitForServer(t, c)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errc:
+		if err != ErrForcedStop {
+			t.Errorf("expecting ErrForcedStop got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expecting Serve to return after Close")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	c := New()
+	c.ShutdownTimeout = 50 * time.Millisecond
+	var hooked []string
+	c.OnShutdown(func(ctx context.Context) { hooked = append(hooked, "db") })
+	c.OnShutdown(func(ctx context.Context) { hooked = append(hooked, "queue") })
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	c.Get("/hang", func(ctx *Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	addr := waitForServer(t, c)
+	go http.Get(fmt.Sprintf("http://%s/hang", addr))
+	<-started
+
+	start := time.Now()
+	if err := c.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting DeadlineExceeded got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expecting shutdown to stop after the timeout, took %s", d)
+	}
+	if err := <-errc; err != ErrForcedStop {
+		t.Errorf("expecting ErrForcedStop got %v", err)
+	}
+	if len(hooked) != 2 || hooked[0] != "db" || hooked[1] != "queue" {
+		t.Errorf("expecting shutdown hooks to run in order got %v", hooked)
+	}
+	c.Shutdown(context.Background())
+	if len(hooked) != 2 {
+		t.Errorf("expecting shutdown hooks to run once got %v", hooked)
+	}
+}
+
+func TestNewServerTimeouts(t *testing.T) {
+	c := New()
+	srv := newServer(":0", c)
+	if srv.ReadTimeout != 5*time.Second || srv.WriteTimeout != 10*time.Second {
+		t.Errorf("expecting default timeouts 5s and 10s got %s and %s", srv.ReadTimeout, srv.WriteTimeout)
+	}
+
+	c.ReadTimeout = time.Second
+	c.ReadHeaderTimeout = 2 * time.Second
+	c.WriteTimeout = 3 * time.Second
+	c.IdleTimeout = 4 * time.Second
+	c.MaxHeaderBytes = 8 << 10
+	srv = newServer(":0", c)
+	if srv.ReadTimeout != time.Second ||
+		srv.ReadHeaderTimeout != 2*time.Second ||
+		srv.WriteTimeout != 3*time.Second ||
+		srv.IdleTimeout != 4*time.Second ||
+		srv.MaxHeaderBytes != 8<<10 {
+		t.Errorf("expecting the configured timeouts got %+v", srv)
+	}
+}
+
+func TestAddr(t *testing.T) {
+	c := New()
+	if c.Addr() != nil {
+		t.Errorf("expecting no address before serving got %s", c.Addr())
+	}
+	listened := make(chan net.Addr, 1)
+	c.OnListen(func(addr net.Addr) { listened <- addr })
+	addr := serveTest(t, c)
+	if got := (<-listened).String(); got != addr {
+		t.Errorf("expecting OnListen with %s got %s", addr, got)
+	}
+	if _, port, _ := net.SplitHostPort(addr); port == "0" {
+		t.Errorf("expecting the resolved port got %s", addr)
+	}
+}
+
+func TestErrForcedStop(t *testing.T) {
+	if !errors.Is(ErrForcedStop, ErrServerClosed) {
+		t.Error("expecting ErrForcedStop to wrap ErrServerClosed")
+	}
+	if ErrServerClosed != http.ErrServerClosed {
+		t.Error("expecting ErrServerClosed to be http.ErrServerClosed")
+	}
+}
+
+func TestServeListenerClosed(t *testing.T) {
+	c := New()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := c.wrap(&http.Server{Handler: c}, nil)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.serve(l)
+	}()
+	<-srv.listening
+	l.Close()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expecting net.ErrClosed got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expecting Serve to return when its listener is closed")
+	}
+}
@@ -0,0 +1,217 @@
+// Package serverless runs a Cherry application, or any http.Handler, on AWS
+// Lambda behind API Gateway (REST and HTTP APIs) or an Application Load
+// Balancer, so the same routes and middleware serve on a normal server and
+// in a function.
+//
+//	func main() {
+//		app := cherry.New()
+//		app.Get("/hello/:name", hello)
+//		lambda.Start(serverless.Handler(app))
+//	}
+//
+// Google Cloud Functions and Azure Functions call an http.Handler directly
+// and need no adapter, register the application itself.
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Request is the union of the API Gateway REST (version 1.0), API Gateway
+// HTTP (version 2.0) and ALB events.
+type Request struct {
+	Version string `json:"version"`
+
+	// version 1.0 and ALB.
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+
+	// version 2.0.
+	RawPath        string   `json:"rawPath"`
+	RawQueryString string   `json:"rawQueryString"`
+	Cookies        []string `json:"cookies"`
+
+	RequestContext  RequestContext `json:"requestContext"`
+	Body            string         `json:"body"`
+	IsBase64Encoded bool           `json:"isBase64Encoded"`
+}
+
+// RequestContext holds the parts of the request context of the events that
+// are used to build the http.Request.
+type RequestContext struct {
+	RequestID string `json:"requestId"`
+	Stage     string `json:"stage"`
+	Identity  struct {
+		SourceIP string `json:"sourceIp"`
+	} `json:"identity"`
+	HTTP struct {
+		Method   string `json:"method"`
+		Path     string `json:"path"`
+		SourceIP string `json:"sourceIp"`
+	} `json:"http"`
+	ELB *struct {
+		TargetGroupArn string `json:"targetGroupArn"`
+	} `json:"elb"`
+}
+
+// Response is the response of the function, its fields are understood by
+// all of the supported event sources.
+type Response struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription,omitempty"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Cookies           []string            `json:"cookies,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// Handler returns the function handler of h, to be passed to lambda.Start.
+// The deadline of the invocation is the deadline of the request context.
+func Handler(h http.Handler) func(ctx context.Context, event json.RawMessage) (*Response, error) {
+	return func(ctx context.Context, event json.RawMessage) (*Response, error) {
+		var req Request
+		if err := json.Unmarshal(event, &req); err != nil {
+			return nil, fmt.Errorf("serverless: decoding event: %w", err)
+		}
+		return Serve(ctx, h, &req)
+	}
+}
+
+// Serve translates req to an http.Request, serves it with h and translates
+// the response back.
+func Serve(ctx context.Context, h http.Handler, req *Request) (*Response, error) {
+	r, err := NewRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return newResponse(req, rec.Result().StatusCode, rec.Header(), rec.Body.Bytes()), nil
+}
+
+// NewRequest returns the http.Request of the event req.
+func NewRequest(ctx context.Context, req *Request) (*http.Request, error) {
+	v2 := req.Version == "2.0"
+	method, path, remote := req.HTTPMethod, req.Path, req.RequestContext.Identity.SourceIP
+	query := url.Values{}
+	if v2 {
+		method, path, remote = req.RequestContext.HTTP.Method, req.RawPath, req.RequestContext.HTTP.SourceIP
+		q, err := url.ParseQuery(req.RawQueryString)
+		if err != nil {
+			return nil, fmt.Errorf("serverless: invalid query: %w", err)
+		}
+		query = q
+	} else if len(req.MultiValueQueryStringParameters) > 0 {
+		for k, vs := range req.MultiValueQueryStringParameters {
+			query[k] = append(query[k], vs...)
+		}
+	} else {
+		for k, v := range req.QueryStringParameters {
+			query.Set(k, v)
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("serverless: invalid base64 body: %w", err)
+		}
+		body = b
+	}
+
+	u := &url.URL{Path: path, RawQuery: query.Encode()}
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("serverless: %w", err)
+	}
+	for k, v := range req.Headers {
+		r.Header.Set(k, v)
+	}
+	for k, vs := range req.MultiValueHeaders {
+		r.Header.Del(k)
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	if len(req.Cookies) > 0 {
+		r.Header.Set("Cookie", strings.Join(req.Cookies, "; "))
+	}
+	r.Host = r.Header.Get("Host")
+	r.URL.Host = r.Host
+	r.RequestURI = u.RequestURI()
+	r.ContentLength = int64(len(body))
+	if remote != "" {
+		r.RemoteAddr = remote + ":0"
+	}
+	if req.RequestContext.RequestID != "" && r.Header.Get("X-Request-Id") == "" {
+		r.Header.Set("X-Request-Id", req.RequestContext.RequestID)
+	}
+	return r, nil
+}
+
+func newResponse(req *Request, code int, header http.Header, body []byte) *Response {
+	resp := &Response{StatusCode: code}
+	if req.RequestContext.ELB != nil {
+		resp.StatusDescription = fmt.Sprintf("%d %s", code, http.StatusText(code))
+	}
+	if req.Version == "2.0" {
+		resp.Cookies = header.Values("Set-Cookie")
+		header = header.Clone()
+		header.Del("Set-Cookie")
+	}
+	// the event sources accept either form, they are answered in the form
+	// the request came in.
+	if len(req.MultiValueHeaders) > 0 {
+		resp.MultiValueHeaders = map[string][]string(header)
+	} else {
+		resp.Headers = make(map[string]string, len(header))
+		for k, vs := range header {
+			resp.Headers[k] = strings.Join(vs, ",")
+		}
+	}
+	if isBinary(header, body) {
+		resp.Body = base64.StdEncoding.EncodeToString(body)
+		resp.IsBase64Encoded = true
+	} else {
+		resp.Body = string(body)
+	}
+	return resp
+}
+
+// isBinary reports whether body must be base64 encoded.
+func isBinary(header http.Header, body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return true
+	}
+	ct := strings.ToLower(header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(ct, "text/"),
+		strings.Contains(ct, "json"),
+		strings.Contains(ct, "xml"),
+		strings.Contains(ct, "javascript"),
+		strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+		return !utf8.Valid(body)
+	}
+	return true
+}
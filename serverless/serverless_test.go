@@ -0,0 +1,106 @@
+package serverless
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pooulad/cherry"
+)
+
+func newApp() *cherry.Cherry {
+	app := cherry.New()
+	app.Get("/hello/:name", func(ctx *cherry.Context) error {
+		http.SetCookie(ctx.Response(), &http.Cookie{Name: "seen", Value: "1"})
+		return ctx.Text(http.StatusOK, "hello "+ctx.Param("name")+" "+ctx.Query("lang")+" "+ctx.Header("Cookie"))
+	})
+	app.Post("/echo", func(ctx *cherry.Context) error {
+		b, _ := io.ReadAll(ctx.Request().Body)
+		ctx.Response().Header().Set("Content-Type", "application/octet-stream")
+		ctx.Response().Write(b)
+		return nil
+	})
+	app.Get("/deadline", func(ctx *cherry.Context) error {
+		if _, ok := ctx.Request().Context().Deadline(); !ok {
+			return ctx.Text(http.StatusOK, "no deadline")
+		}
+		return ctx.Text(http.StatusOK, "deadline")
+	})
+	return app
+}
+
+func invoke(t *testing.T, event string) *Response {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := Handler(newApp())(ctx, json.RawMessage(event))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRESTAPI(t *testing.T) {
+	resp := invoke(t, `{
+		"httpMethod": "GET",
+		"path": "/hello/toby",
+		"headers": {"Cookie": "a=b"},
+		"queryStringParameters": {"lang": "en"},
+		"requestContext": {"requestId": "abc", "identity": {"sourceIp": "1.2.3.4"}}
+	}`)
+	if resp.StatusCode != http.StatusOK || resp.Body != "hello toby en a=b" {
+		t.Errorf("unexpected response %d %q", resp.StatusCode, resp.Body)
+	}
+	if resp.Headers["Set-Cookie"] != "seen=1" || resp.StatusDescription != "" {
+		t.Errorf("unexpected headers %v", resp.Headers)
+	}
+}
+
+func TestHTTPAPI(t *testing.T) {
+	resp := invoke(t, `{
+		"version": "2.0",
+		"rawPath": "/hello/toby",
+		"rawQueryString": "lang=nl",
+		"cookies": ["a=b", "c=d"],
+		"requestContext": {"http": {"method": "GET", "sourceIp": "1.2.3.4"}}
+	}`)
+	if resp.Body != "hello toby nl a=b; c=d" {
+		t.Errorf("unexpected body %q", resp.Body)
+	}
+	if len(resp.Cookies) != 1 || resp.Cookies[0] != "seen=1" || resp.Headers["Set-Cookie"] != "" {
+		t.Errorf("expecting the cookies apart from the headers got %v %v", resp.Cookies, resp.Headers)
+	}
+
+	resp = invoke(t, `{"version": "2.0", "rawPath": "/deadline", "requestContext": {"http": {"method": "GET"}}}`)
+	if resp.Body != "deadline" {
+		t.Errorf("expecting the deadline of the invocation got %q", resp.Body)
+	}
+}
+
+func TestALBBinary(t *testing.T) {
+	body := []byte{0, 1, 2, 255}
+	resp := invoke(t, `{
+		"httpMethod": "POST",
+		"path": "/echo",
+		"multiValueHeaders": {"Content-Type": ["application/octet-stream"]},
+		"body": "`+base64.StdEncoding.EncodeToString(body)+`",
+		"isBase64Encoded": true,
+		"requestContext": {"elb": {"targetGroupArn": "arn"}}
+	}`)
+	if resp.StatusDescription != "200 OK" {
+		t.Errorf("expecting a status description for an ALB got %q", resp.StatusDescription)
+	}
+	if !resp.IsBase64Encoded || resp.Body != base64.StdEncoding.EncodeToString(body) {
+		t.Errorf("expecting the body base64 encoded got %q", resp.Body)
+	}
+	if resp.MultiValueHeaders["Content-Type"][0] != "application/octet-stream" {
+		t.Errorf("expecting multi value headers got %v", resp.MultiValueHeaders)
+	}
+
+	if _, err := Handler(newApp())(context.Background(), json.RawMessage(`{"body": "!", "isBase64Encoded": true}`)); err == nil {
+		t.Error("expecting an error for an invalid base64 body")
+	}
+}
@@ -0,0 +1,320 @@
+package cherry
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionStore is the storage backend of the sessions.
+type SessionStore interface {
+	// Get returns the data of the session id.
+	Get(id string) (map[string]any, bool)
+	// Set stores the data of the session id for the duration of ttl.
+	Set(id string, data map[string]any, ttl time.Duration)
+	// Delete removes the session id.
+	Delete(id string)
+}
+
+// MemorySessionStore is an in-memory SessionStore. Expired sessions are
+// evicted lazily.
+type MemorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]*memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	data    map[string]any
+	expires time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]*memorySession{}, lastSweep: time.Now()}
+}
+
+// Get implements SessionStore.
+func (s *MemorySessionStore) Get(id string) (map[string]any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[id]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return copyData(e.data), true
+}
+
+// Set implements SessionStore.
+func (s *MemorySessionStore) Set(id string, data map[string]any, ttl time.Duration) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = &memorySession{data: copyData(data), expires: now.Add(ttl)}
+	if now.Sub(s.lastSweep) > time.Minute {
+		for id, e := range s.sessions {
+			if now.After(e.expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
+// Delete implements SessionStore.
+func (s *MemorySessionStore) Delete(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+func copyData(data map[string]any) map[string]any {
+	m := make(map[string]any, len(data))
+	for k, v := range data {
+		m[k] = v
+	}
+	return m
+}
+
+// SessionConfig configures the Sessions middleware.
+type SessionConfig struct {
+	// Store keeps the data of the sessions. The default is a
+	// MemorySessionStore
+	Store SessionStore
+
+	// Cookie is the name of the cookie that holds the session id. The
+	// default is "session"
+	Cookie string
+
+	// MaxAge is the lifetime of a session from the time it was last
+	// modified, the cookie is sent again with each change. The default is 24
+	// hours
+	MaxAge time.Duration
+
+	// Path and Domain scope the cookie. The default path is "/"
+	Path   string
+	Domain string
+
+	// Secure restricts the cookie to HTTPS. The default is to set it on the
+	// requests served over TLS only
+	Secure bool
+
+	// SameSite is the SameSite attribute of the cookie. The default is
+	// http.SameSiteLaxMode
+	SameSite http.SameSite
+}
+
+// Sessions returns a middleware Handler that loads the session of a request,
+// identified by a cookie, for Context.Session and saves it after the handler
+// has returned when it was modified. A session and its cookie are only
+// created when a value is set.
+//
+// app.Use(cherry.Sessions(cherry.SessionConfig{Secure: true})).
+func Sessions(cfg SessionConfig) Handler {
+	if cfg.Store == nil {
+		cfg.Store = NewMemorySessionStore()
+	}
+	if cfg.Cookie == "" {
+		cfg.Cookie = "session"
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 24 * time.Hour
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	return func(ctx *Context) error {
+		s := &Session{ctx: ctx, cfg: &cfg}
+		WithValue(ctx, s)
+		ctx.onFinish(s.save)
+		return nil
+	}
+}
+
+// Session is the data that a server keeps for a client between its
+// requests. It is loaded on the first use and is safe for concurrent use by
+// the goroutines of a request.
+type Session struct {
+	mu  sync.Mutex
+	ctx *Context
+	// cfg is nil for the sessions of the requests without the Sessions
+	// middleware, which are not persisted.
+	cfg    *SessionConfig
+	id     string
+	data   map[string]any
+	loaded bool
+	dirty  bool
+	// refreshed tells that the cookie was sent in the response.
+	refreshed bool
+}
+
+// Session returns the session of the request. Without the Sessions
+// middleware the session lives for the request only.
+//
+// ctx.Session().Set("cart", cart).
+func (c *Context) Session() *Session {
+	if s, ok := Value[*Session](c); ok {
+		return s
+	}
+	s := &Session{ctx: c}
+	WithValue(c, s)
+	return s
+}
+
+// ID returns the id of the session, which is empty until a value is set.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.id
+}
+
+// Get returns the value stored under key, or nil.
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.data[key]
+}
+
+// Set stores the value under key, creating the session if needed.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if s.id == "" {
+		s.issue()
+	}
+	s.data[key] = value
+	s.touch()
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if _, ok := s.data[key]; ok {
+		delete(s.data, key)
+		s.touch()
+	}
+}
+
+// Regenerate gives the session a new id and keeps its data, the old id is
+// no longer valid. It must be called when the privileges of a client
+// change, like when logging in, so an id that an attacker planted in the
+// browser of a victim beforehand (session fixation) does not get the
+// privileges. Login and the RememberMe middleware do it automatically.
+//
+// ctx.Session().Regenerate().
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	old := s.id
+	s.issue()
+	if s.cfg != nil {
+		// the data is stored under the new id before the old one is removed,
+		// so concurrent requests never see the session missing.
+		s.cfg.Store.Set(s.id, s.data, s.cfg.MaxAge)
+		if old != "" {
+			s.cfg.Store.Delete(old)
+		}
+	}
+	s.dirty = false
+}
+
+// Destroy removes the session and its data, and expires its cookie.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if s.id != "" && s.cfg != nil {
+		s.cfg.Store.Delete(s.id)
+		s.setCookie("", -1)
+	}
+	s.id = ""
+	s.data = map[string]any{}
+	s.dirty = false
+}
+
+// load reads the session of the cookie of the request.
+func (s *Session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.data = map[string]any{}
+	if s.cfg == nil {
+		return
+	}
+	cookie, err := s.ctx.Request().Cookie(s.cfg.Cookie)
+	if err != nil || cookie.Value == "" {
+		return
+	}
+	if data, ok := s.cfg.Store.Get(cookie.Value); ok {
+		s.id = cookie.Value
+		s.data = data
+	}
+}
+
+// issue gives the session a new random id and sends it in a cookie.
+func (s *Session) issue() {
+	s.id = randomHex(32)
+	if s.cfg != nil {
+		s.setCookie(s.id, int(s.cfg.MaxAge/time.Second))
+	}
+}
+
+// touch marks the session as modified. The store keeps it for MaxAge from
+// the save, so the cookie is sent again with the same lifetime.
+func (s *Session) touch() {
+	s.dirty = true
+	if !s.refreshed && s.id != "" && s.cfg != nil {
+		s.setCookie(s.id, int(s.cfg.MaxAge/time.Second))
+	}
+}
+
+// save stores the session when it was modified.
+func (s *Session) save() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dirty && s.id != "" && s.cfg != nil {
+		s.cfg.Store.Set(s.id, s.data, s.cfg.MaxAge)
+		s.dirty = false
+	}
+}
+
+// setCookie sets the session cookie on the response.
+func (s *Session) setCookie(value string, maxAge int) {
+	cfg := s.cfg
+	s.refreshed = value != ""
+	replaceCookie(s.ctx.Response(), &http.Cookie{
+		Name:     cfg.Cookie,
+		Value:    value,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		MaxAge:   maxAge,
+		Secure:   cfg.Secure || s.ctx.Request().TLS != nil,
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+	})
+}
+
+// replaceCookie sets a cookie on the response, replacing the cookie of the
+// same name set earlier in the request.
+func replaceCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	h := w.Header()
+	cookies := h.Values("Set-Cookie")
+	h.Del("Set-Cookie")
+	for _, c := range cookies {
+		if !strings.HasPrefix(c, cookie.Name+"=") {
+			h.Add("Set-Cookie", c)
+		}
+	}
+	http.SetCookie(w, cookie)
+}
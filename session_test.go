@@ -0,0 +1,125 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	store := NewMemorySessionStore()
+	c := New()
+	c.Use(Sessions(SessionConfig{Store: store}))
+	c.Get("/visit", func(ctx *Context) error {
+		n, _ := ctx.Session().Get("visits").(int)
+		ctx.Session().Set("visits", n+1)
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	c.Get("/read", func(ctx *Context) error {
+		n, _ := ctx.Session().Get("visits").(int)
+		return ctx.JSON(http.StatusOK, n)
+	})
+	c.Get("/logout", func(ctx *Context) error {
+		ctx.Session().Destroy()
+		return nil
+	})
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/read", nil))
+	if rw.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expecting no session to be created by a read got %s", rw.Header().Get("Set-Cookie"))
+	}
+
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/visit", nil))
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expecting a session cookie got %v", cookies)
+	}
+	cookie := cookies[0]
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "/visit", nil)
+		r.AddCookie(cookie)
+		rw = httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		cookies := rw.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Value != cookie.Value || cookies[0].MaxAge != cookie.MaxAge {
+			t.Errorf("expecting the session cookie to be refreshed got %v", cookies)
+		}
+	}
+	r := httptest.NewRequest("GET", "/read", nil)
+	r.AddCookie(cookie)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expecting the session cookie to be kept by a read got %s", rw.Header().Get("Set-Cookie"))
+	}
+	if data, ok := store.Get(cookie.Value); !ok || data["visits"] != 3 {
+		t.Errorf("expecting 3 visits to be stored got %v", data)
+	}
+
+	r = httptest.NewRequest("GET", "/logout", nil)
+	r.AddCookie(cookie)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if cookies := rw.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expecting the cookie to be expired got %v", cookies)
+	}
+	if _, ok := store.Get(cookie.Value); ok {
+		t.Error("expecting the session to be removed from the store")
+	}
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	store := NewMemorySessionStore()
+	c := New()
+	c.Use(Sessions(SessionConfig{Store: store, Cookie: "sid"}))
+	c.Post("/login", func(ctx *Context) error {
+		s := ctx.Session()
+		s.Set("cart", "book")
+		s.Regenerate()
+		s.Set("user", "ann")
+		return nil
+	})
+
+	store.Set("fixed", map[string]any{"cart": "pen"}, time.Hour)
+	r := httptest.NewRequest("POST", "/login", nil)
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "fixed"})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+
+	setCookies := rw.Header().Values("Set-Cookie")
+	if len(setCookies) != 1 || !strings.HasPrefix(setCookies[0], "sid=") {
+		t.Fatalf("expecting a single session cookie got %v", setCookies)
+	}
+	id := rw.Result().Cookies()[0].Value
+	if id == "fixed" || len(id) != 64 {
+		t.Errorf("expecting a new session id got %q", id)
+	}
+	if _, ok := store.Get("fixed"); ok {
+		t.Error("expecting the old session id to be invalidated")
+	}
+	data, _ := store.Get(id)
+	if data["cart"] != "book" || data["user"] != "ann" {
+		t.Errorf("expecting the data to be kept under the new id got %v", data)
+	}
+}
+
+func TestSessionWithoutMiddleware(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		ctx.Session().Set("a", 1)
+		if ctx.Session().Get("a") != 1 {
+			t.Error("expecting the value to live for the request")
+		}
+		return nil
+	})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if rw.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expecting no cookie without the middleware got %s", rw.Header().Get("Set-Cookie"))
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cherry
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals trigger a graceful shutdown.
+var defaultSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// reloadSignals trigger a reload of the binary.
+var reloadSignals = []os.Signal{syscall.SIGUSR2}
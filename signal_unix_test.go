@@ -0,0 +1,92 @@
+//go:build !windows
+
+package cherry
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSetSignals(t *testing.T) {
+	c := New()
+	c.SetSignals(syscall.SIGUSR1)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	waitForServer(t, c)
+	// give closeNotify the time to subscribe to the signals.
+	time.Sleep(20 * time.Millisecond)
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	select {
+	case err := <-errc:
+		if err != ErrServerClosed {
+			t.Errorf("expecting ErrServerClosed got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expecting the server to stop on SIGUSR1")
+	}
+}
+
+func TestDisableSignalHandling(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	if len(c.signals) != 0 {
+		t.Errorf("expecting no signals got %v", c.signals)
+	}
+	srv := &server{signals: c.signals}
+	done := make(chan struct{})
+	go func() {
+		srv.closeNotify(make(chan struct{}))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expecting closeNotify to return without signals")
+	}
+}
+
+func TestReloadSignalOncePerApp(t *testing.T) {
+	var reloads atomic.Int32
+	reload := func() error {
+		reloads.Add(1)
+		return errors.New("reload failed")
+	}
+	var srvs servers
+	a := &server{signals: defaultSignals, reload: reload}
+	b := &server{signals: defaultSignals, reload: reload}
+	srvs.add(a)
+	srvs.add(b)
+	// give watchReload the time to subscribe to the signals.
+	time.Sleep(20 * time.Millisecond)
+
+	for n := int32(1); n <= 2; n++ {
+		syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+		for i := 0; reloads.Load() != n; i++ {
+			if i == 100 {
+				t.Fatalf("expecting %d reloads got %d", n, reloads.Load())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := reloads.Load(); n != 2 {
+		t.Errorf("expecting one reload per signal with two servers got %d", n)
+	}
+
+	srvs.remove(a)
+	if srvs.stopReload == nil {
+		t.Error("expecting the signals to be watched while a server runs")
+	}
+	srvs.remove(b)
+	if srvs.stopReload != nil {
+		t.Error("expecting the watch to end with the last server")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package cherry
+
+import "os"
+
+// defaultSignals trigger a graceful shutdown.
+var defaultSignals = []os.Signal{os.Interrupt}
+
+// reloadSignals is empty, Windows has no signal to reload a binary.
+var reloadSignals []os.Signal
@@ -0,0 +1,341 @@
+package cherry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a server-sent event.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry tells the client how long to wait before it reconnects.
+	Retry time.Duration
+}
+
+// WriteTo writes the event in the text/event-stream format. Every line of
+// the data becomes a data field.
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return buf.WriteTo(w)
+}
+
+// SSE writes a server-sent event to the client and flushes it. The first
+// event sets the headers of the stream.
+//
+// ctx.SSE(cherry.Event{Event: "progress", Data: "42"}).
+func (c *Context) SSE(e Event) error {
+	c.extendWriteDeadline()
+	startEventStream(c.Response())
+	if _, err := e.WriteTo(c.Response()); err != nil {
+		return err
+	}
+	return flush(c.Response())
+}
+
+// startEventStream writes the headers of an event stream, unless they are
+// already written.
+func startEventStream(rw http.ResponseWriter) {
+	h := rw.Header()
+	if h.Get("Content-Type") == "text/event-stream" {
+		return
+	}
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// keeps proxies like nginx from buffering the stream.
+	h.Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+}
+
+func flush(rw http.ResponseWriter) error {
+	err := http.NewResponseController(rw).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// extendWriteDeadline moves the write deadline of the connection a
+// WriteTimeout of the application from now, or removes it without a
+// timeout. The deadline of the server counts from the request, so long
+// running responses extend it before they write.
+func (c *Context) extendWriteDeadline() {
+	var deadline time.Time
+	if c.cherry != nil && c.cherry.WriteTimeout > 0 {
+		deadline = time.Now().Add(c.cherry.WriteTimeout)
+	}
+	http.NewResponseController(c.Response()).SetWriteDeadline(deadline)
+}
+
+// BrokerOptions configures a Broker. Zero values are replaced by sensible
+// defaults.
+type BrokerOptions struct {
+	// History is the number of events of a topic that are kept to replay
+	// to clients that reconnect. Defaults to 100.
+	History int
+
+	// Buffer is the number of events queued for a subscriber. A subscriber
+	// that falls further behind is disconnected, it catches up by replaying
+	// the events when it reconnects. Defaults to 32.
+	Buffer int
+
+	// KeepAlive is the interval of the comments that keep idle streams
+	// open. Defaults to 15 seconds.
+	KeepAlive time.Duration
+}
+
+// Broker publishes server-sent events on named topics to the subscribed
+// clients. Clients that reconnect with a Last-Event-ID header receive the
+// events they missed.
+type Broker struct {
+	opts BrokerOptions
+
+	mu     sync.Mutex
+	seq    uint64
+	topics map[string]*topic
+	closed bool
+	done   chan struct{}
+}
+
+type topic struct {
+	history []brokerEvent
+	subs    map[*subscriber]struct{}
+}
+
+// brokerEvent is a published event with the sequence number of its ID.
+type brokerEvent struct {
+	seq uint64
+	Event
+}
+
+type subscriber struct {
+	topics  []string
+	events  chan Event
+	dropped chan struct{}
+}
+
+// Broker returns a new Broker, which disconnects its subscribers when a
+// shutdown of the application begins so that their connections can drain.
+//
+// notifications := app.Broker(cherry.BrokerOptions{History: 50}).
+func (c *Cherry) Broker(opts BrokerOptions) *Broker {
+	b := NewBroker(opts)
+	c.OnStop(b.Close)
+	return b
+}
+
+// NewBroker returns a new Broker that is not tied to an application.
+func NewBroker(opts BrokerOptions) *Broker {
+	if opts.History <= 0 {
+		opts.History = 100
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 32
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 15 * time.Second
+	}
+	return &Broker{
+		opts:   opts,
+		topics: map[string]*topic{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish sends e to the subscribers of the topic and returns its ID. The
+// IDs are assigned by the broker, the ID of e is ignored.
+func (b *Broker) Publish(name string, e Event) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ""
+	}
+	b.seq++
+	e.ID = strconv.FormatUint(b.seq, 10)
+	t := b.topic(name)
+	t.history = append(t.history, brokerEvent{seq: b.seq, Event: e})
+	if n := len(t.history) - b.opts.History; n > 0 {
+		t.history = append(t.history[:0], t.history[n:]...)
+	}
+	for s := range t.subs {
+		select {
+		case s.events <- e:
+		default:
+			b.drop(s)
+		}
+	}
+	return e.ID
+}
+
+// Subscribers returns the number of clients subscribed to the topic.
+func (b *Broker) Subscribers(name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[name]; ok {
+		return len(t.subs)
+	}
+	return 0
+}
+
+// Handler returns a Handler that streams the events of the topics.
+//
+// app.Get("/events", notifications.Handler("orders", "alerts")).
+func (b *Broker) Handler(topics ...string) Handler {
+	return func(ctx *Context) error {
+		return b.Serve(ctx, topics...)
+	}
+}
+
+// Serve streams the events of the topics to the client until it
+// disconnects or the broker is closed. The events published since the
+// Last-Event-ID of the request are replayed first. Handlers call Serve to
+// choose the topics per request.
+func (b *Broker) Serve(ctx *Context, topics ...string) error {
+	rw := ctx.Response()
+	s, replay := b.subscribe(topics, ctx.Header("Last-Event-ID"))
+	if s == nil {
+		return NewHTTPError(http.StatusServiceUnavailable)
+	}
+	defer b.unsubscribe(s)
+
+	ctx.extendWriteDeadline()
+	startEventStream(rw)
+	for _, e := range replay {
+		if _, err := e.WriteTo(rw); err != nil {
+			return nil
+		}
+	}
+	if err := flush(rw); err != nil {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(b.opts.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case e := <-s.events:
+			ctx.extendWriteDeadline()
+			_, err = e.WriteTo(rw)
+		case <-keepAlive.C:
+			ctx.extendWriteDeadline()
+			_, err = io.WriteString(rw, ": keep-alive\n\n")
+		case <-s.dropped:
+			return nil
+		case <-b.done:
+			return nil
+		case <-ctx.Request().Context().Done():
+			return nil
+		}
+		if err == nil {
+			err = flush(rw)
+		}
+		if err != nil {
+			// the client is gone.
+			return nil
+		}
+	}
+}
+
+// Close disconnects all of the subscribers, events published afterwards
+// are dropped.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	clear(b.topics)
+}
+
+// subscribe registers a subscriber of the topics and returns the events to
+// replay, it returns nil when the broker is closed. The replay is collected
+// under the same lock as the registration, so no event is missed or sent
+// twice.
+func (b *Broker) subscribe(topics []string, lastID string) (*subscriber, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil
+	}
+	s := &subscriber{
+		topics:  topics,
+		events:  make(chan Event, b.opts.Buffer),
+		dropped: make(chan struct{}),
+	}
+	var replay []brokerEvent
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	for _, name := range topics {
+		t := b.topic(name)
+		t.subs[s] = struct{}{}
+		if err != nil {
+			continue
+		}
+		for _, e := range t.history {
+			if e.seq > last {
+				replay = append(replay, e)
+			}
+		}
+	}
+	sort.Slice(replay, func(i, j int) bool { return replay[i].seq < replay[j].seq })
+	events := make([]Event, len(replay))
+	for i, e := range replay {
+		events[i] = e.Event
+	}
+	return s, events
+}
+
+func (b *Broker) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(s)
+}
+
+// drop disconnects a subscriber that fell behind.
+func (b *Broker) drop(s *subscriber) {
+	b.remove(s)
+	close(s.dropped)
+}
+
+func (b *Broker) remove(s *subscriber) {
+	for _, name := range s.topics {
+		if t, ok := b.topics[name]; ok {
+			delete(t.subs, s)
+			if len(t.subs) == 0 && len(t.history) == 0 {
+				delete(b.topics, name)
+			}
+		}
+	}
+}
+
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{subs: map[*subscriber]struct{}{}}
+		b.topics[name] = t
+	}
+	return t
+}
@@ -0,0 +1,163 @@
+package cherry
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventWriteTo(t *testing.T) {
+	var b strings.Builder
+	Event{ID: "1", Event: "update", Data: "a\nb", Retry: time.Second}.WriteTo(&b)
+	expect := "id: 1\nevent: update\nretry: 1000\ndata: a\ndata: b\n\n"
+	if b.String() != expect {
+		t.Errorf("expecting %q got %q", expect, b.String())
+	}
+}
+
+func TestContextSSE(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		ctx.SSE(Event{Data: "one"})
+		return ctx.SSE(Event{Data: "two"})
+	})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if ct := rw.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expecting text/event-stream got %s", ct)
+	}
+	if rw.Body.String() != "data: one\n\ndata: two\n\n" {
+		t.Errorf("unexpected body %q", rw.Body.String())
+	}
+}
+
+// readEvents reads the data of n events from the stream.
+func readEvents(t *testing.T, r *bufio.Reader, n int) []string {
+	var data []string
+	for len(data) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("expecting %d events got %v: %v", n, data, err)
+		}
+		if d, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, strings.TrimSpace(d))
+		}
+	}
+	return data
+}
+
+func subscribe(t *testing.T, url, lastID string) (*bufio.Reader, func()) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bufio.NewReader(res.Body), func() { res.Body.Close() }
+}
+
+func waitSubscribers(t *testing.T, b *Broker, topic string, n int) {
+	for i := 0; b.Subscribers(topic) != n; i++ {
+		if i == 100 {
+			t.Fatalf("expecting %d subscribers got %d", n, b.Subscribers(topic))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	c := New()
+	b := c.Broker(BrokerOptions{})
+	c.Get("/events", b.Handler("orders", "alerts"))
+	addr := serveTest(t, c)
+
+	r, done := subscribe(t, "http://"+addr+"/events", "")
+	waitSubscribers(t, b, "orders", 1)
+	b.Publish("orders", Event{Data: "order 1"})
+	b.Publish("other", Event{Data: "ignored"})
+	b.Publish("alerts", Event{Data: "alert 1"})
+	if data := readEvents(t, r, 2); data[0] != "order 1" || data[1] != "alert 1" {
+		t.Errorf("unexpected events %v", data)
+	}
+	done()
+	waitSubscribers(t, b, "orders", 0)
+
+	b.Publish("orders", Event{Data: "order 2"})
+	r, done = subscribe(t, "http://"+addr+"/events", "1")
+	defer done()
+	if data := readEvents(t, r, 2); data[0] != "alert 1" || data[1] != "order 2" {
+		t.Errorf("expecting the missed events to be replayed got %v", data)
+	}
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expecting the stream to end on shutdown got %v", err)
+	}
+	io.ReadAll(r)
+	if b.Subscribers("orders") != 0 || b.Publish("orders", Event{}) != "" {
+		t.Error("expecting the broker to be closed on shutdown")
+	}
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(BrokerOptions{Buffer: 1, History: 2})
+	s, _ := b.subscribe([]string{"news"}, "")
+	b.Publish("news", Event{Data: "1"})
+	b.Publish("news", Event{Data: "2"})
+	select {
+	case <-s.dropped:
+	default:
+		t.Fatal("expecting the subscriber to be dropped")
+	}
+	if b.Subscribers("news") != 0 {
+		t.Error("expecting no subscribers")
+	}
+	b.Publish("news", Event{Data: "3"})
+	_, replay := b.subscribe([]string{"news"}, "0")
+	if len(replay) != 2 || replay[0].Data != "2" || replay[1].ID != "3" {
+		t.Errorf("expecting the last 2 events to be replayed got %+v", replay)
+	}
+}
+
+// serveWriteTimeout serves c on a local port with the WriteTimeout of c.
+func serveWriteTimeout(t *testing.T, c *Cherry) string {
+	c.DisableSignalHandling()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c, WriteTimeout: c.WriteTimeout})
+	}()
+	addr := waitForServer(t, c)
+	t.Cleanup(func() {
+		c.Close()
+		<-errc
+	})
+	return addr
+}
+
+func TestBrokerOutlivesWriteTimeout(t *testing.T) {
+	c := New()
+	c.WriteTimeout = 200 * time.Millisecond
+	b := c.Broker(BrokerOptions{KeepAlive: 50 * time.Millisecond})
+	c.Get("/events", b.Handler("news"))
+	addr := serveWriteTimeout(t, c)
+
+	r, done := subscribe(t, "http://"+addr+"/events", "")
+	defer done()
+	waitSubscribers(t, b, "news", 1)
+	deadline := time.Now().Add(4 * c.WriteTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Fatalf("expecting the stream to outlive the WriteTimeout got %v", err)
+		}
+	}
+	b.Publish("news", Event{Data: "late"})
+	if data := readEvents(t, r, 1); data[0] != "late" {
+		t.Errorf("unexpected event %v", data)
+	}
+}
@@ -0,0 +1,63 @@
+package cherry
+
+import "sync"
+
+// State holds the long-lived dependencies of an application, like a
+// database pool, that are registered once in main and used by the handlers.
+// It is shared by the application and all of its groups and safe for
+// concurrent use.
+type State struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+func newState() *State {
+	return &State{values: map[string]any{}}
+}
+
+// State returns the state of the application.
+//
+// app.State().Set("db", pool).
+func (c *Cherry) State() *State {
+	return c.state
+}
+
+// Set stores v under key, replacing the previous value.
+func (s *State) Set(key string, v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = v
+}
+
+// Get returns the value stored under key.
+func (s *State) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Delete removes the value stored under key.
+func (s *State) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// StateValue returns the value stored under key in the state of the
+// application that serves ctx. The second result is false when there is no
+// value under key or when it is not a T.
+//
+// db, ok := cherry.StateValue[*sql.DB](ctx, "db").
+func StateValue[T any](ctx *Context, key string) (T, bool) {
+	var zero T
+	if ctx.cherry == nil {
+		return zero, false
+	}
+	v, ok := ctx.cherry.state.Get(key)
+	if !ok {
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
+}
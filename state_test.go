@@ -0,0 +1,43 @@
+package cherry
+
+import (
+	"net/http"
+	"testing"
+)
+
+type testDatastore struct {
+	name string
+}
+
+func TestState(t *testing.T) {
+	c := New()
+	c.State().Set("db", &testDatastore{"mydatabase"})
+	c.State().Set("name", "cherry")
+
+	admin := c.Group("/admin")
+	admin.Get("/", func(ctx *Context) error {
+		db, ok := StateValue[*testDatastore](ctx, "db")
+		if !ok {
+			t.Error("expecting the datastore in the state")
+			return nil
+		}
+		if _, ok := StateValue[int](ctx, "name"); ok {
+			t.Error("expecting a value of the wrong type to be missing")
+		}
+		if _, ok := StateValue[string](ctx, "missing"); ok {
+			t.Error("expecting a missing key to be missing")
+		}
+		return ctx.Text(http.StatusOK, db.name)
+	})
+
+	code, body := doRequest(t, "GET", "/admin", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "mydatabase" {
+		t.Errorf("expecting mydatabase got %s", body)
+	}
+
+	c.State().Delete("db")
+	if _, ok := c.State().Get("db"); ok {
+		t.Error("expecting db to be deleted")
+	}
+}
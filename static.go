@@ -0,0 +1,441 @@
+package cherry
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticOption configures a static file route.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	header        http.Header
+	middleware    []Handler
+	precompressed bool
+	index         []string
+	listing       *template.Template
+	notFound      Handler
+	fallThrough   bool
+	accessCheck   func(ctx *Context, name string) error
+	// offload names the header that hands the file over to the reverse
+	// proxy, offloadPath the location the file name is appended to.
+	offload     string
+	offloadPath string
+}
+
+// listingTemplate is the default template of WithDirectoryListing.
+var listingTemplate = template.Must(template.New("listing").Parse(`<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<ul>
+{{- if ne .Path "/"}}
+<li><a href="../">../</a></li>
+{{- end}}
+{{- range .Entries}}
+<li><a href="{{.URL}}">{{.Name}}{{if .IsDir}}/{{end}}</a></li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+// DirectoryListing is the data passed to a directory listing template.
+type DirectoryListing struct {
+	// Path is the path of the directory relative to the static prefix.
+	Path    string
+	Entries []DirectoryEntry
+}
+
+// DirectoryEntry is a single file or directory in a DirectoryListing.
+type DirectoryEntry struct {
+	Name    string
+	URL     string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// precompressedFiles maps the supported content-encodings to the extension of
+// their precompressed sibling files, in order of preference.
+var precompressedFiles = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// WithMaxAge sets a public Cache-Control header with the given max-age on
+// the served files. Fingerprinted Assets keep their immutable Cache-Control.
+func WithMaxAge(d time.Duration) StaticOption {
+	return WithHeader("Cache-Control", "public, max-age="+strconv.FormatInt(int64(d/time.Second), 10))
+}
+
+// WithHeader sets a header on every successfully served file, like a CORS
+// header for fonts.
+func WithHeader(key, value string) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.header.Set(key, value)
+	}
+}
+
+// WithMiddleware adds middleware that only runs for the static route, after
+// the middleware registered with Use.
+func WithMiddleware(handlers ...Handler) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.middleware = append(cfg.middleware, handlers...)
+	}
+}
+
+// WithPrecompressed serves the precompressed sibling of a file, like
+// app.js.br or app.js.gz, when the client accepts its encoding. Brotli is
+// preferred over zstd and gzip.
+func WithPrecompressed() StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.precompressed = true
+	}
+}
+
+// WithIndex sets the file names that are served when a directory is
+// requested, the first one that exists wins. Defaults to index.html.
+func WithIndex(names ...string) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.index = names
+	}
+}
+
+// WithDirectoryListing enables the listing of directories without an index
+// file, rendered with tmpl which receives a DirectoryListing. When tmpl is
+// nil a plain html list is rendered. Directory listings are disabled by
+// default.
+func WithDirectoryListing(tmpl *template.Template) StaticOption {
+	return func(cfg *staticConfig) {
+		if tmpl == nil {
+			tmpl = listingTemplate
+		}
+		cfg.listing = tmpl
+	}
+}
+
+// WithNotFound sets the Handler that is invoked when a requested file does not
+// exist, e.g. to render a branded 404 page.
+func WithNotFound(h Handler) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.notFound = h
+	}
+}
+
+// WithFallthrough hands requests for files that do not exist over to the
+// NotFound handler of the application, see SetNotFound.
+func WithFallthrough() StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.fallThrough = true
+	}
+}
+
+// WithAccessCheck calls check with the cleaned path of the requested file
+// before it is served. An error returned by check is passed to the error
+// handler and the file is not served, return an HTTPError to choose the
+// status code.
+//
+//	app.Static("/invoices", "./invoices", cherry.WithAccessCheck(func(ctx *cherry.Context, name string) error {
+//	    if !canDownload(ctx, name) {
+//	        return cherry.NewHTTPError(http.StatusForbidden)
+//	    }
+//	    return nil
+//	}))
+func WithAccessCheck(check func(ctx *Context, name string) error) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.accessCheck = check
+	}
+}
+
+// WithXAccelRedirect offloads the transfer of the file to nginx by
+// responding with an X-Accel-Redirect header pointing to the file under the
+// given internal location instead of streaming it.
+func WithXAccelRedirect(location string) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.offload = "X-Accel-Redirect"
+		cfg.offloadPath = location
+	}
+}
+
+// WithXSendfile offloads the transfer of the file to the web server by
+// responding with an X-Sendfile header containing the path of the file
+// under root, as supported by Apache and lighttpd.
+func WithXSendfile(root string) StaticOption {
+	return func(cfg *staticConfig) {
+		cfg.offload = "X-Sendfile"
+		cfg.offloadPath = root
+	}
+}
+
+// Static registers the prefix to the router and start to act as a fileserver.
+// Static files pass through the middleware like any other route.
+//
+// app.Static("/public", "./assets", cherry.WithMaxAge(24*time.Hour)).
+func (c *Cherry) Static(prefix, dir string, opts ...StaticOption) {
+	c.static(prefix, http.Dir(c.resolve(dir)), opts)
+}
+
+// StaticFS registers the prefix to the router and serves the files of fsys,
+// like an embed.FS compiled into the binary.
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	assets, _ := fs.Sub(public, "public")
+//	app.StaticFS("/public", assets)
+func (c *Cherry) StaticFS(prefix string, fsys fs.FS, opts ...StaticOption) {
+	c.static(prefix, http.FS(fsys), opts)
+}
+
+// StaticFile registers a single file on the given route, like a favicon.
+//
+// app.StaticFile("/favicon.ico", "./assets/favicon.ico", cherry.WithMaxAge(24*time.Hour)).
+func (c *Cherry) StaticFile(route, file string, opts ...StaticOption) {
+	dir, name := filepath.Split(c.resolve(file))
+	if dir == "" {
+		dir = "."
+	}
+	c.staticFile(route, http.Dir(dir), name, opts)
+}
+
+// StaticFileFS registers the file name of fsys on the given route.
+func (c *Cherry) StaticFileFS(route, name string, fsys fs.FS, opts ...StaticOption) {
+	c.staticFile(route, http.FS(fsys), name, opts)
+}
+
+func (c *Cherry) static(prefix string, fsys http.FileSystem, opts []StaticOption) {
+	h := c.staticHandler(fsys, opts, func(ctx *Context) string {
+		return ctx.Param("filepath")
+	})
+	route := path.Join(prefix, "*filepath")
+	c.add("GET", route, h)
+	c.add("HEAD", route, h)
+}
+
+func (c *Cherry) staticFile(route string, fsys http.FileSystem, name string, opts []StaticOption) {
+	name = path.Clean("/" + name)
+	h := c.staticHandler(fsys, opts, func(*Context) string {
+		return name
+	})
+	c.add("GET", route, h)
+	c.add("HEAD", route, h)
+}
+
+// staticHandler returns the Handler that serves the file of fsys named by
+// the result of filename.
+func (c *Cherry) staticHandler(fsys http.FileSystem, opts []StaticOption, filename func(*Context) string) Handler {
+	cfg := &staticConfig{
+		header: http.Header{},
+		index:  []string{"index.html"},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.fallThrough && cfg.notFound == nil {
+		cfg.notFound = func(ctx *Context) error {
+			if nf := c.router.NotFound; nf != nil {
+				nf.ServeHTTP(ctx.Response(), ctx.Request())
+			} else {
+				http.NotFound(ctx.Response(), ctx.Request())
+			}
+			return nil
+		}
+	}
+	return chain(func(ctx *Context) error {
+		r := new(http.Request)
+		*r = *ctx.Request()
+		u := *r.URL
+		u.Path = filename(ctx)
+		r.URL = &u
+		if cfg.accessCheck != nil {
+			if err := cfg.accessCheck(ctx, path.Clean("/"+u.Path)); err != nil {
+				return err
+			}
+		}
+		w := &staticWriter{ResponseWriter: ctx.Response(), header: cfg.header}
+		if cfg.offload != "" {
+			w.Header().Set(cfg.offload, path.Join(cfg.offloadPath, path.Clean("/"+u.Path)))
+			w.WriteHeader(http.StatusOK)
+			return nil
+		}
+		if cfg.precompressed {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if servePrecompressed(w, r, fsys) {
+				return nil
+			}
+		}
+		return cfg.serve(ctx, w, r, fsys)
+	}, cfg.middleware...)
+}
+
+// serve writes the file or directory named by the request path.
+func (cfg *staticConfig) serve(ctx *Context, w http.ResponseWriter, r *http.Request, fsys http.FileSystem) error {
+	name := path.Clean("/" + r.URL.Path)
+	f, err := fsys.Open(name)
+	if err != nil {
+		return cfg.error(ctx, w, err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return cfg.error(ctx, w, err)
+	}
+	if !stat.IsDir() {
+		http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+		return nil
+	}
+
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		// redirect relative to the current path so the static prefix is kept.
+		target := path.Base(r.URL.Path) + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		w.Header().Set("Location", target)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return nil
+	}
+	for _, index := range cfg.index {
+		ff, err := fsys.Open(path.Join(name, index))
+		if err != nil {
+			continue
+		}
+		defer ff.Close()
+		if fstat, err := ff.Stat(); err == nil && !fstat.IsDir() {
+			http.ServeContent(w, r, fstat.Name(), fstat.ModTime(), ff)
+			return nil
+		}
+	}
+	if cfg.listing == nil {
+		return cfg.error(ctx, w, fs.ErrNotExist)
+	}
+	return cfg.list(ctx, w, name, f)
+}
+
+func (cfg *staticConfig) list(ctx *Context, w http.ResponseWriter, name string, dir http.File) error {
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		return cfg.error(ctx, w, err)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	listing := DirectoryListing{Path: name}
+	for _, info := range infos {
+		u := url.URL{Path: info.Name()}
+		if info.IsDir() {
+			u.Path += "/"
+		}
+		listing.Entries = append(listing.Entries, DirectoryEntry{
+			Name:    info.Name(),
+			URL:     u.String(),
+			IsDir:   info.IsDir(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	buf := &bytes.Buffer{}
+	if err := cfg.listing.Execute(buf, listing); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// error writes the status that matches err, without revealing the details
+// of the error. Missing files are handed to the NotFound handler if set.
+func (cfg *staticConfig) error(ctx *Context, w http.ResponseWriter, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if cfg.notFound != nil {
+			return cfg.notFound(ctx)
+		}
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+// staticWriter adds the configured headers to successful responses only, so
+// errors are not cached for the max-age of the files. A Cache-Control that
+// is already set, like the immutable one of fingerprinted assets, is kept.
+type staticWriter struct {
+	http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *staticWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code < http.StatusBadRequest {
+			for k, v := range w.header {
+				if k == "Cache-Control" && w.Header().Get(k) != "" {
+					continue
+				}
+				w.Header()[k] = v
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *staticWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// servePrecompressed serves the precompressed sibling of the requested file
+// if one exists for an encoding the client accepts.
+func servePrecompressed(w http.ResponseWriter, r *http.Request, fsys http.FileSystem) bool {
+	accept := r.Header.Get("Accept-Encoding")
+	if accept == "" {
+		return false
+	}
+	name := path.Clean("/" + r.URL.Path)
+	elems := parseAccept([]string{accept})
+	for _, pc := range precompressedFiles {
+		if encodingQuality(elems, pc.encoding) <= 0 {
+			continue
+		}
+		f, err := fsys.Open(name + pc.ext)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		ctype := mime.TypeByExtension(path.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", ctype)
+		w.Header().Set("Content-Encoding", pc.encoding)
+		http.ServeContent(w, r, name, stat.ModTime(), f)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package cherry
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New()
+	c.Use(func(ctx *Context) error {
+		buf.WriteString("a")
+		return nil
+	})
+	c.Static("/public", "./", WithMaxAge(time.Hour), WithHeader("Access-Control-Allow-Origin", "*"),
+		WithMiddleware(func(ctx *Context) error {
+			buf.WriteString("b")
+			return nil
+		}),
+	)
+
+	r, _ := http.NewRequest("GET", "/public/README.md", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if cc := rw.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+		t.Errorf("unexpected Cache-Control %s", cc)
+	}
+	if rw.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("expecting the CORS header to be set")
+	}
+	if buf.String() != "ab" {
+		t.Errorf("expecting ab got %s", buf.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/public/nofile", nil)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("expecting status 404 got %d", rw.Code)
+	}
+	if cc := rw.Header().Get("Cache-Control"); cc != "" {
+		t.Errorf("expecting no Cache-Control on errors got %s", cc)
+	}
+}
+
+func TestStaticMiddlewareAbort(t *testing.T) {
+	c := New()
+	c.Static("/public", "./", WithMiddleware(func(ctx *Context) error {
+		return NewHTTPError(http.StatusUnauthorized)
+	}))
+	code, _ := doRequest(t, "GET", "/public/README.md", nil, c)
+	if code != http.StatusUnauthorized {
+		t.Errorf("expecting status 401 got %d", code)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	c := New()
+	c.StaticFS("/public", fstest.MapFS{
+		"app.js":    &fstest.MapFile{Data: []byte("plain")},
+		"app.js.br": &fstest.MapFile{Data: []byte("brotli")},
+		"app.js.gz": &fstest.MapFile{Data: []byte("gzip")},
+	}, WithPrecompressed())
+
+	tests := []struct {
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"gzip, br", "br", "brotli"},
+		{"gzip", "gzip", "gzip"},
+		{"zstd", "", "plain"},
+		{"", "", "plain"},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/public/app.js", nil)
+		r.Header.Set("Accept-Encoding", test.accept)
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		isHTTPStatusOK(t, rw.Code)
+		if enc := rw.Header().Get("Content-Encoding"); enc != test.encoding {
+			t.Errorf("%q: expecting encoding %q got %q", test.accept, test.encoding, enc)
+		}
+		if rw.Body.String() != test.body {
+			t.Errorf("%q: expecting body %q got %q", test.accept, test.body, rw.Body.String())
+		}
+		if ct := rw.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+			t.Errorf("%q: unexpected Content-Type %s", test.accept, ct)
+		}
+		if rw.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%q: expecting Vary Accept-Encoding", test.accept)
+		}
+	}
+}
+
+func TestStaticDirectories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/index.htm": &fstest.MapFile{Data: []byte("docs")},
+		"files/a.txt":    &fstest.MapFile{Data: []byte("a")},
+		"files/b.txt":    &fstest.MapFile{Data: []byte("b")},
+	}
+	c := New()
+	c.StaticFS("/private", fsys)
+	c.StaticFS("/public", fsys, WithIndex("index.html", "index.htm"), WithDirectoryListing(nil))
+
+	code, _ := doRequest(t, "GET", "/private/files/", nil, c)
+	if code != http.StatusNotFound {
+		t.Errorf("expecting directory listing to be disabled got %d", code)
+	}
+
+	code, body := doRequest(t, "GET", "/public/files/", nil, c)
+	isHTTPStatusOK(t, code)
+	if !strings.Contains(body, `<a href="a.txt">a.txt</a>`) || !strings.Contains(body, `<a href="b.txt">b.txt</a>`) {
+		t.Errorf("expecting a directory listing got %s", body)
+	}
+
+	code, body = doRequest(t, "GET", "/public/docs/", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "docs" {
+		t.Errorf("expecting the index file got %s", body)
+	}
+
+	r, _ := http.NewRequest("GET", "/public/docs", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMovedPermanently || rw.Header().Get("Location") != "docs/" {
+		t.Errorf("expecting a redirect to docs/ got %d %s", rw.Code, rw.Header().Get("Location"))
+	}
+}
+
+func TestStaticNotFound(t *testing.T) {
+	c := New()
+	c.SetNotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("app not found"))
+	}))
+	c.Static("/public", "./", WithMaxAge(time.Hour), WithNotFound(func(ctx *Context) error {
+		return ctx.Text(http.StatusNotFound, "asset not found")
+	}))
+	c.Static("/assets", "./", WithFallthrough())
+
+	r, _ := http.NewRequest("GET", "/public/nofile", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound || rw.Body.String() != "asset not found" {
+		t.Errorf("expecting the static NotFound handler got %d %s", rw.Code, rw.Body.String())
+	}
+	if rw.Header().Get("Cache-Control") != "" {
+		t.Error("expecting no Cache-Control header on a 404")
+	}
+
+	code, body := doRequest(t, "GET", "/assets/nofile", nil, c)
+	if code != http.StatusNotFound || body != "app not found" {
+		t.Errorf("expecting the application NotFound handler got %d %s", code, body)
+	}
+}
+
+func TestStaticFile(t *testing.T) {
+	c := New()
+	c.StaticFile("/readme", "README.md", WithMaxAge(time.Hour))
+	c.StaticFileFS("/favicon.ico", "img/favicon.ico", fstest.MapFS{
+		"img/favicon.ico": &fstest.MapFile{Data: []byte("icon"), ModTime: time.Date(2023, 12, 21, 0, 0, 0, 0, time.UTC)},
+	})
+
+	r, _ := http.NewRequest("GET", "/readme", nil)
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if !strings.Contains(rw.Body.String(), "cherry") {
+		t.Error("expecting body containing string (cherry)")
+	}
+	if rw.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("unexpected Cache-Control %s", rw.Header().Get("Cache-Control"))
+	}
+
+	r, _ = http.NewRequest("GET", "/favicon.ico", nil)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Body.String() != "icon" {
+		t.Errorf("expecting icon got %s", rw.Body.String())
+	}
+	lastModified := rw.Header().Get("Last-Modified")
+	if lastModified != "Thu, 21 Dec 2023 00:00:00 GMT" {
+		t.Errorf("unexpected Last-Modified %s", lastModified)
+	}
+
+	r.Header.Set("If-Modified-Since", lastModified)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotModified {
+		t.Errorf("expecting status 304 got %d", rw.Code)
+	}
+}
+
+func TestStaticAccessCheck(t *testing.T) {
+	c := New()
+	c.Static("/files", "./", WithAccessCheck(func(ctx *Context, name string) error {
+		if ctx.Header("Authorization") == "" {
+			return NewHTTPError(http.StatusForbidden)
+		}
+		if name != "/README.md" {
+			t.Errorf("unexpected name %s", name)
+		}
+		return nil
+	}))
+	c.Static("/protected", "/srv/files", WithXAccelRedirect("/internal"), WithAccessCheck(func(ctx *Context, name string) error {
+		return nil
+	}))
+
+	code, _ := doRequest(t, "GET", "/files/README.md", nil, c)
+	if code != http.StatusForbidden {
+		t.Errorf("expecting status 403 got %d", code)
+	}
+	r, _ := http.NewRequest("GET", "/files/README.md", nil)
+	r.Header.Set("Authorization", "token")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+
+	r, _ = http.NewRequest("GET", "/protected/invoices/../2023/1.pdf", nil)
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+	if h := rw.Header().Get("X-Accel-Redirect"); h != "/internal/2023/1.pdf" {
+		t.Errorf("unexpected X-Accel-Redirect %s", h)
+	}
+	if rw.Body.Len() != 0 {
+		t.Error("expecting an empty body")
+	}
+}
+
+func TestSetBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "public"), 0755)
+	os.WriteFile(filepath.Join(dir, "public", "app.css"), []byte("body{}"), 0644)
+
+	c := New()
+	c.SetBaseDir(dir)
+	c.Static("/assets", "public")
+	c.StaticFile("/style.css", "public/app.css")
+	for _, route := range []string{"/assets/app.css", "/style.css"} {
+		code, body := doRequest(t, "GET", route, nil, c)
+		isHTTPStatusOK(t, code)
+		if body != "body{}" {
+			t.Errorf("%s: expecting body{} got %s", route, body)
+		}
+	}
+}
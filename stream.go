@@ -0,0 +1,41 @@
+package cherry
+
+import "bufio"
+
+// StreamWriter streams a response in chunks, for progress feeds or long
+// exports. step is called until it returns false, each call writes the next
+// chunk to w, which is flushed to the client after every call. The stream
+// stops without an error when the client disconnects. The write deadline of
+// the connection is extended before every chunk, so a stream can outlive
+// the WriteTimeout of the server. The status is 200 unless it was written
+// before.
+//
+// return ctx.StreamWriter(func(w *bufio.Writer) bool { return writeRow(w, rows) }).
+func (c *Context) StreamWriter(step func(w *bufio.Writer) bool) error {
+	rw := c.Response()
+	if rw.Header().Get("Content-Type") == "" {
+		rw.Header().Set("Content-Type", "application/octet-stream")
+	}
+	rw.Header().Del("Content-Length")
+	done := c.Request().Context().Done()
+	w := bufio.NewWriter(rw)
+	for {
+		select {
+		case <-done:
+			// nothing can be written to the client anymore.
+			return nil
+		default:
+		}
+		c.extendWriteDeadline()
+		more := step(w)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := flush(rw); err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cherry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+func TestStreamWriter(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		ctx.Response().Header().Set("Content-Type", "text/csv")
+		i := 0
+		return ctx.StreamWriter(func(w *bufio.Writer) bool {
+			i++
+			fmt.Fprintf(w, "row %d\n", i)
+			return i < 3
+		})
+	})
+	rw := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	isHTTPStatusOK(t, rw.Code)
+	if rw.Body.String() != "row 1\nrow 2\nrow 3\n" {
+		t.Errorf("unexpected body %q", rw.Body.String())
+	}
+	if rw.flushes != 3 {
+		t.Errorf("expecting 3 flushes got %d", rw.flushes)
+	}
+	if ct := rw.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expecting text/csv got %s", ct)
+	}
+}
+
+func TestStreamWriterDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New()
+	var calls int
+	var err error
+	c.Get("/", func(c *Context) error {
+		err = c.StreamWriter(func(w *bufio.Writer) bool {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+			w.WriteString("tick\n")
+			return true
+		})
+		return nil
+	})
+	r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	c.ServeHTTP(httptest.NewRecorder(), r)
+	if calls != 2 || err != nil {
+		t.Errorf("expecting the stream to stop after 2 calls got %d %v", calls, err)
+	}
+}
+
+func TestStreamWriterNoFlusher(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.StreamWriter(func(w *bufio.Writer) bool {
+			w.WriteString("done")
+			return false
+		})
+	})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(struct{ http.ResponseWriter }{rw}, httptest.NewRequest("GET", "/", nil))
+	if rw.Body.String() != "done" {
+		t.Errorf("expecting done got %q", rw.Body.String())
+	}
+}
+
+func TestStreamWriterOutlivesWriteTimeout(t *testing.T) {
+	c := New()
+	c.WriteTimeout = 200 * time.Millisecond
+	c.Get("/", func(ctx *Context) error {
+		i := 0
+		return ctx.StreamWriter(func(w *bufio.Writer) bool {
+			time.Sleep(50 * time.Millisecond)
+			i++
+			fmt.Fprintf(w, "row %d\n", i)
+			return i < 16
+		})
+	})
+	addr := serveWriteTimeout(t, c)
+
+	res, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil || !strings.HasSuffix(string(b), "row 16\n") {
+		t.Errorf("expecting the stream to outlive the WriteTimeout got %v %q", err, b)
+	}
+}
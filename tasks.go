@@ -0,0 +1,56 @@
+package cherry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// tasks tracks the background workers started with Cherry.Go, it is shared
+// by the application and all of its groups.
+type tasks struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newTasks() *tasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &tasks{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in the background for the lifetime of the application, which
+// is useful for queue consumers or cache refreshers. The context passed to
+// fn is cancelled as soon as a shutdown begins, while the in-flight requests
+// are still draining, and the shutdown waits for fn to return before the
+// OnShutdown hooks run. Errors other than a cancelled
+// context are logged.
+//
+// app.Go(func(ctx context.Context) error { return consumer.Run(ctx) }).
+func (c *Cherry) Go(fn func(ctx context.Context) error) {
+	c.tasks.wg.Add(1)
+	go func() {
+		defer c.tasks.wg.Done()
+		if err := fn(c.tasks.ctx); err != nil && !errors.Is(err, context.Canceled) {
+			c.logFailure("background task failed", err)
+		}
+	}()
+}
+
+// wait cancels the background workers, if Shutdown did not already, and
+// waits for them to return or for ctx to be done.
+func (t *tasks) wait(ctx context.Context) error {
+	t.cancel()
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("cherry: background tasks did not stop: %w", ctx.Err())
+	}
+}
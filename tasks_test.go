@@ -0,0 +1,112 @@
+package cherry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGo(t *testing.T) {
+	c := New()
+	out := &bytes.Buffer{}
+	c.Output = out
+
+	stopped := make(chan struct{})
+	var shutdownAfterTask bool
+	c.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		close(stopped)
+		return ctx.Err()
+	})
+	failed := make(chan struct{})
+	c.Go(func(ctx context.Context) error {
+		defer close(failed)
+		return errors.New("queue unreachable")
+	})
+	c.OnShutdown(func(ctx context.Context) {
+		select {
+		case <-stopped:
+			shutdownAfterTask = true
+		default:
+		}
+	})
+
+	<-failed
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !shutdownAfterTask {
+		t.Error("expecting shutdown to wait for the background task")
+	}
+	if !strings.Contains(out.String(), "queue unreachable") {
+		t.Errorf("expecting the task error to be logged got %q", out.String())
+	}
+}
+
+func TestGoShutdownTimeout(t *testing.T) {
+	c := New()
+	c.ShutdownTimeout = 20 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	c.Go(func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err := c.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting DeadlineExceeded got %v", err)
+	}
+}
+
+func TestGoCancelledBeforeDrain(t *testing.T) {
+	c := New()
+	cancelled := make(chan struct{})
+	c.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	started := make(chan struct{})
+	var duringRequest atomic.Bool
+	c.Get("/slow", func(ctx *Context) error {
+		close(started)
+		select {
+		case <-cancelled:
+			duringRequest.Store(true)
+		case <-time.After(2 * time.Second):
+		}
+		return ctx.Text(http.StatusOK, "done")
+	})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeCustom(&http.Server{Addr: "127.0.0.1:0", Handler: c})
+	}()
+	addr := waitForServer(t, c)
+	resc := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			resc <- 0
+			return
+		}
+		resp.Body.Close()
+		resc <- resp.StatusCode
+	}()
+	<-started
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !duringRequest.Load() {
+		t.Error("expecting the tasks to be cancelled before the requests are drained")
+	}
+	if code := <-resc; code != http.StatusOK {
+		t.Errorf("expecting the in-flight request to finish with 200 got %d", code)
+	}
+	<-errc
+}
@@ -0,0 +1,145 @@
+package cherry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"sync"
+)
+
+// views holds the templates rendered by Context.HTML, it is shared by the
+// application and all of its groups.
+type views struct {
+	mu       sync.RWMutex
+	fsys     fs.FS
+	patterns []string
+	funcs    template.FuncMap
+	tmpl     *template.Template
+}
+
+// TemplateFuncs adds functions to the templates, like the FuncMap of
+// Assets. It must be called before Templates.
+//
+// app.TemplateFuncs(assets.FuncMap()).
+func (c *Cherry) TemplateFuncs(funcs template.FuncMap) {
+	c.views.mu.Lock()
+	defer c.views.mu.Unlock()
+	if c.views.funcs == nil {
+		c.views.funcs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		c.views.funcs[name] = fn
+	}
+}
+
+// Templates parses the html templates of fsys that match the patterns,
+// which Context.HTML renders by name. In DebugMode the templates are parsed
+// again for every render, so changes show without a restart.
+//
+// Besides the functions added with TemplateFuncs, the templates can use
+// functions bound to the request: T and Tn translate messages like
+// Context.T, date and number format values for the language of
+// Context.Locale and locale returns that language.
+//
+// err := app.Templates(os.DirFS("templates"), "*.html", "partials/*.html").
+func (c *Cherry) Templates(fsys fs.FS, patterns ...string) error {
+	c.views.mu.Lock()
+	defer c.views.mu.Unlock()
+	c.views.fsys = fsys
+	c.views.patterns = patterns
+	tmpl, err := c.views.parse()
+	if err != nil {
+		return err
+	}
+	c.views.tmpl = tmpl
+	return nil
+}
+
+func (v *views) parse() (*template.Template, error) {
+	tmpl := template.New("").Funcs(requestFuncs(nil)).Funcs(v.funcs)
+	return tmpl.ParseFS(v.fsys, v.patterns...)
+}
+
+// template returns the parsed templates, which are parsed again in
+// DebugMode.
+func (v *views) template() (*template.Template, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.tmpl == nil {
+		return nil, errors.New("cherry: no templates, see Cherry.Templates")
+	}
+	if IsDebug() {
+		return v.parse()
+	}
+	return v.tmpl, nil
+}
+
+// HTML renders the named template with data. The template is rendered into
+// a buffer first, so a failing template is answered by the error handler
+// instead of a half written page.
+//
+// return ctx.HTML(http.StatusOK, "checkout.html", order).
+func (c *Context) HTML(code int, name string, data any) error {
+	if c.cherry == nil {
+		return errors.New("cherry: no templates, see Cherry.Templates")
+	}
+	tmpl, err := c.cherry.views.template()
+	if err != nil {
+		return err
+	}
+	// the clone shares the parsed templates, only the functions bound to
+	// this request are replaced.
+	tmpl, err = tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(requestFuncs(c))
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
+		return fmt.Errorf("cherry: template %s: %w", name, err)
+	}
+	h := c.Response().Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", c.contentType("text/html"))
+	}
+	c.Response().WriteHeader(code)
+	_, err = buf.WriteTo(c.Response())
+	return err
+}
+
+// requestFuncs returns the template functions that are bound to the
+// request. Without a Context they only serve to parse the templates.
+func requestFuncs(c *Context) template.FuncMap {
+	locale := func() string {
+		if c == nil {
+			return ""
+		}
+		return c.Locale().String()
+	}
+	return template.FuncMap{
+		"T": func(key string, args ...any) string {
+			if c == nil {
+				return Interpolate(key, args...)
+			}
+			return c.T(key, args...)
+		},
+		"Tn": func(key string, count any, args ...any) string {
+			args = append([]any{"count", count}, args...)
+			if c == nil {
+				return Interpolate(key, args...)
+			}
+			return c.T(key, args...)
+		},
+		"date": func(t any, layout ...string) (string, error) {
+			return formatDate(languageOf(c), t, layout...)
+		},
+		"number": func(n any) string {
+			return formatNumber(languageOf(c), n)
+		},
+		"locale": locale,
+	}
+}
@@ -0,0 +1,98 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHTML(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html":    {Data: []byte(`{{template "title" .}} <b>{{.Name}}</b>`)},
+		"partial.html": {Data: []byte(`{{define "title"}}{{shout "hi"}}{{end}}`)},
+		"broken.html":  {Data: []byte(`{{.Missing.Field}}`)},
+	}
+	c := New()
+	c.TemplateFuncs(map[string]any{"shout": strings.ToUpper})
+	if err := c.Templates(fsys, "*.html"); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("/", func(ctx *Context) error {
+		return ctx.HTML(http.StatusCreated, "page.html", map[string]string{"Name": "<ada>"})
+	})
+	c.Get("/broken", func(ctx *Context) error {
+		return ctx.HTML(http.StatusOK, "broken.html", 1)
+	})
+	c.Get("/missing", func(ctx *Context) error {
+		return ctx.HTML(http.StatusOK, "missing.html", nil)
+	})
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if rw.Code != http.StatusCreated || rw.Body.String() != "HI <b>&lt;ada&gt;</b>" {
+		t.Errorf("unexpected response %d %q", rw.Code, rw.Body)
+	}
+	if ct := rw.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	for _, route := range []string{"/broken", "/missing"} {
+		if code, body := doRequest(t, "GET", route, nil, c); code != http.StatusInternalServerError || strings.Contains(body, "<b>") {
+			t.Errorf("%s: expecting 500 got %d %q", route, code, body)
+		}
+	}
+
+	// templates are parsed again in debug mode.
+	fsys["page.html"] = &fstest.MapFile{Data: []byte(`changed`)}
+	if _, body := doRequest(t, "GET", "/", nil, c); body != "changed" {
+		t.Errorf("expecting the changed template got %q", body)
+	}
+}
+
+func TestHTMLWithoutTemplates(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.HTML(http.StatusOK, "page.html", nil)
+	})
+	if code, _ := doRequest(t, "GET", "/", nil, c); code != http.StatusInternalServerError {
+		t.Errorf("expecting 500 got %d", code)
+	}
+}
+
+func TestTemplateTranslations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html": {Data: []byte(`{{locale}}|{{T "hello {name}" "name" "ada"}}|{{Tn "{count} items" 3}}|{{date .Date}}|{{date .Date "2006"}}|{{number .Total}}`)},
+	}
+	c := New()
+	if err := c.Templates(fsys, "*.html"); err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]any{"Date": time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), "Total": 1234567.5}
+	c.Get("/", func(ctx *Context) error {
+		WithValue[Translator](ctx, upperTranslator{})
+		return ctx.HTML(http.StatusOK, "page.html", data)
+	})
+	tests := []struct {
+		accept string
+		expect string
+	}{
+		{"en-US", "en-US|HELLO ADA|3 ITEMS|3/9/2024|2024|1,234,567.5"},
+		{"en-GB", "en-GB|HELLO ADA|3 ITEMS|09/03/2024|2024|1,234,567.5"},
+		{"de", "de|HELLO ADA|3 ITEMS|09.03.2024|2024|1.234.567,5"},
+		{"ja", "ja|HELLO ADA|3 ITEMS|2024/03/09|2024|1,234,567.5"},
+		{"", "und|HELLO ADA|3 ITEMS|2024-03-09|2024|1,234,567.5"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept-Language", test.accept)
+		}
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Body.String() != test.expect {
+			t.Errorf("%q: expecting %q got %q", test.accept, test.expect, rw.Body)
+		}
+	}
+}
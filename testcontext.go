@@ -0,0 +1,50 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// NewTestContext returns a Context for w and r outside of the router, to
+// unit test handlers and middleware. It has the settings of a New
+// application, SetApp replaces them. Errors returned by the handler are not
+// passed to an error handler.
+//
+//	rw := httptest.NewRecorder()
+//	ctx := cherry.NewTestContext(rw, httptest.NewRequest("GET", "/users/7", nil))
+//	ctx.SetParam("id", "7")
+//	err := showUser(ctx)
+func NewTestContext(w http.ResponseWriter, r *http.Request) *Context {
+	return &Context{
+		Context:  context.Background(),
+		response: w,
+		request:  r,
+		cherry:   New(),
+	}
+}
+
+// SetParam sets the url parameter name, which Param returns.
+func (c *Context) SetParam(name, value string) {
+	for i, p := range c.vars {
+		if p.Key == name {
+			c.vars[i].Value = value
+			return
+		}
+	}
+	c.vars = append(c.vars, httprouter.Param{Key: name, Value: value})
+}
+
+// SetRoute sets the route pattern, which FullPath returns.
+func (c *Context) SetRoute(pattern string) {
+	c.route = pattern
+}
+
+// SetApp makes the Context use the settings and the bound context of app,
+// or of a Group with &group.Cherry, like the contexts of its routes. The
+// values stored with WithValue are reset.
+func (c *Context) SetApp(app *Cherry) {
+	c.cherry = app
+	c.Context = app.boundContext()
+}
@@ -0,0 +1,56 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testKey struct{}
+
+func TestNewTestContext(t *testing.T) {
+	rw := httptest.NewRecorder()
+	ctx := NewTestContext(rw, httptest.NewRequest("GET", "/users/7?tab=orders", nil))
+	ctx.SetParam("id", "1")
+	ctx.SetParam("id", "7")
+	ctx.SetRoute("/users/:id")
+	WithValue(ctx, "request scoped")
+
+	err := func(ctx *Context) error {
+		v, _ := Value[string](ctx)
+		return ctx.Text(http.StatusOK, ctx.FullPath()+" "+ctx.Param("id")+" "+ctx.Query("tab")+" "+v)
+	}(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := rw.Body.String(); body != "/users/:id 7 orders request scoped" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if ct := rw.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("expecting the defaults of New got %q", ct)
+	}
+}
+
+func TestTestContextSetApp(t *testing.T) {
+	app := New()
+	app.BindContext(context.WithValue(context.Background(), testKey{}, "app"))
+	app.ContentTypes.Charset = "-"
+	api := app.Group("/api")
+	api.BindContext(context.WithValue(context.Background(), testKey{}, "api"))
+	api.Get("/users/:id", func(ctx *Context) error { return nil }).Name("user")
+
+	rw := httptest.NewRecorder()
+	ctx := NewTestContext(rw, httptest.NewRequest("GET", "/", nil))
+	ctx.SetApp(&api.Cherry)
+	if v := ctx.Context.Value(testKey{}); v != "api" {
+		t.Errorf("expecting the bound context of the group got %v", v)
+	}
+	if u, err := ctx.URLFor("user", "id", 1); err != nil || u != "http://example.com/api/users/1" {
+		t.Errorf("unexpected url %q %v", u, err)
+	}
+	ctx.Text(http.StatusOK, "")
+	if ct := rw.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Errorf("expecting the settings of the app got %q", ct)
+	}
+}
@@ -0,0 +1,127 @@
+package cherry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TestServer is a server of the application on a local port, for
+// integration tests, see Cherry.TestServer.
+type TestServer struct {
+	// URL is the base URL of the server, like http://127.0.0.1:41234.
+	URL string
+	// Client is a client for the server, which trusts its test certificate.
+	Client *http.Client
+
+	srv  *server
+	done chan error
+}
+
+// TestServer starts the application on a free local port, with the
+// server settings of the application. Applications with TLS, a TLSConfig,
+// certificates or HTTP2, are served over TLS with a self-signed test
+// certificate that the Client trusts. The server is shut down when the test
+// ends, it does not run the OnStop and OnShutdown hooks.
+//
+//	srv := app.TestServer(t)
+//	res, err := srv.Client.Get(srv.URL + "/users")
+func (c *Cherry) TestServer(t interface{ Cleanup(func()) }) *TestServer {
+	srv := c.wrap(newServer("127.0.0.1:0", c), nil)
+	// closing the test server does not make the application report that it
+	// is shutting down.
+	srv.health = &health{}
+	ts := &TestServer{srv: srv, done: make(chan error, 1), Client: &http.Client{}}
+	useTLS := c.HTTP2 || c.TLSConfig != nil || len(c.certificates) > 0 || c.GetCertificate != nil
+	if useTLS {
+		cert, pool, err := testCertificate()
+		if err != nil {
+			panic("cherry: test certificate: " + err.Error())
+		}
+		srv.TLSConfig.Certificates = nil
+		srv.TLSConfig.GetCertificate = nil
+		srv.certificates = []tls.Certificate{cert}
+		srv.getCertificate = nil
+		ts.Client.Transport = &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool},
+			ForceAttemptHTTP2: c.HTTP2,
+		}
+	}
+
+	c.servers.add(srv)
+	go func() {
+		defer c.servers.remove(srv)
+		if useTLS {
+			ts.done <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		ts.done <- srv.ListenAndServe()
+	}()
+	select {
+	case <-srv.listening:
+	case err := <-ts.done:
+		panic("cherry: test server: " + err.Error())
+	}
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	ts.URL = scheme + "://" + srv.addr.String()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// Close shuts the server down, connections that are still open after 5
+// seconds are closed.
+func (s *TestServer) Close() {
+	select {
+	case <-s.srv.stopped:
+		return
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+	<-s.done
+	s.Client.CloseIdleConnections()
+}
+
+// testCertificate returns a self-signed certificate for the loopback
+// addresses and a pool that trusts it.
+func testCertificate() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Cherry test server"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
@@ -0,0 +1,57 @@
+package cherry
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTestServer(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.Scheme()+" "+ctx.Request().Proto)
+	})
+	srv := c.TestServer(t)
+	if !strings.HasPrefix(srv.URL, "http://127.0.0.1:") {
+		t.Errorf("unexpected url %s", srv.URL)
+	}
+	if c.Addr() == nil || "http://"+c.Addr().String() != srv.URL {
+		t.Errorf("expecting the server to be tracked got %v", c.Addr())
+	}
+	res, err := srv.Client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "http HTTP/1.1" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	srv.Close()
+	if _, err := srv.Client.Get(srv.URL); err == nil {
+		t.Error("expecting the server to be closed")
+	}
+	if c.health.shuttingDown.Load() {
+		t.Error("expecting the application not to be shutting down")
+	}
+}
+
+func TestTestServerTLS(t *testing.T) {
+	c := New()
+	c.HTTP2 = true
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.Scheme()+" "+ctx.Request().Proto)
+	})
+	srv := c.TestServer(t)
+	res, err := srv.Client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.HasPrefix(srv.URL, "https://") || string(body) != "https HTTP/2.0" {
+		t.Errorf("unexpected response %s %q", srv.URL, body)
+	}
+}
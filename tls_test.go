@@ -0,0 +1,215 @@
+package cherry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for name and returns
+// the certificate and key files.
+func writeCertificate(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+// serverName returns the common name of the certificate addr serves for sni.
+func serverName(t *testing.T, addr, sni string) string {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: sni, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestServeTLSWithSNI(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	for _, name := range []string{"a.test", "b.test"} {
+		if err := c.AddCertificate(writeCertificate(t, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dynamic, _ := writeCertificate(t, "dynamic.test")
+	dynamicCert, err := tls.LoadX509KeyPair(dynamic, filepath.Join(filepath.Dir(dynamic), "dynamic.test.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if hello.ServerName == "dynamic.test" {
+			return &dynamicCert, nil
+		}
+		return nil, nil
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeTLS(0, "", "")
+	}()
+	addr := waitForServer(t, c)
+	defer func() {
+		c.Close()
+		<-errc
+	}()
+	_, port, _ := net.SplitHostPort(addr)
+	addr = net.JoinHostPort("127.0.0.1", port)
+
+	for _, test := range []struct{ sni, expect string }{
+		{"a.test", "a.test"},
+		{"b.test", "b.test"},
+		{"dynamic.test", "dynamic.test"},
+		{"unknown.test", "a.test"},
+	} {
+		if got := serverName(t, addr, test.sni); got != test.expect {
+			t.Errorf("%s: expecting certificate %s got %s", test.sni, test.expect, got)
+		}
+	}
+}
+
+func TestServeTLSWithoutCertificate(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	if err := c.ServeTLS(0, "", ""); err == nil {
+		t.Error("expecting an error without certificates")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	config := TLSConfig()
+	if config.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expecting TLS 1.2 as minimum version got %x", config.MinVersion)
+	}
+	for _, id := range config.CipherSuites {
+		for _, insecure := range tls.InsecureCipherSuites() {
+			if id == insecure.ID {
+				t.Errorf("expecting no insecure cipher suites got %s", insecure.Name)
+			}
+		}
+	}
+	config = TLSConfig(WithMinVersion(tls.VersionTLS13), WithCurves(tls.X25519))
+	if config.MinVersion != tls.VersionTLS13 || len(config.CurvePreferences) != 1 {
+		t.Errorf("expecting the options to override the defaults got %+v", config)
+	}
+}
+
+func TestTicketKeyRotation(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, "a.test")
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := TLSConfig(WithTicketKeyRotation(time.Hour))
+	config.Certificates = []tls.Certificate{cert}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				b := make([]byte, 1)
+				conn.Read(b)
+				conn.Write(b)
+			}()
+		}
+	}()
+
+	client := &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "a.test",
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+	resumed := func() bool {
+		conn, err := tls.Dial("tcp", l.Addr().String(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("x"))
+		conn.Read(make([]byte, 1))
+		return conn.ConnectionState().DidResume
+	}
+	if resumed() {
+		t.Error("expecting a full handshake on the first connection")
+	}
+	if !resumed() {
+		t.Error("expecting the session to be resumed with the ticket")
+	}
+}
+
+func TestServeHTTP2(t *testing.T) {
+	c := New()
+	c.DisableSignalHandling()
+	c.HTTP2 = true
+	c.HTTP2Options.MaxConcurrentStreams = 10
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.Request().Proto)
+	})
+	certFile, keyFile := writeCertificate(t, "a.test")
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.ServeTLS(0, certFile, keyFile)
+	}()
+	addr := waitForServer(t, c)
+	defer func() {
+		c.Close()
+		<-errc
+	}()
+	_, port, _ := net.SplitHostPort(addr)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://127.0.0.1:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	isHTTPStatusOK(t, resp.StatusCode)
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expecting HTTP/2 got %s", resp.Proto)
+	}
+}
@@ -0,0 +1,149 @@
+package cherry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// TLSOption configures the tls.Config returned by TLSConfig.
+type TLSOption func(*tls.Config)
+
+// TLSConfig returns a hardened tls.Config, which ServeTLS uses unless
+// Cherry.TLSConfig is set. It requires TLS 1.2 or newer, only offers
+// cipher suites with forward secrecy and authenticated encryption and
+// prefers the fastest curves. The options override the defaults.
+//
+// app.TLSConfig = cherry.TLSConfig(cherry.WithMinVersion(tls.VersionTLS13)).
+func TLSConfig(opts ...TLSOption) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// WithMinVersion sets the minimum TLS version, like tls.VersionTLS13.
+func WithMinVersion(version uint16) TLSOption {
+	return func(c *tls.Config) {
+		c.MinVersion = version
+	}
+}
+
+// WithCipherSuites sets the TLS 1.2 cipher suites. TLS 1.3 suites are not
+// configurable.
+func WithCipherSuites(suites ...uint16) TLSOption {
+	return func(c *tls.Config) {
+		c.CipherSuites = suites
+	}
+}
+
+// WithCurves sets the elliptic curves in order of preference.
+func WithCurves(curves ...tls.CurveID) TLSOption {
+	return func(c *tls.Config) {
+		c.CurvePreferences = curves
+	}
+}
+
+// WithTicketKeyRotation encrypts session tickets with a key that is replaced
+// every period. Tickets of the previous key are still accepted, so a ticket
+// is valid for at most two periods. Without it the keys are rotated by
+// crypto/tls every 24 hours.
+func WithTicketKeyRotation(period time.Duration) TLSOption {
+	return func(c *tls.Config) {
+		k := &ticketKeys{period: period}
+		c.WrapSession = k.wrap
+		c.UnwrapSession = k.unwrap
+	}
+}
+
+// ticketKeys encrypts session tickets with rotating AES-GCM keys.
+type ticketKeys struct {
+	mu      sync.Mutex
+	period  time.Duration
+	keys    []cipher.AEAD
+	rotated time.Time
+}
+
+// current returns the keys, newest first, rotating them when the period is
+// over.
+func (k *ticketKeys) current() ([]cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.keys) > 0 && time.Since(k.rotated) < k.period {
+		return k.keys, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(k.rotated) >= 2*k.period {
+		// the previous key is expired as well.
+		k.keys = nil
+	}
+	k.keys = append([]cipher.AEAD{aead}, k.keys...)
+	if len(k.keys) > 2 {
+		k.keys = k.keys[:2]
+	}
+	k.rotated = time.Now()
+	return k.keys, nil
+}
+
+func (k *ticketKeys) wrap(_ tls.ConnectionState, ss *tls.SessionState) ([]byte, error) {
+	keys, err := k.current()
+	if err != nil {
+		return nil, err
+	}
+	state, err := ss.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, keys[0].NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return keys[0].Seal(nonce, nonce, state, nil), nil
+}
+
+// unwrap decrypts a ticket, tickets that cannot be decrypted are ignored and
+// result in a full handshake.
+func (k *ticketKeys) unwrap(ticket []byte, _ tls.ConnectionState) (*tls.SessionState, error) {
+	keys, err := k.current()
+	if err != nil {
+		return nil, err
+	}
+	for _, aead := range keys {
+		if len(ticket) < aead.NonceSize() {
+			return nil, nil
+		}
+		nonce, sealed := ticket[:aead.NonceSize()], ticket[aead.NonceSize():]
+		state, err := aead.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			continue
+		}
+		return tls.ParseSessionState(state)
+	}
+	return nil, nil
+}
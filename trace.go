@@ -0,0 +1,181 @@
+package cherry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceContext is a W3C trace context, which is propagated with the
+// traceparent and tracestate headers, see https://www.w3.org/TR/trace-context.
+type TraceContext struct {
+	// TraceID is the id of the whole trace, 32 lower case hex characters.
+	TraceID string
+	// SpanID is the id of the current span, 16 lower case hex characters.
+	// It is the parent id of the outgoing requests.
+	SpanID string
+	// Sampled is the sampled flag of the trace.
+	Sampled bool
+	// State is the tracestate header, the vendor data of the trace.
+	State string
+}
+
+// requestID is the X-Request-ID of a request, which is propagated with its
+// trace.
+type requestID string
+
+// NewTraceContext starts a new sampled trace.
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Sampled: true}
+}
+
+// ParseTraceContext returns the trace context of the traceparent and
+// tracestate headers. The second result is false when there is no valid
+// traceparent.
+func ParseTraceContext(h http.Header) (TraceContext, bool) {
+	tp := strings.TrimSpace(h.Get("traceparent"))
+	// version-traceid-parentid-flags, later versions can append fields.
+	if len(tp) < 55 || (len(tp) > 55 && tp[55] != '-') || tp[2] != '-' || tp[35] != '-' || tp[52] != '-' {
+		return TraceContext{}, false
+	}
+	version, traceID, spanID, flags := tp[:2], tp[3:35], tp[36:52], tp[53:55]
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(tp) != 55) ||
+		!isLowerHex(traceID) || isZeroHex(traceID) || !isLowerHex(spanID) || isZeroHex(spanID) || !isLowerHex(flags) {
+		return TraceContext{}, false
+	}
+	b, _ := hex.DecodeString(flags)
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: b[0]&1 == 1,
+		State:   strings.Join(h.Values("tracestate"), ","),
+	}, true
+}
+
+// Child returns the trace context of a new span of the trace, like the span
+// of an outgoing request.
+func (t TraceContext) Child() TraceContext {
+	t.SpanID = randomHex(8)
+	return t
+}
+
+// Traceparent returns the traceparent header of the trace context.
+func (t TraceContext) Traceparent() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + flags
+}
+
+// Inject sets the traceparent and tracestate headers of the trace context.
+func (t TraceContext) Inject(h http.Header) {
+	h.Set("traceparent", t.Traceparent())
+	if t.State != "" {
+		h.Set("tracestate", t.State)
+	} else {
+		h.Del("tracestate")
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// Trace returns a middleware Handler that continues the trace of the
+// traceparent header of the requests, or starts a new trace, and keeps it
+// with the request id of the X-Request-ID header for the outgoing requests
+// of TraceTransport. The traceparent of the trace is set on the response.
+//
+// app.UsePhase(cherry.PhaseEarly, cherry.Trace()).
+func Trace() Handler {
+	return func(ctx *Context) error {
+		t := ctx.Trace()
+		ctx.Response().Header().Set("traceparent", t.Traceparent())
+		return nil
+	}
+}
+
+// Trace returns the trace context of the request. The trace of the
+// traceparent header is continued in a new span, and requests without one
+// start a new trace. The trace is kept in the contexts of the request, so
+// the outgoing requests of TraceTransport made with them carry it.
+func (c *Context) Trace() TraceContext {
+	if t, ok := Value[TraceContext](c); ok {
+		return t
+	}
+	r := c.Request()
+	t, ok := r.Context().Value(typeKey[TraceContext]{}).(TraceContext)
+	if !ok {
+		if t, ok = ParseTraceContext(r.Header); ok {
+			t = t.Child()
+		} else {
+			t = NewTraceContext()
+		}
+	}
+	WithValue(c, t)
+	rctx := context.WithValue(r.Context(), typeKey[TraceContext]{}, t)
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		WithValue(c, requestID(id))
+		rctx = context.WithValue(rctx, typeKey[requestID]{}, requestID(id))
+	}
+	c.request = r.WithContext(rctx)
+	return t
+}
+
+// TraceID returns the id of the trace of the request, see Context.Trace.
+func (c *Context) TraceID() string {
+	return c.Trace().TraceID
+}
+
+// TraceTransport returns a RoundTripper that forwards the trace and the
+// request id of the context of the outgoing requests, which are the contexts
+// of a Context after its Trace is read. Every request gets its own span of the
+// trace. A nil base uses http.DefaultTransport.
+//
+//	client := &http.Client{Transport: cherry.TraceTransport(nil)}
+//	req, _ := http.NewRequestWithContext(ctx.Request().Context(), "GET", url, nil)
+func TraceTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{base: base}
+}
+
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	tc, ok := r.Context().Value(typeKey[TraceContext]{}).(TraceContext)
+	id, _ := r.Context().Value(typeKey[requestID]{}).(requestID)
+	if !ok && id == "" {
+		return t.base.RoundTrip(r)
+	}
+	// a RoundTripper must not modify the request.
+	r = r.Clone(r.Context())
+	if ok && r.Header.Get("traceparent") == "" {
+		tc.Child().Inject(r.Header)
+	}
+	if id != "" && r.Header.Get("X-Request-ID") == "" {
+		r.Header.Set("X-Request-ID", string(id))
+	}
+	return t.base.RoundTrip(r)
+}
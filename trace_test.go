@@ -0,0 +1,125 @@
+package cherry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceContext(t *testing.T) {
+	tests := []struct {
+		traceparent string
+		ok          bool
+		sampled     bool
+	}{
+		{testTraceparent, true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"", false, false},
+	}
+	for _, test := range tests {
+		h := http.Header{}
+		h.Set("traceparent", test.traceparent)
+		h.Add("tracestate", "congo=t61rcWkgMzE")
+		h.Add("tracestate", "rojo=00f067aa0ba902b7")
+		tc, ok := ParseTraceContext(h)
+		if ok != test.ok || tc.Sampled != test.sampled {
+			t.Errorf("%q: expecting %v sampled %v got %v %+v", test.traceparent, test.ok, test.sampled, ok, tc)
+		}
+		if ok && (tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID != "00f067aa0ba902b7" || tc.State != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7") {
+			t.Errorf("%q: unexpected trace context %+v", test.traceparent, tc)
+		}
+	}
+}
+
+func TestTraceContextInject(t *testing.T) {
+	h := http.Header{}
+	h.Set("tracestate", "stale=1")
+	tc := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	tc.Inject(h)
+	if h.Get("traceparent") != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00" || h.Get("tracestate") != "" {
+		t.Errorf("unexpected headers %v", h)
+	}
+	child := tc.Child()
+	if child.TraceID != tc.TraceID || child.SpanID == tc.SpanID || len(child.SpanID) != 16 {
+		t.Errorf("expecting a new span of the trace got %+v", child)
+	}
+	if parsed, ok := ParseTraceContext(http.Header{"Traceparent": {NewTraceContext().Traceparent()}}); !ok || !parsed.Sampled {
+		t.Errorf("expecting a new trace to be valid and sampled got %+v", parsed)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var outgoing http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Clone()
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: TraceTransport(nil)}
+
+	c := New()
+	c.Use(Trace())
+	c.Get("/", func(ctx *Context) error {
+		req, err := http.NewRequestWithContext(ctx.Request().Context(), "GET", upstream.URL, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		return ctx.Text(http.StatusOK, ctx.TraceID())
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("traceparent", testTraceparent)
+	r.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	r.Header.Set("X-Request-ID", "req-1")
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Body.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expecting the trace id of the request got %q", rw.Body)
+	}
+	server, _ := ParseTraceContext(rw.Header())
+	out, ok := ParseTraceContext(outgoing)
+	if !ok || out.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || out.State != "congo=t61rcWkgMzE" {
+		t.Errorf("expecting the trace to be forwarded got %v", outgoing)
+	}
+	if server.SpanID == "00f067aa0ba902b7" || out.SpanID == server.SpanID || out.SpanID == "00f067aa0ba902b7" {
+		t.Errorf("expecting new spans for the server and the outgoing request got %s and %s", server.SpanID, out.SpanID)
+	}
+	if outgoing.Get("X-Request-ID") != "req-1" {
+		t.Errorf("expecting the request id to be forwarded got %v", outgoing)
+	}
+
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if len(rw.Body.String()) != 32 || outgoing.Get("traceparent") == "" || outgoing.Get("X-Request-ID") != "" {
+		t.Errorf("expecting a new trace without a request id got %q %v", rw.Body, outgoing)
+	}
+}
+
+func TestTraceTransportWithoutTrace(t *testing.T) {
+	var outgoing http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Clone()
+	}))
+	defer upstream.Close()
+	res, err := (&http.Client{Transport: TraceTransport(nil)}).Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if outgoing.Get("traceparent") != "" {
+		t.Errorf("expecting no trace for a request without one got %v", outgoing)
+	}
+}
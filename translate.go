@@ -0,0 +1,70 @@
+package cherry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Translator translates messages to the locale of a request. Middleware,
+// like the one of the i18n package, stores it with WithValue.
+type Translator interface {
+	Translate(key string, args ...any) string
+}
+
+// T translates the message key with the Translator of the request. The args
+// are pairs of names and values, or a single map[string]any, that fill the
+// {name} placeholders of the message, a "count" selects its plural form.
+// Without a Translator the key is returned with its placeholders filled.
+//
+// ctx.T("cart.items", "count", 3).
+func (c *Context) T(key string, args ...any) string {
+	if t, ok := Value[Translator](c); ok {
+		return t.Translate(key, args...)
+	}
+	return Interpolate(key, args...)
+}
+
+// Interpolate replaces the {name} placeholders of s with the values of the
+// args, which are pairs of names and values or a single map[string]any.
+// Unknown placeholders are left as they are.
+func Interpolate(s string, args ...any) string {
+	if len(args) == 0 || !strings.Contains(s, "{") {
+		return s
+	}
+	values := Args(args...)
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(s[:start])
+		if v, ok := values[s[start+1:end]]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// Args returns the args of T as a map.
+func Args(args ...any) map[string]any {
+	if len(args) == 1 {
+		if m, ok := args[0].(map[string]any); ok {
+			return m
+		}
+	}
+	m := make(map[string]any, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		m[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return m
+}
@@ -0,0 +1,49 @@
+package cherry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type upperTranslator struct{}
+
+func (upperTranslator) Translate(key string, args ...any) string {
+	return strings.ToUpper(Interpolate(key, args...))
+}
+
+func TestContextT(t *testing.T) {
+	c := New()
+	c.Get("/", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.T("hello {name}", "name", "ada"))
+	})
+	c.Get("/translated", func(ctx *Context) error {
+		WithValue[Translator](ctx, upperTranslator{})
+		return ctx.Text(http.StatusOK, ctx.T("hello {name}", "name", "ada"))
+	})
+	if _, body := doRequest(t, "GET", "/", nil, c); body != "hello ada" {
+		t.Errorf("expecting hello ada got %q", body)
+	}
+	if _, body := doRequest(t, "GET", "/translated", nil, c); body != "HELLO ADA" {
+		t.Errorf("expecting HELLO ADA got %q", body)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		s      string
+		args   []any
+		expect string
+	}{
+		{"{count} items", []any{"count", 3}, "3 items"},
+		{"{a}{b}", []any{map[string]any{"a": 1, "b": "x"}}, "1x"},
+		{"{missing} {a}", []any{"a", 1}, "{missing} 1"},
+		{"unclosed {a", []any{"a", 1}, "unclosed {a"},
+		{"no args {a}", nil, "no args {a}"},
+	}
+	for _, test := range tests {
+		if got := Interpolate(test.s, test.args...); got != test.expect {
+			t.Errorf("%q: expecting %q got %q", test.s, test.expect, got)
+		}
+	}
+}
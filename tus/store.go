@@ -0,0 +1,192 @@
+package tus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store for uploads that do not exist.
+var ErrNotFound = errors.New("tus: upload not found")
+
+// Info describes an upload.
+type Info struct {
+	ID     string `json:"id"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+	// Metadata holds the decoded Upload-Metadata of the creation request.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// ExpiresAt is zero when the upload does not expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Complete reports whether all of the bytes of the upload were received.
+func (i Info) Complete() bool {
+	return i.Offset == i.Size
+}
+
+func (i Info) expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt)
+}
+
+// Store keeps the uploads. The Handler serializes the calls for a single
+// upload.
+type Store interface {
+	// Create stores a new and empty upload.
+	Create(ctx context.Context, info Info) error
+	// Get returns the upload with the given id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Info, error)
+	// Append writes r at the offset of the upload and returns the number of
+	// bytes written. The bytes that were written before an error must be
+	// kept, so that the client can resume from there.
+	Append(ctx context.Context, id string, r io.Reader) (int64, error)
+	// Delete removes an upload.
+	Delete(ctx context.Context, id string) error
+	// List returns all of the uploads, it is used to clean up the uploads
+	// that expired.
+	List(ctx context.Context) ([]Info, error)
+}
+
+// FileStore is a Store that keeps each upload in a directory, as a .bin
+// file with the content and a .info file with its Info.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a FileStore that keeps the uploads in dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Path returns the path of the content of the upload with the given id.
+func (s *FileStore) Path(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *FileStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+// Create creates the files of the upload.
+func (s *FileStore) Create(ctx context.Context, info Info) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path(info.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.writeInfo(info)
+}
+
+// Get reads the Info of an upload.
+func (s *FileStore) Get(ctx context.Context, id string) (Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readInfo(id)
+}
+
+// Append appends r to the content of the upload.
+func (s *FileStore) Append(ctx context.Context, id string, r io.Reader) (int64, error) {
+	info, err := s.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(info.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	info.Offset += n
+	if werr := s.writeInfo(info); err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+// Delete removes the files of the upload.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(s.Path(id))
+}
+
+// List reads the Info of all of the uploads in the directory.
+func (s *FileStore) List(ctx context.Context) ([]Info, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Info
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".info")
+		if !ok {
+			continue
+		}
+		info, err := s.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+func (s *FileStore) readInfo(id string) (Info, error) {
+	var info Info
+	b, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return info, ErrNotFound
+	}
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
+
+// writeInfo replaces the .info file through a rename, so that it is never
+// read half written.
+func (s *FileStore) writeInfo(info Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.ID))
+}
@@ -0,0 +1,359 @@
+// Package tus serves resumable uploads with the tus protocol 1.0.0, with the
+// creation, expiration and termination extensions, see https://tus.io.
+//
+//	h := tus.New(tus.NewFileStore("uploads"), tus.Options{
+//		MaxSize:    1 << 30,
+//		Expiration: 24 * time.Hour,
+//	})
+//	h.Mount(app.Group("/files"))
+package tus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pooulad/cherry"
+)
+
+// Version is the version of the protocol that is supported.
+const Version = "1.0.0"
+
+const (
+	extensions  = "creation,expiration,termination"
+	contentType = "application/offset+octet-stream"
+	expiresFmt  = http.TimeFormat
+)
+
+// Options configures a Handler.
+type Options struct {
+	// MaxSize is the largest upload that is accepted, zero allows any size.
+	MaxSize int64
+
+	// Expiration is how long an upload may stay incomplete after it was
+	// created, zero keeps uploads until they are deleted.
+	Expiration time.Duration
+
+	// OnComplete is called once all of the bytes of an upload are
+	// received, before the response of the last PATCH request is written.
+	// An error is returned to the client.
+	OnComplete func(ctx *cherry.Context, info Info) error
+}
+
+// Handler serves the uploads of a Store.
+type Handler struct {
+	store Store
+	opts  Options
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+// New returns a Handler for the uploads of store.
+func New(store Store, opts Options) *Handler {
+	return &Handler{store: store, opts: opts, busy: map[string]bool{}}
+}
+
+// Mount registers the endpoints of the protocol on g. Uploads are created
+// on the path of the group and live below it. The middleware of the group,
+// like an authentication, applies to all of them.
+func (h *Handler) Mount(g *cherry.Group) {
+	g.Options("/", h.options)
+	g.Post("/", h.create)
+	g.Options("/:id", h.options)
+	g.Head("/:id", h.head)
+	g.Patch("/:id", h.patch)
+	g.Delete("/:id", h.delete)
+}
+
+// Cleanup deletes the uploads that expired, it is meant to be called
+// periodically from a background task of the application.
+func (h *Handler) Cleanup(ctx context.Context) error {
+	list, err := h.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, info := range list {
+		if !info.expired(now) || !h.lock(info.ID) {
+			continue
+		}
+		err := h.store.Delete(ctx, info.ID)
+		h.unlock(info.ID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) options(ctx *cherry.Context) error {
+	header := ctx.Response().Header()
+	header.Set("Tus-Resumable", Version)
+	header.Set("Tus-Version", Version)
+	header.Set("Tus-Extension", extensions)
+	if h.opts.MaxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(h.opts.MaxSize, 10))
+	}
+	ctx.Response().WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) create(ctx *cherry.Context) error {
+	if err := h.check(ctx); err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(ctx.Header("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return cherry.NewHTTPError(http.StatusBadRequest, "invalid Upload-Length")
+	}
+	if h.opts.MaxSize > 0 && size > h.opts.MaxSize {
+		return cherry.NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	metadata, err := parseMetadata(ctx.Header("Upload-Metadata"))
+	if err != nil {
+		return cherry.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	info := Info{ID: id, Size: size, Metadata: metadata, ExpiresAt: h.expiresAt()}
+	if err := h.store.Create(ctx.Request().Context(), info); err != nil {
+		return err
+	}
+	if info.Complete() {
+		if err := h.complete(ctx, info); err != nil {
+			return err
+		}
+	}
+	header := ctx.Response().Header()
+	header.Set("Location", path.Join(ctx.Request().URL.Path, id))
+	h.setExpires(header, info)
+	ctx.Response().WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (h *Handler) head(ctx *cherry.Context) error {
+	if err := h.check(ctx); err != nil {
+		return err
+	}
+	info, err := h.get(ctx)
+	if err != nil {
+		return err
+	}
+	header := ctx.Response().Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(info.Size, 10))
+	if len(info.Metadata) > 0 {
+		header.Set("Upload-Metadata", formatMetadata(info.Metadata))
+	}
+	h.setExpires(header, info)
+	ctx.Response().WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (h *Handler) patch(ctx *cherry.Context) error {
+	if err := h.check(ctx); err != nil {
+		return err
+	}
+	if ct := ctx.Header("Content-Type"); ct != contentType {
+		return cherry.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be "+contentType)
+	}
+	offset, err := strconv.ParseInt(ctx.Header("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return cherry.NewHTTPError(http.StatusBadRequest, "invalid Upload-Offset")
+	}
+	id := ctx.Param("id")
+	if !h.lock(id) {
+		return cherry.NewHTTPError(http.StatusLocked, "the upload is being written")
+	}
+	defer h.unlock(id)
+
+	info, err := h.get(ctx)
+	if err != nil {
+		return err
+	}
+	if offset != info.Offset {
+		return cherry.NewHTTPError(http.StatusConflict, fmt.Sprintf("the offset of the upload is %d", info.Offset))
+	}
+	if cl := ctx.Request().ContentLength; cl > info.Size-info.Offset {
+		return cherry.NewHTTPError(http.StatusRequestEntityTooLarge, "the body exceeds the length of the upload")
+	}
+	// the body is limited to the rest of the upload, a client that sends
+	// more is cut off.
+	body := io.LimitReader(ctx.Request().Body, info.Size-info.Offset)
+	n, err := h.store.Append(ctx.Request().Context(), id, body)
+	info.Offset += n
+	if err != nil {
+		return err
+	}
+	if info.Complete() {
+		if err := h.complete(ctx, info); err != nil {
+			return err
+		}
+	}
+	header := ctx.Response().Header()
+	header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	h.setExpires(header, info)
+	ctx.Response().WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) delete(ctx *cherry.Context) error {
+	if err := h.check(ctx); err != nil {
+		return err
+	}
+	id := ctx.Param("id")
+	if !h.lock(id) {
+		return cherry.NewHTTPError(http.StatusLocked, "the upload is being written")
+	}
+	defer h.unlock(id)
+	if _, err := h.get(ctx); err != nil {
+		return err
+	}
+	if err := h.store.Delete(ctx.Request().Context(), id); err != nil {
+		return notFound(err)
+	}
+	ctx.Response().WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// check sets the Tus-Resumable header and rejects clients of another
+// version of the protocol.
+func (h *Handler) check(ctx *cherry.Context) error {
+	header := ctx.Response().Header()
+	header.Set("Tus-Resumable", Version)
+	if ctx.Header("Tus-Resumable") != Version {
+		header.Set("Tus-Version", Version)
+		return cherry.NewHTTPError(http.StatusPreconditionFailed, "unsupported version of the tus protocol")
+	}
+	return nil
+}
+
+// get returns the upload of the request, expired uploads are gone.
+func (h *Handler) get(ctx *cherry.Context) (Info, error) {
+	id := ctx.Param("id")
+	if !validID(id) {
+		return Info{}, cherry.NewHTTPError(http.StatusNotFound)
+	}
+	info, err := h.store.Get(ctx.Request().Context(), id)
+	if err != nil {
+		return info, notFound(err)
+	}
+	if info.expired(time.Now()) {
+		return info, cherry.NewHTTPError(http.StatusGone)
+	}
+	return info, nil
+}
+
+func (h *Handler) complete(ctx *cherry.Context, info Info) error {
+	if h.opts.OnComplete == nil {
+		return nil
+	}
+	return h.opts.OnComplete(ctx, info)
+}
+
+func (h *Handler) expiresAt() time.Time {
+	if h.opts.Expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(h.opts.Expiration)
+}
+
+func (h *Handler) setExpires(header http.Header, info Info) {
+	if !info.ExpiresAt.IsZero() && !info.Complete() {
+		header.Set("Upload-Expires", info.ExpiresAt.UTC().Format(expiresFmt))
+	}
+}
+
+// lock marks an upload as busy, it returns false when it already is.
+func (h *Handler) lock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[id] {
+		return false
+	}
+	h.busy[id] = true
+	return true
+}
+
+func (h *Handler) unlock(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.busy, id)
+}
+
+func notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return cherry.NewHTTPError(http.StatusNotFound)
+	}
+	return err
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id could have been created by newID, which keeps
+// ids that are not from us out of the paths of the stores.
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// parseMetadata decodes an Upload-Metadata header, a comma separated list
+// of keys with an optional base64 encoded value.
+func parseMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value of %q", key)
+		}
+		m[key] = string(b)
+	}
+	return m, nil
+}
+
+func formatMetadata(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k
+		if v := m[k]; v != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(v))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,164 @@
+package tus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pooulad/cherry"
+)
+
+func newApp(t *testing.T, opts Options) (*cherry.Cherry, *Handler, *FileStore) {
+	store := NewFileStore(t.TempDir())
+	h := New(store, opts)
+	app := cherry.New()
+	h.Mount(app.Group("/files"))
+	return app, h, store
+}
+
+func do(app *cherry.Cherry, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Tus-Resumable", Version)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	rw := httptest.NewRecorder()
+	app.ServeHTTP(rw, r)
+	return rw
+}
+
+func TestUpload(t *testing.T) {
+	var completed Info
+	app, _, store := newApp(t, Options{
+		MaxSize:    100,
+		Expiration: time.Hour,
+		OnComplete: func(ctx *cherry.Context, info Info) error {
+			completed = info
+			return nil
+		},
+	})
+
+	rw := do(app, "OPTIONS", "/files", "", nil)
+	if rw.Code != http.StatusNoContent || rw.Header().Get("Tus-Max-Size") != "100" || rw.Header().Get("Tus-Extension") != extensions {
+		t.Fatalf("unexpected OPTIONS response %d %v", rw.Code, rw.Header())
+	}
+
+	rw = do(app, "POST", "/files", "", map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": "filename aGVsbG8udHh0,private",
+	})
+	if rw.Code != http.StatusCreated {
+		t.Fatalf("expecting 201 got %d %s", rw.Code, rw.Body.String())
+	}
+	location := rw.Header().Get("Location")
+	if !strings.HasPrefix(location, "/files/") || rw.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("unexpected creation headers %v", rw.Header())
+	}
+
+	patch := func(offset, body string) *httptest.ResponseRecorder {
+		return do(app, "PATCH", location, body, map[string]string{
+			"Content-Type":  contentType,
+			"Upload-Offset": offset,
+		})
+	}
+	if rw = patch("0", "hello "); rw.Code != http.StatusNoContent || rw.Header().Get("Upload-Offset") != "6" {
+		t.Fatalf("expecting offset 6 got %d %v", rw.Code, rw.Header())
+	}
+	if rw = patch("0", "hello "); rw.Code != http.StatusConflict {
+		t.Errorf("expecting 409 for a wrong offset got %d", rw.Code)
+	}
+
+	rw = do(app, "HEAD", location, "", nil)
+	if rw.Code != http.StatusOK || rw.Header().Get("Upload-Offset") != "6" || rw.Header().Get("Upload-Length") != "11" {
+		t.Errorf("unexpected HEAD response %d %v", rw.Code, rw.Header())
+	}
+	if m := rw.Header().Get("Upload-Metadata"); m != "filename aGVsbG8udHh0,private" {
+		t.Errorf("unexpected metadata %q", m)
+	}
+
+	if rw = patch("6", "world"); rw.Code != http.StatusNoContent || rw.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("expecting offset 11 got %d %v", rw.Code, rw.Header())
+	}
+	if !completed.Complete() || completed.Metadata["filename"] != "hello.txt" {
+		t.Errorf("expecting OnComplete to be called got %+v", completed)
+	}
+	b, _ := os.ReadFile(store.Path(completed.ID))
+	if string(b) != "hello world" {
+		t.Errorf("expecting hello world got %q", b)
+	}
+
+	if rw = do(app, "DELETE", location, "", nil); rw.Code != http.StatusNoContent {
+		t.Errorf("expecting 204 got %d", rw.Code)
+	}
+	if rw = do(app, "HEAD", location, "", nil); rw.Code != http.StatusNotFound {
+		t.Errorf("expecting 404 after a delete got %d", rw.Code)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	app, _, _ := newApp(t, Options{MaxSize: 10})
+
+	rw := do(app, "POST", "/files", "", map[string]string{"Upload-Length": "11"})
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting 413 got %d", rw.Code)
+	}
+	rw = do(app, "POST", "/files", "", map[string]string{"Upload-Length": "5", "Tus-Resumable": "0.2.2"})
+	if rw.Code != http.StatusPreconditionFailed || rw.Header().Get("Tus-Version") != Version {
+		t.Errorf("expecting 412 got %d %v", rw.Code, rw.Header())
+	}
+	rw = do(app, "HEAD", "/files/../../etc", "", nil)
+	if rw.Code == http.StatusOK {
+		t.Error("expecting an invalid id to be rejected")
+	}
+
+	rw = do(app, "POST", "/files", "", map[string]string{"Upload-Length": "5"})
+	location := rw.Header().Get("Location")
+	rw = do(app, "PATCH", location, "hello", map[string]string{"Upload-Offset": "0"})
+	if rw.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expecting 415 got %d", rw.Code)
+	}
+	rw = do(app, "PATCH", location, "hello world", map[string]string{"Upload-Offset": "0", "Content-Type": contentType})
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting 413 got %d", rw.Code)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	app, h, store := newApp(t, Options{Expiration: time.Hour})
+	rw := do(app, "POST", "/files", "", map[string]string{"Upload-Length": "5"})
+	id := strings.TrimPrefix(rw.Header().Get("Location"), "/files/")
+
+	if err := h.Cleanup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := store.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("expecting the upload to be kept got %v", err)
+	}
+
+	info.ExpiresAt = time.Now().Add(-time.Minute)
+	store.writeInfo(info)
+	if rw := do(app, "HEAD", "/files/"+id, "", nil); rw.Code != http.StatusGone {
+		t.Errorf("expecting 410 for an expired upload got %d", rw.Code)
+	}
+	if err := h.Cleanup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), id); err != ErrNotFound {
+		t.Errorf("expecting the upload to be deleted got %v", err)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata("filename aGVsbG8udHh0, is_confidential")
+	if err != nil || m["filename"] != "hello.txt" || m["is_confidential"] != "" || len(m) != 2 {
+		t.Errorf("unexpected metadata %v %v", m, err)
+	}
+	if _, err := parseMetadata("filename !!!"); err == nil {
+		t.Error("expecting an error for an invalid value")
+	}
+}
@@ -0,0 +1,318 @@
+package cherry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// defaultMultipartMemory is the part of a multipart form kept in memory,
+// the rest of the files is stored in temporary files.
+const defaultMultipartMemory = 32 << 20
+
+// maxFilenameLength is the maximum length of a sanitized filename in bytes.
+const maxFilenameLength = 255
+
+// UploadLimits limits the multipart forms of the requests. Zero values
+// are no limit, except MaxMemory which defaults to 32MB.
+type UploadLimits struct {
+	// MaxMemory is the part of the form kept in memory, the rest of the
+	// files is stored in temporary files.
+	MaxMemory int64
+
+	// MaxSize is the maximum size of the request body in bytes.
+	MaxSize int64
+
+	// MaxFiles is the maximum number of files in the form.
+	MaxFiles int
+
+	// AllowedTypes are the allowed media types of the files, like
+	// "image/png" or "image/*". The type is sniffed from the content of the
+	// file, the type claimed by the client is ignored.
+	AllowedTypes []string
+}
+
+// merge returns l with its zero values taken from defaults.
+func (l UploadLimits) merge(defaults UploadLimits) UploadLimits {
+	if l.MaxMemory == 0 {
+		l.MaxMemory = defaults.MaxMemory
+	}
+	if l.MaxSize == 0 {
+		l.MaxSize = defaults.MaxSize
+	}
+	if l.MaxFiles == 0 {
+		l.MaxFiles = defaults.MaxFiles
+	}
+	if l.AllowedTypes == nil {
+		l.AllowedTypes = defaults.AllowedTypes
+	}
+	return l
+}
+
+// Uploads returns a middleware Handler that parses and checks the multipart
+// form of the request before the handler runs, with limits that take
+// precedence over the UploadLimits of the application. Requests that are
+// not a multipart form pass.
+//
+// app.Post("/avatar", cherry.Chain(saveAvatar, cherry.Uploads(cherry.UploadLimits{MaxSize: 5 << 20, AllowedTypes: []string{"image/*"}}))).
+func Uploads(limits UploadLimits) Handler {
+	return func(ctx *Context) error {
+		if !isMultipart(ctx.Request()) {
+			return nil
+		}
+		defaults := UploadLimits{}
+		if ctx.cherry != nil {
+			defaults = ctx.cherry.UploadLimits
+		}
+		return ctx.parseMultipart(limits.merge(defaults))
+	}
+}
+
+// MultipartForm parses the multipart form of the request and returns it.
+// The form is checked against the UploadLimits of the application, unless
+// it was already parsed by the Uploads middleware. A request that is not a
+// multipart form fails with a 400 HTTPError.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	r := c.Request()
+	if r.MultipartForm == nil {
+		var limits UploadLimits
+		if c.cherry != nil {
+			limits = c.cherry.UploadLimits
+		}
+		if err := c.parseMultipart(limits); err != nil {
+			return nil, err
+		}
+	}
+	return r.MultipartForm, nil
+}
+
+// parseForm parses the form of the request, a multipart form is checked
+// against the UploadLimits of the application like in MultipartForm.
+func (c *Context) parseForm() error {
+	r := c.Request()
+	if r.Form != nil {
+		return nil
+	}
+	if isMultipart(r) {
+		_, err := c.MultipartForm()
+		return err
+	}
+	return r.ParseForm()
+}
+
+// parseMultipart parses the multipart form within limits.
+func (c *Context) parseMultipart(limits UploadLimits) error {
+	r := c.Request()
+	if limits.MaxMemory <= 0 {
+		limits.MaxMemory = defaultMultipartMemory
+	}
+	if limits.MaxSize > 0 {
+		if r.ContentLength > limits.MaxSize {
+			return NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		r.Body = http.MaxBytesReader(c.Response(), r.Body, limits.MaxSize)
+	}
+	if err := r.ParseMultipartForm(limits.MaxMemory); err != nil {
+		return multipartError(err)
+	}
+	if err := checkUploads(r.MultipartForm, limits); err != nil {
+		r.MultipartForm.RemoveAll()
+		r.MultipartForm = nil
+		// the values of the rejected form are not used either.
+		r.Form, r.PostForm = r.URL.Query(), url.Values{}
+		return err
+	}
+	return nil
+}
+
+// checkUploads checks the number and the types of the files of form.
+func checkUploads(form *multipart.Form, limits UploadLimits) error {
+	count := 0
+	for _, files := range form.File {
+		count += len(files)
+	}
+	if limits.MaxFiles > 0 && count > limits.MaxFiles {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many files, at most %d are allowed", limits.MaxFiles))
+	}
+	if len(limits.AllowedTypes) == 0 {
+		return nil
+	}
+	for _, files := range form.File {
+		for _, fh := range files {
+			ct, err := sniffType(fh)
+			if err != nil {
+				return err
+			}
+			if !typeAllowed(ct, limits.AllowedTypes) {
+				return NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("file %q of type %s is not allowed", fh.Filename, ct))
+			}
+		}
+	}
+	return nil
+}
+
+// sniffType detects the media type of the content of fh.
+func sniffType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	ct, _, _ := strings.Cut(http.DetectContentType(buf[:n]), ";")
+	return ct, nil
+}
+
+func typeAllowed(ct string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == ct || a == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(ct, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(strings.ToLower(r.Header.Get("Content-Type")), "multipart/form-data")
+}
+
+// FormFile returns the first file of the multipart form field name. A
+// missing file fails with a 400 HTTPError.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, NewHTTPError(http.StatusBadRequest, fmt.Sprintf("missing file %q", name))
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile saves the uploaded file fh to dst. When dst is a
+// directory, or ends in a slash, the file is saved in it under the
+// sanitized name given by the client. Missing directories are created.
+// A file that does not have the size announced by the client is removed
+// and fails with a 400 HTTPError.
+//
+// fh, err := ctx.FormFile("avatar")
+// err = ctx.SaveUploadedFile(fh, "uploads/").
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	if strings.HasSuffix(dst, "/") || strings.HasSuffix(dst, string(filepath.Separator)) || isDir(dst) {
+		dst = filepath.Join(dst, SanitizeFilename(fh.Filename))
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, src)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n != fh.Size {
+		err = NewHTTPError(http.StatusBadRequest, "incomplete upload")
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// SanitizeFilename turns a filename given by a client into a name that is
+// safe to store: the directories are dropped, control characters and
+// characters that are reserved on common file systems are replaced, leading
+// dots are removed and the name is shortened to 255 bytes, keeping its
+// extension. An empty result becomes "file".
+func SanitizeFilename(name string) string {
+	// clients on Windows send paths with backslashes.
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	name = strings.TrimRight(name, ". ")
+	if base := strings.ToUpper(strings.TrimSuffix(name, filepath.Ext(name))); isReservedName(base) {
+		name = "_" + name
+	}
+	if len(name) > maxFilenameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = truncateUTF8(name[:len(name)-len(ext)], maxFilenameLength-len(ext)) + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// isReservedName reports whether name is a device name on Windows.
+func isReservedName(name string) bool {
+	switch name {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(name) == 4 && (strings.HasPrefix(name, "COM") || strings.HasPrefix(name, "LPT")) {
+		return name[3] >= '1' && name[3] <= '9'
+	}
+	return false
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !isRuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+func multipartError(err error) error {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingBoundary):
+		return NewHTTPError(http.StatusBadRequest, "expecting a multipart form")
+	}
+	return NewHTTPError(http.StatusBadRequest, "invalid multipart form: "+err.Error())
+}
@@ -0,0 +1,232 @@
+package cherry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxStreamValueSize limits the size of the fields of a streamed form that
+// are not files, which are kept in memory.
+const maxStreamValueSize = 1 << 20
+
+// maxStreamValuesSize limits the size of all of the fields of a streamed
+// form that are not files, with their names, like net/http does for the
+// forms it parses.
+const maxStreamValuesSize = 10 << 20
+
+// UploadPart describes a file of a streamed multipart form.
+type UploadPart struct {
+	FormName string
+	Filename string
+	// ContentType is sniffed from the content of the file.
+	ContentType string
+	Header      textproto.MIMEHeader
+}
+
+// UploadSink stores the files of a streamed multipart form, like a
+// directory on disk or an object store. Store reads the file from r and
+// returns where it was stored.
+type UploadSink interface {
+	Store(ctx context.Context, part UploadPart, r io.Reader) (location string, err error)
+}
+
+// UploadedFile is a file stored by an UploadSink.
+type UploadedFile struct {
+	UploadPart
+	Size     int64
+	Location string
+}
+
+// StreamedForm is the result of StreamUploads.
+type StreamedForm struct {
+	Values map[string][]string
+	Files  []UploadedFile
+}
+
+// UploadProgress reports the progress of StreamUploads.
+type UploadProgress struct {
+	// Part is the file being stored, and Written the bytes of it read so
+	// far.
+	Part    UploadPart
+	Written int64
+	// Read is the number of bytes of the body read so far and Total the
+	// length of the body, or -1 when it is unknown.
+	Read  int64
+	Total int64
+}
+
+// StreamOptions configures StreamUploads.
+type StreamOptions struct {
+	// Limits take precedence over the UploadLimits of the application,
+	// MaxMemory is not used.
+	Limits UploadLimits
+
+	// Progress is called as the files are read.
+	Progress func(UploadProgress)
+}
+
+// StreamUploads reads the multipart form of the request part by part and
+// passes each file to sink as it arrives, without buffering it in memory or
+// in temporary files, which suits large uploads. The limits are checked
+// while reading, the fields that are not files are kept in memory and
+// limited to 1MB each and 10MB in total. When a limit is exceeded or the sink fails, the files
+// stored so far are returned with the error, the sink may clean them up.
+//
+// form, err := ctx.StreamUploads(cherry.DirSink("uploads"), cherry.StreamOptions{}).
+func (c *Context) StreamUploads(sink UploadSink, opts StreamOptions) (*StreamedForm, error) {
+	r := c.Request()
+	limits := opts.Limits
+	if c.cherry != nil {
+		limits = limits.merge(c.cherry.UploadLimits)
+	}
+	if limits.MaxSize > 0 && r.ContentLength > limits.MaxSize {
+		return nil, NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	counter := &countingReader{r: r.Body}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{counter, r.Body}
+	if limits.MaxSize > 0 {
+		r.Body = http.MaxBytesReader(c.Response(), r.Body, limits.MaxSize)
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, multipartError(err)
+	}
+
+	form := &StreamedForm{Values: map[string][]string{}}
+	valuesSize := 0
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return form, multipartError(err)
+		}
+		if p.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(p, maxStreamValueSize+1))
+			if err != nil {
+				return form, multipartError(err)
+			}
+			if len(b) > maxStreamValueSize {
+				return form, NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("field %q is too large", p.FormName()))
+			}
+			if valuesSize += len(p.FormName()) + len(b); valuesSize > maxStreamValuesSize {
+				return form, NewHTTPError(http.StatusRequestEntityTooLarge, "the fields of the form are too large")
+			}
+			form.Values[p.FormName()] = append(form.Values[p.FormName()], string(b))
+			continue
+		}
+		if limits.MaxFiles > 0 && len(form.Files) >= limits.MaxFiles {
+			return form, NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many files, at most %d are allowed", limits.MaxFiles))
+		}
+		file, err := c.streamPart(p, sink, limits, counter, opts.Progress)
+		if err != nil {
+			return form, err
+		}
+		form.Files = append(form.Files, file)
+	}
+}
+
+func (c *Context) streamPart(p *multipart.Part, sink UploadSink, limits UploadLimits, counter *countingReader, progress func(UploadProgress)) (UploadedFile, error) {
+	br := bufio.NewReaderSize(p, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return UploadedFile{}, multipartError(err)
+	}
+	ct, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	part := UploadPart{
+		FormName:    p.FormName(),
+		Filename:    p.FileName(),
+		ContentType: ct,
+		Header:      p.Header,
+	}
+	if len(limits.AllowedTypes) > 0 && !typeAllowed(ct, limits.AllowedTypes) {
+		return UploadedFile{}, NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("file %q of type %s is not allowed", part.Filename, ct))
+	}
+	pr := &progressReader{r: br, part: part, counter: counter, total: c.Request().ContentLength, fn: progress}
+	location, err := sink.Store(c.Request().Context(), part, pr)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			err = NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		return UploadedFile{}, err
+	}
+	return UploadedFile{UploadPart: part, Size: pr.written, Location: location}, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type progressReader struct {
+	r       io.Reader
+	part    UploadPart
+	written int64
+	counter *countingReader
+	total   int64
+	fn      func(UploadProgress)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.written += int64(n)
+	if r.fn != nil && n > 0 {
+		r.fn(UploadProgress{Part: r.part, Written: r.written, Read: r.counter.n, Total: r.total})
+	}
+	return n, err
+}
+
+// DirSink is an UploadSink that stores the files in a directory under their
+// sanitized names. A number is added to names that are taken.
+type DirSink string
+
+// Store writes the file to the directory and returns its path.
+func (d DirSink) Store(ctx context.Context, part UploadPart, r io.Reader) (string, error) {
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return "", err
+	}
+	name := SanitizeFilename(part.Filename)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		path := filepath.Join(string(d), name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			name = base + "-" + strconv.Itoa(i) + ext
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(f, r)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		return path, nil
+	}
+}
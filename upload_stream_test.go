@@ -0,0 +1,137 @@
+package cherry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type memorySink map[string]string
+
+func (m memorySink) Store(ctx context.Context, part UploadPart, r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	m[part.Filename] = string(b)
+	return "mem://" + part.Filename, nil
+}
+
+func TestStreamUploads(t *testing.T) {
+	sink := memorySink{}
+	var progress []UploadProgress
+	c := New()
+	c.Post("/upload", func(ctx *Context) error {
+		form, err := ctx.StreamUploads(sink, StreamOptions{
+			Progress: func(p UploadProgress) { progress = append(progress, p) },
+		})
+		if err != nil {
+			return err
+		}
+		if form.Values["title"][0] != "holiday" {
+			t.Errorf("expecting the title field got %v", form.Values)
+		}
+		if len(form.Files) != 1 {
+			t.Fatalf("expecting 1 file got %d", len(form.Files))
+		}
+		f := form.Files[0]
+		if f.Size != 5 || f.Location != "mem://a.txt" || f.ContentType != "text/plain" {
+			t.Errorf("unexpected file %+v", f)
+		}
+		return nil
+	})
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, multipartRequest(t, map[string]string{"a.txt": "hello"}))
+	isHTTPStatusOK(t, rw.Code)
+	if sink["a.txt"] != "hello" {
+		t.Errorf("expecting hello in the sink got %q", sink["a.txt"])
+	}
+	if len(progress) == 0 || progress[len(progress)-1].Written != 5 || progress[0].Read == 0 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+}
+
+func TestStreamUploadsLimits(t *testing.T) {
+	c := New()
+	c.Post("/upload", func(ctx *Context) error {
+		_, err := ctx.StreamUploads(memorySink{}, StreamOptions{
+			Limits: UploadLimits{MaxSize: 1 << 10, AllowedTypes: []string{"text/*"}},
+		})
+		return err
+	})
+
+	tests := []struct {
+		files  map[string]string
+		expect int
+	}{
+		{map[string]string{"a.txt": "hello"}, http.StatusOK},
+		{map[string]string{"a.png": "\x89PNG\r\n\x1a\n"}, http.StatusUnsupportedMediaType},
+		{map[string]string{"big.txt": strings.Repeat("a", 2<<10)}, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, multipartRequest(t, test.files))
+		if rw.Code != test.expect {
+			t.Errorf("%v: expecting %d got %d", test.files, test.expect, rw.Code)
+		}
+	}
+}
+
+func TestStreamUploadsValuesSize(t *testing.T) {
+	c := New()
+	c.Post("/upload", func(ctx *Context) error {
+		_, err := ctx.StreamUploads(memorySink{}, StreamOptions{})
+		return err
+	})
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	value := strings.Repeat("a", maxStreamValueSize)
+	for i := 0; i*maxStreamValueSize <= maxStreamValuesSize; i++ {
+		w.WriteField("note", value)
+	}
+	w.Close()
+	r := httptest.NewRequest("POST", "/upload", body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting the fields to be limited in total got %d", rw.Code)
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir := t.TempDir()
+	sink := DirSink(dir)
+	for i := 0; i < 2; i++ {
+		if _, err := sink.Store(context.Background(), UploadPart{Filename: "../a.txt"}, strings.NewReader("a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.txt", "a-1.txt"} {
+		if b, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(b) != "a" {
+			t.Errorf("%s: expecting a got %q %v", name, b, err)
+		}
+	}
+
+	_, err := sink.Store(context.Background(), UploadPart{Filename: "b.txt"}, io.MultiReader(strings.NewReader("b"), errReader{}))
+	if err == nil {
+		t.Error("expecting the read error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expecting the partial file to be removed got %v", err)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("broken") }
@@ -0,0 +1,199 @@
+package cherry
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func multipartRequest(t *testing.T, files map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	w.WriteField("title", "holiday")
+	for name, content := range files {
+		fw, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	w.Close()
+	r := httptest.NewRequest("POST", "/upload", body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestSaveUploadedFile(t *testing.T) {
+	dir := t.TempDir()
+	c := New()
+	c.Post("/upload", func(ctx *Context) error {
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			return err
+		}
+		if form.Value["title"][0] != "holiday" {
+			t.Errorf("expecting the title field got %v", form.Value)
+		}
+		fh, err := ctx.FormFile("file")
+		if err != nil {
+			return err
+		}
+		if err := ctx.SaveUploadedFile(fh, dir+"/photos/"); err != nil {
+			return err
+		}
+		return ctx.SaveUploadedFile(fh, filepath.Join(dir, "copy.txt"))
+	})
+
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, multipartRequest(t, map[string]string{"../../etc/passwd": "root"}))
+	isHTTPStatusOK(t, rw.Code)
+	for _, name := range []string{"photos/passwd", "copy.txt"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != "root" {
+			t.Errorf("%s: expecting root got %q %v", name, b, err)
+		}
+	}
+
+	code, _ := doRequest(t, "POST", "/upload", strings.NewReader("plain"), c)
+	if code != http.StatusBadRequest {
+		t.Errorf("expecting 400 for a request that is not multipart got %d", code)
+	}
+}
+
+func TestFormFileMissing(t *testing.T) {
+	c := New()
+	c.Post("/upload", func(ctx *Context) error {
+		_, err := ctx.FormFile("avatar")
+		return err
+	})
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, multipartRequest(t, map[string]string{"a.txt": "a"}))
+	if rw.Code != http.StatusBadRequest || !strings.Contains(rw.Body.String(), `missing file "avatar"`) {
+		t.Errorf("expecting a 400 for a missing file got %d %s", rw.Code, rw.Body.String())
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":                       "photo.jpg",
+		"../../etc/passwd":                "passwd",
+		`C:\Users\toby\report.pdf`:        "report.pdf",
+		"..":                              "file",
+		"":                                "file",
+		".htaccess":                       "htaccess",
+		"what?.txt":                       "what_.txt",
+		"new\nline.txt":                   "new_line.txt",
+		"CON.txt":                         "_CON.txt",
+		"com1":                            "_com1",
+		"trailing. ":                      "trailing",
+		strings.Repeat("é", 200) + ".png": strings.Repeat("é", 125) + ".png",
+	}
+	for name, expect := range tests {
+		if got := SanitizeFilename(name); got != expect {
+			t.Errorf("%q: expecting %q got %q", name, expect, got)
+		}
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 100)
+	c := New()
+	c.UploadLimits = UploadLimits{MaxFiles: 1}
+	ok := func(ctx *Context) error {
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, form.Value["title"][0])
+	}
+	c.Post("/upload", ok)
+	c.Put("/upload", Chain(ok, Uploads(UploadLimits{MaxFiles: 3, MaxSize: 1024, AllowedTypes: []string{"image/*"}})))
+
+	tests := []struct {
+		method string
+		files  map[string]string
+		expect int
+	}{
+		{"POST", map[string]string{"a.txt": "a"}, http.StatusOK},
+		{"POST", map[string]string{"a.txt": "a", "b.txt": "b"}, http.StatusRequestEntityTooLarge},
+		{"PUT", map[string]string{"a.png": png, "b.png": png}, http.StatusOK},
+		{"PUT", map[string]string{"a.png": png, "b.png": "plain text"}, http.StatusUnsupportedMediaType},
+		{"PUT", map[string]string{"a.png": png + strings.Repeat("\x00", 2048)}, http.StatusRequestEntityTooLarge},
+	}
+	for i, test := range tests {
+		r := multipartRequest(t, test.files)
+		r.Method = test.method
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.expect {
+			t.Errorf("%d: expecting %d got %d %s", i, test.expect, rw.Code, rw.Body.String())
+		}
+	}
+
+	// the size is also checked when the client does not announce it.
+	r := multipartRequest(t, map[string]string{"a.png": png + strings.Repeat("\x00", 2048)})
+	r.Method = "PUT"
+	r.ContentLength = -1
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting 413 got %d", rw.Code)
+	}
+}
+
+func TestFormUploadLimits(t *testing.T) {
+	c := New()
+	c.UploadLimits = UploadLimits{MaxFiles: 1}
+	c.Post("/upload", func(ctx *Context) error {
+		var form struct {
+			Title string `form:"title"`
+		}
+		if err := ctx.Bind(&form); err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, form.Title)
+	})
+	c.Put("/upload", func(ctx *Context) error {
+		return ctx.Text(http.StatusOK, ctx.Form("title"))
+	})
+
+	tests := []struct {
+		method string
+		files  map[string]string
+		code   int
+		body   string
+	}{
+		{"POST", map[string]string{"a.txt": "a"}, http.StatusOK, "holiday"},
+		{"POST", map[string]string{"a.txt": "a", "b.txt": "b"}, http.StatusRequestEntityTooLarge, ""},
+		{"PUT", map[string]string{"a.txt": "a"}, http.StatusOK, "holiday"},
+		{"PUT", map[string]string{"a.txt": "a", "b.txt": "b"}, http.StatusOK, ""},
+	}
+	for i, test := range tests {
+		r := multipartRequest(t, test.files)
+		r.Method = test.method
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code || (test.code == http.StatusOK && rw.Body.String() != test.body) {
+			t.Errorf("%d: expecting %d %q got %d %q", i, test.code, test.body, rw.Code, rw.Body.String())
+		}
+	}
+}
+
+func TestTypeAllowed(t *testing.T) {
+	allowed := []string{"image/*", "application/pdf"}
+	for ct, expect := range map[string]bool{
+		"image/png":       true,
+		"application/pdf": true,
+		"text/plain":      false,
+		"imagex/png":      false,
+	} {
+		if typeAllowed(ct, allowed) != expect {
+			t.Errorf("%s: expecting %v", ct, expect)
+		}
+	}
+}
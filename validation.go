@@ -0,0 +1,103 @@
+package cherry
+
+import (
+	"errors"
+	"strings"
+)
+
+// FieldError is the error of a field that failed validation. Tag names the
+// failed rule, like "required" or "max", and Param its argument. Bind
+// answers it with a message in the language of the request, see
+// Context.ErrorMessage.
+//
+// return &cherry.FieldError{Field: "age", Tag: "min", Param: "18"}.
+type FieldError struct {
+	Field string
+	Tag   string
+	Param string
+	Value any
+}
+
+func (e *FieldError) Error() string {
+	return Interpolate(validationMessage(e.Tag), e.args(e.Field)...)
+}
+
+func (e *FieldError) args(field string) []any {
+	return []any{"field", field, "param", e.Param, "value", e.Value}
+}
+
+// ValidationErrors are the errors of all the fields that failed validation.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidationMessages are the messages of the validation tags, used when the
+// Translator of the request has no "validation.<tag>" message. The {field},
+// {param} and {value} placeholders are filled from the FieldError. Tags
+// without a message use the "invalid" one.
+var ValidationMessages = map[string]string{
+	"invalid":    "{field} is invalid",
+	"type":       "{field} must be a valid {param}",
+	"required":   "{field} is required",
+	"min":        "{field} must be at least {param}",
+	"max":        "{field} must be at most {param}",
+	"len":        "{field} must have a length of {param}",
+	"oneof":      "{field} must be one of {param}",
+	"email":      "{field} must be a valid email address",
+	"url":        "{field} must be a valid URL",
+	"sort":       "{field} is not a sortable field",
+	"filter":     "{field} can not be filtered with {param}",
+	"pattern":    "{field} must match {param}",
+	"gt":         "{field} must be greater than {param}",
+	"lt":         "{field} must be less than {param}",
+	"unique":     "{field} must not have duplicate items",
+	"notallowed": "{field} is not allowed",
+}
+
+func validationMessage(tag string) string {
+	if msg, ok := ValidationMessages[tag]; ok {
+		return msg
+	}
+	return ValidationMessages["invalid"]
+}
+
+// ErrorMessage returns the message of a FieldError or of ValidationErrors
+// in the language of the request. The message of a tag is the
+// "validation.<tag>" message of the Translator, falling back to
+// ValidationMessages, and the name of a field is its "fields.<field>"
+// message when there is one. Other errors return their Error.
+//
+//	{"validation": {"required": "{field} est obligatoire"}, "fields": {"email": "L'e-mail"}}
+func (c *Context) ErrorMessage(err error) string {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		msgs := make([]string, len(errs))
+		for i, fe := range errs {
+			msgs[i] = c.fieldMessage(fe)
+		}
+		return strings.Join(msgs, "; ")
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return c.fieldMessage(fe)
+	}
+	return err.Error()
+}
+
+func (c *Context) fieldMessage(fe *FieldError) string {
+	field := fe.Field
+	if key := "fields." + fe.Field; c.T(key) != key {
+		field = c.T(key)
+	}
+	key := "validation." + fe.Tag
+	if msg := c.T(key, fe.args(field)...); msg != key {
+		return msg
+	}
+	return Interpolate(validationMessage(fe.Tag), fe.args(field)...)
+}
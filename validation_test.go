@@ -0,0 +1,97 @@
+package cherry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type signupInput struct {
+	Email string `json:"email"`
+	Age   int    `json:"age" query:"age"`
+}
+
+func (in signupInput) Validate() error {
+	var errs ValidationErrors
+	if in.Email == "" {
+		errs = append(errs, &FieldError{Field: "email", Tag: "required"})
+	}
+	if in.Age < 18 {
+		errs = append(errs, &FieldError{Field: "age", Tag: "min", Param: "18", Value: in.Age})
+	}
+	return errs
+}
+
+// frenchTranslator translates the validation messages and field names.
+type frenchTranslator map[string]string
+
+func (t frenchTranslator) Translate(key string, args ...any) string {
+	if msg, ok := t[key]; ok {
+		return Interpolate(msg, args...)
+	}
+	return Interpolate(key, args...)
+}
+
+func TestValidationMessages(t *testing.T) {
+	french := frenchTranslator{
+		"validation.required": "{field} est obligatoire",
+		"validation.min":      "{field} doit être au moins {param}",
+		"fields.email":        "L'e-mail",
+	}
+	c := New()
+	c.Post("/signup", func(ctx *Context) error {
+		if ctx.Query("lang") == "fr" {
+			WithValue[Translator](ctx, french)
+		}
+		var in signupInput
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		return ctx.Text(http.StatusOK, "ok")
+	})
+	tests := []struct {
+		target string
+		body   string
+		code   int
+		expect string
+	}{
+		{"/signup", `{}`, http.StatusUnprocessableEntity, "email is required; age must be at least 18"},
+		{"/signup?lang=fr", `{}`, http.StatusUnprocessableEntity, "L'e-mail est obligatoire; age doit être au moins 18"},
+		{"/signup?age=abc", `{}`, http.StatusBadRequest, "age must be a valid integer"},
+		{"/signup", `{"email":"a@b.c","age":20}`, http.StatusOK, "ok"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", test.target, strings.NewReader(test.body))
+		r.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		if rw.Code != test.code || !strings.Contains(rw.Body.String(), test.expect) {
+			t.Errorf("%s %s: expecting %d %q got %d %q", test.target, test.body, test.code, test.expect, rw.Code, rw.Body)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	ctx := &Context{request: httptest.NewRequest("GET", "/", nil)}
+	fe := &FieldError{Field: "size", Tag: "unknown"}
+	tests := []struct {
+		err    error
+		expect string
+	}{
+		{fe, "size is invalid"},
+		{fmt.Errorf("signup: %w", fe), "size is invalid"},
+		{ValidationErrors{{Field: "code", Tag: "len", Param: "6"}}, "code must have a length of 6"},
+		{errors.New("plain"), "plain"},
+	}
+	for _, test := range tests {
+		if msg := ctx.ErrorMessage(test.err); msg != test.expect {
+			t.Errorf("expecting %q got %q", test.expect, msg)
+		}
+	}
+	if fe.Error() != "size is invalid" {
+		t.Errorf("unexpected error %q", fe.Error())
+	}
+}
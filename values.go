@@ -0,0 +1,34 @@
+package cherry
+
+import "context"
+
+// typeKey is the context key of the values stored with WithValue. Every
+// type gets its own key, so values of different types never collide.
+type typeKey[T any] struct{}
+
+// WithValue stores v in the context of the request, keyed by its type T.
+// A later WithValue of the same type replaces it. Middleware usually stores
+// its own named types, like a *User, so the handlers can retrieve them with
+// Value.
+//
+// cherry.WithValue(ctx, user).
+func WithValue[T any](ctx *Context, v T) {
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx.Context = context.WithValue(parent, typeKey[T]{}, v)
+}
+
+// Value returns the value of type T stored with WithValue. The second result
+// is false when no value of type T was stored.
+//
+// user, ok := cherry.Value[*User](ctx).
+func Value[T any](ctx *Context) (T, bool) {
+	if ctx.Context == nil {
+		var zero T
+		return zero, false
+	}
+	v, ok := ctx.Context.Value(typeKey[T]{}).(T)
+	return v, ok
+}
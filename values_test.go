@@ -0,0 +1,57 @@
+package cherry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type testUser struct {
+	name string
+}
+
+type testRole string
+
+func TestValue(t *testing.T) {
+	c := New()
+	c.BindContext(context.WithValue(context.Background(), "foo", "bar"))
+	c.Use(func(ctx *Context) error {
+		WithValue(ctx, &testUser{"toby"})
+		WithValue(ctx, testRole("admin"))
+		WithValue(ctx, "plain string")
+		return nil
+	})
+	c.Get("/", func(ctx *Context) error {
+		user, ok := Value[*testUser](ctx)
+		if !ok {
+			t.Fatal("expecting a user")
+		}
+		role, _ := Value[testRole](ctx)
+		s, _ := Value[string](ctx)
+		if s != "plain string" {
+			t.Errorf("expecting the string value got %q", s)
+		}
+		if _, ok := Value[int](ctx); ok {
+			t.Error("expecting no int value")
+		}
+		if ctx.Context.Value("foo") != "bar" {
+			t.Error("expecting the bound context to be kept")
+		}
+		return ctx.Text(http.StatusOK, user.name+" "+string(role))
+	})
+
+	code, body := doRequest(t, "GET", "/", nil, c)
+	isHTTPStatusOK(t, code)
+	if body != "toby admin" {
+		t.Errorf("expecting toby admin got %s", body)
+	}
+
+	ctx := &Context{}
+	if _, ok := Value[string](ctx); ok {
+		t.Error("expecting no value without a context")
+	}
+	WithValue(ctx, 42)
+	if n, _ := Value[int](ctx); n != 42 {
+		t.Errorf("expecting 42 got %d", n)
+	}
+}
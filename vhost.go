@@ -0,0 +1,104 @@
+package cherry
+
+import (
+	"io/fs"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// StaticHosts serves a different directory under prefix depending on the
+// Host of the request. Hosts can be exact names, wildcards like
+// "*.example.com" that match any subdomain, or "*" which matches any host.
+// Exact names win over wildcards, requests for an unknown host get a 404.
+//
+//	app.StaticHosts("/", map[string]string{
+//	    "docs.example.com": "./docs",
+//	    "cdn.example.com":  "./assets",
+//	})
+func (c *Cherry) StaticHosts(prefix string, roots map[string]string, opts ...StaticOption) {
+	hosts := make(map[string]http.FileSystem, len(roots))
+	for host, dir := range roots {
+		hosts[host] = http.Dir(c.resolve(dir))
+	}
+	c.staticHosts(prefix, hosts, opts)
+}
+
+// StaticHostsFS is like StaticHosts but serves an fs.FS for each host.
+func (c *Cherry) StaticHostsFS(prefix string, roots map[string]fs.FS, opts ...StaticOption) {
+	hosts := make(map[string]http.FileSystem, len(roots))
+	for host, fsys := range roots {
+		hosts[host] = http.FS(fsys)
+	}
+	c.staticHosts(prefix, hosts, opts)
+}
+
+func (c *Cherry) staticHosts(prefix string, roots map[string]http.FileSystem, opts []StaticOption) {
+	filename := func(ctx *Context) string {
+		return ctx.Param("filepath")
+	}
+	m := hostMatcher{}
+	handlers := make(map[string]Handler, len(roots))
+	for host, fsys := range roots {
+		host = strings.ToLower(host)
+		m.add(host)
+		handlers[host] = c.staticHandler(fsys, opts, filename)
+	}
+	h := func(ctx *Context) error {
+		host, ok := m.match(ctx.Request().Host)
+		if !ok {
+			http.NotFound(ctx.Response(), ctx.Request())
+			return nil
+		}
+		return handlers[host](ctx)
+	}
+	route := path.Join(prefix, "*filepath")
+	c.add("GET", route, h)
+	c.add("HEAD", route, h)
+}
+
+// hostMatcher matches the Host header of a request against a set of host
+// patterns.
+type hostMatcher struct {
+	exact     map[string]bool
+	wildcards []string
+	any       bool
+}
+
+func (m *hostMatcher) add(pattern string) {
+	switch {
+	case pattern == "*":
+		m.any = true
+	case strings.HasPrefix(pattern, "*."):
+		m.wildcards = append(m.wildcards, pattern)
+		// longer suffixes are more specific and are tried first.
+		sort.Slice(m.wildcards, func(i, j int) bool { return len(m.wildcards[i]) > len(m.wildcards[j]) })
+	default:
+		if m.exact == nil {
+			m.exact = map[string]bool{}
+		}
+		m.exact[pattern] = true
+	}
+}
+
+// match returns the pattern that matches host.
+func (m *hostMatcher) match(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if m.exact[host] {
+		return host, true
+	}
+	for _, w := range m.wildcards {
+		if strings.HasSuffix(host, w[1:]) {
+			return w, true
+		}
+	}
+	if m.any {
+		return "*", true
+	}
+	return "", false
+}
@@ -0,0 +1,57 @@
+package cherry
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticHosts(t *testing.T) {
+	file := func(s string) fstest.MapFS {
+		return fstest.MapFS{"index.html": &fstest.MapFile{Data: []byte(s)}}
+	}
+	c := New()
+	c.StaticHostsFS("/", map[string]fs.FS{
+		"docs.example.com": file("docs"),
+		"*.example.com":    file("subdomain"),
+		"*":                file("default"),
+	})
+
+	tests := []struct {
+		host   string
+		expect string
+	}{
+		{"docs.example.com", "docs"},
+		{"DOCS.example.com:8080", "docs"},
+		{"cdn.example.com", "subdomain"},
+		{"example.org", "default"},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Host = test.host
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, r)
+		isHTTPStatusOK(t, rw.Code)
+		if rw.Body.String() != test.expect {
+			t.Errorf("%s: expecting %s got %s", test.host, test.expect, rw.Body.String())
+		}
+	}
+}
+
+func TestStaticHostsUnknownHost(t *testing.T) {
+	c := New()
+	c.StaticHosts("/public", map[string]string{"docs.example.com": "./"})
+	r, _ := http.NewRequest("GET", "/public/README.md", nil)
+	r.Host = "example.org"
+	rw := httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("expecting status 404 got %d", rw.Code)
+	}
+	r.Host = "docs.example.com"
+	rw = httptest.NewRecorder()
+	c.ServeHTTP(rw, r)
+	isHTTPStatusOK(t, rw.Code)
+}
@@ -0,0 +1,457 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+// The types of data messages.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0
+	opText         = 1
+	opBinary       = 2
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// The status codes of a close frame.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseAbnormal        = 1006
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+// compressMinSize is the size below which messages are not worth
+// compressing.
+const compressMinSize = 128
+
+// deflateTail completes a compressed message: the empty stored block that
+// the sender stripped, followed by a final empty block that ends the
+// stream.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// ErrClosed is returned by writes on a closed connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// CloseError is returned by ReadMessage when the connection was closed
+// with a close frame, sent by the client or by the server after a protocol
+// violation.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. A single goroutine may read from it
+// while any number of goroutines write to it.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+	compress    bool
+	opts        Options
+
+	// readErr makes the first error of a read permanent.
+	readErr error
+
+	// wmu serializes the writes, which come from the application and from
+	// the keepalive.
+	wmu    sync.Mutex
+	closed bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, subprotocol string, compress bool, opts Options) *Conn {
+	c := &Conn{
+		conn:        conn,
+		br:          br,
+		subprotocol: subprotocol,
+		compress:    compress,
+		opts:        opts,
+		done:        make(chan struct{}),
+	}
+	if opts.PingInterval > 0 {
+		go c.keepAlive()
+	}
+	return c
+}
+
+// Subprotocol returns the negotiated subprotocol, or an empty string.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed reports whether permessage-deflate was negotiated.
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+// RemoteAddr returns the address of the client.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage returns the next data message. Pings are answered and close
+// frames are echoed while reading. Once an error is returned the connection
+// is closed and all further reads return the same error.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	typ, msg, err := c.readMessage()
+	if err != nil {
+		var ce *CloseError
+		if errors.As(err, &ce) {
+			c.CloseWithCode(ce.Code, ce.Reason)
+		} else {
+			c.closeConn()
+		}
+		c.readErr = err
+		return 0, nil, err
+	}
+	return typ, msg, nil
+}
+
+// ReadJSON reads the next message and decodes it into v.
+func (c *Conn) ReadJSON(v any) error {
+	_, msg, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, v)
+}
+
+// WriteMessage writes a data message, which is compressed when
+// permessage-deflate was negotiated.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	if c.compress && len(data) >= compressMinSize {
+		compressed, err := c.deflate(data)
+		if err != nil {
+			return err
+		}
+		return c.writeFrame(int(typ), true, compressed)
+	}
+	return c.writeFrame(int(typ), false, data)
+}
+
+// WriteJSON writes v encoded as JSON in a text message.
+func (c *Conn) WriteJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, b)
+}
+
+// Close closes the connection with CloseNormal.
+func (c *Conn) Close() error {
+	return c.CloseWithCode(CloseNormal, "")
+}
+
+// CloseWithCode sends a close frame with the code and reason and closes
+// the connection. Only the first close has an effect.
+func (c *Conn) CloseWithCode(code int, reason string) error {
+	var err error
+	c.closeOnce.Do(func() {
+		payload := make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+		if code == CloseNoStatus {
+			// 1005 must not be sent, it stands for a close frame without
+			// a code.
+			payload = nil
+		}
+		err = c.writeFrame(opClose, false, payload)
+		c.shutdown()
+	})
+	return err
+}
+
+// closeConn closes the connection without a close frame, after the
+// connection failed.
+func (c *Conn) closeConn() {
+	c.closeOnce.Do(c.shutdown)
+}
+
+func (c *Conn) shutdown() {
+	c.wmu.Lock()
+	c.closed = true
+	c.wmu.Unlock()
+	close(c.done)
+	c.conn.Close()
+}
+
+// keepAlive pings the client until the connection is closed.
+func (c *Conn) keepAlive() {
+	t := time.NewTicker(c.opts.PingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := c.writeFrame(opPing, false, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// frameHeader is the header of a frame that was read.
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	op     int
+	length int64
+	mask   [4]byte
+}
+
+func (c *Conn) readMessage() (MessageType, []byte, error) {
+	var (
+		typ        MessageType
+		compressed bool
+		buf        []byte
+	)
+	for {
+		h, err := c.readHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+		if h.op < opClose && int64(len(buf))+h.length > c.opts.ReadLimit {
+			return 0, nil, &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+		}
+		payload := make([]byte, h.length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return 0, nil, err
+		}
+		for i := range payload {
+			payload[i] ^= h.mask[i%4]
+		}
+
+		switch h.op {
+		case opPing:
+			if err := c.writeFrame(opPong, false, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return 0, nil, closeFrameError(payload)
+		case opText, opBinary:
+			if typ != 0 {
+				return 0, nil, protocolError("expecting a continuation frame")
+			}
+			typ, compressed = MessageType(h.op), h.rsv1
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, protocolError("unexpected continuation frame")
+			}
+			if h.rsv1 {
+				return 0, nil, protocolError("compression bit on a continuation frame")
+			}
+		default:
+			return 0, nil, protocolError(fmt.Sprintf("unknown opcode %d", h.op))
+		}
+		buf = append(buf, payload...)
+		if h.fin {
+			break
+		}
+	}
+	if compressed {
+		var err error
+		if buf, err = c.inflate(buf); err != nil {
+			return 0, nil, err
+		}
+	}
+	if typ == TextMessage && !utf8.Valid(buf) {
+		return 0, nil, &CloseError{Code: CloseInvalidPayload, Reason: "invalid utf-8"}
+	}
+	return typ, buf, nil
+}
+
+func (c *Conn) readHeader() (frameHeader, error) {
+	var h frameHeader
+	if c.opts.ReadTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.opts.ReadTimeout))
+	}
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+	h.fin = b[0]&0x80 != 0
+	h.rsv1 = b[0]&0x40 != 0
+	h.op = int(b[0] & 0x0f)
+	if b[0]&0x30 != 0 {
+		return h, protocolError("reserved bits set")
+	}
+	if h.rsv1 && !c.compress {
+		return h, protocolError("compression bit without permessage-deflate")
+	}
+	if b[1]&0x80 == 0 {
+		return h, protocolError("frames of the client must be masked")
+	}
+	h.length = int64(b[1] & 0x7f)
+	switch h.length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n > 1<<63-1 {
+			return h, protocolError("invalid frame length")
+		}
+		h.length = int64(n)
+	}
+	if h.op >= opClose && (!h.fin || h.length > 125 || h.rsv1) {
+		return h, protocolError("invalid control frame")
+	}
+	if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// writeFrame writes a single, unmasked, frame.
+func (c *Conn) writeFrame(op int, rsv1 bool, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | byte(op)
+	if rsv1 {
+		header[0] |= 0x40
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if c.opts.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+	}
+	bufs := net.Buffers{header, payload}
+	_, err := bufs.WriteTo(c.conn)
+	return err
+}
+
+var flateWriters sync.Map // level -> *sync.Pool
+
+func (c *Conn) deflate(data []byte) ([]byte, error) {
+	p, _ := flateWriters.LoadOrStore(c.opts.CompressionLevel, &sync.Pool{})
+	pool := p.(*sync.Pool)
+	var buf bytes.Buffer
+	fw, _ := pool.Get().(*flate.Writer)
+	if fw == nil {
+		var err error
+		if fw, err = flate.NewWriter(&buf, c.opts.CompressionLevel); err != nil {
+			return nil, err
+		}
+	} else {
+		fw.Reset(&buf)
+	}
+	defer pool.Put(fw)
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	// the sync flush ends with an empty stored block, which the receiver
+	// adds back.
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail[:4]), nil
+}
+
+func (c *Conn) inflate(data []byte) ([]byte, error) {
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer fr.Close()
+	out, err := io.ReadAll(io.LimitReader(fr, c.opts.ReadLimit+1))
+	if err != nil {
+		return nil, &CloseError{Code: CloseInvalidPayload, Reason: "invalid compressed message"}
+	}
+	if int64(len(out)) > c.opts.ReadLimit {
+		return nil, &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+	}
+	return out, nil
+}
+
+func protocolError(reason string) error {
+	return &CloseError{Code: CloseProtocolError, Reason: reason}
+}
+
+// closeFrameError returns the CloseError of a close frame that was
+// received, or a protocol error when the frame is invalid.
+func closeFrameError(payload []byte) error {
+	switch {
+	case len(payload) == 0:
+		return &CloseError{Code: CloseNoStatus}
+	case len(payload) == 1:
+		return protocolError("invalid close frame")
+	}
+	code := int(binary.BigEndian.Uint16(payload))
+	reason := payload[2:]
+	if !validCloseCode(code) || !utf8.Valid(reason) {
+		return protocolError("invalid close frame")
+	}
+	return &CloseError{Code: code, Reason: string(reason)}
+}
+
+// validCloseCode reports whether a client may send the code.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	}
+	return code != 1004 && code != CloseNoStatus && code != CloseAbnormal
+}
@@ -0,0 +1,242 @@
+// Package websocket serves WebSocket connections (RFC 6455) on Cherry
+// routes, with subprotocol negotiation, per-message compression (RFC 7692),
+// read limits, deadlines and a ping keepalive.
+//
+//	app.Get("/chat", websocket.Handler(websocket.Options{
+//		Subprotocols: []string{"chat.v2", "chat.v1"},
+//		Compression:  true,
+//		PingInterval: 30 * time.Second,
+//	}, func(conn *websocket.Conn) error {
+//		for {
+//			typ, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return err
+//			}
+//			if err := conn.WriteMessage(typ, msg); err != nil {
+//				return err
+//			}
+//		}
+//	}))
+package websocket
+
+import (
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pooulad/cherry"
+)
+
+// acceptGUID is appended to the key of the client to compute the accept
+// key of the handshake.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// defaultReadLimit is the default of Options.ReadLimit.
+const defaultReadLimit = 16 << 20
+
+// Options configures the connections of Upgrade and Handler. Zero values
+// are replaced by sensible defaults.
+type Options struct {
+	// Subprotocols are the supported subprotocols in order of preference.
+	// The first one the client offers is chosen, the connection has no
+	// subprotocol when none of them is offered.
+	Subprotocols []string
+
+	// Compression enables the permessage-deflate extension when the client
+	// offers it.
+	Compression bool
+
+	// CompressionLevel is the flate level of the messages that are
+	// written. Defaults to flate.BestSpeed.
+	CompressionLevel int
+
+	// ReadLimit is the largest message that is accepted, after
+	// decompression. Larger messages close the connection with
+	// CloseMessageTooBig. Defaults to 16MB.
+	ReadLimit int64
+
+	// ReadTimeout closes connections that receive nothing for this long.
+	// Defaults to twice the PingInterval, or no timeout without pings.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the deadline of every write.
+	WriteTimeout time.Duration
+
+	// PingInterval is the interval of the pings sent to the client. The
+	// pongs it answers with keep the ReadTimeout from expiring on idle
+	// connections.
+	PingInterval time.Duration
+
+	// CheckOrigin reports whether the Origin of the request is allowed. By
+	// default only requests without an Origin or from the same host are
+	// allowed.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Handler returns a cherry.Handler that upgrades the request and calls fn
+// with the connection. The connection is closed when fn returns, with
+// CloseNormal when fn returns nil or an error of the client and with
+// CloseInternalError for other errors.
+func Handler(opts Options, fn func(conn *Conn) error) cherry.Handler {
+	return func(ctx *cherry.Context) error {
+		conn, err := Upgrade(ctx, opts)
+		if err != nil {
+			return err
+		}
+		err = fn(conn)
+		var ce *CloseError
+		switch {
+		case err == nil, errors.As(err, &ce):
+			conn.Close()
+		default:
+			conn.CloseWithCode(CloseInternalError, "")
+		}
+		return nil
+	}
+}
+
+// Upgrade completes the handshake of a WebSocket request and takes over the
+// connection. A request that is not a valid handshake is answered with an
+// *cherry.HTTPError. The connection must be used and closed before the
+// handler returns.
+func Upgrade(ctx *cherry.Context, opts Options) (*Conn, error) {
+	r := ctx.Request()
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, cherry.NewHTTPError(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		ctx.Response().Header().Set("Sec-WebSocket-Version", "13")
+		return nil, cherry.NewHTTPError(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, cherry.NewHTTPError(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := opts.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, cherry.NewHTTPError(http.StatusForbidden, "origin not allowed")
+	}
+
+	subprotocol := negotiateSubprotocol(r.Header, opts.Subprotocols)
+	compress := opts.Compression && negotiateDeflate(r.Header)
+
+	netConn, brw, err := http.NewResponseController(ctx.Response()).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// deadlines set by the server for the request no longer apply.
+	netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	b.WriteString("\r\n")
+	if opts.WriteTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(opts.WriteTimeout))
+	}
+	if _, err := brw.WriteString(b.String()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	if opts.ReadLimit <= 0 {
+		opts.ReadLimit = defaultReadLimit
+	}
+	if opts.ReadTimeout <= 0 && opts.PingInterval > 0 {
+		opts.ReadTimeout = 2 * opts.PingInterval
+	}
+	if opts.CompressionLevel == 0 {
+		opts.CompressionLevel = flate.BestSpeed
+	}
+	return newConn(netConn, brw.Reader, subprotocol, compress, opts), nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// sameOrigin allows requests without an Origin header and requests from a
+// page of the same host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContains reports whether the comma separated values of the header
+// contain the token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// negotiateSubprotocol returns the first of the supported subprotocols the
+// client offers.
+func negotiateSubprotocol(h http.Header, supported []string) string {
+	for _, p := range supported {
+		if headerContains(h, "Sec-WebSocket-Protocol", p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// negotiateDeflate reports whether the client offers permessage-deflate
+// with parameters the server can honor. The server always responds without
+// context takeover, which every client has to accept, and with the full
+// window size, which rules out offers that limit the window of the server.
+func negotiateDeflate(h http.Header) bool {
+	for _, v := range h.Values("Sec-WebSocket-Extensions") {
+	offers:
+		for _, offer := range strings.Split(v, ",") {
+			params := strings.Split(offer, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+			for _, p := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+				switch name {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					if strings.Trim(value, `"`) != "15" {
+						continue offers
+					}
+				default:
+					continue offers
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pooulad/cherry"
+)
+
+// client is a minimal WebSocket client for the tests.
+type client struct {
+	conn net.Conn
+	br   *bufio.Reader
+	res  *http.Response
+}
+
+func dial(t *testing.T, srv *httptest.Server, header map[string]string) *client {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req, _ := http.NewRequest("GET", srv.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	req.Write(conn)
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{conn: conn, br: br, res: res}
+}
+
+func (c *client) write(op byte, rsv1 bool, payload []byte) {
+	header := []byte{0x80 | op, 0x80}
+	if rsv1 {
+		header[0] |= 0x40
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] |= byte(n)
+	case n <= 0xffff:
+		header[1] |= 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] |= 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	header = append(header, mask[:]...)
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	c.conn.Write(append(header, masked...))
+}
+
+func (c *client) read(t *testing.T) (op byte, rsv1 bool, payload []byte) {
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		t.Fatal(err)
+	}
+	op, rsv1 = b[0]&0x0f, b[0]&0x40 != 0
+	n := int(b[1] & 0x7f)
+	switch n {
+	case 126:
+		io.ReadFull(c.br, b[:2])
+		n = int(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		io.ReadFull(c.br, b[:8])
+		n = int(binary.BigEndian.Uint64(b[:8]))
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return op, rsv1, payload
+}
+
+func (c *client) expectClose(t *testing.T, code int) {
+	op, _, payload := c.read(t)
+	if op != opClose || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		t.Fatalf("expecting a close frame with %d got %d %q", code, op, payload)
+	}
+}
+
+func echoServer(t *testing.T, opts Options) *httptest.Server {
+	app := cherry.New()
+	app.Get("/ws", Handler(opts, func(conn *Conn) error {
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return err
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				return err
+			}
+		}
+	}))
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHandshake(t *testing.T) {
+	srv := echoServer(t, Options{Subprotocols: []string{"chat.v2", "chat.v1"}})
+	c := dial(t, srv, map[string]string{"Sec-WebSocket-Protocol": "chat.v1, chat.v2"})
+	if c.res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expecting 101 got %d", c.res.StatusCode)
+	}
+	if accept := c.res.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept key %s", accept)
+	}
+	if p := c.res.Header.Get("Sec-WebSocket-Protocol"); p != "chat.v2" {
+		t.Errorf("expecting chat.v2 got %q", p)
+	}
+	if ext := c.res.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Errorf("expecting no extensions got %q", ext)
+	}
+
+	// a fragmented message with a ping in between.
+	c.conn.Write([]byte{0x01, 0x83, 0, 0, 0, 0, 'h', 'e', 'l'})
+	c.write(opPing, false, []byte("p"))
+	c.conn.Write([]byte{0x80, 0x82, 0, 0, 0, 0, 'l', 'o'})
+	if op, _, payload := c.read(t); op != opPong || string(payload) != "p" {
+		t.Errorf("expecting a pong got %d %q", op, payload)
+	}
+	if op, _, payload := c.read(t); op != opText || string(payload) != "hello" {
+		t.Errorf("expecting hello got %d %q", op, payload)
+	}
+
+	c.write(opClose, false, []byte{0x03, 0xe8})
+	c.expectClose(t, CloseNormal)
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	srv := echoServer(t, Options{})
+	tests := []struct {
+		header map[string]string
+		expect int
+	}{
+		{map[string]string{"Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+		{map[string]string{"Sec-WebSocket-Key": "short"}, http.StatusBadRequest},
+		{map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{map[string]string{"Upgrade": "h2c"}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		c := dial(t, srv, test.header)
+		if c.res.StatusCode != test.expect {
+			t.Errorf("%v: expecting %d got %d", test.header, test.expect, c.res.StatusCode)
+		}
+	}
+	c := dial(t, srv, map[string]string{"Origin": srv.URL})
+	if c.res.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expecting the same origin to be allowed got %d", c.res.StatusCode)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	srv := echoServer(t, Options{Compression: true})
+	c := dial(t, srv, map[string]string{"Sec-WebSocket-Extensions": "permessage-deflate; client_max_window_bits"})
+	if ext := c.res.Header.Get("Sec-WebSocket-Extensions"); !strings.HasPrefix(ext, "permessage-deflate") {
+		t.Fatalf("expecting permessage-deflate got %q", ext)
+	}
+
+	msg := strings.Repeat("compress me ", 50)
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	fw.Write([]byte(msg))
+	fw.Flush()
+	c.write(opText, true, bytes.TrimSuffix(buf.Bytes(), deflateTail[:4]))
+
+	op, rsv1, payload := c.read(t)
+	if op != opText || !rsv1 {
+		t.Fatalf("expecting a compressed text message got %d %v", op, rsv1)
+	}
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)))
+	b, err := io.ReadAll(fr)
+	if err != nil || string(b) != msg {
+		t.Errorf("expecting the message back got %q %v", b, err)
+	}
+
+	c.write(opText, false, []byte("small"))
+	if op, rsv1, payload := c.read(t); rsv1 || string(payload) != "small" {
+		t.Errorf("expecting small messages uncompressed got %d %v %q", op, rsv1, payload)
+	}
+}
+
+func TestNegotiateDeflate(t *testing.T) {
+	tests := []struct {
+		offer  string
+		expect bool
+	}{
+		{"permessage-deflate", true},
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate", true},
+		{"permessage-deflate; server_max_window_bits=10", false},
+		{"permessage-deflate; unknown", false},
+		{"x-webkit-deflate-frame", false},
+	}
+	for _, test := range tests {
+		h := http.Header{"Sec-Websocket-Extensions": {test.offer}}
+		if got := negotiateDeflate(h); got != test.expect {
+			t.Errorf("%q: expecting %v got %v", test.offer, test.expect, got)
+		}
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	srv := echoServer(t, Options{ReadLimit: 10})
+	c := dial(t, srv, nil)
+	c.write(opBinary, false, make([]byte, 11))
+	c.expectClose(t, CloseMessageTooBig)
+}
+
+func TestProtocolErrors(t *testing.T) {
+	srv := echoServer(t, Options{})
+	tests := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		{"unmasked", []byte{0x81, 0x01, 'a'}, CloseProtocolError},
+		{"compressed without extension", []byte{0xc1, 0x81, 0, 0, 0, 0, 'a'}, CloseProtocolError},
+		{"continuation", []byte{0x80, 0x81, 0, 0, 0, 0, 'a'}, CloseProtocolError},
+		{"invalid utf-8", []byte{0x81, 0x81, 0, 0, 0, 0, 0xff}, CloseInvalidPayload},
+	}
+	for _, test := range tests {
+		c := dial(t, srv, nil)
+		c.conn.Write(test.frame)
+		op, _, payload := c.read(t)
+		if op != opClose || int(binary.BigEndian.Uint16(payload)) != test.code {
+			t.Errorf("%s: expecting a close with %d got %d %q", test.name, test.code, op, payload)
+		}
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	srv := echoServer(t, Options{PingInterval: 20 * time.Millisecond})
+	c := dial(t, srv, nil)
+	if op, _, _ := c.read(t); op != opPing {
+		t.Fatalf("expecting a ping got %d", op)
+	}
+	c.write(opPong, false, nil)
+
+	// without pongs the read timeout closes the connection.
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, err := c.br.ReadByte(); err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				t.Fatal("expecting the connection to be closed")
+			}
+			return
+		}
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	app := cherry.New()
+	app.Get("/ws", Handler(Options{}, func(conn *Conn) error {
+		conn.WriteJSON(map[string]int{"n": 1})
+		return errors.New("database is down")
+	}))
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+	c := dial(t, srv, nil)
+	if _, _, payload := c.read(t); string(payload) != `{"n":1}` {
+		t.Errorf("unexpected message %q", payload)
+	}
+	c.expectClose(t, CloseInternalError)
+}